@@ -21,15 +21,31 @@ import (
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
 
-type Resource struct {
+// CELResource is an object emitted by the CEL expander
+type CELResource struct {
 	Name       string               `json:"name"`
 	Definition runtime.RawExtension `json:"definition"`
 	Template   string               `json:"template,omitempty"`
+	// IncludeWhen are CEL expressions returning a bool. The resource is
+	// only emitted when all of them are true. With forEach, they are
+	// evaluated for each item and can use the iterator.
+	IncludeWhen []string `json:"includeWhen,omitempty"`
+	// ForEach emits the resource once for each item of a list
+	ForEach *CELForEach `json:"forEach,omitempty"`
+}
+
+// CELForEach emits a resource for each item of a list
+type CELForEach struct {
+	// Expression is a CEL expression returning the list of items
+	Expression string `json:"expression"`
+	// Iterator is the name of the variable holding the item in the
+	// expressions of the resource
+	Iterator string `json:"iterator"`
 }
 
 // CELConfigurationSpec defines the desired state of CELConfiguration
 type CELConfigurationSpec struct {
-	Resources []Resource `json:"resources"`
+	Resources []CELResource `json:"resources"`
 }
 
 // CELConfigurationStatus defines the observed state of CELConfiguration
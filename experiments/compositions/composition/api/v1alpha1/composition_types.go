@@ -17,6 +17,9 @@ limitations under the License.
 package v1alpha1
 
 import (
+	"fmt"
+	"strings"
+
 	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
@@ -34,6 +37,8 @@ const (
 	ValidationFailed ConditionType = "ValidationFailed"
 	// Waiting - Plan is waiting for values to progress
 	Waiting ConditionType = "Waiting"
+	// Warnings - One or more expanders returned warning diagnostics
+	Warnings ConditionType = "Warnings"
 )
 
 // Schema represents the attributes that define an instance of
@@ -178,11 +183,67 @@ const (
 	ValidationStatusError ValidationStatus = "error"
 )
 
+type DiagnosticSeverity string
+
+const (
+	DiagnosticSeverityError   DiagnosticSeverity = "Error"
+	DiagnosticSeverityWarning DiagnosticSeverity = "Warning"
+	DiagnosticSeverityInfo    DiagnosticSeverity = "Info"
+)
+
+// Diagnostic is a structured error, warning or info message returned by an expander
+type Diagnostic struct {
+	Severity DiagnosticSeverity `json:"severity,omitempty"`
+	// Code is a machine readable identifier for the diagnostic
+	Code    string `json:"code,omitempty"`
+	Message string `json:"message"`
+	// File, Line and Column point into the expander config
+	File   string `json:"file,omitempty"`
+	Line   int32  `json:"line,omitempty"`
+	Column int32  `json:"column,omitempty"`
+	// FieldPath is the facade field the diagnostic relates to
+	FieldPath string `json:"fieldPath,omitempty"`
+}
+
+func (d Diagnostic) String() string {
+	s := ""
+	if d.File != "" {
+		s = d.File
+		if d.Line != 0 {
+			s += fmt.Sprintf(":%d", d.Line)
+			if d.Column != 0 {
+				s += fmt.Sprintf(":%d", d.Column)
+			}
+		}
+		s += ": "
+	}
+	if d.Code != "" {
+		s += d.Code + ": "
+	}
+	s += d.Message
+	if d.FieldPath != "" {
+		s += fmt.Sprintf(" (field: %s)", d.FieldPath)
+	}
+	return s
+}
+
+// DiagnosticsMessage joins diagnostics of the given severity into a single message
+func DiagnosticsMessage(diagnostics []Diagnostic, severity DiagnosticSeverity) string {
+	messages := []string{}
+	for _, d := range diagnostics {
+		if d.Severity == severity {
+			messages = append(messages, d.String())
+		}
+	}
+	return strings.Join(messages, "; ")
+}
+
 // StageStatus captures the status of a stage
 type StageValidationStatus struct {
 	ValidationStatus ValidationStatus `json:"validationStatus,omitempty"`
 	Reason           string           `json:"reason,omitempty"`
 	Message          string           `json:"message,omitempty"`
+	Diagnostics      []Diagnostic     `json:"diagnostics,omitempty"`
 }
 
 // CompositionStatus defines the observed state of Composition
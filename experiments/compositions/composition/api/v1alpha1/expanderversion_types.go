@@ -19,6 +19,7 @@ package v1alpha1
 import (
 	"fmt"
 
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
@@ -34,6 +35,8 @@ const (
 	ExpanderTypeJob ExpanderType = "job"
 	// ExpanderTypeGRPC expect expander service to be present
 	ExpanderTypeGRPC ExpanderType = "grpc"
+	// ExpanderTypeInProc expander is compiled into the manager
+	ExpanderTypeInProc ExpanderType = "inproc"
 )
 
 type ExpanderConfigGVK struct {
@@ -60,7 +63,8 @@ type ExpanderVersionSpec struct {
 	// Type indicates what sort of expander:
 	//   job - job based expander. ephemeral
 	//   grpc - grpc service expander. persistent
-	// +kubebuilder:validation:Enum=job;grpc
+	//   inproc - expander compiled into the manager. no service needed
+	// +kubebuilder:validation:Enum=job;grpc;inproc
 	// +kubebuilder:default=job
 	Type ExpanderType `json:"type"`
 
@@ -68,10 +72,43 @@ type ExpanderVersionSpec struct {
 	Config ExpanderConfigGVK `json:"config,omitempty"`
 }
 
+type ExpanderResultType string
+
+const (
+	// ExpanderResultTypeManifests expander returns kubernetes objects
+	ExpanderResultTypeManifests ExpanderResultType = "manifests"
+	// ExpanderResultTypeValues expander returns values for later stages
+	ExpanderResultTypeValues ExpanderResultType = "values"
+)
+
+// ExpanderCapabilities is what an expander reports about itself via GetCapabilities
+type ExpanderCapabilities struct {
+	// ProtocolVersions served by the expander, for example v1 and v2
+	ProtocolVersions []string `json:"protocolVersions,omitempty"`
+
+	// Config GVK accepted in configref
+	Config *ExpanderConfigGVK `json:"config,omitempty"`
+
+	// Validate is true if the expander validates its config
+	Validate bool `json:"validate"`
+
+	// ResultType is manifests or values. Empty if not reported.
+	ResultType ExpanderResultType `json:"resultType,omitempty"`
+
+	// TemplateSchema is the schema of the inline template if the expander has one
+	// +kubebuilder:validation:Schemaless
+	// +kubebuilder:validation:Type=object
+	// +kubebuilder:pruning:PreserveUnknownFields
+	TemplateSchema *apiextensionsv1.JSONSchemaProps `json:"templateSchema,omitempty"`
+}
+
 // ExpanderVersionStatus defines the observed state of ExpanderVersion
 type ExpanderVersionStatus struct {
 	VersionMap map[string]string  `json:"versionMap,omitempty"`
 	Conditions []metav1.Condition `json:"conditions,omitempty"`
+
+	// Capabilities reported by grpc and inproc expanders keyed by the same versions as VersionMap
+	Capabilities map[string]ExpanderCapabilities `json:"capabilities,omitempty"`
 }
 
 //+kubebuilder:object:root=true
@@ -100,6 +137,26 @@ func (s *ExpanderVersionStatus) ClearCondition(condition ConditionType) {
 	meta.RemoveStatusCondition(&s.Conditions, string(condition))
 }
 
+// Capabilities returns the capabilities reported for version, if any
+func (ev *ExpanderVersion) Capabilities(version string) *ExpanderCapabilities {
+	c, ok := ev.Status.Capabilities[version]
+	if !ok {
+		return nil
+	}
+	return &c
+}
+
+// ConfigGVK returns spec.config, falling back to the GVK reported by the expander
+func (ev *ExpanderVersion) ConfigGVK(version string) ExpanderConfigGVK {
+	if ev.Spec.Config.Kind != "" {
+		return ev.Spec.Config
+	}
+	if c := ev.Capabilities(version); c != nil && c.Config != nil {
+		return *c.Config
+	}
+	return ev.Spec.Config
+}
+
 // Validation
 func (ev *ExpanderVersion) Validate() bool {
 	// Several of these validations should eventually be CEL rules on the composition CRD
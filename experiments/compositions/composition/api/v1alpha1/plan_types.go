@@ -21,12 +21,52 @@ import (
 
 	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 )
 
 type Stage struct {
+	// Manifest is a yaml stream, as written to the Plan by job expanders
 	Manifest string `json:"manifest,omitempty"`
-	Values   string `json:"values,omitempty"`
+	// Objects are returned by grpc expanders and applied as they are
+	Objects []runtime.RawExtension `json:"objects,omitempty"`
+	Values  string                 `json:"values,omitempty"`
+	// Hooks are applied in their phase instead of with the manifest
+	Hooks []Hook `json:"hooks,omitempty"`
+}
+
+// HookPhase is when the objects of a hook are applied
+type HookPhase string
+
+const (
+	HookPreInstall  HookPhase = "pre-install"
+	HookPostInstall HookPhase = "post-install"
+	HookPreUpgrade  HookPhase = "pre-upgrade"
+	HookPostUpgrade HookPhase = "post-upgrade"
+	HookPreDelete   HookPhase = "pre-delete"
+	HookPostDelete  HookPhase = "post-delete"
+)
+
+// HookDeletePolicy is when the objects of a hook are deleted
+type HookDeletePolicy string
+
+const (
+	HookBeforeHookCreation HookDeletePolicy = "before-hook-creation"
+	HookSucceeded          HookDeletePolicy = "hook-succeeded"
+	HookFailed             HookDeletePolicy = "hook-failed"
+)
+
+// Hook is a group of objects of a stage applied in one of its phases
+type Hook struct {
+	//+kubebuilder:validation:Enum=pre-install;post-install;pre-upgrade;post-upgrade;pre-delete;post-delete
+	Phase HookPhase `json:"phase"`
+	// Hooks of a phase run in order of weight
+	Weight int32 `json:"weight,omitempty"`
+	// Without delete policies, objects are deleted before the hook runs again
+	DeletePolicies []HookDeletePolicy `json:"deletePolicies,omitempty"`
+	Manifest       string             `json:"manifest,omitempty"`
+	// Objects are set instead of Manifest for hooks returned by grpc expanders
+	Objects []runtime.RawExtension `json:"objects,omitempty"`
 }
 
 // PlanSpec defines the desired state of Plan
@@ -57,6 +97,23 @@ type StageStatus struct {
 	ResourceCount int              `json:"resourceCount"`
 	AppliedCount  int              `json:"appliedCount,omitempty"`
 	LastApplied   []ResourceStatus `json:"lastApplied,omitempty"`
+	Diagnostics   []Diagnostic     `json:"diagnostics,omitempty"`
+	Hooks         *HookStatus      `json:"hooks,omitempty"`
+}
+
+// HookStatus is the progress of the hooks of a stage
+type HookStatus struct {
+	// Revision is a digest of the manifest and hooks of the stage the hooks run for
+	Revision string `json:"revision"`
+	// Upgrade is true if the stage was applied before the revision, and the
+	// upgrade hooks run instead of the install hooks
+	Upgrade bool `json:"upgrade,omitempty"`
+	// Completed are the steps that completed for the revision, by phase and
+	// weight, for example pre-upgrade/-5
+	Completed []string `json:"completed,omitempty"`
+	// Failed is the step that failed for the revision. It is not run again
+	// until the revision changes.
+	Failed string `json:"failed,omitempty"`
 }
 
 // PlanStatus defines the observed state of Plan
@@ -25,6 +25,143 @@ import (
 	"k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CELConfiguration) DeepCopyInto(out *CELConfiguration) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	out.Status = in.Status
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CELConfiguration.
+func (in *CELConfiguration) DeepCopy() *CELConfiguration {
+	if in == nil {
+		return nil
+	}
+	out := new(CELConfiguration)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *CELConfiguration) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CELConfigurationList) DeepCopyInto(out *CELConfigurationList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]CELConfiguration, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CELConfigurationList.
+func (in *CELConfigurationList) DeepCopy() *CELConfigurationList {
+	if in == nil {
+		return nil
+	}
+	out := new(CELConfigurationList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *CELConfigurationList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CELConfigurationSpec) DeepCopyInto(out *CELConfigurationSpec) {
+	*out = *in
+	if in.Resources != nil {
+		in, out := &in.Resources, &out.Resources
+		*out = make([]CELResource, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CELConfigurationSpec.
+func (in *CELConfigurationSpec) DeepCopy() *CELConfigurationSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(CELConfigurationSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CELConfigurationStatus) DeepCopyInto(out *CELConfigurationStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CELConfigurationStatus.
+func (in *CELConfigurationStatus) DeepCopy() *CELConfigurationStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(CELConfigurationStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CELForEach) DeepCopyInto(out *CELForEach) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CELForEach.
+func (in *CELForEach) DeepCopy() *CELForEach {
+	if in == nil {
+		return nil
+	}
+	out := new(CELForEach)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CELResource) DeepCopyInto(out *CELResource) {
+	*out = *in
+	in.Definition.DeepCopyInto(&out.Definition)
+	if in.IncludeWhen != nil {
+		in, out := &in.IncludeWhen, &out.IncludeWhen
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ForEach != nil {
+		in, out := &in.ForEach, &out.ForEach
+		*out = new(CELForEach)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CELResource.
+func (in *CELResource) DeepCopy() *CELResource {
+	if in == nil {
+		return nil
+	}
+	out := new(CELResource)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Composition) DeepCopyInto(out *Composition) {
 	*out = *in
@@ -130,7 +267,7 @@ func (in *CompositionStatus) DeepCopyInto(out *CompositionStatus) {
 		in, out := &in.Stages, &out.Stages
 		*out = make(map[string]StageValidationStatus, len(*in))
 		for key, val := range *in {
-			(*out)[key] = val
+			(*out)[key] = *val.DeepCopy()
 		}
 	}
 }
@@ -249,6 +386,21 @@ func (in *ContextStatus) DeepCopy() *ContextStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Diagnostic) DeepCopyInto(out *Diagnostic) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Diagnostic.
+func (in *Diagnostic) DeepCopy() *Diagnostic {
+	if in == nil {
+		return nil
+	}
+	out := new(Diagnostic)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Expander) DeepCopyInto(out *Expander) {
 	*out = *in
@@ -265,6 +417,35 @@ func (in *Expander) DeepCopy() *Expander {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ExpanderCapabilities) DeepCopyInto(out *ExpanderCapabilities) {
+	*out = *in
+	if in.ProtocolVersions != nil {
+		in, out := &in.ProtocolVersions, &out.ProtocolVersions
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Config != nil {
+		in, out := &in.Config, &out.Config
+		*out = new(ExpanderConfigGVK)
+		**out = **in
+	}
+	if in.TemplateSchema != nil {
+		in, out := &in.TemplateSchema, &out.TemplateSchema
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ExpanderCapabilities.
+func (in *ExpanderCapabilities) DeepCopy() *ExpanderCapabilities {
+	if in == nil {
+		return nil
+	}
+	out := new(ExpanderCapabilities)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ExpanderConfig) DeepCopyInto(out *ExpanderConfig) {
 	*out = *in
@@ -402,6 +583,13 @@ func (in *ExpanderVersionStatus) DeepCopyInto(out *ExpanderVersionStatus) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.Capabilities != nil {
+		in, out := &in.Capabilities, &out.Capabilities
+		*out = make(map[string]ExpanderCapabilities, len(*in))
+		for key, val := range *in {
+			(*out)[key] = *val.DeepCopy()
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ExpanderVersionStatus.
@@ -637,6 +825,53 @@ func (in *GetterConfigurationStatus) DeepCopy() *GetterConfigurationStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Hook) DeepCopyInto(out *Hook) {
+	*out = *in
+	if in.DeletePolicies != nil {
+		in, out := &in.DeletePolicies, &out.DeletePolicies
+		*out = make([]HookDeletePolicy, len(*in))
+		copy(*out, *in)
+	}
+	if in.Objects != nil {
+		in, out := &in.Objects, &out.Objects
+		*out = make([]runtime.RawExtension, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Hook.
+func (in *Hook) DeepCopy() *Hook {
+	if in == nil {
+		return nil
+	}
+	out := new(Hook)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HookStatus) DeepCopyInto(out *HookStatus) {
+	*out = *in
+	if in.Completed != nil {
+		in, out := &in.Completed, &out.Completed
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HookStatus.
+func (in *HookStatus) DeepCopy() *HookStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(HookStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Jinja2) DeepCopyInto(out *Jinja2) {
 	*out = *in
@@ -718,7 +953,7 @@ func (in *PlanSpec) DeepCopyInto(out *PlanSpec) {
 		in, out := &in.Stages, &out.Stages
 		*out = make(map[string]Stage, len(*in))
 		for key, val := range *in {
-			(*out)[key] = val
+			(*out)[key] = *val.DeepCopy()
 		}
 	}
 }
@@ -868,6 +1103,20 @@ func (in *SimpleSchema) DeepCopy() *SimpleSchema {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Stage) DeepCopyInto(out *Stage) {
 	*out = *in
+	if in.Objects != nil {
+		in, out := &in.Objects, &out.Objects
+		*out = make([]runtime.RawExtension, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Hooks != nil {
+		in, out := &in.Hooks, &out.Hooks
+		*out = make([]Hook, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Stage.
@@ -888,6 +1137,16 @@ func (in *StageStatus) DeepCopyInto(out *StageStatus) {
 		*out = make([]ResourceStatus, len(*in))
 		copy(*out, *in)
 	}
+	if in.Diagnostics != nil {
+		in, out := &in.Diagnostics, &out.Diagnostics
+		*out = make([]Diagnostic, len(*in))
+		copy(*out, *in)
+	}
+	if in.Hooks != nil {
+		in, out := &in.Hooks, &out.Hooks
+		*out = new(HookStatus)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StageStatus.
@@ -903,6 +1162,11 @@ func (in *StageStatus) DeepCopy() *StageStatus {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *StageValidationStatus) DeepCopyInto(out *StageValidationStatus) {
 	*out = *in
+	if in.Diagnostics != nil {
+		in, out := &in.Diagnostics, &out.Diagnostics
+		*out = make([]Diagnostic, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StageValidationStatus.
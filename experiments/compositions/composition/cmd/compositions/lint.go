@@ -0,0 +1,156 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	compositionv1alpha1 "github.com/cloud-native-compositions/compositions/composition/api/v1alpha1"
+	"github.com/cloud-native-compositions/compositions/composition/pkg/expanderclient"
+	"github.com/cloud-native-compositions/compositions/composition/pkg/inproc"
+	"github.com/cloud-native-compositions/compositions/composition/pkg/lint"
+	"github.com/cloud-native-compositions/compositions/composition/pkg/render"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func runLint(args []string) error {
+	fs := flag.NewFlagSet("lint", flag.ContinueOnError)
+	var expanders stringList
+	registerExpanderFlag(fs, &expanders)
+	output := fs.String("output", "text", "output format: text or json")
+	noValidate := fs.Bool("no-validate", false, "do not call the Validate rpc of the expanders")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: compositions lint [flags] <file or dir>...\n\n"+
+			"Checks the Compositions in the files. The other objects in the files are used as\n"+
+			"expander configs, and ExpanderVersions to check expander types and versions.\n\nFlags:\n")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *output != "text" && *output != "json" {
+		return fmt.Errorf("invalid --output %q, expected text or json", *output)
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return fmt.Errorf("a file or directory to lint is required")
+	}
+	uris, err := parseExpanders(expanders)
+	if err != nil {
+		return err
+	}
+
+	documents := []*lint.Document{}
+	for _, arg := range fs.Args() {
+		files, err := yamlFiles(arg)
+		if err != nil {
+			return err
+		}
+		for _, file := range files {
+			docs, err := lint.ReadFile(file)
+			if err != nil {
+				return err
+			}
+			documents = append(documents, docs...)
+		}
+	}
+
+	compositions := []*compositionv1alpha1.Composition{}
+	opts := lint.Options{}
+	for _, doc := range documents {
+		switch doc.Object.GetKind() {
+		case "Composition":
+			c, err := render.DecodeComposition(doc.Object)
+			if err != nil {
+				return fmt.Errorf("parsing Composition in %s: %w", doc.File, err)
+			}
+			compositions = append(compositions, c)
+		case "ExpanderVersion":
+			ev := &compositionv1alpha1.ExpanderVersion{}
+			if err := runtime.DefaultUnstructuredConverter.FromUnstructured(doc.Object.Object, ev); err != nil {
+				return fmt.Errorf("parsing ExpanderVersion in %s: %w", doc.File, err)
+			}
+			opts.ExpanderVersions = append(opts.ExpanderVersions, ev)
+		default:
+			opts.Configs = append(opts.Configs, doc.Object)
+		}
+	}
+	if len(compositions) == 0 {
+		return fmt.Errorf("no Composition found")
+	}
+	if !*noValidate {
+		opts.Client = func(expanderType, version string) (*expanderclient.Client, error) {
+			if uri, ok := uris[expanderType]; ok {
+				return expanderclient.New(uri)
+			}
+			if _, ok := inproc.Get(expanderType); ok {
+				return expanderclient.New(inproc.URI(expanderType))
+			}
+			return nil, nil
+		}
+	}
+
+	findings := []lint.Finding{}
+	for _, c := range compositions {
+		findings = append(findings, lint.Composition(context.Background(), c, opts)...)
+	}
+	lint.Locate(findings, documents)
+	lint.Sort(findings)
+
+	if *output == "json" {
+		b, err := json.MarshalIndent(struct {
+			Findings []lint.Finding `json:"findings"`
+		}{findings}, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(os.Stdout, string(b))
+	} else {
+		for _, f := range findings {
+			fmt.Fprintln(os.Stdout, f.String())
+		}
+	}
+	if lint.HasErrors(findings) {
+		return fmt.Errorf("lint found errors")
+	}
+	return nil
+}
+
+// yamlFiles returns path, or the .yaml and .yml files under path if it is a directory
+func yamlFiles(path string) ([]string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return []string{path}, nil
+	}
+	files := []string{}
+	err = filepath.WalkDir(path, func(p string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if ext := filepath.Ext(p); !d.IsDir() && (ext == ".yaml" || ext == ".yml") {
+			files = append(files, p)
+		}
+		return nil
+	})
+	return files, err
+}
@@ -0,0 +1,204 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	celexpander "github.com/cloud-native-compositions/compositions/composition/pkg/expanders/cel"
+	"github.com/cloud-native-compositions/compositions/composition/pkg/expandersdk"
+	"github.com/cloud-native-compositions/compositions/composition/pkg/inproc"
+	"github.com/cloud-native-compositions/compositions/composition/pkg/render"
+	pbv2 "github.com/cloud-native-compositions/compositions/composition/proto/v2"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+)
+
+func init() {
+	// The getter is provided by the renderer, reading the rendered objects
+	inproc.Register("cel", expandersdk.NewServer(celexpander.New()).V2())
+}
+
+// renderFlags are the inputs shared by the commands that render compositions
+type renderFlags struct {
+	composition string
+	facade      string
+	facadeCRD   string
+	context     string
+	configs     stringList
+	objects     stringList
+	status      stringList
+	expanders   stringList
+	kubeVersion string
+	apiVersions stringList
+}
+
+func (f *renderFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&f.composition, "composition", "", "Composition YAML file. Other objects in the file are used as expander configs.")
+	fs.StringVar(&f.facade, "facade", "", "facade instance YAML file")
+	fs.StringVar(&f.facadeCRD, "facade-crd", "", "CRD of the facade YAML file, for the plural of the facade (optional)")
+	fs.StringVar(&f.context, "context", "", "Context YAML file (optional)")
+	fs.Var(&f.configs, "config", "expander config CRs referenced by configref. May be repeated.")
+	fs.Var(&f.objects, "objects", "objects that exist in the cluster, read by the getter. May be repeated.")
+	fs.Var(&f.status, "stub-status", "objects whose status replaces the status of matching rendered objects. May be repeated.")
+	fs.StringVar(&f.kubeVersion, "kube-version", "", "server version sent to the expanders, for example v1.30.2 (optional)")
+	fs.Var(&f.apiVersions, "api-versions", "API version sent to the expanders, for example apps/v1 or apps/v1/Deployment. May be repeated.")
+	registerExpanderFlag(fs, &f.expanders)
+}
+
+func registerExpanderFlag(fs *flag.FlagSet, expanders *stringList) {
+	fs.Var(expanders, "expander", "grpc address of an expander as <type>=<host:port>. May be repeated.\n"+
+		"cel and getter run in-process unless overridden.")
+}
+
+func (f *renderFlags) options() (render.Options, error) {
+	opts := render.Options{
+		Logger: zap.New(zap.WriteTo(io.Discard)),
+	}
+	var err error
+	if f.composition == "" || f.facade == "" {
+		return opts, fmt.Errorf("--composition and --facade are required")
+	}
+	// Expander configs may be kept in the file of the composition
+	if opts.Composition, opts.Configs, err = render.ReadComposition(f.composition); err != nil {
+		return opts, err
+	}
+	if opts.Facade, err = render.ReadObject(f.facade); err != nil {
+		return opts, err
+	}
+	if f.facadeCRD != "" {
+		if opts.FacadeCRD, err = render.ReadObject(f.facadeCRD); err != nil {
+			return opts, err
+		}
+	}
+	if f.context != "" {
+		if opts.Context, err = render.ReadObject(f.context); err != nil {
+			return opts, err
+		}
+	}
+	for _, l := range []struct {
+		files stringList
+		into  *[]*unstructured.Unstructured
+	}{
+		{f.configs, &opts.Configs},
+		{f.objects, &opts.Objects},
+		{f.status, &opts.Status},
+	} {
+		for _, path := range l.files {
+			objects, err := render.ReadObjects(path)
+			if err != nil {
+				return opts, err
+			}
+			*l.into = append(*l.into, objects...)
+		}
+	}
+	if f.kubeVersion != "" || len(f.apiVersions) != 0 {
+		opts.Cluster = &pbv2.Cluster{
+			KubeVersion: f.kubeVersion,
+			ApiVersions: f.apiVersions,
+		}
+	}
+	opts.Expanders, err = parseExpanders(f.expanders)
+	return opts, err
+}
+
+// parseExpanders parses the --expander flags
+func parseExpanders(flags stringList) (map[string]string, error) {
+	expanders := map[string]string{}
+	for _, e := range flags {
+		expanderType, uri, ok := strings.Cut(e, "=")
+		if !ok || expanderType == "" || uri == "" {
+			return nil, fmt.Errorf("invalid --expander %q, expected <type>=<host:port>", e)
+		}
+		expanders[expanderType] = uri
+	}
+	return expanders, nil
+}
+
+func runRender(args []string) error {
+	fs := flag.NewFlagSet("render", flag.ContinueOnError)
+	f := &renderFlags{}
+	f.register(fs)
+	outputDir := fs.String("output-dir", "", "write the output of each stage to <dir>/<stage>.yaml instead of stdout")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: compositions render --composition <file> --facade <file> [flags]\n\n"+
+			"Runs the stages of the composition in order and prints the manifests of each stage.\n"+
+			"Objects are assumed ready once rendered. Use --stub-status to set the status later stages read.\n\nFlags:\n")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	opts, err := f.options()
+	if err != nil {
+		return err
+	}
+
+	stages, renderErr := render.Render(context.Background(), opts)
+	for _, stage := range stages {
+		for _, d := range stage.Diagnostics {
+			fmt.Fprintf(os.Stderr, "%s: stage %s: %s\n", d.Severity, stage.Name, d.String())
+		}
+		if *outputDir != "" {
+			if err := writeStage(*outputDir, stage); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := printStage(os.Stdout, stage); err != nil {
+			return err
+		}
+	}
+	return renderErr
+}
+
+func writeStage(dir string, stage *render.Stage) error {
+	b, err := stage.YAML()
+	if err != nil {
+		return fmt.Errorf("stage %s: %w", stage.Name, err)
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	b = append(b, stage.HooksYAML()...)
+	return os.WriteFile(filepath.Join(dir, stage.Name+".yaml"), b, 0644)
+}
+
+func printStage(w io.Writer, stage *render.Stage) error {
+	b, err := stage.YAML()
+	if err != nil {
+		return fmt.Errorf("stage %s: %w", stage.Name, err)
+	}
+	if stage.Values == nil {
+		_, err = fmt.Fprintf(w, "# Stage: %s (%s)\n%s%s", stage.Name, stage.Type, b, stage.HooksYAML())
+		return err
+	}
+	// Values are not manifests, print them as comments
+	if _, err := fmt.Fprintf(w, "# Stage: %s (%s) values:\n", stage.Name, stage.Type); err != nil {
+		return err
+	}
+	for _, line := range strings.Split(strings.TrimSuffix(string(b), "\n"), "\n") {
+		if _, err := fmt.Fprintf(w, "#   %s\n", line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,136 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/cloud-native-compositions/compositions/composition/pkg/crds"
+	"sigs.k8s.io/yaml"
+)
+
+const valuesSchemaFile = "values.schema.json"
+
+func runSchema(args []string) error {
+	fs := flag.NewFlagSet("schema", flag.ContinueOnError)
+	path := fs.String("path", "", "dot separated path of the facade spec in the values, for example sqls.spec")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: compositions schema [flags] <file>\n\n"+
+			"Prints the SimpleSchema of a facade spec derived from a values schema, for spec.schema.spec\n"+
+			"of a Composition. The file is a JSON schema, a HelmConfiguration with spec.valuesSchema,\n"+
+			"a chart directory or a packaged chart with a "+valuesSchemaFile+".\n\nFlags:\n")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return fmt.Errorf("a values schema file is required")
+	}
+
+	schema, err := readValuesSchema(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	if *path != "" {
+		for _, name := range strings.Split(*path, ".") {
+			properties, _ := schema["properties"].(map[string]interface{})
+			if schema, _ = properties[name].(map[string]interface{}); schema == nil {
+				return fmt.Errorf("the values schema has no properties.%s for --path %s", name, *path)
+			}
+		}
+	}
+	spec, skipped, err := crds.SimpleSchemaFromJSONSchema(schema)
+	if err != nil {
+		return err
+	}
+	for _, s := range skipped {
+		fmt.Fprintf(os.Stderr, "skipped %s\n", s)
+	}
+	out, err := yaml.Marshal(spec)
+	if err != nil {
+		return err
+	}
+	_, err = os.Stdout.Write(out)
+	return err
+}
+
+// readValuesSchema reads the values schema of a schema file, a
+// HelmConfiguration, a chart directory or a packaged chart
+func readValuesSchema(path string) (map[string]interface{}, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	var raw []byte
+	switch {
+	case info.IsDir():
+		raw, err = os.ReadFile(filepath.Join(path, valuesSchemaFile))
+	case strings.HasSuffix(path, ".tgz") || strings.HasSuffix(path, ".tar.gz"):
+		raw, err = readArchiveValuesSchema(path)
+	default:
+		raw, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	schema := map[string]interface{}{}
+	if err := yaml.Unmarshal(raw, &schema); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	if schema["kind"] == "HelmConfiguration" {
+		spec, _ := schema["spec"].(map[string]interface{})
+		if schema, _ = spec["valuesSchema"].(map[string]interface{}); schema == nil {
+			return nil, fmt.Errorf("%s has no spec.valuesSchema", path)
+		}
+	}
+	return schema, nil
+}
+
+// readArchiveValuesSchema reads the values schema of the chart in a packaged
+// chart, not the ones of its subcharts
+func readArchiveValuesSchema(path string) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	tr := tar.NewReader(gz)
+	for {
+		h, err := tr.Next()
+		if err == io.EOF {
+			return nil, fmt.Errorf("%s has no %s", path, valuesSchemaFile)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", path, err)
+		}
+		if parts := strings.Split(h.Name, "/"); len(parts) == 2 && parts[1] == valuesSchemaFile {
+			return io.ReadAll(tr)
+		}
+	}
+}
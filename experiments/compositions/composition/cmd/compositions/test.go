@@ -0,0 +1,103 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/cloud-native-compositions/compositions/composition/pkg/compositiontest"
+	"github.com/cloud-native-compositions/compositions/composition/pkg/render"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+)
+
+func runTest(args []string) error {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	composition := fs.String("composition", "", "Composition YAML file (default <dir>/composition.yaml)")
+	var configs, expanders stringList
+	fs.Var(&configs, "config", "expander config CRs in addition to the ones in the composition file. May be repeated.")
+	registerExpanderFlag(fs, &expanders)
+	update := fs.Bool("update", false, "rewrite the expected files with the rendered output")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: compositions test [flags] <dir>...\n\n"+
+			"Renders each case in <dir> and compares the stages with <case>/expected/<stage>.yaml.\n"+
+			"A case is a directory with facade.yaml and optional context.yaml, objects.yaml and status.yaml.\n\nFlags:\n")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return fmt.Errorf("a test directory is required")
+	}
+
+	opts := compositiontest.Options{
+		Composition: *composition,
+		Update:      *update,
+		Logger:      zap.New(zap.WriteTo(io.Discard)),
+	}
+	var err error
+	if opts.Expanders, err = parseExpanders(expanders); err != nil {
+		return err
+	}
+	for _, path := range configs {
+		objects, err := render.ReadObjects(path)
+		if err != nil {
+			return err
+		}
+		opts.Configs = append(opts.Configs, objects...)
+	}
+
+	failed := 0
+	for _, dir := range fs.Args() {
+		results, err := compositiontest.Run(context.Background(), dir, opts)
+		for _, result := range results {
+			printResult(os.Stdout, result)
+			if !result.Passed() {
+				failed++
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if failed > 0 {
+		fmt.Fprintln(os.Stdout, "FAIL")
+		return fmt.Errorf("%d test case(s) failed", failed)
+	}
+	fmt.Fprintln(os.Stdout, "PASS")
+	return nil
+}
+
+func printResult(w io.Writer, result *compositiontest.Result) {
+	if result.Passed() {
+		fmt.Fprintf(w, "--- PASS: %s\n", result.Dir)
+	} else {
+		fmt.Fprintf(w, "--- FAIL: %s\n", result.Dir)
+	}
+	for _, failure := range result.Failures {
+		for _, line := range strings.Split(strings.TrimSuffix(failure, "\n"), "\n") {
+			fmt.Fprintf(w, "    %s\n", line)
+		}
+	}
+	for _, file := range result.Updated {
+		fmt.Fprintf(w, "    updated %s\n", file)
+	}
+}
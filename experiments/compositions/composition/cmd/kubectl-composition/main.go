@@ -0,0 +1,212 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// kubectl-composition is a kubectl plugin that shows how a facade instance was
+// expanded: its Composition, Plan, stages and applied resources.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	compositionv1alpha1 "github.com/cloud-native-compositions/compositions/composition/api/v1alpha1"
+	"github.com/cloud-native-compositions/compositions/composition/pkg/inspect"
+	"golang.org/x/term"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/clientcmd"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+type command struct {
+	name    string
+	summary string
+	run     func(ctx context.Context, k *kubeFlags, args []string) error
+	flags   func(fs *flag.FlagSet)
+}
+
+var color = "auto"
+
+var commands = []command{
+	{name: "status", summary: "Show the composition, stages and resources of a facade", run: runStatus},
+	{name: "tree", summary: "Show the facade, plan, stages and resources as a tree", run: runTree, flags: func(fs *flag.FlagSet) {
+		fs.StringVar(&color, "color", color, "colorize the tree: auto, always or never")
+	}},
+	{name: "events", summary: "Show the events of a facade, its plan and applied resources", run: runEvents},
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage: kubectl composition <command> <type>/<name> [flags]\n\nCommands:\n")
+	for _, c := range commands {
+		fmt.Fprintf(os.Stderr, "  %-8s %s\n", c.name, c.summary)
+	}
+	fmt.Fprintf(os.Stderr, "\nRun 'kubectl composition <command> -h' for the flags of a command.\n")
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+	for _, c := range commands {
+		if c.name != os.Args[1] {
+			continue
+		}
+		fs := flag.NewFlagSet(c.name, flag.ContinueOnError)
+		k := registerKubeFlags(fs)
+		if c.flags != nil {
+			c.flags(fs)
+		}
+		fs.Usage = func() {
+			fmt.Fprintf(fs.Output(), "Usage: kubectl composition %s <type>/<name> [flags]\n\n%s.\n"+
+				"<type> is the facade resource, kind or resource.group, like in kubectl get.\n\nFlags:\n", c.name, c.summary)
+			fs.PrintDefaults()
+		}
+		args, err := parseInterspersed(fs, os.Args[2:])
+		if err == nil {
+			err = c.run(context.Background(), k, args)
+		}
+		if err != nil {
+			if err != flag.ErrHelp {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			}
+			os.Exit(1)
+		}
+		return
+	}
+	usage()
+	os.Exit(2)
+}
+
+// parseInterspersed parses flags before and after the positional arguments,
+// since kubectl users write the flags after the object.
+func parseInterspersed(fs *flag.FlagSet, args []string) ([]string, error) {
+	positional := []string{}
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			return positional, nil
+		}
+		if args[0] == "--" {
+			return append(positional, args[1:]...), nil
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+// kubeFlags are the kubectl flags the plugin supports
+type kubeFlags struct {
+	kubeconfig string
+	context    string
+	namespace  string
+}
+
+func registerKubeFlags(fs *flag.FlagSet) *kubeFlags {
+	k := &kubeFlags{}
+	fs.StringVar(&k.kubeconfig, "kubeconfig", "", "path to the kubeconfig file")
+	fs.StringVar(&k.context, "context", "", "kubeconfig context to use")
+	fs.StringVar(&k.namespace, "namespace", "", "namespace of the facade (default from kubeconfig)")
+	fs.StringVar(&k.namespace, "n", "", "shorthand for --namespace")
+	return k
+}
+
+// client returns a client for the cluster and the namespace to use
+func (k *kubeFlags) client() (client.Client, string, error) {
+	rules := clientcmd.NewDefaultClientConfigLoadingRules()
+	rules.ExplicitPath = k.kubeconfig
+	overrides := &clientcmd.ConfigOverrides{CurrentContext: k.context}
+	overrides.Context.Namespace = k.namespace
+	config := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(rules, overrides)
+
+	namespace, _, err := config.Namespace()
+	if err != nil {
+		return nil, "", err
+	}
+	restConfig, err := config.ClientConfig()
+	if err != nil {
+		return nil, "", err
+	}
+
+	scheme := runtime.NewScheme()
+	if err := compositionv1alpha1.AddToScheme(scheme); err != nil {
+		return nil, "", err
+	}
+	if err := corev1.AddToScheme(scheme); err != nil {
+		return nil, "", err
+	}
+	c, err := client.New(restConfig, client.Options{Scheme: scheme})
+	if err != nil {
+		return nil, "", err
+	}
+	return c, namespace, nil
+}
+
+func inspectFacade(ctx context.Context, k *kubeFlags, args []string) (client.Client, *inspect.Report, error) {
+	gr, name, err := inspect.ParseFacadeArgs(args)
+	if err != nil {
+		return nil, nil, err
+	}
+	c, namespace, err := k.client()
+	if err != nil {
+		return nil, nil, err
+	}
+	report, err := inspect.Inspect(ctx, c, gr, namespace, name)
+	return c, report, err
+}
+
+func runStatus(ctx context.Context, k *kubeFlags, args []string) error {
+	_, report, err := inspectFacade(ctx, k, args)
+	if err != nil {
+		return err
+	}
+	return inspect.PrintStatus(os.Stdout, report)
+}
+
+func runTree(ctx context.Context, k *kubeFlags, args []string) error {
+	useColor := false
+	switch color {
+	case "always":
+		useColor = true
+	case "auto":
+		useColor = term.IsTerminal(int(os.Stdout.Fd())) && os.Getenv("NO_COLOR") == ""
+	case "never":
+	default:
+		return fmt.Errorf("--color must be auto, always or never, got %q", color)
+	}
+	_, report, err := inspectFacade(ctx, k, args)
+	if err != nil {
+		return err
+	}
+	inspect.PrintTree(os.Stdout, report, useColor)
+	return nil
+}
+
+func runEvents(ctx context.Context, k *kubeFlags, args []string) error {
+	c, report, err := inspectFacade(ctx, k, args)
+	if err != nil {
+		return err
+	}
+	events, err := inspect.Events(ctx, c, report)
+	if err != nil {
+		return err
+	}
+	return inspect.PrintEvents(os.Stdout, events, time.Now())
+}
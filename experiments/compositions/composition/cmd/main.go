@@ -17,8 +17,11 @@ limitations under the License.
 package main
 
 import (
+	"context"
 	"flag"
 	"os"
+	"path/filepath"
+	"time"
 
 	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
 	// to ensure that exec-entrypoint and run can make use of them.
@@ -29,14 +32,23 @@ import (
 	extv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/dynamic"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
 
 	compositionv1alpha1 "github.com/cloud-native-compositions/compositions/composition/api/v1alpha1"
 	"github.com/cloud-native-compositions/compositions/composition/internal/controller"
+	webhookcompositionv1alpha1 "github.com/cloud-native-compositions/compositions/composition/internal/webhook/v1alpha1"
+	celexpander "github.com/cloud-native-compositions/compositions/composition/pkg/expanders/cel"
+	"github.com/cloud-native-compositions/compositions/composition/pkg/expanders/getter"
+	"github.com/cloud-native-compositions/compositions/composition/pkg/expandersdk"
+	"github.com/cloud-native-compositions/compositions/composition/pkg/inproc"
+	"github.com/cloud-native-compositions/compositions/composition/pkg/webhookcert"
 	//+kubebuilder:scaffold:imports
 )
 
@@ -45,6 +57,17 @@ var (
 	setupLog = ctrl.Log.WithName("setup")
 )
 
+// Names of the webhook objects in config/webhook after the kustomize namePrefix.
+// The facade webhook configuration is created at runtime.
+const (
+	webhookServiceName       = "composition-webhook-service"
+	webhookSecretName        = "composition-webhook-server-cert"
+	validatingWebhookConfig  = "composition-validating-webhook-configuration"
+	facadeWebhookConfig      = "composition-facade-validating-webhook-configuration"
+	defaultManagerNamespace  = "composition-system"
+	expanderVersionNamespace = "composition-system"
+)
+
 func init() {
 	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
 
@@ -59,11 +82,26 @@ func main() {
 	var metricsAddr string
 	var enableLeaderElection bool
 	var probeAddr string
+	var enableWebhooks bool
+	var webhookCertDir string
+	var webhookValidateTimeout time.Duration
+	var facadeWebhookPolicy string
+	var facadeWebhookRenderTimeout time.Duration
 	flag.StringVar(&metricsAddr, "metrics-bind-address", ":8080", "The address the metric endpoint binds to.")
 	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
 	flag.BoolVar(&enableLeaderElection, "leader-elect", false,
 		"Enable leader election for controller manager. "+
 			"Enabling this will ensure there is only one active controller manager.")
+	flag.BoolVar(&enableWebhooks, "enable-webhooks", false,
+		"Serve the admission webhooks. The serving certificate is generated and kept in the "+webhookSecretName+" Secret.")
+	flag.StringVar(&webhookCertDir, "webhook-cert-dir", filepath.Join(os.TempDir(), "k8s-webhook-server", "serving-certs"),
+		"The directory the webhook serving certificate is written to.")
+	flag.DurationVar(&webhookValidateTimeout, "webhook-validate-timeout", 2*time.Second,
+		"Timeout of the expander Validate calls made by the Composition webhook. 0 disables the calls.")
+	flag.StringVar(&facadeWebhookPolicy, "facade-webhook-policy", string(webhookcompositionv1alpha1.FacadePolicyWarn),
+		"What the facade webhook does with facades of a Composition that is not Ready: warn or deny.")
+	flag.DurationVar(&facadeWebhookRenderTimeout, "facade-webhook-render-timeout", 0,
+		"Timeout of the render of the first stage of a facade by the facade webhook. Facades failing to render are rejected. 0 disables the render check.")
 	opts := zap.Options{
 		Development: true,
 	}
@@ -76,6 +114,7 @@ func main() {
 		Scheme:                 scheme,
 		Metrics:                metricsserver.Options{BindAddress: metricsAddr},
 		HealthProbeBindAddress: probeAddr,
+		WebhookServer:          webhook.NewServer(webhook.Options{CertDir: webhookCertDir}),
 		LeaderElection:         enableLeaderElection,
 		LeaderElectionID:       "60e39cac.google.com",
 		// LeaderElectionReleaseOnCancel defines if the leader should step down voluntarily
@@ -95,6 +134,16 @@ func main() {
 		os.Exit(1)
 	}
 
+	// Expanders compiled into the manager, used by ExpanderVersions of type inproc
+	dynamicClient, err := dynamic.NewForConfig(mgr.GetConfig())
+	if err != nil {
+		setupLog.Error(err, "unable to create dynamic client")
+		os.Exit(1)
+	}
+	inproc.Register("cel", expandersdk.NewServer(celexpander.New()).V2())
+	inproc.Register("getter", expandersdk.NewServer(getter.New(dynamicClient)).V2())
+	setupLog.Info("registered inproc expanders", "expanders", inproc.Names())
+
 	if err = (&controller.CompositionReconciler{
 		Client:   mgr.GetClient(),
 		Scheme:   mgr.GetScheme(),
@@ -140,6 +189,14 @@ func main() {
 	}
 	//+kubebuilder:scaffold:builder
 
+	if enableWebhooks {
+		if err := setupWebhooks(mgr, webhookCertDir, webhookValidateTimeout,
+			webhookcompositionv1alpha1.FacadePolicy(facadeWebhookPolicy), facadeWebhookRenderTimeout); err != nil {
+			setupLog.Error(err, "unable to set up webhooks")
+			os.Exit(1)
+		}
+	}
+
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
 		setupLog.Error(err, "unable to set up health check")
 		os.Exit(1)
@@ -155,3 +212,56 @@ func main() {
 		os.Exit(1)
 	}
 }
+
+// setupWebhooks provisions the serving certificate, registers the webhooks and
+// sets up the controller keeping the facade webhook configuration
+func setupWebhooks(mgr ctrl.Manager, certDir string, validateTimeout time.Duration,
+	facadePolicy webhookcompositionv1alpha1.FacadePolicy, facadeRenderTimeout time.Duration) error {
+	namespace := os.Getenv("POD_NAMESPACE")
+	if namespace == "" {
+		namespace = defaultManagerNamespace
+	}
+	// The manager's client is not usable before it starts
+	c, err := client.New(mgr.GetConfig(), client.Options{Scheme: mgr.GetScheme()})
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	ca, err := webhookcert.Ensure(ctx, webhookcert.Options{
+		Client:                c,
+		Namespace:             namespace,
+		SecretName:            webhookSecretName,
+		ServiceName:           webhookServiceName,
+		CertDir:               certDir,
+		WebhookConfigurations: []string{validatingWebhookConfig, facadeWebhookConfig},
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := webhookcompositionv1alpha1.SetupCompositionWebhookWithManager(mgr, &webhookcompositionv1alpha1.CompositionCustomValidator{
+		Client:            mgr.GetAPIReader(),
+		ExpanderNamespace: expanderVersionNamespace,
+		ValidateTimeout:   validateTimeout,
+	}); err != nil {
+		return err
+	}
+	if err := webhookcompositionv1alpha1.SetupFacadeWebhookWithManager(mgr, &webhookcompositionv1alpha1.FacadeValidator{
+		Client:            mgr.GetAPIReader(),
+		ExpanderNamespace: expanderVersionNamespace,
+		Policy:            facadePolicy,
+		RenderTimeout:     facadeRenderTimeout,
+	}); err != nil {
+		return err
+	}
+	return (&controller.FacadeWebhookReconciler{
+		Client:           mgr.GetClient(),
+		Scheme:           mgr.GetScheme(),
+		Name:             facadeWebhookConfig,
+		ServiceName:      webhookServiceName,
+		ServiceNamespace: namespace,
+		Path:             webhookcompositionv1alpha1.FacadeWebhookPath,
+		CABundle:         ca,
+	}).SetupWithManager(mgr)
+}
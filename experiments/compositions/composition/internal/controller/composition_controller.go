@@ -25,22 +25,21 @@ import (
 	"strings"
 	"sync"
 
-	"github.com/awslabs/kro/pkg/simpleschema"
 	compositionv1alpha1 "github.com/cloud-native-compositions/compositions/composition/api/v1alpha1"
 	"github.com/cloud-native-compositions/compositions/composition/pkg/crds"
-	pb "github.com/cloud-native-compositions/compositions/composition/proto"
+	"github.com/cloud-native-compositions/compositions/composition/pkg/expanderclient"
+	pbv2 "github.com/cloud-native-compositions/compositions/composition/proto/v2"
 	"github.com/go-logr/logr"
-	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials/insecure"
 	corev1 "k8s.io/api/core/v1"
 	extv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
-	"k8s.io/apimachinery/pkg/util/yaml"
+	"k8s.io/client-go/discovery"
 	"k8s.io/client-go/tools/record"
 	"k8s.io/client-go/util/retry"
 	ctrl "sigs.k8s.io/controller-runtime"
@@ -59,6 +58,8 @@ type CompositionReconciler struct {
 	Recorder        record.EventRecorder
 	mgr             ctrl.Manager
 	handoffChannels map[schema.GroupVersionKind]chan event.GenericEvent
+	// cluster is shared by the facade reconcilers
+	cluster *expanderclient.Cluster
 }
 
 // TODO: To simplify preview for customers, grant superuser to the composition controller. This should be revisited going forward.
@@ -118,6 +119,9 @@ func (r *CompositionReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 		}
 	}()
 
+	// Summarize the conditions in Ready. Runs before the status update above.
+	defer setReadyCondition(&composition)
+
 	logger = logger.WithName(composition.Name).WithName(fmt.Sprintf("%d", composition.Generation))
 
 	composition.Status.ClearCondition(compositionv1alpha1.Error)
@@ -146,9 +150,11 @@ func (r *CompositionReconciler) validateExpanders(
 	ctx context.Context, logger logr.Logger, c *compositionv1alpha1.Composition,
 ) error {
 	errorStages := []string{}
+	warningStages := []string{}
 	if c.Status.Stages == nil {
 		c.Status.Stages = make(map[string]compositionv1alpha1.StageValidationStatus)
 	}
+	resource, facadeSchema := r.facadeSchema(ctx, logger, c)
 	for _, expander := range c.Spec.Expanders {
 		uri, ev, reason, err := r.getExpanderValue(ctx, expander.Version, expander.Type)
 		if err != nil {
@@ -165,14 +171,20 @@ func (r *CompositionReconciler) validateExpanders(
 		logger.Info("Got valid expander uri", "uri", uri)
 
 		// We dont have validate for Job type expander
-		if ev.Spec.Type != compositionv1alpha1.ExpanderTypeGRPC {
+		if ev.Spec.Type == compositionv1alpha1.ExpanderTypeJob {
 			c.Status.Stages[expander.Name] = compositionv1alpha1.StageValidationStatus{
 				ValidationStatus: compositionv1alpha1.ValidationStatusUnknown,
 				Message:          "expander type does not implement validation",
 				Reason:           "NoValidationSupport",
 			}
+		} else if capabilities := ev.Capabilities(expander.Version); capabilities != nil && !capabilities.Validate {
+			c.Status.Stages[expander.Name] = compositionv1alpha1.StageValidationStatus{
+				ValidationStatus: compositionv1alpha1.ValidationStatusUnknown,
+				Message:          "expander reports that it does not implement validation",
+				Reason:           "NoValidationSupport",
+			}
 		} else {
-			reason, err := r.validateExpanderConfig(ctx, logger, expander, ev, uri)
+			diagnostics, reason, err := r.validateExpanderConfig(ctx, logger, expander, ev, uri, resource, facadeSchema)
 			if err != nil {
 				logger.Error(err, "Validating config failed", "type", expander.Type, "version", expander.Version)
 				errorStages = append(errorStages, expander.Name)
@@ -180,6 +192,7 @@ func (r *CompositionReconciler) validateExpanders(
 					ValidationStatus: compositionv1alpha1.ValidationStatusFailed,
 					Reason:           reason,
 					Message:          err.Error(),
+					Diagnostics:      diagnostics,
 				}
 				// Try the next expander
 				continue
@@ -189,10 +202,27 @@ func (r *CompositionReconciler) validateExpanders(
 				ValidationStatus: compositionv1alpha1.ValidationStatusSuccess,
 				Reason:           "ValidationPassed",
 				Message:          "",
+				Diagnostics:      diagnostics,
+			}
+			if warnings := compositionv1alpha1.DiagnosticsMessage(diagnostics, compositionv1alpha1.DiagnosticSeverityWarning); warnings != "" {
+				warningStages = append(warningStages, fmt.Sprintf("%s: %s", expander.Name, warnings))
 			}
 		}
 	}
 
+	c.Status.ClearCondition(compositionv1alpha1.Warnings)
+	if len(warningStages) != 0 {
+		message := fmt.Sprintf("Validation returned warnings for stages: %s", strings.Join(warningStages, ", "))
+		c.Status.Conditions = append(c.Status.Conditions, metav1.Condition{
+			LastTransitionTime: metav1.Now(),
+			Message:            message,
+			Reason:             "ValidationWarnings",
+			Type:               string(compositionv1alpha1.Warnings),
+			Status:             metav1.ConditionTrue,
+		})
+		r.Recorder.Event(c, "Warning", "ValidationWarnings", message)
+	}
+
 	if len(errorStages) != 0 {
 		message := fmt.Sprintf("Validating failed for stages: %s", strings.Join(errorStages, ", "))
 		c.Status.Conditions = append(c.Status.Conditions, metav1.Condition{
@@ -208,34 +238,87 @@ func (r *CompositionReconciler) validateExpanders(
 
 	return nil
 }
+
+// facadeSchema returns the resource name and the JSON encoded OpenAPI v3
+// schema of the facade, for expanders to check their config against. The
+// schema is nil if the facade CRD is not known yet.
+func (r *CompositionReconciler) facadeSchema(ctx context.Context, logger logr.Logger,
+	c *compositionv1alpha1.Composition,
+) (string, []byte) {
+	resource, facadeSchema, err := crds.FacadeSchema(c)
+	if err != nil {
+		// Reported when creating the facade CRD
+		logger.Error(err, "failed to build OpenAPI schema for instance")
+		return "", nil
+	}
+	if c.Spec.InputAPIGroup != "" {
+		var crd extv1.CustomResourceDefinition
+		if err := r.Client.Get(ctx, types.NamespacedName{Name: c.Spec.InputAPIGroup}, &crd); err != nil {
+			logger.Info("Validating without the facade schema", "crd", c.Spec.InputAPIGroup, "error", err.Error())
+			return "", nil
+		}
+		resource = crd.Spec.Names.Plural
+		if version := storageVersion(&crd); version != nil && version.Schema != nil {
+			facadeSchema = version.Schema.OpenAPIV3Schema
+		}
+	}
+	if facadeSchema == nil {
+		return resource, nil
+	}
+	schemaBytes, err := json.Marshal(facadeSchema)
+	if err != nil {
+		logger.Error(err, "failed to marshal the facade schema")
+		return resource, nil
+	}
+	return resource, schemaBytes
+}
+
+// storageVersion returns the version of the CRD facades are stored in, or the
+// first version if none is marked as the storage version
+func storageVersion(crd *extv1.CustomResourceDefinition) *extv1.CustomResourceDefinitionVersion {
+	for i := range crd.Spec.Versions {
+		if crd.Spec.Versions[i].Storage {
+			return &crd.Spec.Versions[i]
+		}
+	}
+	if len(crd.Spec.Versions) != 0 {
+		return &crd.Spec.Versions[0]
+	}
+	return nil
+}
+
 func (r *CompositionReconciler) validateExpanderConfig(ctx context.Context, logger logr.Logger,
-	expander compositionv1alpha1.Expander, ev *compositionv1alpha1.ExpanderVersion, grpcService string) (string, error) {
+	expander compositionv1alpha1.Expander, ev *compositionv1alpha1.ExpanderVersion, grpcService string,
+	resource string, facadeSchema []byte,
+) ([]compositionv1alpha1.Diagnostic, string, error) {
 	// Set up a connection to the server.
-	conn, err := grpc.NewClient(grpcService, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	expanderClient, err := expanderclient.New(grpcService)
 	if err != nil {
 		logger.Error(err, "grpc dial failed: "+grpcService)
-		return "GRPCConnError", err
+		return nil, "GRPCConnError", err
 	}
+	defer expanderClient.Close()
 
 	// marshall expander config
 	// read Expander config from  in cr.namespace
 	var configBytes []byte
 	if expander.ConfigRef != nil {
+		configGVK := ev.ConfigGVK(expander.Version)
 		expanderConfigcr := unstructured.Unstructured{}
 		expanderConfigcr.SetGroupVersionKind(schema.GroupVersionKind{
-			Group:   ev.Spec.Config.Group,
-			Version: ev.Spec.Config.Version,
-			Kind:    ev.Spec.Config.Kind,
+			Group:   configGVK.Group,
+			Version: configGVK.Version,
+			Kind:    configGVK.Kind,
 		})
 		expanderConfigNN := types.NamespacedName{Namespace: expander.ConfigRef.Namespace, Name: expander.ConfigRef.Name}
 		if err := r.Get(ctx, expanderConfigNN, &expanderConfigcr); err != nil {
 			logger.Error(err, "unable to fetch ExpanderConfig CR", "expander config", expanderConfigNN)
-			return "GetExpanderConfigFailed", err
+			return nil, "GetExpanderConfigFailed", err
 		}
 		configBytes, err = json.Marshal(expanderConfigcr.Object)
 		if err != nil {
 			logger.Error(err, "failed to marshal ExpanderConfig Object")
-			return "MarshallExpanderConfigFailed", err
+			return nil, "MarshallExpanderConfigFailed", err
 		}
 	} else {
 		// TODO check if json.Marshall is escaping quotes
@@ -245,26 +328,28 @@ func (r *CompositionReconciler) validateExpanderConfig(ctx context.Context, logg
 		configBytes = []byte(expander.Template)
 		if err != nil {
 			logger.Error(err, "failed to marshall Expander template")
-			return "MarshallExpanderTemplateFailed", err
+			return nil, "MarshallExpanderTemplateFailed", err
 		}
 	}
 
-	expanderClient := pb.NewExpanderClient(conn)
 	result, err := expanderClient.Validate(ctx,
-		&pb.ValidateRequest{
-			Config: configBytes,
+		&pbv2.ValidateRequest{
+			Config:       configBytes,
+			Resource:     resource,
+			FacadeSchema: facadeSchema,
 		})
 	if err != nil {
 		logger.Error(err, "expander.Validate() Failed", "expander", expander.Name)
-		return "ValidateError", err
+		return nil, "ValidateError", err
 	}
-	if result.Status != pb.Status_SUCCESS {
+	diagnostics := expanderclient.ToAPIDiagnostics(result.Diagnostics)
+	if result.Status != pbv2.Status_SUCCESS {
+		message := expanderclient.ErrorMessage(result.Diagnostics, result.Status.String())
 		logger.Error(nil, "expander.Validate() Status is not Success", "expander",
-			expander.Name, "status", result.Status, "message", result.Error.Message)
-		err = fmt.Errorf(result.Error.Message)
-		return "ValidateStatusFailed", err
+			expander.Name, "status", result.Status, "message", message)
+		return diagnostics, "ValidateStatusFailed", errors.New(message)
 	}
-	return "", nil
+	return diagnostics, "", nil
 }
 
 func (r *CompositionReconciler) getExpanderValue(
@@ -302,25 +387,6 @@ func (r *CompositionReconciler) getExpanderValue(
 	return value, &ev, "", nil
 }
 
-// converts simpleschema raw bytes to
-// schema is expected to be defined using the "SimpleSchema" format.
-func buildSchema(raw []byte) (*extv1.JSONSchemaProps, error) {
-	// unmarshal the to a map[string]interface{} to
-	// make it easier to work with.
-	ssinstance := map[string]interface{}{}
-	err := yaml.UnmarshalStrict(raw, &ssinstance)
-	if err != nil {
-		return nil, fmt.Errorf("failed to unmarshal simpleschema bytes: %w", err)
-	}
-
-	// generate openapi spec from simpleschema instance
-	openapiSchema, err := simpleschema.ToOpenAPISpec(ssinstance)
-	if err != nil {
-		return nil, fmt.Errorf("failed to build OpenAPI schema for instance: %v", err)
-	}
-	return openapiSchema, nil
-}
-
 func (r *CompositionReconciler) ensureInputCRD(
 	ctx context.Context, c *compositionv1alpha1.Composition, logger logr.Logger,
 ) (*extv1.CustomResourceDefinition, error) {
@@ -343,7 +409,7 @@ func (r *CompositionReconciler) ensureInputCRD(
 		// The instance resource has a schema defined using the "SimpleSchema" format.
 		crdInfo := crds.NewFacadeCRDInfo(gvk, "", nil, nil, nil)
 		crdName = crdInfo.Name()
-		specSchema, err := buildSchema(c.Spec.Schema.Spec.Raw)
+		specSchema, err := crds.BuildSchema(c.Spec.Schema.Spec.Raw)
 		if err == nil {
 			err = crdInfo.SetSpec(specSchema)
 			if err == nil {
@@ -458,6 +524,7 @@ func (r *CompositionReconciler) processComposition(
 		InputGVR:                  gvk.GroupVersion().WithResource(crd.Spec.Names.Plural),
 		RESTMapper:                r.mgr.GetRESTMapper(),
 		Config:                    r.mgr.GetConfig(),
+		Cluster:                   r.cluster,
 		CompositionChangedWatcher: r.handoffChannels[gvk],
 	}
 
@@ -478,10 +545,37 @@ func (r *CompositionReconciler) processComposition(
 	return nil
 }
 
+// setReadyCondition sets Ready to False if an Error or ValidationFailed condition is set
+// and records the generation the status is for. The facade webhook only admits
+// facades of Ready compositions.
+func setReadyCondition(c *compositionv1alpha1.Composition) {
+	c.Status.Generation = c.Generation
+	ready := metav1.Condition{
+		Type:    string(compositionv1alpha1.Ready),
+		Status:  metav1.ConditionTrue,
+		Reason:  "Processed",
+		Message: "Validated the expanders and started the facade reconciler",
+	}
+	for _, t := range []compositionv1alpha1.ConditionType{compositionv1alpha1.Error, compositionv1alpha1.ValidationFailed} {
+		if condition := meta.FindStatusCondition(c.Status.Conditions, string(t)); condition != nil && condition.Status == metav1.ConditionTrue {
+			ready.Status = metav1.ConditionFalse
+			ready.Reason = condition.Reason
+			ready.Message = condition.Message
+			break
+		}
+	}
+	meta.SetStatusCondition(&c.Status.Conditions, ready)
+}
+
 // SetupWithManager sets up the controller with the Manager.
 func (r *CompositionReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	r.mgr = mgr
 	r.handoffChannels = make(map[schema.GroupVersionKind]chan event.GenericEvent)
+	discoveryClient, err := discovery.NewDiscoveryClientForConfig(mgr.GetConfig())
+	if err != nil {
+		return fmt.Errorf("unable to create discovery client: %w", err)
+	}
+	r.cluster = expanderclient.NewCluster(discoveryClient)
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&compositionv1alpha1.Composition{}).
 		Complete(r)
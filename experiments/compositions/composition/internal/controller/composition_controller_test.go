@@ -0,0 +1,46 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"testing"
+
+	extv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+)
+
+func TestStorageVersion(t *testing.T) {
+	crd := &extv1.CustomResourceDefinition{
+		Spec: extv1.CustomResourceDefinitionSpec{
+			Versions: []extv1.CustomResourceDefinitionVersion{
+				{Name: "v1alpha1", Served: true},
+				{Name: "v1", Served: true, Storage: true},
+			},
+		},
+	}
+	if v := storageVersion(crd); v == nil || v.Name != "v1" {
+		t.Errorf("want the storage version v1, got %v", v)
+	}
+
+	crd.Spec.Versions[1].Storage = false
+	if v := storageVersion(crd); v == nil || v.Name != "v1alpha1" {
+		t.Errorf("want the first version without a storage version, got %v", v)
+	}
+
+	if v := storageVersion(&extv1.CustomResourceDefinition{}); v != nil {
+		t.Errorf("want no version for a CRD without versions, got %v", v)
+	}
+}
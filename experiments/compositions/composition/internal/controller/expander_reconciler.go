@@ -18,17 +18,18 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"reflect"
 	"strings"
 	"time"
 
 	compositionv1alpha1 "github.com/cloud-native-compositions/compositions/composition/api/v1alpha1"
 	"github.com/cloud-native-compositions/compositions/composition/pkg/applier"
 	"github.com/cloud-native-compositions/compositions/composition/pkg/containerexecutor/jobcontainerexecutor"
-	pb "github.com/cloud-native-compositions/compositions/composition/proto"
+	"github.com/cloud-native-compositions/compositions/composition/pkg/expanderclient"
+	"github.com/cloud-native-compositions/compositions/composition/pkg/stagevalues"
+	pbv2 "github.com/cloud-native-compositions/compositions/composition/proto/v2"
 	"github.com/go-logr/logr"
 	"golang.org/x/time/rate"
-	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials/insecure"
 	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/meta"
@@ -65,6 +66,7 @@ type ExpanderReconciler struct {
 	RESTMapper                meta.RESTMapper
 	Config                    *rest.Config
 	Dynamic                   *dynamic.DynamicClient
+	Cluster                   *expanderclient.Cluster
 	InputGVK                  schema.GroupVersionKind
 	InputGVR                  schema.GroupVersionResource
 	Composition               types.NamespacedName
@@ -243,20 +245,47 @@ func (r *ExpanderReconciler) Reconcile(ctx context.Context, req ctrl.Request) (c
 		return ctrl.Result{}, err
 	}
 
+	// Keep the status of the stages this pass does not reach, so their hooks
+	// carry on from where they stopped instead of running as a fresh install
+	for _, name := range stagesEvaluated {
+		if stage := plancr.Status.Stages[name]; stage != nil {
+			newStatus.Stages[name] = stage.DeepCopy()
+		}
+	}
+
 	// Grab a top level logger so we can add expander name in the eval and apply sections
 	loggerCR := logger
 	stagesApplied := []string{}
+	warningStages := []string{}
 	values := map[string]interface{}{}
 	requeueAgain := false
 
+	// Surface expander warnings even if a later stage fails or waits
+	defer func() {
+		if len(warningStages) != 0 {
+			message := fmt.Sprintf("Expanders returned warnings: %s", strings.Join(warningStages, ", "))
+			newStatus.AppendCondition(compositionv1alpha1.Warnings, metav1.ConditionTrue, message, "ExpanderWarnings")
+		}
+	}()
+
 	// ---------- Evaluate and Apply expanders in order ---------------------
 	for index, expander := range compositionCR.Spec.Expanders {
 		planUpdated := false
 		reason := ""
+		var diagnostics []compositionv1alpha1.Diagnostic
 
 		// ------------------- EVALUATION SECTION -----------------------
 
-		values, planUpdated, reason, err = r.evaluate(ctx, logger, &inputcr, planNN, expander, values, expanderDebugLogsEnabled)
+		values, planUpdated, diagnostics, reason, err = r.evaluate(ctx, logger, &inputcr, planNN, expander, values, expanderDebugLogsEnabled)
+		if stage := newStatus.Stages[expander.Name]; stage != nil {
+			stage.Diagnostics = diagnostics
+		} else if len(diagnostics) != 0 {
+			newStatus.Stages[expander.Name] = &compositionv1alpha1.StageStatus{Diagnostics: diagnostics}
+		}
+		if warnings := compositionv1alpha1.DiagnosticsMessage(diagnostics, compositionv1alpha1.DiagnosticSeverityWarning); warnings != "" {
+			warningStages = append(warningStages, fmt.Sprintf("%s: %s", expander.Name, warnings))
+			r.Recorder.Event(&inputcr, "Warning", "ExpanderWarnings", fmt.Sprintf("name: %s, warnings: %s", expander.Name, warnings))
+		}
 		_, iswaitErr := err.(*EvaluateWaitError)
 		if iswaitErr {
 			newStatus.AppendWaitingCondition(expander.Name, err.Error(), reason)
@@ -292,8 +321,8 @@ func (r *ExpanderReconciler) Reconcile(ctx context.Context, req ctrl.Request) (c
 		}
 		stage, ok := plancr.Spec.Stages[expander.Name]
 		if !ok {
-			err := fmt.Errorf("plancr.spec.stages[%s] not found !!", stage)
-			logger.Error(err, "error applying stage", "stage", stage)
+			err := fmt.Errorf("plancr.spec.stages[%s] not found !!", expander.Name)
+			logger.Error(err, "error applying stage", "stage", expander.Name)
 			// This is not expected since we just processed the stage above
 			// We dont want to return error. Lets retry again in 20 secs.
 			return ctrl.Result{RequeueAfter: 20 * time.Second}, nil
@@ -306,7 +335,7 @@ func (r *ExpanderReconciler) Reconcile(ctx context.Context, req ctrl.Request) (c
 		// Lets not make empty manifests from a stage an error
 		// We may have conditional code that generates no manifests in a stage
 		// We will log it though
-		if stage.Manifest == "" {
+		if stage.Manifest == "" && len(stage.Objects) == 0 {
 			logger.Info("Empty manifests returned for stage", "stage", stage)
 		}
 
@@ -320,6 +349,13 @@ func (r *ExpanderReconciler) Reconcile(ctx context.Context, req ctrl.Request) (c
 		if compositionCR.Spec.NamespaceMode != compositionv1alpha1.NamespaceModeExplicit {
 			namespace = inputcr.GetNamespace()
 		}
+		// Hooks of the stage run before and after its objects are applied
+		hookRunner, err := applier.NewHookRunner(ctx, logger, ac, expander.Name, namespace, plancr, plancr.Status.Stages[expander.Name])
+		if err != nil {
+			logger.Error(err, "Unable to load hooks")
+			newStatus.AppendErrorCondition(expander.Name, err.Error(), "FailedLoadingHooksFromPlan")
+			return ctrl.Result{}, err
+		}
 		applier := applier.NewApplier(ctx, logger, ac, expander.Name, namespace, r.InputGVR.Resource, plancr, compositionCR.Spec.Readiness)
 		err = applier.Load() // Load Manifests
 		if err != nil {
@@ -333,7 +369,19 @@ func (r *ExpanderReconciler) Reconcile(ctx context.Context, req ctrl.Request) (c
 		if newStatus.Stages == nil {
 			newStatus.Stages = map[string]*compositionv1alpha1.StageStatus{}
 		}
-		newStatus.Stages[expander.Name] = &compositionv1alpha1.StageStatus{ResourceCount: applier.Count()}
+		newStatus.Stages[expander.Name] = &compositionv1alpha1.StageStatus{ResourceCount: applier.Count(), Diagnostics: diagnostics}
+
+		if hookRunner != nil {
+			newStatus.Stages[expander.Name].Hooks = hookRunner.Status
+			done, err := r.runHooks(&inputcr, &newStatus, expander.Name, hookRunner, hookRunner.PrePhase())
+			if err != nil {
+				return ctrl.Result{}, err
+			}
+			if !done {
+				requeueAgain = true
+				break
+			}
+		}
 
 		// Prune only for the last expander section
 		prune := false
@@ -379,6 +427,17 @@ func (r *ExpanderReconciler) Reconcile(ctx context.Context, req ctrl.Request) (c
 		}
 		logger.Info("Applied resources successfully.")
 
+		if hookRunner != nil {
+			done, err := r.runHooks(&inputcr, &newStatus, expander.Name, hookRunner, hookRunner.PostPhase())
+			if err != nil {
+				return ctrl.Result{}, err
+			}
+			if !done {
+				requeueAgain = true
+				break
+			}
+		}
+
 		// Implicit getter: Make the applied objects available in the values passed to subsequent stages
 		values = applier.AddAppliedObjectsIntoValues(values)
 
@@ -412,12 +471,30 @@ func (r *ExpanderReconciler) Reconcile(ctx context.Context, req ctrl.Request) (c
 	return ctrl.Result{}, nil
 }
 
+// runHooks runs the hooks of a stage for a phase. It returns false while they
+// are running.
+func (r *ExpanderReconciler) runHooks(inputcr *unstructured.Unstructured, newStatus *compositionv1alpha1.PlanStatus,
+	stage string, hookRunner *applier.HookRunner, phase compositionv1alpha1.HookPhase) (bool, error) {
+	done, err := hookRunner.Run(phase)
+	if err != nil {
+		r.Recorder.Event(inputcr, "Warning", "HookFailed", fmt.Sprintf("%s hooks failed. name: %s", phase, stage))
+		newStatus.AppendErrorCondition(stage, err.Error(), "HookFailed")
+		return false, err
+	}
+	if !done {
+		newStatus.AppendWaitingCondition(stage, fmt.Sprintf("Waiting for %s hooks", phase), "WaitingForHooks")
+		return false, nil
+	}
+	return true, nil
+}
+
 func (r *ExpanderReconciler) evaluate(ctx context.Context, logger logr.Logger,
 	cr *unstructured.Unstructured, planNN types.NamespacedName,
 	expander compositionv1alpha1.Expander, values map[string]interface{},
-	expanderDebugLogsEnabled bool) (map[string]interface{}, bool, string, error) {
+	expanderDebugLogsEnabled bool) (map[string]interface{}, bool, []compositionv1alpha1.Diagnostic, string, error) {
 
 	planUpdated := false
+	var diagnostics []compositionv1alpha1.Diagnostic
 
 	logger = logger.WithName(expander.Name).WithName("Expand")
 
@@ -425,7 +502,7 @@ func (r *ExpanderReconciler) evaluate(ctx context.Context, logger logr.Logger,
 	if err != nil {
 		logger.Error(err, "Error getting expander version", "expander", expander.Type,
 			"version", expander.Version, "reason", reason)
-		return values, planUpdated, reason, err
+		return values, planUpdated, diagnostics, reason, err
 	}
 
 	logger.Info("Got valid expander uri", "uri", uri)
@@ -437,14 +514,14 @@ func (r *ExpanderReconciler) evaluate(ctx context.Context, logger logr.Logger,
 	if ev.Spec.Type == compositionv1alpha1.ExpanderTypeJob {
 		reason, err = r.runJob(ctx, logger, cr, expander.Name, planNN.Name, uri, ev.Spec.ImageRegistry)
 	} else {
-		values, planUpdated, reason, err = r.evaluateAndSavePlan(ctx, logger, cr, values, expander, planNN, ev, uri, expanderDebugLogsEnabled)
+		values, planUpdated, diagnostics, reason, err = r.evaluateAndSavePlan(ctx, logger, cr, values, expander, planNN, ev, uri, expanderDebugLogsEnabled)
 	}
 
 	if err == nil && expanderDebugLogsEnabled {
 		r.Recorder.Event(cr, "Normal", fmt.Sprintf("Evaluated expander stage: %s", expander.Name), expanderDebugLog(cr))
 	}
 
-	return values, planUpdated, reason, err
+	return values, planUpdated, diagnostics, reason, err
 
 }
 
@@ -521,15 +598,18 @@ func (r *ExpanderReconciler) runJob(ctx context.Context, logger logr.Logger,
 
 func (r *ExpanderReconciler) evaluateAndSavePlan(ctx context.Context, logger logr.Logger,
 	cr *unstructured.Unstructured, values map[string]interface{}, expander compositionv1alpha1.Expander,
-	planNN types.NamespacedName, ev *compositionv1alpha1.ExpanderVersion, grpcService string, expanderDebugLogEnabled bool) (map[string]interface{}, bool, string, error) {
+	planNN types.NamespacedName, ev *compositionv1alpha1.ExpanderVersion, grpcService string, expanderDebugLogEnabled bool,
+) (map[string]interface{}, bool, []compositionv1alpha1.Diagnostic, string, error) {
 	// Set up a connection to the server.
 	updated := false
+	var diagnostics []compositionv1alpha1.Diagnostic
 
-	conn, err := grpc.NewClient(grpcService, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	expanderClient, err := expanderclient.New(grpcService)
 	if err != nil {
 		logger.Error(err, "grpc dial failed: "+grpcService)
-		return values, updated, "GRPCConnError", err
+		return values, updated, diagnostics, "GRPCConnError", err
 	}
+	defer expanderClient.Close()
 
 	// read context in cr.namespace
 	var contextBytes []byte
@@ -539,7 +619,7 @@ func (r *ExpanderReconciler) evaluateAndSavePlan(ctx context.Context, logger log
 	if err := r.Get(ctx, contextNN, &contextcr); err != nil {
 		logger.Error(err, "unable to fetch Context CR", "context", contextNN)
 		if !apierrors.IsNotFound(err) {
-			return values, updated, "ErrorGettingContext", err
+			return values, updated, diagnostics, "ErrorGettingContext", err
 		}
 		// If context doesnt exist ignore it. If a composition uses context,
 		//  it will fail evaluation
@@ -547,7 +627,7 @@ func (r *ExpanderReconciler) evaluateAndSavePlan(ctx context.Context, logger log
 		contextBytes, err = json.Marshal(contextcr.Object)
 		if err != nil {
 			logger.Error(err, "failed to marshal Context Object")
-			return values, updated, "MarshallContextFailed", err
+			return values, updated, diagnostics, "MarshallContextFailed", err
 		}
 	}
 
@@ -555,28 +635,29 @@ func (r *ExpanderReconciler) evaluateAndSavePlan(ctx context.Context, logger log
 	facadeBytes, err := json.Marshal(cr.Object)
 	if err != nil {
 		logger.Error(err, "failed to marshall Facade Object")
-		return values, updated, "MarshallFacadeFailed", err
+		return values, updated, diagnostics, "MarshallFacadeFailed", err
 	}
 
 	// marshall expander config
 	// read Expander config from  in cr.namespace
 	var configBytes []byte
 	if expander.ConfigRef != nil {
+		configGVK := ev.ConfigGVK(expander.Version)
 		expanderconfigcr := unstructured.Unstructured{}
 		expanderconfigcr.SetGroupVersionKind(schema.GroupVersionKind{
-			Group:   ev.Spec.Config.Group,
-			Version: ev.Spec.Config.Version,
-			Kind:    ev.Spec.Config.Kind,
+			Group:   configGVK.Group,
+			Version: configGVK.Version,
+			Kind:    configGVK.Kind,
 		})
 		expanderconfigNN := types.NamespacedName{Namespace: expander.ConfigRef.Namespace, Name: expander.ConfigRef.Name}
 		if err := r.Get(ctx, expanderconfigNN, &expanderconfigcr); err != nil {
 			logger.Error(err, "unable to fetch ExpanderConfig CR", "expander config", expanderconfigNN)
-			return values, updated, "GetExpanderConfigFailed", err
+			return values, updated, diagnostics, "GetExpanderConfigFailed", err
 		}
 		configBytes, err = json.Marshal(expanderconfigcr.Object)
 		if err != nil {
 			logger.Error(err, "failed to marshal ExpanderConfig Object")
-			return values, updated, "MarshallExpanderConfigFailed", err
+			return values, updated, diagnostics, "MarshallExpanderConfigFailed", err
 		}
 	} else {
 		// TODO check if json.Marshall is escaping quotes
@@ -586,7 +667,7 @@ func (r *ExpanderReconciler) evaluateAndSavePlan(ctx context.Context, logger log
 		configBytes = []byte(expander.Template)
 		if err != nil {
 			logger.Error(err, "failed to marshall Expander template")
-			return values, updated, "MarshallExpanderTemplateFailed", err
+			return values, updated, diagnostics, "MarshallExpanderTemplateFailed", err
 		}
 	}
 
@@ -594,33 +675,42 @@ func (r *ExpanderReconciler) evaluateAndSavePlan(ctx context.Context, logger log
 	valuesBytes, err := json.Marshal(values)
 	if err != nil {
 		logger.Error(err, "failed to marshall Getter Values")
-		return values, updated, "MarshallValuesFailed", err
+		return values, updated, diagnostics, "MarshallValuesFailed", err
 	}
-	evaluateRequest := &pb.EvaluateRequest{
+	evaluateRequest := &pbv2.EvaluateRequest{
 		Config:   configBytes,
 		Context:  contextBytes,
 		Facade:   facadeBytes,
 		Resource: r.InputGVR.Resource,
 		Value:    valuesBytes,
 	}
-	expanderClient := pb.NewExpanderClient(conn)
+	if r.Cluster != nil {
+		// Expanders render without the cluster version and API versions if
+		// they could not be discovered
+		evaluateRequest.Cluster, err = r.Cluster.Get()
+		if err != nil {
+			logger.Error(err, "unable to discover the cluster")
+		}
+	}
 	if expanderDebugLogEnabled {
 		logger.Info(expanderDebugLog(cr) + fmt.Sprintf("---sending expander request: %v", evaluateRequest))
 	}
 	result, err := expanderClient.Evaluate(ctx, evaluateRequest)
 	if err != nil {
 		logger.Error(err, "expander.Evaluate() Failed", "expander", expander.Name)
-		return values, updated, "EvaluateError", err
+		return values, updated, diagnostics, "EvaluateError", err
 	}
-	if result.Status == pb.Status_EVALUATE_WAIT {
-		logger.Error(nil, "expander.Evaluate() returned WAIT", "expander", expander.Name, "status", result.Status, "msg", result.Error.Message)
-		err = &EvaluateWaitError{msg: fmt.Sprintf("Expander returned WAIT: %s", result.Error.Message)}
-		return values, updated, "EvaluateStatusWait", err
+	diagnostics = expanderclient.ToAPIDiagnostics(result.Diagnostics)
+	if result.Status == pbv2.Status_EVALUATE_WAIT {
+		message := expanderclient.ErrorMessage(result.Diagnostics, "")
+		logger.Error(nil, "expander.Evaluate() returned WAIT", "expander", expander.Name, "status", result.Status, "msg", message)
+		err = &EvaluateWaitError{msg: fmt.Sprintf("Expander returned WAIT: %s", message)}
+		return values, updated, diagnostics, "EvaluateStatusWait", err
 	}
-	if result.Status != pb.Status_SUCCESS {
+	if result.Status != pbv2.Status_SUCCESS {
 		logger.Error(nil, "expander.Evaluate() Status is not Success", "expander", expander.Name, "status", result.Status)
-		err = fmt.Errorf("Evaluate Failed: %s", result.Error.Message)
-		return values, updated, "EvaluateStatusFailed", err
+		err = fmt.Errorf("Evaluate Failed: %s", expanderclient.ErrorMessage(result.Diagnostics, result.Status.String()))
+		return values, updated, diagnostics, "EvaluateStatusFailed", err
 	}
 	if expanderDebugLogEnabled {
 		logger.Info(expanderDebugLog(cr) + fmt.Sprintf("---sent expander request: %v, received results: %v", evaluateRequest, result))
@@ -632,7 +722,7 @@ func (r *ExpanderReconciler) evaluateAndSavePlan(ctx context.Context, logger log
 	plancr := compositionv1alpha1.Plan{}
 	if err := r.Client.Get(ctx, planNN, &plancr); err != nil {
 		logger.Error(err, "unable to read Plan CR", "plan", planNN)
-		return values, updated, "GetPlanFailed", err
+		return values, updated, diagnostics, "GetPlanFailed", err
 	}
 
 	if plancr.Spec.Stages == nil {
@@ -643,17 +733,23 @@ func (r *ExpanderReconciler) evaluateAndSavePlan(ctx context.Context, logger log
 		updated = true
 	}
 
-	if result.Type == pb.ResultType_MANIFESTS {
-		err = nil
-		//s, err := strconv.Unquote(string(result.Manifests))
-		s := string(result.Manifests)
+	if result.Type == pbv2.ResultType_OBJECTS {
+		// The objects are stored as they are, the applier does not parse them again
+		objects, err := expanderclient.RawFromObjects(result.Objects)
+		if err != nil {
+			logger.Error(err, "unable to convert expanded objects")
+			return values, updated, diagnostics, "ConvertObjectsFailed", err
+		}
+		hooks, err := expanderclient.HooksFromProto(result.Hooks)
 		if err != nil {
-			logger.Error(err, "unable to unquote grpc response")
-			return values, updated, "UnquoteResponseFailed", err
+			logger.Error(err, "unable to convert expanded hooks")
+			return values, updated, diagnostics, "ConvertHooksFailed", err
 		}
-		if s != plancr.Spec.Stages[expander.Name].Manifest {
+		stage := plancr.Spec.Stages[expander.Name]
+		if stage.Manifest != "" || !reflect.DeepEqual(objects, stage.Objects) || !reflect.DeepEqual(hooks, stage.Hooks) {
 			plancr.Spec.Stages[expander.Name] = compositionv1alpha1.Stage{
-				Manifest: s,
+				Objects: objects,
+				Hooks:   hooks,
 			}
 			updated = true
 		}
@@ -661,7 +757,7 @@ func (r *ExpanderReconciler) evaluateAndSavePlan(ctx context.Context, logger log
 		//s, err := strconv.Unquote(string(result.Values))
 		//if err != nil {
 		//	logger.Error(err, "unable to unquote grpc response")
-		//	return values, updated, "UnquoteResponseFailed", err
+		//	return values, updated, diagnostics, "UnquoteResponseFailed", err
 		//}
 		s := string(result.Values)
 		if s != plancr.Spec.Stages[expander.Name].Values {
@@ -676,26 +772,21 @@ func (r *ExpanderReconciler) evaluateAndSavePlan(ctx context.Context, logger log
 		err = json.Unmarshal([]byte(plancr.Spec.Stages[expander.Name].Values), &stageValues)
 		if err != nil {
 			logger.Error(err, "Failed unmarshalling response.Values field")
-			return values, updated, "UnmarshallValuesFailed", err
-		}
-		for k := range stageValues {
-			_, ok := values[k]
-			if ok {
-				err := fmt.Errorf("values[%s] already exists from one of the previous stages.", k)
-				logger.Error(err, "Duplicate Value Key")
-				return values, updated, "DuplicateValueKey", err
-			}
-			values[k] = stageValues[k]
+			return values, updated, diagnostics, "UnmarshallValuesFailed", err
+		}
+		if err := stagevalues.Merge(values, stageValues); err != nil {
+			logger.Error(err, "Duplicate Value Key")
+			return values, updated, diagnostics, "DuplicateValueKey", err
 		}
 	}
 
 	err = r.Client.Update(ctx, &plancr)
 	if err != nil {
 		logger.Error(err, "error updating plan", "plan", planNN)
-		return values, updated, "UpdatePlanFailed", err
+		return values, updated, diagnostics, "UpdatePlanFailed", err
 	}
 
-	return values, updated, "", nil
+	return values, updated, diagnostics, "", nil
 }
 
 func expanderDebugLog(cr *unstructured.Unstructured) string {
@@ -749,8 +840,22 @@ func (r *ExpanderReconciler) reconcileDelete(ctx context.Context, logger logr.Lo
 		return ctrl.Result{}, err
 	}
 	stages := strings.Split(stageList, ",")
+	// Hooks are created in the facade namespace like the other objects, unless
+	// the composition sets namespaces explicitly
+	namespace := inputcr.GetNamespace()
+	var compositionCR compositionv1alpha1.Composition
+	if err := r.Client.Get(ctx, r.Composition, &compositionCR); err == nil && compositionCR.Spec.NamespaceMode == compositionv1alpha1.NamespaceModeExplicit {
+		namespace = ""
+	}
 	numFound := 0
 	for i := len(stages) - 1; i >= 0; i-- {
+		done, err := r.runDeleteHooks(ctx, logger, &inputcr, &plancr, stages[i], namespace, compositionv1alpha1.HookPreDelete)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		if !done {
+			return ctrl.Result{RequeueAfter: 5 * time.Second}, nil
+		}
 		r.Recorder.Eventf(&inputcr, corev1.EventTypeNormal, "Delete", "Deleting objects for stage %s", stages[i])
 		nsList, ok := annotations[apply.ApplySetAdditionalNamespacesAnnotation]
 		if !ok {
@@ -794,6 +899,13 @@ func (r *ExpanderReconciler) reconcileDelete(ctx context.Context, logger logr.Lo
 		if numFound > 0 {
 			break
 		}
+		done, err = r.runDeleteHooks(ctx, logger, &inputcr, &plancr, stages[i], namespace, compositionv1alpha1.HookPostDelete)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		if !done {
+			return ctrl.Result{RequeueAfter: 5 * time.Second}, nil
+		}
 	}
 	if numFound > 0 {
 		return ctrl.Result{Requeue: true, RequeueAfter: 10 * time.Second}, nil
@@ -814,6 +926,43 @@ func (r *ExpanderReconciler) reconcileDelete(ctx context.Context, logger logr.Lo
 	return ctrl.Result{}, nil
 }
 
+// runDeleteHooks runs the hooks of a stage for a phase of the deletion of the
+// facade, and records their progress in the Plan status
+func (r *ExpanderReconciler) runDeleteHooks(ctx context.Context, logger logr.Logger, inputcr *unstructured.Unstructured,
+	plancr *compositionv1alpha1.Plan, stage, namespace string, phase compositionv1alpha1.HookPhase) (bool, error) {
+	ac := applier.ApplierClient{
+		Client:     r.Client,
+		Dynamic:    r.Dynamic,
+		RESTMapper: r.RESTMapper,
+	}
+	hookRunner, err := applier.NewHookRunner(ctx, logger, ac, stage, namespace, plancr, plancr.Status.Stages[stage])
+	if err != nil {
+		logger.Error(err, "Unable to load hooks", "stage", stage)
+		return false, err
+	}
+	if hookRunner == nil {
+		return true, nil
+	}
+	previous := hookRunner.Status.DeepCopy()
+	done, err := hookRunner.Run(phase)
+	if err != nil {
+		logger.Error(err, "Hooks failed", "stage", stage, "phase", phase)
+		r.Recorder.Eventf(inputcr, corev1.EventTypeWarning, "HookFailed", "%s hooks failed for stage %s: %v", phase, stage, err)
+	}
+	if !reflect.DeepEqual(previous, hookRunner.Status) {
+		status := plancr.Status.DeepCopy()
+		if status.Stages == nil {
+			status.Stages = map[string]*compositionv1alpha1.StageStatus{}
+		}
+		if status.Stages[stage] == nil {
+			status.Stages[stage] = &compositionv1alpha1.StageStatus{}
+		}
+		status.Stages[stage].Hooks = hookRunner.Status
+		r.updatePlanStatus(ctx, plancr, status)
+	}
+	return done, err
+}
+
 func deleteListOpts(stage, applysetId string) (metav1.ListOptions, error) {
 	stageReq, err := labels.NewRequirement(applier.StageLabel, selection.Equals, []string{stage})
 	if err != nil {
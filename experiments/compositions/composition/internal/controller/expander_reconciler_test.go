@@ -0,0 +1,113 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	compositionv1alpha1 "github.com/cloud-native-compositions/compositions/composition/api/v1alpha1"
+	"github.com/cloud-native-compositions/compositions/composition/pkg/expandersdk"
+	"github.com/cloud-native-compositions/compositions/composition/pkg/inproc"
+)
+
+func TestExpanderReconcileKeepsStatusOfStagesNotReached(t *testing.T) {
+	// The first stage waits, so the second stage is not reached
+	inproc.Register("waiting", expandersdk.NewServer(&expandersdk.Expander[string]{
+		Name: "waiting",
+		Evaluate: func(ctx context.Context, req *expandersdk.Request[string]) (*expandersdk.Result, error) {
+			return nil, expandersdk.Wait("not ready yet")
+		},
+	}).V2())
+
+	facadeGVK := schema.GroupVersionKind{Group: "facade.compositions.google.com", Version: "v1", Kind: "Team"}
+	scheme := runtime.NewScheme()
+	if err := compositionv1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	scheme.AddKnownTypeWithName(facadeGVK, &unstructured.Unstructured{})
+	metav1.AddToGroupVersion(scheme, facadeGVK.GroupVersion())
+
+	facade := &unstructured.Unstructured{}
+	facade.SetGroupVersionKind(facadeGVK)
+	facade.SetName("demo")
+	facade.SetNamespace("default")
+	ev := &compositionv1alpha1.ExpanderVersion{
+		ObjectMeta: metav1.ObjectMeta{Name: "composition-waiting", Namespace: "composition-system"},
+		Spec:       compositionv1alpha1.ExpanderVersionSpec{Type: compositionv1alpha1.ExpanderTypeInProc, ValidVersions: []string{"v0.0.1"}},
+		Status:     compositionv1alpha1.ExpanderVersionStatus{VersionMap: map[string]string{"v0.0.1": inproc.URI("waiting")}},
+	}
+	composition := &compositionv1alpha1.Composition{
+		ObjectMeta: metav1.ObjectMeta{Name: "team", Namespace: "default"},
+		Spec: compositionv1alpha1.CompositionSpec{
+			InputAPIGroup: "teams.facade.compositions.google.com",
+			Expanders: []compositionv1alpha1.Expander{
+				{Name: "first", Type: "waiting", Version: "v0.0.1", ExpanderConfig: compositionv1alpha1.ExpanderConfig{Template: "first"}},
+				{Name: "second", Type: "waiting", Version: "v0.0.1", ExpanderConfig: compositionv1alpha1.ExpanderConfig{Template: "second"}},
+			},
+		},
+	}
+	second := &compositionv1alpha1.StageStatus{
+		ResourceCount: 1,
+		AppliedCount:  1,
+		LastApplied:   []compositionv1alpha1.ResourceStatus{{Group: "batch", Version: "v1", Kind: "Job", Namespace: "default", Name: "migrate", Health: compositionv1alpha1.Healthy}},
+		Hooks:         &compositionv1alpha1.HookStatus{Revision: "abc", Upgrade: true, Completed: []string{"pre-upgrade/0"}},
+	}
+	plan := &compositionv1alpha1.Plan{
+		ObjectMeta: metav1.ObjectMeta{Name: "teams-demo", Namespace: "default", Finalizers: []string{finalizerName}},
+		Status:     compositionv1alpha1.PlanStatus{Stages: map[string]*compositionv1alpha1.StageStatus{"second": second}},
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).
+		WithObjects(facade, ev, composition, plan).
+		WithStatusSubresource(plan).Build()
+	r := &ExpanderReconciler{
+		Client:      c,
+		Scheme:      scheme,
+		Recorder:    record.NewFakeRecorder(100),
+		InputGVK:    facadeGVK,
+		InputGVR:    facadeGVK.GroupVersion().WithResource("teams"),
+		Composition: types.NamespacedName{Name: composition.Name, Namespace: composition.Namespace},
+	}
+	ctx := context.Background()
+
+	result, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: types.NamespacedName{Name: "demo", Namespace: "default"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.RequeueAfter != 5*time.Second {
+		t.Errorf("want a requeue while the first stage waits, got %+v", result)
+	}
+	got := &compositionv1alpha1.Plan{}
+	if err := c.Get(ctx, types.NamespacedName{Name: plan.Name, Namespace: plan.Namespace}, got); err != nil {
+		t.Fatal(err)
+	}
+	// The hooks of the second stage continue as an upgrade on the next pass
+	if !reflect.DeepEqual(got.Status.Stages["second"], second) {
+		t.Errorf("want the status of the second stage kept, got %+v", got.Status.Stages["second"])
+	}
+}
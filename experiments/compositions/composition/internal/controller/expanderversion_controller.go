@@ -19,20 +19,32 @@ package controller
 import (
 	"context"
 	"fmt"
-	"reflect"
 	"sort"
 	"strings"
+	"time"
 
 	semver "github.com/Masterminds/semver/v3"
 	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/util/retry"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
 
 	compositionv1alpha1 "github.com/cloud-native-compositions/compositions/composition/api/v1alpha1"
+	"github.com/cloud-native-compositions/compositions/composition/pkg/expanderclient"
+	"github.com/cloud-native-compositions/compositions/composition/pkg/inproc"
+)
+
+const (
+	capabilitiesTimeout       = 5 * time.Second
+	capabilitiesRetryInterval = 30 * time.Second
 )
 
 // ExpanderVersionReconciler reconciles a ExpanderVersion object
@@ -73,7 +85,9 @@ func (r *ExpanderVersionReconciler) Reconcile(ctx context.Context, req ctrl.Requ
 
 	// Try updating status before returning
 	defer func() {
-		if !reflect.DeepEqual(oldStatus, ev.Status) {
+		// Semantic treats the empty maps of a failed reconcile like the nil
+		// maps read back from the API server
+		if !equality.Semantic.DeepEqual(*oldStatus, ev.Status) {
 			newStatus := ev.Status.DeepCopy()
 			err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
 				nn := types.NamespacedName{Namespace: ev.Namespace, Name: ev.Name}
@@ -98,12 +112,86 @@ func (r *ExpanderVersionReconciler) Reconcile(ctx context.Context, req ctrl.Requ
 		return ctrl.Result{}, fmt.Errorf("Validation failed")
 	}
 
-	ev.Status.ClearCondition(compositionv1alpha1.Error)
 	logger.Info("Processing ExpanderVersion object")
 	r.processExpanderVersion(&ev, logger)
+
+	if ev.Spec.Type == compositionv1alpha1.ExpanderTypeGRPC || ev.Spec.Type == compositionv1alpha1.ExpanderTypeInProc {
+		logger.Info("Getting expander capabilities")
+		if err := r.getCapabilities(ctx, &ev, logger); err != nil {
+			// Keeps the transition time of an earlier failure, so retrying
+			// does not change the status
+			meta.SetStatusCondition(&ev.Status.Conditions, metav1.Condition{
+				Message: err.Error(),
+				Reason:  "GetCapabilitiesFailed",
+				Type:    string(compositionv1alpha1.Error),
+				Status:  metav1.ConditionTrue,
+			})
+			// The expander service may not be up yet
+			return ctrl.Result{RequeueAfter: capabilitiesRetryInterval}, nil
+		}
+	}
+	ev.Status.ClearCondition(compositionv1alpha1.Error)
 	return ctrl.Result{}, nil
 }
 
+// getCapabilities calls GetCapabilities on every version of the expander service
+// and records the results in the status.
+func (r *ExpanderVersionReconciler) getCapabilities(
+	ctx context.Context, ev *compositionv1alpha1.ExpanderVersion, logger logr.Logger,
+) error {
+	capabilities := map[string]compositionv1alpha1.ExpanderCapabilities{}
+	byURI := map[string]compositionv1alpha1.ExpanderCapabilities{}
+	failed := []string{}
+
+	versions := []string{}
+	for version := range ev.Status.VersionMap {
+		versions = append(versions, version)
+	}
+	sort.Strings(versions)
+
+	for _, version := range versions {
+		uri := ev.Status.VersionMap[version]
+		if c, ok := byURI[uri]; ok {
+			capabilities[version] = c
+			continue
+		}
+		c, err := getExpanderCapabilities(ctx, uri)
+		if err != nil {
+			logger.Error(err, "GetCapabilities failed", "version", version, "uri", uri)
+			failed = append(failed, fmt.Sprintf("%s: %v", version, err))
+			// Keep what we learnt earlier
+			if old, ok := ev.Status.Capabilities[version]; ok {
+				capabilities[version] = old
+			}
+			continue
+		}
+		byURI[uri] = c
+		capabilities[version] = c
+	}
+
+	ev.Status.Capabilities = capabilities
+	if len(failed) != 0 {
+		return fmt.Errorf("GetCapabilities failed for versions: %s", strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+func getExpanderCapabilities(ctx context.Context, uri string) (compositionv1alpha1.ExpanderCapabilities, error) {
+	expanderClient, err := expanderclient.New(uri)
+	if err != nil {
+		return compositionv1alpha1.ExpanderCapabilities{}, err
+	}
+	defer expanderClient.Close()
+
+	ctx, cancel := context.WithTimeout(ctx, capabilitiesTimeout)
+	defer cancel()
+	result, err := expanderClient.GetCapabilities(ctx)
+	if err != nil {
+		return compositionv1alpha1.ExpanderCapabilities{}, err
+	}
+	return expanderclient.ToAPICapabilities(result)
+}
+
 func (r *ExpanderVersionReconciler) processExpanderVersion(
 	ev *compositionv1alpha1.ExpanderVersion, logger logr.Logger,
 ) {
@@ -130,6 +218,9 @@ func (r *ExpanderVersionReconciler) processExpanderVersion(
 
 		if ev.Spec.Type == compositionv1alpha1.ExpanderTypeJob {
 			value = fmt.Sprintf("%s/%s:%s", ev.Spec.ImageRegistry, image, key)
+		} else if ev.Spec.Type == compositionv1alpha1.ExpanderTypeInProc {
+			// All versions are served by the expander compiled into the manager
+			value = inproc.URI(expander)
 		} else {
 			svcVersion := strings.Replace(key, ".", "-", -1)
 			value = fmt.Sprintf("composition-%s-%s:8443", expander, svcVersion)
@@ -145,6 +236,7 @@ func (r *ExpanderVersionReconciler) processExpanderVersion(
 // SetupWithManager sets up the controller with the Manager.
 func (r *ExpanderVersionReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
-		For(&compositionv1alpha1.ExpanderVersion{}).
+		// Status updates must not trigger a reconcile, retries are requeued
+		For(&compositionv1alpha1.ExpanderVersion{}, builder.WithPredicates(predicate.GenerationChangedPredicate{})).
 		Complete(r)
 }
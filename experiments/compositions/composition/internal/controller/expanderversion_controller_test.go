@@ -18,11 +18,14 @@ package controller
 
 import (
 	"context"
+	"testing"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -82,3 +85,52 @@ var _ = Describe("ExpanderVersion Controller", func() {
 		})
 	})
 })
+
+func TestExpanderVersionCapabilitiesRetry(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := compositionv1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	// Not registered in the manager, so GetCapabilities fails
+	ev := &compositionv1alpha1.ExpanderVersion{
+		ObjectMeta: metav1.ObjectMeta{Name: "composition-missing", Namespace: "default"},
+		Spec: compositionv1alpha1.ExpanderVersionSpec{
+			Type:          compositionv1alpha1.ExpanderTypeInProc,
+			ValidVersions: []string{"v0.0.1"},
+		},
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(ev).WithStatusSubresource(ev).Build()
+	r := &ExpanderVersionReconciler{Client: c, Scheme: scheme}
+	ctx := context.Background()
+	req := reconcile.Request{NamespacedName: types.NamespacedName{Name: ev.Name, Namespace: ev.Namespace}}
+
+	reconcileOnce := func() *compositionv1alpha1.ExpanderVersion {
+		t.Helper()
+		result, err := r.Reconcile(ctx, req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.RequeueAfter != capabilitiesRetryInterval {
+			t.Errorf("want a requeue after %s, got %+v", capabilitiesRetryInterval, result)
+		}
+		got := &compositionv1alpha1.ExpanderVersion{}
+		if err := c.Get(ctx, req.NamespacedName, got); err != nil {
+			t.Fatal(err)
+		}
+		return got
+	}
+
+	first := reconcileOnce()
+	second := reconcileOnce()
+	if len(second.Status.Conditions) != 1 {
+		t.Fatalf("want 1 condition, got %+v", second.Status.Conditions)
+	}
+	if second.Status.Conditions[0].Reason != "GetCapabilitiesFailed" {
+		t.Errorf("want reason GetCapabilitiesFailed, got %+v", second.Status.Conditions[0])
+	}
+	// An unchanged status is not written again, so it does not trigger
+	// another reconcile
+	if first.ResourceVersion != second.ResourceVersion {
+		t.Errorf("want the status unchanged by the retry, resourceVersion %s -> %s", first.ResourceVersion, second.ResourceVersion)
+	}
+}
@@ -0,0 +1,172 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"sort"
+
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	extv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+
+	"github.com/cloud-native-compositions/compositions/composition/pkg/crds"
+)
+
+// FacadeWebhookName is the name of the webhook sending facades to the facade webhook
+const FacadeWebhookName = "vfacade.composition.google.com"
+
+// FacadeWebhookReconciler keeps a ValidatingWebhookConfiguration with a rule
+// for each facade CRD, so facades are sent to the facade webhook. The facade
+// CRDs are created at runtime and can not be listed in config/webhook.
+type FacadeWebhookReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+
+	// Name of the ValidatingWebhookConfiguration
+	Name string
+	// ServiceName and ServiceNamespace locate the webhook server
+	ServiceName      string
+	ServiceNamespace string
+	// Path the facade webhook is served at
+	Path string
+	// CABundle is the CA of the webhook serving certificate
+	CABundle []byte
+}
+
+//+kubebuilder:rbac:groups=admissionregistration.k8s.io,resources=validatingwebhookconfigurations,verbs=get;list;watch;create;update;patch;delete
+
+// Reconcile lists the facade CRDs and updates the webhook rules. All requests
+// reconcile the same object. The configuration is deleted when there are no
+// facade CRDs.
+func (r *FacadeWebhookReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+
+	crdList := &extv1.CustomResourceDefinitionList{}
+	if err := r.List(ctx, crdList, client.MatchingLabels{crds.FacadeLabel: "yes"}); err != nil {
+		logger.Error(err, "unable to list facade CRDs")
+		return ctrl.Result{}, err
+	}
+	rules := facadeRules(crdList.Items)
+
+	config := &admissionregistrationv1.ValidatingWebhookConfiguration{ObjectMeta: metav1.ObjectMeta{Name: r.Name}}
+	if len(rules) == 0 {
+		if err := r.Delete(ctx, config); err != nil && !apierrors.IsNotFound(err) {
+			logger.Error(err, "unable to delete facade webhook configuration")
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{}, nil
+	}
+
+	op, err := controllerutil.CreateOrUpdate(ctx, r.Client, config, func() error {
+		if config.Labels == nil {
+			config.Labels = map[string]string{}
+		}
+		config.Labels["app.kubernetes.io/managed-by"] = "composition"
+		config.Webhooks = []admissionregistrationv1.ValidatingWebhook{r.webhook(rules)}
+		return nil
+	})
+	if err != nil {
+		logger.Error(err, "unable to update facade webhook configuration")
+		return ctrl.Result{}, err
+	}
+	if op != controllerutil.OperationResultNone {
+		logger.Info("Updated facade webhook configuration", "name", r.Name, "operation", op, "rules", len(rules))
+	}
+	return ctrl.Result{}, nil
+}
+
+// webhook fails open like the Composition webhook. The facade controller still
+// reports facades it can not reconcile.
+func (r *FacadeWebhookReconciler) webhook(rules []admissionregistrationv1.RuleWithOperations) admissionregistrationv1.ValidatingWebhook {
+	path := r.Path
+	failurePolicy := admissionregistrationv1.Ignore
+	sideEffects := admissionregistrationv1.SideEffectClassNone
+	timeoutSeconds := int32(10)
+	return admissionregistrationv1.ValidatingWebhook{
+		Name: FacadeWebhookName,
+		ClientConfig: admissionregistrationv1.WebhookClientConfig{
+			Service: &admissionregistrationv1.ServiceReference{
+				Name:      r.ServiceName,
+				Namespace: r.ServiceNamespace,
+				Path:      &path,
+			},
+			CABundle: r.CABundle,
+		},
+		Rules:                   rules,
+		FailurePolicy:           &failurePolicy,
+		SideEffects:             &sideEffects,
+		TimeoutSeconds:          &timeoutSeconds,
+		AdmissionReviewVersions: []string{"v1"},
+	}
+}
+
+// facadeRules returns a rule for the served versions of each CRD, sorted by CRD name
+func facadeRules(items []extv1.CustomResourceDefinition) []admissionregistrationv1.RuleWithOperations {
+	sort.Slice(items, func(i, j int) bool { return items[i].Name < items[j].Name })
+	rules := []admissionregistrationv1.RuleWithOperations{}
+	for _, crd := range items {
+		if !crd.DeletionTimestamp.IsZero() {
+			continue
+		}
+		versions := []string{}
+		for _, version := range crd.Spec.Versions {
+			if version.Served {
+				versions = append(versions, version.Name)
+			}
+		}
+		if len(versions) == 0 {
+			continue
+		}
+		scope := admissionregistrationv1.AllScopes
+		switch crd.Spec.Scope {
+		case extv1.NamespaceScoped:
+			scope = admissionregistrationv1.NamespacedScope
+		case extv1.ClusterScoped:
+			scope = admissionregistrationv1.ClusterScope
+		}
+		rules = append(rules, admissionregistrationv1.RuleWithOperations{
+			Operations: []admissionregistrationv1.OperationType{admissionregistrationv1.Create, admissionregistrationv1.Update},
+			Rule: admissionregistrationv1.Rule{
+				APIGroups:   []string{crd.Spec.Group},
+				APIVersions: versions,
+				Resources:   []string{crd.Spec.Names.Plural},
+				Scope:       &scope,
+			},
+		})
+	}
+	return rules
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *FacadeWebhookReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	isFacade := predicate.NewPredicateFuncs(func(obj client.Object) bool {
+		return obj.GetLabels()[crds.FacadeLabel] == "yes"
+	})
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("facadewebhook").
+		For(&extv1.CustomResourceDefinition{}, builder.WithPredicates(isFacade)).
+		Complete(r)
+}
@@ -0,0 +1,108 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	extv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/cloud-native-compositions/compositions/composition/pkg/crds"
+)
+
+func facadeCRD(plural, group string, labels map[string]string, versions ...string) *extv1.CustomResourceDefinition {
+	crd := &extv1.CustomResourceDefinition{
+		ObjectMeta: metav1.ObjectMeta{Name: plural + "." + group, Labels: labels},
+		Spec: extv1.CustomResourceDefinitionSpec{
+			Group: group,
+			Names: extv1.CustomResourceDefinitionNames{Plural: plural},
+			Scope: extv1.NamespaceScoped,
+		},
+	}
+	for _, version := range versions {
+		crd.Spec.Versions = append(crd.Spec.Versions, extv1.CustomResourceDefinitionVersion{Name: version, Served: true})
+	}
+	return crd
+}
+
+func TestFacadeWebhookReconcile(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := extv1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	facade := map[string]string{crds.FacadeLabel: "yes"}
+	teams := facadeCRD("teams", "facade.compositions.google.com", facade, "v1", "v2")
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		teams,
+		facadeCRD("apps", "facade.compositions.google.com", facade, "v1"),
+		facadeCRD("widgets", "example.com", nil, "v1"),
+	).Build()
+	r := &FacadeWebhookReconciler{Client: c, Scheme: scheme, Name: "facade-webhook",
+		ServiceName: "webhook-service", ServiceNamespace: "composition-system", Path: "/validate-facade", CABundle: []byte("ca")}
+	ctx := context.Background()
+
+	if _, err := r.Reconcile(ctx, ctrl.Request{}); err != nil {
+		t.Fatal(err)
+	}
+	config := &admissionregistrationv1.ValidatingWebhookConfiguration{}
+	if err := c.Get(ctx, types.NamespacedName{Name: "facade-webhook"}, config); err != nil {
+		t.Fatal(err)
+	}
+	if len(config.Webhooks) != 1 {
+		t.Fatalf("want 1 webhook, got %d", len(config.Webhooks))
+	}
+	webhook := config.Webhooks[0]
+	if *webhook.ClientConfig.Service.Path != "/validate-facade" || string(webhook.ClientConfig.CABundle) != "ca" {
+		t.Errorf("unexpected client config %+v", webhook.ClientConfig)
+	}
+	got := []string{}
+	for _, rule := range webhook.Rules {
+		got = append(got, rule.APIGroups[0]+"/"+rule.Resources[0]+"@"+strings.Join(rule.APIVersions, ","))
+	}
+	want := []string{"facade.compositions.google.com/apps@v1", "facade.compositions.google.com/teams@v1,v2"}
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("want rules %q, got %q", want, got)
+	}
+
+	// The configuration is removed with the last facade CRD
+	for _, name := range []string{"teams.facade.compositions.google.com", "apps.facade.compositions.google.com"} {
+		if err := c.Delete(ctx, &extv1.CustomResourceDefinition{ObjectMeta: metav1.ObjectMeta{Name: name}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := r.Reconcile(ctx, ctrl.Request{}); err != nil {
+		t.Fatal(err)
+	}
+	err := c.Get(ctx, client.ObjectKeyFromObject(config), config)
+	if !apierrors.IsNotFound(err) {
+		t.Errorf("want the configuration to be deleted, got %v", err)
+	}
+}
@@ -0,0 +1,215 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1alpha1
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	compositionv1alpha1 "github.com/cloud-native-compositions/compositions/composition/api/v1alpha1"
+	"github.com/cloud-native-compositions/compositions/composition/pkg/expanderclient"
+	"github.com/cloud-native-compositions/compositions/composition/pkg/lint"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	apimachineryvalidation "k8s.io/apimachinery/pkg/api/validation"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// log is for logging in this package.
+var compositionlog = logf.Log.WithName("composition-webhook")
+
+// SetupCompositionWebhookWithManager registers the webhook for Composition in the manager.
+func SetupCompositionWebhookWithManager(mgr ctrl.Manager, validator *CompositionCustomValidator) error {
+	return ctrl.NewWebhookManagedBy(mgr).For(&compositionv1alpha1.Composition{}).
+		WithValidator(validator).
+		Complete()
+}
+
+// The webhook fails open, the controller still reports invalid Compositions in their status.
+//+kubebuilder:webhook:path=/validate-composition-google-com-v1alpha1-composition,mutating=false,failurePolicy=ignore,sideEffects=None,groups=composition.google.com,resources=compositions,verbs=create;update,versions=v1alpha1,name=vcomposition.composition.google.com,admissionReviewVersions=v1,timeoutSeconds=10
+
+// CompositionCustomValidator rejects invalid Compositions when they are created or updated.
+// It runs the same checks as `compositions lint` against the installed ExpanderVersions.
+type CompositionCustomValidator struct {
+	// Client reads ExpanderVersions and expander configs
+	Client client.Reader
+	// ExpanderNamespace is the namespace of the ExpanderVersions
+	ExpanderNamespace string
+	// ValidateTimeout bounds the Validate rpc of each stage. The expanders are not
+	// called if it is 0.
+	ValidateTimeout time.Duration
+}
+
+var _ webhook.CustomValidator = &CompositionCustomValidator{}
+
+// ValidateCreate implements webhook.CustomValidator so a webhook will be registered for the type Composition.
+func (v *CompositionCustomValidator) ValidateCreate(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	composition, ok := obj.(*compositionv1alpha1.Composition)
+	if !ok {
+		return nil, fmt.Errorf("expected a Composition object but got %T", obj)
+	}
+	compositionlog.Info("Validation for Composition upon creation", "name", composition.GetName())
+	return v.validate(ctx, nil, composition)
+}
+
+// ValidateUpdate implements webhook.CustomValidator so a webhook will be registered for the type Composition.
+func (v *CompositionCustomValidator) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) (admission.Warnings, error) {
+	composition, ok := newObj.(*compositionv1alpha1.Composition)
+	if !ok {
+		return nil, fmt.Errorf("expected a Composition object for the newObj but got %T", newObj)
+	}
+	old, ok := oldObj.(*compositionv1alpha1.Composition)
+	if !ok {
+		return nil, fmt.Errorf("expected a Composition object for the oldObj but got %T", oldObj)
+	}
+	// Metadata updates, like removing finalizers, must not be blocked by a
+	// Composition that was accepted before the webhook was installed.
+	if !composition.DeletionTimestamp.IsZero() || equality.Semantic.DeepEqual(old.Spec, composition.Spec) {
+		return nil, nil
+	}
+	compositionlog.Info("Validation for Composition upon update", "name", composition.GetName())
+	return v.validate(ctx, old, composition)
+}
+
+// ValidateDelete implements webhook.CustomValidator so a webhook will be registered for the type Composition.
+func (v *CompositionCustomValidator) ValidateDelete(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+// validate runs the checks and returns the warnings and an Invalid error for error findings
+func (v *CompositionCustomValidator) validate(ctx context.Context, old, c *compositionv1alpha1.Composition) (admission.Warnings, error) {
+	warnings := admission.Warnings{}
+	errs := field.ErrorList{}
+	if old != nil {
+		errs = append(errs, immutableFields(old, c)...)
+	}
+
+	findings := []lint.Finding{}
+	findings = append(findings, lint.StageNames(c)...)
+	findings = append(findings, lint.Schema(c)...)
+	findings = append(findings, lint.Readiness(c)...)
+
+	evList := &compositionv1alpha1.ExpanderVersionList{}
+	if err := v.Client.List(ctx, evList, client.InNamespace(v.ExpanderNamespace)); err != nil {
+		return nil, apierrors.NewInternalError(fmt.Errorf("listing ExpanderVersions: %w", err))
+	}
+	opts := lint.Options{ValidateTimeout: v.ValidateTimeout}
+	// ExpanderVersions are named composition-<type>
+	expanderVersions := map[string]*compositionv1alpha1.ExpanderVersion{}
+	for i := range evList.Items {
+		ev := &evList.Items[i]
+		opts.ExpanderVersions = append(opts.ExpanderVersions, ev)
+		expanderVersions[strings.TrimPrefix(ev.Name, "composition-")] = ev
+	}
+	if len(opts.ExpanderVersions) == 0 {
+		warnings = append(warnings, fmt.Sprintf("no ExpanderVersions found in namespace %s, expander types and versions are not checked", v.ExpanderNamespace))
+	} else {
+		findings = append(findings, lint.ExpanderTypes(c, expanderVersions)...)
+		if v.ValidateTimeout != 0 && !lint.HasErrors(findings) {
+			configs, configWarnings := expanderConfigs(ctx, v.Client, c, expanderVersions)
+			warnings = append(warnings, configWarnings...)
+			opts.Configs = configs
+			opts.Client = func(expanderType, version string) (*expanderclient.Client, error) {
+				return validateClient(expanderVersions[expanderType], version)
+			}
+			findings = append(findings, lint.Validate(ctx, c, opts)...)
+		}
+	}
+
+	for _, f := range findings {
+		path := f.Path
+		if f.Object != "Composition/"+c.Name {
+			path = f.Object + " " + f.Path
+		}
+		switch f.Severity {
+		case lint.SeverityError:
+			errs = append(errs, field.Invalid(field.NewPath(path), field.OmitValueType{}, fmt.Sprintf("%s: %s", f.Code, f.Message)))
+		case lint.SeverityWarning:
+			warnings = append(warnings, fmt.Sprintf("%s: %s: %s", path, f.Code, f.Message))
+		}
+	}
+	if len(errs) == 0 {
+		return warnings, nil
+	}
+	return warnings, apierrors.NewInvalid(compositionv1alpha1.GroupVersion.WithKind("Composition").GroupKind(), c.Name, errs)
+}
+
+// immutableFields rejects changes of the facade API, the controller does not
+// migrate facades to a different CRD
+func immutableFields(old, c *compositionv1alpha1.Composition) field.ErrorList {
+	spec := field.NewPath("spec")
+	errs := apimachineryvalidation.ValidateImmutableField(c.Spec.InputAPIGroup, old.Spec.InputAPIGroup, spec.Child("inputAPIGroup"))
+	if (old.Spec.Schema == nil) != (c.Spec.Schema == nil) {
+		return append(errs, field.Forbidden(spec.Child("schema"), "schema can not be added or removed"))
+	}
+	if c.Spec.Schema == nil {
+		return errs
+	}
+	schemaPath := spec.Child("schema")
+	errs = append(errs, apimachineryvalidation.ValidateImmutableField(c.Spec.Schema.Group, old.Spec.Schema.Group, schemaPath.Child("group"))...)
+	errs = append(errs, apimachineryvalidation.ValidateImmutableField(c.Spec.Schema.Kind, old.Spec.Schema.Kind, schemaPath.Child("kind"))...)
+	errs = append(errs, apimachineryvalidation.ValidateImmutableField(c.Spec.Schema.APIVersion, old.Spec.Schema.APIVersion, schemaPath.Child("apiVersion"))...)
+	return errs
+}
+
+// expanderConfigs reads the expander configs referenced by the stages. Stages whose
+// config does not exist yet are not validated.
+func expanderConfigs(ctx context.Context, r client.Reader, c *compositionv1alpha1.Composition,
+	expanderVersions map[string]*compositionv1alpha1.ExpanderVersion) ([]*unstructured.Unstructured, []string) {
+	configs := []*unstructured.Unstructured{}
+	warnings := []string{}
+	for i, expander := range c.Spec.Expanders {
+		ev := expanderVersions[expander.Type]
+		if expander.ConfigRef == nil || ev == nil {
+			continue
+		}
+		config := &unstructured.Unstructured{}
+		configGVK := ev.ConfigGVK(expander.Version)
+		config.SetGroupVersionKind(schema.GroupVersionKind{Group: configGVK.Group, Version: configGVK.Version, Kind: configGVK.Kind})
+		nn := types.NamespacedName{Namespace: expander.ConfigRef.Namespace, Name: expander.ConfigRef.Name}
+		if err := r.Get(ctx, nn, config); err != nil {
+			warnings = append(warnings, fmt.Sprintf("spec.expanders[%d].configref: %s %s not validated: %v", i, config.GetKind(), nn, err))
+			continue
+		}
+		configs = append(configs, config)
+	}
+	return configs, warnings
+}
+
+// validateClient returns a client for the expander version, or nil if it can not be validated
+func validateClient(ev *compositionv1alpha1.ExpanderVersion, version string) (*expanderclient.Client, error) {
+	if ev == nil || ev.Spec.Type == compositionv1alpha1.ExpanderTypeJob {
+		return nil, nil
+	}
+	if capabilities := ev.Capabilities(version); capabilities != nil && !capabilities.Validate {
+		return nil, nil
+	}
+	uri, ok := ev.Status.VersionMap[version]
+	if !ok {
+		return nil, fmt.Errorf("version %s is not in the version map of ExpanderVersion %s", version, ev.Name)
+	}
+	return expanderclient.New(uri)
+}
@@ -0,0 +1,237 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1alpha1
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	compositionv1alpha1 "github.com/cloud-native-compositions/compositions/composition/api/v1alpha1"
+	"github.com/cloud-native-compositions/compositions/composition/pkg/expandersdk"
+	"github.com/cloud-native-compositions/compositions/composition/pkg/inproc"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func init() {
+	// Fails configs containing "invalid" and hangs on "slow".
+	// Fails to evaluate facades named "broken" and waits on "pending".
+	inproc.Register("webhooktest", expandersdk.NewServer(&expandersdk.Expander[string]{
+		Name: "webhooktest",
+		Validate: func(ctx context.Context, req *expandersdk.Request[string]) (*expandersdk.Result, error) {
+			config := string(req.RawConfig)
+			if strings.Contains(config, "slow") {
+				<-ctx.Done()
+				return nil, ctx.Err()
+			}
+			if strings.Contains(config, "invalid") {
+				return nil, expandersdk.Failed(&expandersdk.Diagnostic{
+					Severity: expandersdk.SeverityError,
+					Code:     "TemplateSyntaxError",
+					Message:  "unexpected end of template",
+				})
+			}
+			return &expandersdk.Result{}, nil
+		},
+		Evaluate: func(ctx context.Context, req *expandersdk.Request[string]) (*expandersdk.Result, error) {
+			name, _, _ := unstructured.NestedString(req.Facade.Object, "spec", "name")
+			switch name {
+			case "broken":
+				return nil, expandersdk.Failedf("name %q is reserved", name)
+			case "pending":
+				return nil, expandersdk.Wait("waiting for %s", name)
+			}
+			return &expandersdk.Result{}, nil
+		},
+	}).V2())
+}
+
+func newValidator(t *testing.T, objects ...client.Object) *CompositionCustomValidator {
+	scheme := runtime.NewScheme()
+	if err := compositionv1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	ev := &compositionv1alpha1.ExpanderVersion{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "composition-system", Name: "composition-test"},
+		Spec: compositionv1alpha1.ExpanderVersionSpec{
+			Type:          compositionv1alpha1.ExpanderTypeInProc,
+			ValidVersions: []string{"v0.0.1"},
+			Config:        compositionv1alpha1.ExpanderConfigGVK{Group: "composition.google.com", Version: "v1alpha1", Kind: "TestConfiguration"},
+		},
+		Status: compositionv1alpha1.ExpanderVersionStatus{
+			VersionMap: map[string]string{"latest": inproc.URI("webhooktest"), "v0.0.1": inproc.URI("webhooktest")},
+		},
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(append(objects, ev)...).Build()
+	return &CompositionCustomValidator{Client: c, ExpanderNamespace: "composition-system", ValidateTimeout: time.Second}
+}
+
+func composition(expanders ...compositionv1alpha1.Expander) *compositionv1alpha1.Composition {
+	return &compositionv1alpha1.Composition{
+		ObjectMeta: metav1.ObjectMeta{Name: "team"},
+		Spec: compositionv1alpha1.CompositionSpec{
+			Schema: &compositionv1alpha1.Schema{
+				Kind:       "Team",
+				APIVersion: "v1",
+				Spec:       runtime.RawExtension{Raw: []byte(`{"name": "string"}`)},
+			},
+			Expanders: expanders,
+		},
+	}
+}
+
+func stage(name, template string) compositionv1alpha1.Expander {
+	return compositionv1alpha1.Expander{Name: name, Type: "test", Version: "latest",
+		ExpanderConfig: compositionv1alpha1.ExpanderConfig{Template: template}}
+}
+
+// wantErrors checks that err lists exactly the given messages
+func wantErrors(t *testing.T, err error, want ...string) {
+	t.Helper()
+	if len(want) == 0 {
+		if err != nil {
+			t.Errorf("want no error, got: %v", err)
+		}
+		return
+	}
+	if err == nil {
+		t.Fatalf("want error containing %q", want)
+	}
+	for _, w := range want {
+		if !strings.Contains(err.Error(), w) {
+			t.Errorf("want error containing %q, got: %v", w, err)
+		}
+	}
+}
+
+func TestValidateCreate(t *testing.T) {
+	v := newValidator(t)
+	ctx := context.Background()
+
+	_, err := v.ValidateCreate(ctx, composition(stage("a", "ok"), stage("b", "ok")))
+	wantErrors(t, err)
+
+	_, err = v.ValidateCreate(ctx, composition(stage("a", "ok"), stage("a", "ok"), stage("", "ok")))
+	wantErrors(t, err, "spec.expanders[1].name", "DuplicateStageName", "spec.expanders[2].name", "EmptyStageName")
+
+	unknown := composition(stage("a", "ok"), stage("b", "ok"))
+	unknown.Spec.Expanders[0].Type = "jinja3"
+	unknown.Spec.Expanders[1].Version = "v9"
+	_, err = v.ValidateCreate(ctx, unknown)
+	wantErrors(t, err, "spec.expanders[0].type", "UnknownExpanderType", "spec.expanders[1].version", "UnknownExpanderVersion")
+
+	readiness := composition(stage("a", "ok"))
+	readiness.Spec.Readiness = []compositionv1alpha1.ReadyOn{{Group: "sql.cnrm.cloud.google.com", Kind: "SQLInstance", Ready: "status.ready =="}}
+	_, err = v.ValidateCreate(ctx, readiness)
+	wantErrors(t, err, "spec.readiness[0].readyIf", "InvalidReadyIf")
+
+	badSchema := composition(stage("a", "ok"))
+	badSchema.Spec.Schema.Spec.Raw = []byte(`{"name": "strin"}`)
+	_, err = v.ValidateCreate(ctx, badSchema)
+	wantErrors(t, err, "spec.schema.spec", "InvalidSchema")
+
+	_, err = v.ValidateCreate(ctx, composition(stage("a", "invalid")))
+	wantErrors(t, err, "spec.expanders[0].template", "TemplateSyntaxError: unexpected end of template")
+}
+
+func TestValidateTimeout(t *testing.T) {
+	v := newValidator(t)
+	v.ValidateTimeout = 50 * time.Millisecond
+	warnings, err := v.ValidateCreate(context.Background(), composition(stage("a", "slow")))
+	wantErrors(t, err)
+	if len(warnings) != 1 || !strings.Contains(warnings[0], "ExpanderUnreachable") {
+		t.Errorf("want an ExpanderUnreachable warning, got %q", warnings)
+	}
+
+	// Without a timeout the expanders are not called
+	v.ValidateTimeout = 0
+	_, err = v.ValidateCreate(context.Background(), composition(stage("a", "invalid")))
+	wantErrors(t, err)
+}
+
+func TestValidateConfigRef(t *testing.T) {
+	config := &unstructured.Unstructured{}
+	config.SetAPIVersion("composition.google.com/v1alpha1")
+	config.SetKind("TestConfiguration")
+	config.SetNamespace("default")
+	config.SetName("invalid-config")
+	v := newValidator(t, config)
+
+	withConfig := func(name string) *compositionv1alpha1.Composition {
+		c := composition(stage("a", ""))
+		c.Spec.Expanders[0].ConfigRef = &compositionv1alpha1.ConfigReference{Name: name, Namespace: "default"}
+		return c
+	}
+	_, err := v.ValidateCreate(context.Background(), withConfig("invalid-config"))
+	wantErrors(t, err, "TestConfiguration/invalid-config spec", "TemplateSyntaxError")
+
+	// Configs can be created after the Composition
+	warnings, err := v.ValidateCreate(context.Background(), withConfig("later"))
+	wantErrors(t, err)
+	if len(warnings) != 1 || !strings.Contains(warnings[0], "not validated") {
+		t.Errorf("want a warning for the missing config, got %q", warnings)
+	}
+}
+
+func TestValidateUpdate(t *testing.T) {
+	v := newValidator(t)
+	ctx := context.Background()
+	old := composition(stage("a", "ok"))
+
+	changed := old.DeepCopy()
+	changed.Spec.Schema.Kind = "Group"
+	changed.Spec.Schema.APIVersion = "v2"
+	changed.Spec.Schema.Spec.Raw = []byte(`{"name": "string", "size": "integer"}`)
+	_, err := v.ValidateUpdate(ctx, old, changed)
+	wantErrors(t, err, "spec.schema.kind: Invalid value", "spec.schema.apiVersion: Invalid value", "field is immutable")
+	if strings.Contains(err.Error(), "spec.schema.spec") {
+		t.Errorf("want the schema spec to be mutable, got: %v", err)
+	}
+
+	legacy := old.DeepCopy()
+	legacy.Spec.Schema = nil
+	legacy.Spec.InputAPIGroup = "teams.facade.compositions.google.com"
+	_, err = v.ValidateUpdate(ctx, old, legacy)
+	wantErrors(t, err, "spec.inputAPIGroup", "spec.schema: Forbidden")
+
+	// Metadata only updates of Compositions that do not pass are allowed
+	invalid := composition(stage("a", "ok"), stage("a", "ok"))
+	finalized := invalid.DeepCopy()
+	finalized.Finalizers = nil
+	_, err = v.ValidateUpdate(ctx, invalid, finalized)
+	wantErrors(t, err)
+}
+
+func TestNoExpanderVersions(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := compositionv1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	v := &CompositionCustomValidator{
+		Client:            fake.NewClientBuilder().WithScheme(scheme).Build(),
+		ExpanderNamespace: "composition-system",
+		ValidateTimeout:   time.Second,
+	}
+	warnings, err := v.ValidateCreate(context.Background(), composition(stage("a", "invalid")))
+	wantErrors(t, err)
+	if len(warnings) != 1 || !strings.Contains(warnings[0], "no ExpanderVersions") {
+		t.Errorf("want a warning, got %q", warnings)
+	}
+}
@@ -0,0 +1,246 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1alpha1
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	compositionv1alpha1 "github.com/cloud-native-compositions/compositions/composition/api/v1alpha1"
+	"github.com/cloud-native-compositions/compositions/composition/pkg/crds"
+	"github.com/cloud-native-compositions/compositions/composition/pkg/render"
+	admissionv1 "k8s.io/api/admission/v1"
+	extv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// FacadeWebhookPath is the path the facade webhook is served at. The
+// ValidatingWebhookConfiguration is kept by the FacadeWebhookReconciler.
+const FacadeWebhookPath = "/validate-facade"
+
+var facadelog = logf.Log.WithName("facade-webhook")
+
+// FacadePolicy is what the facade webhook does with facades of a Composition that is not Ready
+type FacadePolicy string
+
+const (
+	// FacadePolicyWarn admits the facade with a warning
+	FacadePolicyWarn FacadePolicy = "warn"
+	// FacadePolicyDeny rejects the facade
+	FacadePolicyDeny FacadePolicy = "deny"
+)
+
+// SetupFacadeWebhookWithManager registers the facade webhook in the manager.
+func SetupFacadeWebhookWithManager(mgr ctrl.Manager, validator *FacadeValidator) error {
+	if validator.Policy != FacadePolicyWarn && validator.Policy != FacadePolicyDeny {
+		return fmt.Errorf("unknown facade webhook policy %q, expected %s or %s", validator.Policy, FacadePolicyWarn, FacadePolicyDeny)
+	}
+	mgr.GetWebhookServer().Register(FacadeWebhookPath, &webhook.Admission{Handler: validator})
+	return nil
+}
+
+// FacadeValidator checks the Composition of a facade when the facade is
+// created or its spec changes. Facades of a Composition that is missing, not
+// yet processed or not Ready are admitted with a warning or rejected, depending
+// on the Policy.
+type FacadeValidator struct {
+	// Client reads Compositions, facade CRDs, ExpanderVersions, expander configs
+	// and Contexts
+	Client client.Reader
+	// ExpanderNamespace is the namespace of the ExpanderVersions
+	ExpanderNamespace string
+	// Policy for facades of a Composition that is not Ready
+	Policy FacadePolicy
+	// RenderTimeout bounds the render of the first stage. Facades failing to
+	// render are rejected. The stage is not rendered if it is 0.
+	RenderTimeout time.Duration
+}
+
+var _ admission.Handler = &FacadeValidator{}
+
+// Handle implements admission.Handler
+func (v *FacadeValidator) Handle(ctx context.Context, req admission.Request) admission.Response {
+	if req.Operation != admissionv1.Create && req.Operation != admissionv1.Update {
+		return admission.Allowed("")
+	}
+	facade := &unstructured.Unstructured{}
+	if err := json.Unmarshal(req.Object.Raw, &facade.Object); err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+	// Metadata updates, like removing finalizers, are not blocked
+	if facade.GetDeletionTimestamp() != nil {
+		return admission.Allowed("")
+	}
+	if req.Operation == admissionv1.Update {
+		old := &unstructured.Unstructured{}
+		if err := json.Unmarshal(req.OldObject.Raw, &old.Object); err != nil {
+			return admission.Errored(http.StatusBadRequest, err)
+		}
+		if equality.Semantic.DeepEqual(old.Object["spec"], facade.Object["spec"]) {
+			return admission.Allowed("")
+		}
+	}
+	if facade.GetNamespace() == "" {
+		facade.SetNamespace(req.Namespace)
+	}
+
+	crdName := req.Resource.Resource + "." + req.Resource.Group
+	composition, err := v.composition(ctx, crdName)
+	if err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+	if problem := notReady(composition, crdName); problem != "" {
+		facadelog.Info("Composition is not ready", "facade", req.Name, "namespace", req.Namespace, "resource", crdName, "problem", problem)
+		if v.Policy == FacadePolicyDeny {
+			return admission.Denied(problem)
+		}
+		return admission.Allowed("").WithWarnings(problem + ", the facade is not reconciled until it is")
+	}
+
+	if v.RenderTimeout == 0 {
+		return admission.Allowed("")
+	}
+	warning, err := v.render(ctx, composition, facade)
+	if err != nil {
+		facadelog.Info("Facade failed to render", "facade", req.Name, "namespace", req.Namespace, "resource", crdName, "error", err.Error())
+		return admission.Denied(fmt.Sprintf("render check of Composition %s failed: %v", composition.Name, err))
+	}
+	if warning != "" {
+		return admission.Allowed("").WithWarnings(warning)
+	}
+	return admission.Allowed("")
+}
+
+// composition returns the Composition the facade CRD was created for, or nil
+func (v *FacadeValidator) composition(ctx context.Context, crdName string) (*compositionv1alpha1.Composition, error) {
+	compositions := &compositionv1alpha1.CompositionList{}
+	if err := v.Client.List(ctx, compositions); err != nil {
+		return nil, fmt.Errorf("listing Compositions: %w", err)
+	}
+	for i := range compositions.Items {
+		if crds.FacadeCRDName(&compositions.Items[i]) == crdName {
+			return &compositions.Items[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// notReady returns why the facades of the composition are not reconciled, or ""
+func notReady(c *compositionv1alpha1.Composition, crdName string) string {
+	if c == nil {
+		return fmt.Sprintf("no Composition found for %s", crdName)
+	}
+	name := types.NamespacedName{Namespace: c.Namespace, Name: c.Name}
+	if !c.DeletionTimestamp.IsZero() {
+		return fmt.Sprintf("Composition %s is being deleted", name)
+	}
+	if c.Status.Generation != c.Generation {
+		return fmt.Sprintf("Composition %s has not been processed since it changed", name)
+	}
+	ready := meta.FindStatusCondition(c.Status.Conditions, string(compositionv1alpha1.Ready))
+	if ready == nil {
+		return fmt.Sprintf("Composition %s has no Ready condition", name)
+	}
+	if ready.Status != metav1.ConditionTrue {
+		return fmt.Sprintf("Composition %s is not Ready: %s", name, ready.Message)
+	}
+	return ""
+}
+
+// render dry-runs the first stage against the facade and returns an error if
+// the expander fails to evaluate it. A stage waiting for its inputs passes.
+// Stages that can not be rendered here are skipped with a warning.
+func (v *FacadeValidator) render(ctx context.Context, c *compositionv1alpha1.Composition, facade *unstructured.Unstructured) (string, error) {
+	if len(c.Spec.Expanders) == 0 {
+		return "", nil
+	}
+	expander := c.Spec.Expanders[0]
+
+	// ExpanderVersions are named composition-<type>
+	ev := &compositionv1alpha1.ExpanderVersion{}
+	nn := types.NamespacedName{Namespace: v.ExpanderNamespace, Name: "composition-" + expander.Type}
+	if err := v.Client.Get(ctx, nn, ev); err != nil {
+		return fmt.Sprintf("render check skipped: ExpanderVersion %s: %v", nn, err), nil
+	}
+	if ev.Spec.Type == compositionv1alpha1.ExpanderTypeJob {
+		return "", nil
+	}
+	uri, ok := ev.Status.VersionMap[expander.Version]
+	if !ok {
+		return fmt.Sprintf("render check skipped: version %s is not in the version map of ExpanderVersion %s", expander.Version, nn), nil
+	}
+
+	configs, warnings := expanderConfigs(ctx, v.Client, c, map[string]*compositionv1alpha1.ExpanderVersion{expander.Type: ev})
+	if len(warnings) != 0 {
+		return "render check skipped: " + warnings[0], nil
+	}
+
+	var contextObj *unstructured.Unstructured
+	contextcr := &unstructured.Unstructured{}
+	contextcr.SetGroupVersionKind(compositionv1alpha1.GroupVersion.WithKind("Context"))
+	err := v.Client.Get(ctx, types.NamespacedName{Namespace: facade.GetNamespace(), Name: "context"}, contextcr)
+	switch {
+	case err == nil:
+		contextObj = contextcr
+	case !apierrors.IsNotFound(err):
+		return fmt.Sprintf("render check skipped: reading the Context: %v", err), nil
+	}
+
+	// Without the CRD the plural of the facade is guessed from its kind
+	facadeCRD := &unstructured.Unstructured{}
+	facadeCRD.SetGroupVersionKind(extv1.SchemeGroupVersion.WithKind("CustomResourceDefinition"))
+	if err := v.Client.Get(ctx, types.NamespacedName{Name: crds.FacadeCRDName(c)}, facadeCRD); err != nil {
+		facadelog.Info("Rendering without the facade CRD", "crd", crds.FacadeCRDName(c), "error", err.Error())
+		facadeCRD = nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, v.RenderTimeout)
+	defer cancel()
+	_, err = render.Render(ctx, render.Options{
+		Composition: c,
+		Facade:      facade,
+		FacadeCRD:   facadeCRD,
+		Context:     contextObj,
+		Configs:     configs,
+		Expanders:   map[string]string{expander.Type: uri},
+		Stages:      1,
+		Logger:      facadelog,
+	})
+	var wait *render.WaitError
+	var failed *render.EvaluateError
+	switch {
+	case err == nil, errors.As(err, &wait):
+		return "", nil
+	case errors.As(err, &failed):
+		return "", err
+	case ctx.Err() != nil:
+		return fmt.Sprintf("render check skipped: stage %s did not finish within %s", expander.Name, v.RenderTimeout), nil
+	}
+	return fmt.Sprintf("render check skipped: %v", err), nil
+}
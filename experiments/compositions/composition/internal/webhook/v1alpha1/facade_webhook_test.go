@@ -0,0 +1,142 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1alpha1
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	compositionv1alpha1 "github.com/cloud-native-compositions/compositions/composition/api/v1alpha1"
+	admissionv1 "k8s.io/api/admission/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+func newFacadeValidator(t *testing.T, policy FacadePolicy, objects ...client.Object) *FacadeValidator {
+	v := newValidator(t, objects...)
+	return &FacadeValidator{Client: v.Client, ExpanderNamespace: v.ExpanderNamespace, Policy: policy}
+}
+
+// readyComposition returns a Composition for teams.facade.compositions.google.com
+func readyComposition(status metav1.ConditionStatus, message string) *compositionv1alpha1.Composition {
+	c := composition(stage("a", "ok"))
+	c.Namespace = "default"
+	c.Generation = 2
+	c.Spec.Schema.Group = "facade.compositions.google.com"
+	c.Status.Generation = 2
+	c.Status.Conditions = []metav1.Condition{{Type: string(compositionv1alpha1.Ready), Status: status, Message: message}}
+	return c
+}
+
+func facadeRequest(operation admissionv1.Operation, name string, oldName string) admission.Request {
+	object := func(name string) runtime.RawExtension {
+		return runtime.RawExtension{Raw: []byte(fmt.Sprintf(
+			`{"apiVersion": "facade.compositions.google.com/v1", "kind": "Team", "metadata": {"name": "team-a"}, "spec": {"name": %q}}`, name))}
+	}
+	req := admission.Request{AdmissionRequest: admissionv1.AdmissionRequest{
+		Operation: operation,
+		Resource:  metav1.GroupVersionResource{Group: "facade.compositions.google.com", Version: "v1", Resource: "teams"},
+		Namespace: "team-a",
+		Name:      "team-a",
+		Object:    object(name),
+	}}
+	if oldName != "" {
+		req.OldObject = object(oldName)
+	}
+	return req
+}
+
+// wantResponse checks the response is allowed or denied with a message or
+// warning containing want
+func wantResponse(t *testing.T, resp admission.Response, allowed bool, want string) {
+	t.Helper()
+	if resp.Allowed != allowed {
+		t.Errorf("want allowed=%v, got %v: %v", allowed, resp.Allowed, resp.Result)
+	}
+	got := strings.Join(resp.Warnings, "; ")
+	if resp.Result != nil {
+		got += resp.Result.Message
+	}
+	if want == "" && got != "" {
+		t.Errorf("want no message or warning, got %q", got)
+	}
+	if !strings.Contains(got, want) {
+		t.Errorf("want a message or warning containing %q, got %q", want, got)
+	}
+}
+
+func TestFacadeNotReady(t *testing.T) {
+	ctx := context.Background()
+	create := facadeRequest(admissionv1.Create, "a", "")
+
+	for _, tc := range []struct {
+		name        string
+		composition *compositionv1alpha1.Composition
+		want        string
+	}{
+		{name: "missing", want: "no Composition found for teams.facade.compositions.google.com"},
+		{name: "not ready", composition: readyComposition(metav1.ConditionFalse, "Validating failed for stages: a"),
+			want: "Composition default/team is not Ready: Validating failed for stages: a"},
+		{name: "not processed", composition: func() *compositionv1alpha1.Composition {
+			c := readyComposition(metav1.ConditionTrue, "")
+			c.Generation = 3
+			return c
+		}(), want: "has not been processed since it changed"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			objects := []client.Object{}
+			if tc.composition != nil {
+				objects = append(objects, tc.composition)
+			}
+			wantResponse(t, newFacadeValidator(t, FacadePolicyWarn, objects...).Handle(ctx, create), true, tc.want)
+			wantResponse(t, newFacadeValidator(t, FacadePolicyDeny, objects...).Handle(ctx, create), false, tc.want)
+		})
+	}
+
+	ready := readyComposition(metav1.ConditionTrue, "")
+	wantResponse(t, newFacadeValidator(t, FacadePolicyDeny, ready).Handle(ctx, create), true, "")
+
+	// Updates that do not change the spec are not blocked
+	v := newFacadeValidator(t, FacadePolicyDeny)
+	wantResponse(t, v.Handle(ctx, facadeRequest(admissionv1.Update, "a", "a")), true, "")
+	wantResponse(t, v.Handle(ctx, facadeRequest(admissionv1.Update, "b", "a")), false, "no Composition found")
+	wantResponse(t, v.Handle(ctx, facadeRequest(admissionv1.Delete, "a", "a")), true, "")
+}
+
+func TestFacadeRenderCheck(t *testing.T) {
+	ctx := context.Background()
+	v := newFacadeValidator(t, FacadePolicyDeny, readyComposition(metav1.ConditionTrue, ""))
+
+	// Not rendered without a timeout
+	wantResponse(t, v.Handle(ctx, facadeRequest(admissionv1.Create, "broken", "")), true, "")
+
+	v.RenderTimeout = time.Second
+	wantResponse(t, v.Handle(ctx, facadeRequest(admissionv1.Create, "a", "")), true, "")
+	wantResponse(t, v.Handle(ctx, facadeRequest(admissionv1.Create, "pending", "")), true, "")
+	wantResponse(t, v.Handle(ctx, facadeRequest(admissionv1.Create, "broken", "")), false,
+		`render check of Composition team failed: stage a: Evaluate Failed: name "broken" is reserved`)
+
+	// Stages that can not be rendered are skipped
+	unknown := readyComposition(metav1.ConditionTrue, "")
+	unknown.Spec.Expanders[0].Type = "jinja3"
+	v = newFacadeValidator(t, FacadePolicyDeny, unknown)
+	v.RenderTimeout = time.Second
+	wantResponse(t, v.Handle(ctx, facadeRequest(admissionv1.Create, "broken", "")), true, "render check skipped")
+}
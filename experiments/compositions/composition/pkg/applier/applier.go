@@ -17,14 +17,15 @@ package applier
 import (
 	"context"
 	"fmt"
-	"strings"
 
 	compositionv1alpha1 "github.com/cloud-native-compositions/compositions/composition/api/v1alpha1"
 	"github.com/cloud-native-compositions/compositions/composition/pkg/cel"
+	"github.com/cloud-native-compositions/compositions/composition/pkg/stagevalues"
 	"github.com/go-logr/logr"
 	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/dynamic"
 	"sigs.k8s.io/cli-utils/pkg/kstatus/status"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -36,7 +37,7 @@ const StageLabel = "compositions.google.com/applier-stage"
 
 type ApplierClient struct {
 	RESTMapper meta.RESTMapper
-	Dynamic    *dynamic.DynamicClient
+	Dynamic    dynamic.Interface
 	Client     client.Client
 }
 
@@ -160,12 +161,12 @@ func (a *Applier) Load() error {
 	a.objects = []applyset.ApplyableObject{}
 
 	// We dont error out on empty manifests
-	if stage.Manifest == "" {
+	if stage.Manifest == "" && len(stage.Objects) == 0 {
 		a.logger.Info(".spec.stages[name] has empty manifests. Nothing to apply")
 		return nil
 	}
 
-	objects, err := manifest.ParseObjects(a.ctx, stage.Manifest)
+	objects, err := parseObjects(a.ctx, stage.Manifest, stage.Objects)
 	if err != nil {
 		a.logger.Error(err, "Error parsing manifest")
 		return err
@@ -195,6 +196,24 @@ func (a *Applier) Load() error {
 	return nil
 }
 
+// parseObjects returns the objects of a stage or hook. Objects returned by
+// grpc expanders are stored as JSON objects, only job expanders write a
+// manifest that needs parsing.
+func parseObjects(ctx context.Context, m string, raw []runtime.RawExtension) (*manifest.Objects, error) {
+	if len(raw) == 0 {
+		return manifest.ParseObjects(ctx, m)
+	}
+	objects := &manifest.Objects{}
+	for _, r := range raw {
+		o, err := manifest.ParseJSONToObject(r.Raw)
+		if err != nil {
+			return nil, err
+		}
+		objects.Items = append(objects.Items, o)
+	}
+	return objects, nil
+}
+
 func (a *Applier) injectOwnerRef(objects *manifest.Objects) error {
 	for _, o := range objects.Items {
 		// TODO (barney-s): This would result in some objects not being cleaned up.
@@ -396,69 +415,15 @@ func (a *Applier) AreResourcesReady() (bool, error) {
 	return allReady, nil
 }
 
+// AddAppliedObjectsIntoValues is the implicit getter. It makes the applied objects
+// available to subsequent stages.
 func (a *Applier) AddAppliedObjectsIntoValues(values map[string]interface{}) map[string]interface{} {
+	objects := []*unstructured.Unstructured{}
 	for _, resultObj := range a.results.Objects {
 		if resultObj.Apply.IsPruned {
 			continue
 		}
-		obj := resultObj.LastApplied
-		name := obj.GetName()
-		gvk := obj.GroupVersionKind()
-		kind := strings.ToLower(gvk.Kind)
-
-		// short path: values.<kind>.<name>. May clash
-		_, ok := values[kind]
-		if !ok {
-			values[kind] = map[string]interface{}{}
-		}
-		ref := values[kind].(map[string]interface{})
-
-		_, ok = ref[name]
-		if ok {
-			// Clash !! We will ignore
-			a.logger.Info("Clash when adding applied objects to values.", "kind", kind, "name", name)
-		} else {
-			ref[name] = obj.Object
-		}
-
-		// long path: values.<group>.<kind>.<namespace>.<name> will not clash
-		// Long path may not be practical since the namespace is not part of the composition and
-		// most templating languages dont support nested templatable variable.
-		// ex: {{ values.deployment.teampage.status.something  }} will work in jinja2
-		// but this {{ values.apps.deployment.{{teampage.metadata.namespace}}.status.something }} wont work
-		// So leaving this code commented for now.
-		/*
-			group := strings.ReplaceAll(strings.ToLower(gvk.Group), ".", "_")
-			namespace := obj.GetNamespace()
-
-			if group == "" {
-				group = "core"
-			}
-			_, ok = values[group]
-			if !ok {
-				values[group] = map[string]interface{}{}
-			}
-			ref = values[group].(map[string]interface{})
-
-			_, ok = ref[kind]
-			if !ok {
-				ref[kind] = map[string]interface{}{}
-			}
-			ref = ref[kind].(map[string]interface{})
-
-			_, ok = ref[namespace]
-			if !ok {
-				ref[namespace] = map[string]interface{}{}
-			}
-			ref = ref[namespace].(map[string]interface{})
-
-			_, ok = ref[name]
-			if !ok {
-				ref[name] = map[string]interface{}{}
-			}
-			ref[name] = obj.Object
-		*/
-
+		objects = append(objects, resultObj.LastApplied)
 	}
-	return values
+	return stagevalues.AddObjects(a.logger, values, objects)
 }
@@ -0,0 +1,371 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package applier
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	compositionv1alpha1 "github.com/cloud-native-compositions/compositions/composition/api/v1alpha1"
+	"github.com/go-logr/logr"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/dynamic"
+	"sigs.k8s.io/cli-utils/pkg/kstatus/status"
+)
+
+// HookRevisionAnnotation is the revision of the stage a hook object was created for
+const HookRevisionAnnotation = "compositions.google.com/hook-revision"
+
+// HookRunner applies the hooks of a stage, one step of a phase at a time.
+// A step is the hooks of a phase with the same weight. Its objects are created
+// together, and the next step runs once they all succeeded.
+type HookRunner struct {
+	client    ApplierClient
+	planCR    *compositionv1alpha1.Plan
+	stageName string
+	namespace string
+	logger    logr.Logger
+	ctx       context.Context
+	hooks     []compositionv1alpha1.Hook
+
+	// Status is the progress of the hooks, to be written to the stage status
+	Status *compositionv1alpha1.HookStatus
+}
+
+// NewHookRunner returns the runner of the hooks of the stage in the plan, or
+// nil if it has none. previous is the stage status the plan was last reconciled
+// with.
+func NewHookRunner(
+	ctx context.Context, logger logr.Logger,
+	ac ApplierClient,
+	stage string, namespace string,
+	plan *compositionv1alpha1.Plan,
+	previous *compositionv1alpha1.StageStatus,
+) (*HookRunner, error) {
+	s, ok := plan.Spec.Stages[stage]
+	if !ok || len(s.Hooks) == 0 {
+		return nil, nil
+	}
+	revision, err := stageRevision(s)
+	if err != nil {
+		return nil, err
+	}
+	r := &HookRunner{
+		client:    ac,
+		planCR:    plan,
+		stageName: stage,
+		namespace: namespace,
+		logger:    logger.WithName("Hooks"),
+		ctx:       ctx,
+		hooks:     s.Hooks,
+	}
+	switch {
+	case previous == nil:
+		r.Status = &compositionv1alpha1.HookStatus{Revision: revision}
+	case previous.Hooks != nil && previous.Hooks.Revision == revision:
+		r.Status = previous.Hooks.DeepCopy()
+	default:
+		// Objects of the stage applied for an earlier revision make this an upgrade
+		upgrade := len(previous.LastApplied) != 0 || (previous.Hooks != nil && previous.Hooks.Upgrade)
+		r.Status = &compositionv1alpha1.HookStatus{Revision: revision, Upgrade: upgrade}
+	}
+	return r, nil
+}
+
+// stageRevision is a digest of the manifest and hooks of a stage
+func stageRevision(stage compositionv1alpha1.Stage) (string, error) {
+	j, err := json.Marshal(stage)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal stage: %w", err)
+	}
+	return fmt.Sprintf("%x", sha256.Sum256(j))[:16], nil
+}
+
+// PrePhase is the phase of the hooks run before the objects of the stage are applied
+func (r *HookRunner) PrePhase() compositionv1alpha1.HookPhase {
+	if r.Status.Upgrade {
+		return compositionv1alpha1.HookPreUpgrade
+	}
+	return compositionv1alpha1.HookPreInstall
+}
+
+// PostPhase is the phase of the hooks run once the objects of the stage are ready
+func (r *HookRunner) PostPhase() compositionv1alpha1.HookPhase {
+	if r.Status.Upgrade {
+		return compositionv1alpha1.HookPostUpgrade
+	}
+	return compositionv1alpha1.HookPostInstall
+}
+
+// hookStep is the hooks of a phase with the same weight
+type hookStep struct {
+	name  string
+	hooks []compositionv1alpha1.Hook
+}
+
+// steps returns the steps of a phase in order of weight
+func (r *HookRunner) steps(phase compositionv1alpha1.HookPhase) []hookStep {
+	weights := []int32{}
+	byWeight := map[int32][]compositionv1alpha1.Hook{}
+	for _, h := range r.hooks {
+		if h.Phase != phase {
+			continue
+		}
+		if _, ok := byWeight[h.Weight]; !ok {
+			weights = append(weights, h.Weight)
+		}
+		byWeight[h.Weight] = append(byWeight[h.Weight], h)
+	}
+	sort.Slice(weights, func(i, j int) bool { return weights[i] < weights[j] })
+	steps := []hookStep{}
+	for _, w := range weights {
+		steps = append(steps, hookStep{name: fmt.Sprintf("%s/%d", phase, w), hooks: byWeight[w]})
+	}
+	return steps
+}
+
+// Run runs the steps of the phase that did not complete for the revision. It
+// returns true once they all completed, and false while waiting for hook
+// objects to be ready or deleted.
+func (r *HookRunner) Run(phase compositionv1alpha1.HookPhase) (bool, error) {
+	if r.Status.Failed != "" {
+		return false, fmt.Errorf("hook %s failed, it runs again once the stage changes", r.Status.Failed)
+	}
+	for _, step := range r.steps(phase) {
+		if slices.Contains(r.Status.Completed, step.name) {
+			continue
+		}
+		done, err := r.runStep(step)
+		var failed *hookFailedError
+		if errors.As(err, &failed) {
+			r.Status.Failed = step.name
+			return false, fmt.Errorf("hook %s failed: %w", step.name, err)
+		}
+		if err != nil {
+			return false, fmt.Errorf("error running hook %s: %w", step.name, err)
+		}
+		if !done {
+			r.logger.Info("Waiting for hook", "step", step.name)
+			return false, nil
+		}
+		r.logger.Info("Hook completed", "step", step.name)
+		r.Status.Completed = append(r.Status.Completed, step.name)
+	}
+	return true, nil
+}
+
+// hookFailedError is returned for hook objects that failed, as opposed to
+// errors talking to the cluster
+type hookFailedError struct {
+	message string
+}
+
+func (e *hookFailedError) Error() string { return e.message }
+
+// hookObject is an object of a hook with the delete policies of the hook
+type hookObject struct {
+	object   *unstructured.Unstructured
+	resource dynamic.ResourceInterface
+	policies []compositionv1alpha1.HookDeletePolicy
+}
+
+func (o *hookObject) deletedOn(policy compositionv1alpha1.HookDeletePolicy) bool {
+	if len(o.policies) == 0 {
+		return policy == compositionv1alpha1.HookBeforeHookCreation
+	}
+	return slices.Contains(o.policies, policy)
+}
+
+func (o *hookObject) String() string {
+	return fmt.Sprintf("%s %s", o.object.GetKind(), o.object.GetName())
+}
+
+// runStep creates the objects of the step and returns true once they all succeeded
+func (r *HookRunner) runStep(step hookStep) (bool, error) {
+	objects, err := r.load(step)
+	if err != nil {
+		return false, err
+	}
+
+	done := true
+	failed := []string{}
+	for _, o := range objects {
+		existing, err := o.resource.Get(r.ctx, o.object.GetName(), metav1.GetOptions{})
+		switch {
+		case apierrors.IsNotFound(err):
+			r.logger.Info("Creating hook object", "step", step.name, "object", o.String())
+			if _, err := o.resource.Create(r.ctx, o.object, metav1.CreateOptions{}); err != nil {
+				return false, fmt.Errorf("failed to create %s: %w", o, err)
+			}
+			done = false
+			continue
+		case err != nil:
+			return false, fmt.Errorf("failed to get %s: %w", o, err)
+		case existing.GetDeletionTimestamp() != nil:
+			done = false
+			continue
+		case existing.GetAnnotations()[HookRevisionAnnotation] != r.Status.Revision:
+			// Left over by an earlier run of the hook
+			if !o.deletedOn(compositionv1alpha1.HookBeforeHookCreation) {
+				return false, &hookFailedError{message: fmt.Sprintf("%s of an earlier revision exists and the hook has no %s delete policy", o, compositionv1alpha1.HookBeforeHookCreation)}
+			}
+			if err := r.delete(o); err != nil {
+				return false, err
+			}
+			done = false
+			continue
+		}
+
+		succeeded, message, err := hookResult(existing)
+		if err != nil {
+			failed = append(failed, fmt.Sprintf("%s: %v", o, err))
+			continue
+		}
+		if !succeeded {
+			r.logger.Info("Hook object is not ready", "step", step.name, "object", o.String(), "message", message)
+			done = false
+		}
+	}
+
+	if len(failed) != 0 {
+		for _, o := range objects {
+			if o.deletedOn(compositionv1alpha1.HookFailed) {
+				if err := r.delete(o); err != nil {
+					return false, err
+				}
+			}
+		}
+		return false, &hookFailedError{message: strings.Join(failed, ", ")}
+	}
+	if !done {
+		return false, nil
+	}
+	for _, o := range objects {
+		if o.deletedOn(compositionv1alpha1.HookSucceeded) {
+			if err := r.delete(o); err != nil {
+				return false, err
+			}
+		}
+	}
+	return true, nil
+}
+
+// load parses the objects of the hooks of a step. They are owned by the plan
+// like the other objects of the stage, but are not part of its applyset, so
+// they are neither pruned nor deleted with the stage.
+func (r *HookRunner) load(step hookStep) ([]*hookObject, error) {
+	objects := []*hookObject{}
+	for _, h := range step.hooks {
+		parsed, err := parseObjects(r.ctx, h.Manifest, h.Objects)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing manifest: %w", err)
+		}
+		for _, item := range parsed.Items {
+			// If namespace is passed it is namespace mode composition
+			if r.namespace != "" {
+				if err := item.SetNamespace(r.namespace); err != nil {
+					return nil, fmt.Errorf("error setting namespace: %w", err)
+				}
+			}
+			u := item.UnstructuredObject()
+			if u.GetNamespace() == r.planCR.GetNamespace() {
+				u.SetOwnerReferences([]metav1.OwnerReference{*metav1.NewControllerRef(r.planCR, compositionv1alpha1.GroupVersion.WithKind("Plan"))})
+			}
+			labels := u.GetLabels()
+			if labels == nil {
+				labels = map[string]string{}
+			}
+			labels[StageLabel] = r.stageName
+			u.SetLabels(labels)
+			annotations := u.GetAnnotations()
+			if annotations == nil {
+				annotations = map[string]string{}
+			}
+			annotations[HookRevisionAnnotation] = r.Status.Revision
+			u.SetAnnotations(annotations)
+
+			gvk := u.GroupVersionKind()
+			mapping, err := r.client.RESTMapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+			if err != nil {
+				return nil, fmt.Errorf("failed to map %s: %w", gvk, err)
+			}
+			var resource dynamic.ResourceInterface = r.client.Dynamic.Resource(mapping.Resource)
+			if u.GetNamespace() != "" {
+				resource = r.client.Dynamic.Resource(mapping.Resource).Namespace(u.GetNamespace())
+			}
+			objects = append(objects, &hookObject{object: u, resource: resource, policies: h.DeletePolicies})
+		}
+	}
+	return objects, nil
+}
+
+func (r *HookRunner) delete(o *hookObject) error {
+	r.logger.Info("Deleting hook object", "object", o.String())
+	// Jobs leave their pods behind with the default orphan propagation
+	propagation := metav1.DeletePropagationBackground
+	err := o.resource.Delete(r.ctx, o.object.GetName(), metav1.DeleteOptions{PropagationPolicy: &propagation})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("failed to delete %s: %w", o, err)
+	}
+	return nil
+}
+
+// hookResult returns whether a hook object succeeded. Jobs succeed when they
+// complete and Pods when they exit successfully. Other objects succeed when
+// they are ready.
+func hookResult(u *unstructured.Unstructured) (bool, string, error) {
+	switch u.GroupVersionKind().GroupKind().String() {
+	case "Job.batch":
+		conditions, _, _ := unstructured.NestedSlice(u.Object, "status", "conditions")
+		for _, c := range conditions {
+			condition, ok := c.(map[string]interface{})
+			if !ok || condition["status"] != "True" {
+				continue
+			}
+			switch condition["type"] {
+			case "Complete":
+				return true, "", nil
+			case "Failed":
+				return false, "", fmt.Errorf("job failed: %v", condition["message"])
+			}
+		}
+		return false, "job is running", nil
+	case "Pod":
+		phase, _, _ := unstructured.NestedString(u.Object, "status", "phase")
+		switch phase {
+		case "Succeeded":
+			return true, "", nil
+		case "Failed":
+			return false, "", fmt.Errorf("pod failed")
+		}
+		return false, fmt.Sprintf("pod is %s", phase), nil
+	}
+	result, err := status.Compute(u)
+	if err != nil {
+		return false, "", err
+	}
+	if result.Status == status.FailedStatus {
+		return false, "", fmt.Errorf("%s", result.Message)
+	}
+	return result.Status == status.CurrentStatus, result.Message, nil
+}
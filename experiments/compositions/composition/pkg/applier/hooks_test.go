@@ -0,0 +1,279 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package applier
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	compositionv1alpha1 "github.com/cloud-native-compositions/compositions/composition/api/v1alpha1"
+	"github.com/go-logr/logr"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"sigs.k8s.io/kubebuilder-declarative-pattern/pkg/patterns/declarative/pkg/manifest"
+)
+
+var jobs = schema.GroupVersionResource{Group: "batch", Version: "v1", Resource: "jobs"}
+
+func jobManifest(name string) string {
+	return `---
+apiVersion: batch/v1
+kind: Job
+metadata:
+  name: ` + name + `
+spec:
+  template:
+    spec:
+      restartPolicy: Never
+      containers:
+      - name: migrate
+        image: migrate
+`
+}
+
+func hookPlan(manifest string, hooks ...compositionv1alpha1.Hook) *compositionv1alpha1.Plan {
+	return &compositionv1alpha1.Plan{
+		ObjectMeta: metav1.ObjectMeta{Name: "sqls-app", Namespace: "team", UID: "1234"},
+		Spec: compositionv1alpha1.PlanSpec{
+			Stages: map[string]compositionv1alpha1.Stage{
+				"app": {Manifest: manifest, Hooks: hooks},
+			},
+		},
+	}
+}
+
+func hookClient() (ApplierClient, *dynamicfake.FakeDynamicClient) {
+	mapper := meta.NewDefaultRESTMapper(nil)
+	mapper.Add(schema.GroupVersionKind{Group: "batch", Version: "v1", Kind: "Job"}, meta.RESTScopeNamespace)
+	dynamic := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{jobs: "JobList"})
+	return ApplierClient{RESTMapper: mapper, Dynamic: dynamic}, dynamic
+}
+
+// setJobCondition sets a condition of a job as its controller would
+func setJobCondition(t *testing.T, dynamic *dynamicfake.FakeDynamicClient, name, condition string) {
+	job, err := dynamic.Resource(jobs).Namespace("team").Get(context.Background(), name, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("failed to get job %s: %v", name, err)
+	}
+	conditions := []interface{}{map[string]interface{}{"type": condition, "status": "True", "message": "BackoffLimitExceeded"}}
+	if err := unstructured.SetNestedSlice(job.Object, conditions, "status", "conditions"); err != nil {
+		t.Fatalf("failed to set job conditions: %v", err)
+	}
+	if _, err := dynamic.Resource(jobs).Namespace("team").Update(context.Background(), job, metav1.UpdateOptions{}); err != nil {
+		t.Fatalf("failed to update job %s: %v", name, err)
+	}
+}
+
+func jobExists(t *testing.T, dynamic *dynamicfake.FakeDynamicClient, name string) bool {
+	_, err := dynamic.Resource(jobs).Namespace("team").Get(context.Background(), name, metav1.GetOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		t.Fatalf("failed to get job %s: %v", name, err)
+	}
+	return err == nil
+}
+
+func run(t *testing.T, r *HookRunner, phase compositionv1alpha1.HookPhase, want bool) {
+	t.Helper()
+	done, err := r.Run(phase)
+	if err != nil {
+		t.Fatalf("Run(%s) failed: %v", phase, err)
+	}
+	if done != want {
+		t.Fatalf("Run(%s): want done %v, got %v", phase, want, done)
+	}
+}
+
+func TestHookRunnerInstall(t *testing.T) {
+	ac, dynamic := hookClient()
+	plan := hookPlan("",
+		compositionv1alpha1.Hook{Phase: compositionv1alpha1.HookPreInstall, Weight: 5, Manifest: jobManifest("seed")},
+		compositionv1alpha1.Hook{
+			Phase:          compositionv1alpha1.HookPreInstall,
+			Weight:         -5,
+			DeletePolicies: []compositionv1alpha1.HookDeletePolicy{compositionv1alpha1.HookSucceeded},
+			Manifest:       jobManifest("migrate"),
+		},
+		compositionv1alpha1.Hook{Phase: compositionv1alpha1.HookPreUpgrade, Manifest: jobManifest("upgrade")},
+	)
+	r, err := NewHookRunner(context.Background(), logr.Discard(), ac, "app", "team", plan, nil)
+	if err != nil {
+		t.Fatalf("NewHookRunner() failed: %v", err)
+	}
+	if phase := r.PrePhase(); phase != compositionv1alpha1.HookPreInstall {
+		t.Fatalf("want phase pre-install, got %s", phase)
+	}
+
+	// The lower weight runs first
+	run(t, r, r.PrePhase(), false)
+	if !jobExists(t, dynamic, "migrate") || jobExists(t, dynamic, "seed") {
+		t.Fatalf("want only the migrate job created")
+	}
+	job, _ := dynamic.Resource(jobs).Namespace("team").Get(context.Background(), "migrate", metav1.GetOptions{})
+	if job.GetAnnotations()[HookRevisionAnnotation] != r.Status.Revision || job.GetLabels()[StageLabel] != "app" {
+		t.Errorf("want the revision annotation and stage label, got %v %v", job.GetAnnotations(), job.GetLabels())
+	}
+	if refs := job.GetOwnerReferences(); len(refs) != 1 || refs[0].Name != "sqls-app" || refs[0].Kind != "Plan" {
+		t.Errorf("want the job owned by the plan, got %v", refs)
+	}
+	run(t, r, r.PrePhase(), false)
+
+	// hook-succeeded deletes the job once it completes
+	setJobCondition(t, dynamic, "migrate", "Complete")
+	run(t, r, r.PrePhase(), false)
+	if jobExists(t, dynamic, "migrate") || !jobExists(t, dynamic, "seed") {
+		t.Fatalf("want the migrate job deleted and the seed job created")
+	}
+	setJobCondition(t, dynamic, "seed", "Complete")
+	run(t, r, r.PrePhase(), true)
+	if !jobExists(t, dynamic, "seed") {
+		t.Errorf("want the seed job kept")
+	}
+	if jobExists(t, dynamic, "upgrade") {
+		t.Errorf("want no upgrade hooks run on install")
+	}
+	want := []string{"pre-install/-5", "pre-install/5"}
+	if !reflect.DeepEqual(r.Status.Completed, want) {
+		t.Errorf("want completed %v, got %v", want, r.Status.Completed)
+	}
+
+	// Completed hooks do not run again for the revision
+	previous := &compositionv1alpha1.StageStatus{Hooks: r.Status}
+	r, err = NewHookRunner(context.Background(), logr.Discard(), ac, "app", "team", plan, previous)
+	if err != nil {
+		t.Fatalf("NewHookRunner() failed: %v", err)
+	}
+	run(t, r, r.PrePhase(), true)
+	if jobExists(t, dynamic, "migrate") {
+		t.Errorf("want the migrate job not run again")
+	}
+}
+
+func TestHookRunnerUpgrade(t *testing.T) {
+	ac, dynamic := hookClient()
+	hook := compositionv1alpha1.Hook{Phase: compositionv1alpha1.HookPreUpgrade, Manifest: jobManifest("migrate")}
+	plan := hookPlan("v1", hook)
+	previous := &compositionv1alpha1.StageStatus{
+		LastApplied: []compositionv1alpha1.ResourceStatus{{Kind: "Deployment", Name: "app"}},
+	}
+	r, err := NewHookRunner(context.Background(), logr.Discard(), ac, "app", "team", plan, previous)
+	if err != nil {
+		t.Fatalf("NewHookRunner() failed: %v", err)
+	}
+	if phase := r.PrePhase(); phase != compositionv1alpha1.HookPreUpgrade {
+		t.Fatalf("want phase pre-upgrade, got %s", phase)
+	}
+	run(t, r, r.PrePhase(), false)
+	setJobCondition(t, dynamic, "migrate", "Complete")
+	run(t, r, r.PrePhase(), true)
+
+	// A new revision deletes the job of the earlier one before creating it again
+	plan = hookPlan("v2", hook)
+	r, err = NewHookRunner(context.Background(), logr.Discard(), ac, "app", "team", plan, &compositionv1alpha1.StageStatus{Hooks: r.Status})
+	if err != nil {
+		t.Fatalf("NewHookRunner() failed: %v", err)
+	}
+	if !r.Status.Upgrade || len(r.Status.Completed) != 0 {
+		t.Fatalf("want an upgrade with no completed hooks, got %+v", r.Status)
+	}
+	run(t, r, r.PrePhase(), false)
+	if jobExists(t, dynamic, "migrate") {
+		t.Fatalf("want the job of the earlier revision deleted")
+	}
+	run(t, r, r.PrePhase(), false)
+	job, err := dynamic.Resource(jobs).Namespace("team").Get(context.Background(), "migrate", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("want the job created again: %v", err)
+	}
+	if job.GetAnnotations()[HookRevisionAnnotation] != r.Status.Revision {
+		t.Errorf("want the job of revision %s, got %v", r.Status.Revision, job.GetAnnotations())
+	}
+}
+
+func TestHookRunnerFailed(t *testing.T) {
+	ac, dynamic := hookClient()
+	plan := hookPlan("",
+		compositionv1alpha1.Hook{
+			Phase:          compositionv1alpha1.HookPostInstall,
+			DeletePolicies: []compositionv1alpha1.HookDeletePolicy{compositionv1alpha1.HookFailed},
+			Manifest:       jobManifest("check"),
+		},
+	)
+	r, err := NewHookRunner(context.Background(), logr.Discard(), ac, "app", "team", plan, nil)
+	if err != nil {
+		t.Fatalf("NewHookRunner() failed: %v", err)
+	}
+	run(t, r, r.PostPhase(), false)
+	setJobCondition(t, dynamic, "check", "Failed")
+	_, err = r.Run(r.PostPhase())
+	if err == nil || !strings.Contains(err.Error(), "hook post-install/0 failed: Job check: job failed: BackoffLimitExceeded") {
+		t.Fatalf("want the job failure, got %v", err)
+	}
+	if r.Status.Failed != "post-install/0" {
+		t.Errorf("want the failed step recorded, got %q", r.Status.Failed)
+	}
+	if jobExists(t, dynamic, "check") {
+		t.Errorf("want the failed job deleted")
+	}
+
+	// The hook does not run again until the stage changes
+	_, err = r.Run(r.PostPhase())
+	if err == nil || !strings.Contains(err.Error(), "runs again once the stage changes") {
+		t.Fatalf("want the earlier failure, got %v", err)
+	}
+	if jobExists(t, dynamic, "check") {
+		t.Errorf("want the failed job not created again")
+	}
+}
+
+func TestHookRunnerObjects(t *testing.T) {
+	ac, dynamic := hookClient()
+	job, err := manifest.ParseObjects(context.Background(), jobManifest("migrate"))
+	if err != nil {
+		t.Fatalf("failed to parse job: %v", err)
+	}
+	raw, err := job.Items[0].UnstructuredObject().MarshalJSON()
+	if err != nil {
+		t.Fatalf("failed to marshal job: %v", err)
+	}
+	// grpc expanders store the objects of hooks instead of a manifest
+	plan := hookPlan("", compositionv1alpha1.Hook{
+		Phase:   compositionv1alpha1.HookPreInstall,
+		Objects: []runtime.RawExtension{{Raw: raw}},
+	})
+	r, err := NewHookRunner(context.Background(), logr.Discard(), ac, "app", "team", plan, nil)
+	if err != nil {
+		t.Fatalf("NewHookRunner() failed: %v", err)
+	}
+	run(t, r, r.PrePhase(), false)
+	if !jobExists(t, dynamic, "migrate") {
+		t.Fatalf("want the migrate job created")
+	}
+}
+
+func TestNewHookRunnerWithoutHooks(t *testing.T) {
+	ac, _ := hookClient()
+	r, err := NewHookRunner(context.Background(), logr.Discard(), ac, "app", "team", hookPlan(jobManifest("app")), nil)
+	if err != nil || r != nil {
+		t.Fatalf("want no runner, got %v %v", r, err)
+	}
+}
@@ -15,51 +15,46 @@
 package cel
 
 import (
+	"github.com/cloud-native-compositions/compositions/composition/pkg/cel/library"
 	"github.com/google/cel-go/cel"
 	"github.com/google/cel-go/common/types/ref"
-	"github.com/wzshiming/easycel"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 )
 
 type Engine struct {
-	u        *unstructured.Unstructured
-	registry *easycel.Registry
-	env      *easycel.Environment
+	u   *unstructured.Unstructured
+	env *cel.Env
 }
 
 func NewEngine(u *unstructured.Unstructured) (*Engine, error) {
-	// TODO: what withtagname ? can we pass yaml ?
-	registry := easycel.NewRegistry("cel-engine", easycel.WithTagName("json"))
-
-	// Register variables
-	err := registry.RegisterVariable("spec", map[string]interface{}{})
-	if err != nil {
-		return nil, err
-	}
-	err = registry.RegisterVariable("status", map[string]interface{}{})
-	if err != nil {
-		return nil, err
-	}
-	err = registry.RegisterVariable("metadata", map[string]interface{}{})
-	if err != nil {
-		return nil, err
-	}
-
-	// Environment
-	env, err := easycel.NewEnvironment(cel.Lib(registry))
+	objectType := cel.MapType(cel.StringType, cel.DynType)
+	env, err := cel.NewEnv(
+		cel.Variable("spec", objectType),
+		cel.Variable("status", objectType),
+		cel.Variable("metadata", objectType),
+		library.Lib(),
+	)
 	if err != nil {
 		return nil, err
 	}
 
 	return &Engine{
-		u:        u,
-		registry: registry,
-		env:      env,
+		u:   u,
+		env: env,
 	}, nil
 }
 
+// program parses and type checks an expression
+func (e *Engine) program(expression string) (cel.Program, error) {
+	ast, issues := e.env.Compile(expression)
+	if issues != nil && issues.Err() != nil {
+		return nil, issues.Err()
+	}
+	return e.env.Program(ast)
+}
+
 func (e *Engine) Eval(expression string) (ref.Val, error) {
-	prog, err := e.env.Program(expression)
+	prog, err := e.program(expression)
 	if err != nil {
 		return nil, err
 	}
@@ -70,3 +65,13 @@ func (e *Engine) Eval(expression string) (ref.Val, error) {
 
 	return val, nil
 }
+
+// Compile checks that a readiness expression compiles
+func Compile(expression string) error {
+	e, err := NewEngine(nil)
+	if err != nil {
+		return err
+	}
+	_, err = e.program(expression)
+	return err
+}
@@ -0,0 +1,42 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package library
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Markdown returns the docs of the library, docs/cel_functions.md
+func Markdown() string {
+	b := strings.Builder{}
+	b.WriteString("<!-- Generated from composition/pkg/cel/library with: go test ./pkg/cel/library -update-docs -->\n\n")
+	b.WriteString("# CEL functions\n\n")
+	b.WriteString("The readiness rules of Compositions and the expressions of the\n")
+	b.WriteString("[CEL expander](cel_expander.md) have these functions, along with the CEL\n")
+	b.WriteString("standard library and [optional types](https://github.com/google/cel-spec/wiki/proposal-246),\n")
+	b.WriteString("like `spec.?replicas.orValue(1)`.\n")
+	for _, f := range Functions() {
+		fmt.Fprintf(&b, "\n## %s\n\n%s\n\n```\n", f.Name, f.Description)
+		for _, o := range f.Overloads {
+			fmt.Fprintf(&b, "%s\n", f.Signature(o))
+		}
+		b.WriteString("```\n\n| Example | Result |\n|---------|--------|\n")
+		for _, e := range f.Examples {
+			fmt.Fprintf(&b, "| `%s` | `%s` |\n", e.Expression, e.Result)
+		}
+	}
+	return b.String()
+}
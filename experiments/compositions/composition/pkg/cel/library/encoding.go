@@ -0,0 +1,106 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package library
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"unicode/utf8"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/types"
+	"github.com/google/cel-go/common/types/ref"
+)
+
+var encodingFunctions = []Function{
+	{
+		Name:        "base64.encode",
+		Description: "Encodes a string or bytes with standard base64, for example for the data of a Secret.",
+		Examples:    []Example{{Expression: "base64.encode('admin')", Result: "'YWRtaW4='"}},
+		Overloads: []Overload{
+			{ID: "base64_encode_string", Args: []*cel.Type{cel.StringType}, Result: cel.StringType,
+				Binding: cel.UnaryBinding(func(v ref.Val) ref.Val {
+					return types.String(base64.StdEncoding.EncodeToString([]byte(v.(types.String))))
+				})},
+			{ID: "base64_encode_bytes", Args: []*cel.Type{cel.BytesType}, Result: cel.StringType,
+				Binding: cel.UnaryBinding(func(v ref.Val) ref.Val {
+					return types.String(base64.StdEncoding.EncodeToString(v.(types.Bytes)))
+				})},
+		},
+	},
+	{
+		Name:        "base64.decode",
+		Description: "Decodes standard base64 to a string. The decoded bytes must be UTF-8.",
+		Examples:    []Example{{Expression: "base64.decode('YWRtaW4=')", Result: "'admin'"}},
+		Overloads: []Overload{
+			{ID: "base64_decode_string", Args: []*cel.Type{cel.StringType}, Result: cel.StringType,
+				Binding: cel.UnaryBinding(func(v ref.Val) ref.Val {
+					b, err := base64.StdEncoding.DecodeString(string(v.(types.String)))
+					if err != nil {
+						return types.NewErr("base64.decode: %v", err)
+					}
+					if !utf8.Valid(b) {
+						return types.NewErr("base64.decode: the decoded bytes are not UTF-8")
+					}
+					return types.String(b)
+				})},
+		},
+	},
+	{
+		Name:        "sha256",
+		Description: "Returns the hex encoded SHA-256 hash of a string.",
+		Examples: []Example{{Expression: "sha256('abc')",
+			Result: "'ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad'"}},
+		Overloads: []Overload{
+			{ID: "sha256_string", Args: []*cel.Type{cel.StringType}, Result: cel.StringType,
+				Binding: cel.UnaryBinding(func(v ref.Val) ref.Val {
+					return types.String(hashString(string(v.(types.String))))
+				})},
+		},
+	},
+	{
+		Name: "shortHash",
+		Description: "Returns the first characters of the SHA-256 hash of a string, 8 by default, " +
+			"for names that are stable and unique, like a name suffix.",
+		Examples: []Example{
+			{Expression: "shortHash('abc')", Result: "'ba7816bf'"},
+			{Expression: "shortHash('abc', 4)", Result: "'ba78'"},
+		},
+		Overloads: []Overload{
+			{ID: "shortHash_string", Args: []*cel.Type{cel.StringType}, Result: cel.StringType,
+				Binding: cel.UnaryBinding(func(v ref.Val) ref.Val {
+					return types.String(shortHash(string(v.(types.String)), 8))
+				})},
+			{ID: "shortHash_string_int", Args: []*cel.Type{cel.StringType, cel.IntType}, Result: cel.StringType,
+				Binding: cel.BinaryBinding(func(v, n ref.Val) ref.Val {
+					length := int(n.(types.Int))
+					if length < 1 || length > sha256.Size*2 {
+						return types.NewErr("shortHash: the length must be between 1 and %d, got %d", sha256.Size*2, length)
+					}
+					return types.String(shortHash(string(v.(types.String)), length))
+				})},
+		},
+	},
+}
+
+func hashString(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+func shortHash(s string, length int) string {
+	return hashString(s)[:length]
+}
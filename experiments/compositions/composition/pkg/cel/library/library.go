@@ -0,0 +1,115 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package library is the CEL function library of compositions, shared by the
+// readiness rules and the CEL expander. Each function is declared once with
+// its documentation and examples, docs/cel_functions.md is generated from the
+// declarations and the examples are tested.
+package library
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/google/cel-go/cel"
+)
+
+// Function is a function of the library with its documentation
+type Function struct {
+	Name string
+	// Description is a sentence or two for the docs
+	Description string
+	// Examples are expressions with the value they return, written as CEL
+	Examples  []Example
+	Overloads []Overload
+}
+
+// Example is an expression of the docs and the value it returns
+type Example struct {
+	Expression string
+	Result     string
+}
+
+// Overload is a signature of a function
+type Overload struct {
+	ID string
+	// Member overloads are called on their first argument, like s.format(args)
+	Member bool
+	Args   []*cel.Type
+	Result *cel.Type
+	// Binding implements the overload
+	Binding cel.OverloadOpt
+	// NonStrict overloads are called with errors, like a missing field
+	NonStrict bool
+}
+
+// Signature returns the overload as written in the docs, for example
+// base64.encode(string) -> string
+func (f *Function) Signature(o Overload) string {
+	args := []string{}
+	for _, a := range o.Args {
+		args = append(args, a.String())
+	}
+	if o.Member {
+		return fmt.Sprintf("%s.%s(%s) -> %s", args[0], f.Name, strings.Join(args[1:], ", "), o.Result)
+	}
+	return fmt.Sprintf("%s(%s) -> %s", f.Name, strings.Join(args, ", "), o.Result)
+}
+
+// Lib returns the option adding the functions of the library and CEL
+// optional types to an environment
+func Lib() cel.EnvOption {
+	return cel.Lib(library{})
+}
+
+type library struct{}
+
+func (library) LibraryName() string {
+	return "compositions"
+}
+
+func (library) CompileOptions() []cel.EnvOption {
+	opts := []cel.EnvOption{cel.OptionalTypes()}
+	for _, f := range Functions() {
+		overloads := []cel.FunctionOpt{}
+		for _, o := range f.Overloads {
+			overloadOpts := []cel.OverloadOpt{o.Binding}
+			if o.NonStrict {
+				overloadOpts = append(overloadOpts, cel.OverloadIsNonStrict())
+			}
+			if o.Member {
+				overloads = append(overloads, cel.MemberOverload(o.ID, o.Args, o.Result, overloadOpts...))
+			} else {
+				overloads = append(overloads, cel.Overload(o.ID, o.Args, o.Result, overloadOpts...))
+			}
+		}
+		opts = append(opts, cel.Function(f.Name, overloads...))
+	}
+	return opts
+}
+
+func (library) ProgramOptions() []cel.ProgramOption {
+	return nil
+}
+
+// Functions returns the functions of the library, in the order of the docs
+func Functions() []Function {
+	functions := []Function{}
+	functions = append(functions, encodingFunctions...)
+	functions = append(functions, stringFunctions...)
+	functions = append(functions, quantityFunctions...)
+	functions = append(functions, semverFunctions...)
+	functions = append(functions, valueFunctions...)
+	return functions
+}
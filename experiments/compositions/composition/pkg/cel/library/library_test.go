@@ -0,0 +1,116 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package library
+
+import (
+	"flag"
+	"os"
+	"testing"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/types"
+	"github.com/google/go-cmp/cmp"
+)
+
+var update = flag.Bool("update-docs", false, "write docs/cel_functions.md instead of comparing")
+
+const docsPath = "../../../../docs/cel_functions.md"
+
+func TestExamples(t *testing.T) {
+	env, err := cel.NewEnv(Lib())
+	if err != nil {
+		t.Fatalf("NewEnv() failed: %v", err)
+	}
+	for _, f := range Functions() {
+		if len(f.Examples) == 0 {
+			t.Errorf("%s has no examples", f.Name)
+		}
+		for _, e := range f.Examples {
+			t.Run(e.Expression, func(t *testing.T) {
+				ast, issues := env.Compile("(" + e.Expression + ") == (" + e.Result + ")")
+				if issues.Err() != nil {
+					t.Fatalf("Compile() failed: %v", issues.Err())
+				}
+				prog, err := env.Program(ast)
+				if err != nil {
+					t.Fatalf("Program() failed: %v", err)
+				}
+				val, _, err := prog.Eval(map[string]interface{}{})
+				if err != nil {
+					t.Fatalf("Eval() failed: %v", err)
+				}
+				if val != types.True {
+					got, _ := env.Compile(e.Expression)
+					gotProg, _ := env.Program(got)
+					gotVal, _, _ := gotProg.Eval(map[string]interface{}{})
+					t.Errorf("want %s, got %v", e.Result, gotVal)
+				}
+			})
+		}
+	}
+}
+
+func TestErrors(t *testing.T) {
+	env, err := cel.NewEnv(Lib())
+	if err != nil {
+		t.Fatalf("NewEnv() failed: %v", err)
+	}
+	testcases := []struct {
+		expression string
+		err        string
+	}{
+		{expression: "base64.decode('not base64')", err: "base64.decode: illegal base64 data at input byte 3"},
+		{expression: "shortHash('abc', 0)", err: "shortHash: the length must be between 1 and 64, got 0"},
+		{expression: "dnsLabel('..')", err: `dnsLabel: ".." has no letters or digits`},
+		{expression: "'%s-%s'.format(['a'])", err: `format: "%s-%s" has more verbs than the 1 arguments`},
+		{expression: "'%s'.format(['a', 'b'])", err: `format: "%s" has 1 verbs for 2 arguments`},
+		{expression: "'%d'.format(['a'])", err: "format: %d can not format a string"},
+		{expression: "quantity.add('1Gi', 'lots')", err: "quantity.add: quantities must match the regular expression '^([+-]?[0-9.]+)([eEinumkKMGTP]*[-+]?[0-9]*)$'"},
+		{expression: "semver.compare('1.0', 'latest')", err: `semver.compare: "latest": Invalid Semantic Version`},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.expression, func(t *testing.T) {
+			ast, issues := env.Compile(tc.expression)
+			if issues.Err() != nil {
+				t.Fatalf("Compile() failed: %v", issues.Err())
+			}
+			prog, err := env.Program(ast)
+			if err != nil {
+				t.Fatalf("Program() failed: %v", err)
+			}
+			_, _, err = prog.Eval(map[string]interface{}{})
+			if err == nil || err.Error() != tc.err {
+				t.Errorf("want error %q, got %v", tc.err, err)
+			}
+		})
+	}
+}
+
+func TestDocs(t *testing.T) {
+	docs := Markdown()
+	if *update {
+		if err := os.WriteFile(docsPath, []byte(docs), 0644); err != nil {
+			t.Fatalf("writing %s: %v", docsPath, err)
+		}
+		return
+	}
+	want, err := os.ReadFile(docsPath)
+	if err != nil {
+		t.Fatalf("reading %s: %v", docsPath, err)
+	}
+	if diff := cmp.Diff(string(want), docs); diff != "" {
+		t.Errorf("%s is out of date, run go test ./pkg/cel/library -update-docs (-want +got):\n%s", docsPath, diff)
+	}
+}
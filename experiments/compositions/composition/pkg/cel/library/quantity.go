@@ -0,0 +1,124 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package library
+
+import (
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/types"
+	"github.com/google/cel-go/common/types/ref"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+var quantityFunctions = []Function{
+	{
+		Name:        "quantity.add",
+		Description: "Adds two Kubernetes quantities, like memory requests.",
+		Examples:    []Example{{Expression: "quantity.add('1Gi', '512Mi')", Result: "'1536Mi'"}},
+		Overloads: []Overload{
+			{ID: "quantity_add_string_string", Args: []*cel.Type{cel.StringType, cel.StringType}, Result: cel.StringType,
+				Binding: quantityBinary("quantity.add", func(a, b *resource.Quantity) ref.Val {
+					a.Add(*b)
+					return types.String(a.String())
+				})},
+		},
+	},
+	{
+		Name:        "quantity.sub",
+		Description: "Subtracts the second quantity from the first one.",
+		Examples:    []Example{{Expression: "quantity.sub('1', '250m')", Result: "'750m'"}},
+		Overloads: []Overload{
+			{ID: "quantity_sub_string_string", Args: []*cel.Type{cel.StringType, cel.StringType}, Result: cel.StringType,
+				Binding: quantityBinary("quantity.sub", func(a, b *resource.Quantity) ref.Val {
+					a.Sub(*b)
+					return types.String(a.String())
+				})},
+		},
+	},
+	{
+		Name:        "quantity.mul",
+		Description: "Multiplies a quantity by an int, for example the request of each replica.",
+		Examples:    []Example{{Expression: "quantity.mul('500m', 3)", Result: "'1500m'"}},
+		Overloads: []Overload{
+			{ID: "quantity_mul_string_int", Args: []*cel.Type{cel.StringType, cel.IntType}, Result: cel.StringType,
+				Binding: cel.BinaryBinding(func(v, n ref.Val) ref.Val {
+					q, err := resource.ParseQuantity(string(v.(types.String)))
+					if err != nil {
+						return types.NewErr("quantity.mul: %v", err)
+					}
+					if !q.Mul(int64(n.(types.Int))) {
+						return types.NewErr("quantity.mul: %s * %d overflows", v, n)
+					}
+					return types.String(q.String())
+				})},
+		},
+	},
+	{
+		Name:        "quantity.compare",
+		Description: "Returns -1, 0 or 1 if the first quantity is less than, equal to or greater than the second.",
+		Examples:    []Example{{Expression: "quantity.compare('1Gi', '1000Mi')", Result: "1"}},
+		Overloads: []Overload{
+			{ID: "quantity_compare_string_string", Args: []*cel.Type{cel.StringType, cel.StringType}, Result: cel.IntType,
+				Binding: quantityBinary("quantity.compare", func(a, b *resource.Quantity) ref.Val {
+					return types.Int(a.Cmp(*b))
+				})},
+		},
+	},
+	{
+		Name:        "quantity.value",
+		Description: "Returns a quantity as an int, rounded up.",
+		Examples:    []Example{{Expression: "quantity.value('1Ki')", Result: "1024"}, {Expression: "quantity.value('1500m')", Result: "2"}},
+		Overloads: []Overload{
+			{ID: "quantity_value_string", Args: []*cel.Type{cel.StringType}, Result: cel.IntType,
+				Binding: quantityUnary("quantity.value", func(q *resource.Quantity) ref.Val {
+					return types.Int(q.Value())
+				})},
+		},
+	},
+	{
+		Name:        "quantity.milliValue",
+		Description: "Returns a quantity in thousandths as an int, rounded up, for example millicores.",
+		Examples:    []Example{{Expression: "quantity.milliValue('1.5')", Result: "1500"}},
+		Overloads: []Overload{
+			{ID: "quantity_milliValue_string", Args: []*cel.Type{cel.StringType}, Result: cel.IntType,
+				Binding: quantityUnary("quantity.milliValue", func(q *resource.Quantity) ref.Val {
+					return types.Int(q.MilliValue())
+				})},
+		},
+	},
+}
+
+func quantityUnary(name string, f func(q *resource.Quantity) ref.Val) cel.OverloadOpt {
+	return cel.UnaryBinding(func(v ref.Val) ref.Val {
+		q, err := resource.ParseQuantity(string(v.(types.String)))
+		if err != nil {
+			return types.NewErr("%s: %v", name, err)
+		}
+		return f(&q)
+	})
+}
+
+func quantityBinary(name string, f func(a, b *resource.Quantity) ref.Val) cel.OverloadOpt {
+	return cel.BinaryBinding(func(v1, v2 ref.Val) ref.Val {
+		a, err := resource.ParseQuantity(string(v1.(types.String)))
+		if err != nil {
+			return types.NewErr("%s: %v", name, err)
+		}
+		b, err := resource.ParseQuantity(string(v2.(types.String)))
+		if err != nil {
+			return types.NewErr("%s: %v", name, err)
+		}
+		return f(&a, &b)
+	})
+}
@@ -0,0 +1,75 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package library
+
+import (
+	"github.com/Masterminds/semver/v3"
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/types"
+	"github.com/google/cel-go/common/types/ref"
+)
+
+var semverFunctions = []Function{
+	{
+		Name:        "semver.isValid",
+		Description: "Returns whether a string is a semantic version. A `v` prefix is allowed.",
+		Examples:    []Example{{Expression: "semver.isValid('v1.30.2')", Result: "true"}, {Expression: "semver.isValid('latest')", Result: "false"}},
+		Overloads: []Overload{
+			{ID: "semver_isValid_string", Args: []*cel.Type{cel.StringType}, Result: cel.BoolType,
+				Binding: cel.UnaryBinding(func(v ref.Val) ref.Val {
+					_, err := semver.NewVersion(string(v.(types.String)))
+					return types.Bool(err == nil)
+				})},
+		},
+	},
+	{
+		Name:        "semver.compare",
+		Description: "Returns -1, 0 or 1 if the first version is lower than, equal to or higher than the second.",
+		Examples:    []Example{{Expression: "semver.compare('1.10.0', '1.9.3')", Result: "1"}},
+		Overloads: []Overload{
+			{ID: "semver_compare_string_string", Args: []*cel.Type{cel.StringType, cel.StringType}, Result: cel.IntType,
+				Binding: cel.BinaryBinding(func(v1, v2 ref.Val) ref.Val {
+					a, err := semver.NewVersion(string(v1.(types.String)))
+					if err != nil {
+						return types.NewErr("semver.compare: %q: %v", v1, err)
+					}
+					b, err := semver.NewVersion(string(v2.(types.String)))
+					if err != nil {
+						return types.NewErr("semver.compare: %q: %v", v2, err)
+					}
+					return types.Int(a.Compare(b))
+				})},
+		},
+	},
+	{
+		Name:        "semver.satisfies",
+		Description: "Returns whether a version satisfies a constraint, like `>= 1.28` or `~1.2.0`.",
+		Examples:    []Example{{Expression: "semver.satisfies('1.29.4', '>= 1.28, < 1.31')", Result: "true"}},
+		Overloads: []Overload{
+			{ID: "semver_satisfies_string_string", Args: []*cel.Type{cel.StringType, cel.StringType}, Result: cel.BoolType,
+				Binding: cel.BinaryBinding(func(v1, v2 ref.Val) ref.Val {
+					version, err := semver.NewVersion(string(v1.(types.String)))
+					if err != nil {
+						return types.NewErr("semver.satisfies: %q: %v", v1, err)
+					}
+					constraint, err := semver.NewConstraint(string(v2.(types.String)))
+					if err != nil {
+						return types.NewErr("semver.satisfies: %q: %v", v2, err)
+					}
+					return types.Bool(constraint.Check(version))
+				})},
+		},
+	},
+}
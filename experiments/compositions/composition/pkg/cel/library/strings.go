@@ -0,0 +1,171 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package library
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/types"
+	"github.com/google/cel-go/common/types/ref"
+	"github.com/google/cel-go/common/types/traits"
+)
+
+// maxDNSLabel is the length of a DNS label, RFC 1123
+const maxDNSLabel = 63
+
+var stringFunctions = []Function{
+	{
+		Name: "dnsLabel",
+		Description: "Turns a string into a DNS label, the format of most object names: lower case, " +
+			"other characters than letters, digits and `-` replaced with `-`, and at most 63 characters. " +
+			"Longer strings are cut and get a short hash of the whole string.",
+		Examples: []Example{
+			{Expression: "dnsLabel('My_App.v2')", Result: "'my-app-v2'"},
+			{Expression: "size(dnsLabel('app-' + sha256('abc')))", Result: "63"},
+		},
+		Overloads: []Overload{
+			{ID: "dnsLabel_string", Args: []*cel.Type{cel.StringType}, Result: cel.StringType,
+				Binding: cel.UnaryBinding(func(v ref.Val) ref.Val {
+					label, err := dnsLabel(string(v.(types.String)))
+					if err != nil {
+						return types.WrapErr(err)
+					}
+					return types.String(label)
+				})},
+		},
+	},
+	{
+		Name: "format",
+		Description: "Formats the arguments into the string. `%s` and `%v` format strings, numbers and bools, " +
+			"`%d` ints, `%f` doubles, `%q` quoted strings, `%x` ints and strings in hex and `%%` is a `%`.",
+		Examples: []Example{
+			{Expression: "'%s-%d'.format(['web', 2])", Result: "'web-2'"},
+			{Expression: "'%q is %.1f%%'.format(['cpu', 12.34])", Result: `'"cpu" is 12.3%'`},
+		},
+		Overloads: []Overload{
+			{ID: "string_format_list", Member: true, Args: []*cel.Type{cel.StringType, cel.ListType(cel.DynType)},
+				Result: cel.StringType,
+				Binding: cel.BinaryBinding(func(format, args ref.Val) ref.Val {
+					s, err := formatString(string(format.(types.String)), args.(traits.Lister))
+					if err != nil {
+						return types.WrapErr(err)
+					}
+					return types.String(s)
+				})},
+		},
+	},
+}
+
+func dnsLabel(s string) (string, error) {
+	b := strings.Builder{}
+	for _, c := range strings.ToLower(s) {
+		if (c >= 'a' && c <= 'z') || (c >= '0' && c <= '9') {
+			b.WriteRune(c)
+		} else if !strings.HasSuffix(b.String(), "-") {
+			b.WriteByte('-')
+		}
+	}
+	label := strings.Trim(b.String(), "-")
+	if len(label) > maxDNSLabel {
+		suffix := "-" + shortHash(s, 8)
+		label = strings.TrimRight(label[:maxDNSLabel-len(suffix)], "-") + suffix
+	}
+	if label == "" {
+		return "", fmt.Errorf("dnsLabel: %q has no letters or digits", s)
+	}
+	return label, nil
+}
+
+// formatString formats args with the verbs of format, which can have a
+// precision like %.2f
+func formatString(format string, args traits.Lister) (string, error) {
+	out := strings.Builder{}
+	n := int(args.Size().(types.Int))
+	arg := 0
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' {
+			out.WriteByte(format[i])
+			continue
+		}
+		verb := strings.Builder{}
+		verb.WriteByte('%')
+		for i++; i < len(format) && (format[i] == '.' || (format[i] >= '0' && format[i] <= '9')); i++ {
+			verb.WriteByte(format[i])
+		}
+		if i == len(format) {
+			return "", fmt.Errorf("format: %q ends with an incomplete verb", format)
+		}
+		if format[i] == '%' {
+			out.WriteByte('%')
+			continue
+		}
+		if arg == n {
+			return "", fmt.Errorf("format: %q has more verbs than the %d arguments", format, n)
+		}
+		value := args.Get(types.Int(arg))
+		arg++
+		verb.WriteByte(format[i])
+		s, err := formatValue(verb.String(), value)
+		if err != nil {
+			return "", err
+		}
+		out.WriteString(s)
+	}
+	if arg != n {
+		return "", fmt.Errorf("format: %q has %d verbs for %d arguments", format, arg, n)
+	}
+	return out.String(), nil
+}
+
+func formatValue(verb string, value ref.Val) (string, error) {
+	switch verb[len(verb)-1] {
+	case 's', 'v':
+		switch v := value.(type) {
+		case types.String:
+			return string(v), nil
+		case types.Int, types.Uint, types.Bool:
+			return fmt.Sprint(v.Value()), nil
+		case types.Double:
+			return strconv.FormatFloat(float64(v), 'f', -1, 64), nil
+		}
+	case 'd':
+		switch v := value.(type) {
+		case types.Int, types.Uint:
+			return fmt.Sprintf(verb, v.Value()), nil
+		}
+	case 'f':
+		switch v := value.(type) {
+		case types.Double:
+			return fmt.Sprintf(verb, float64(v)), nil
+		case types.Int:
+			return fmt.Sprintf(verb, float64(v)), nil
+		}
+	case 'q':
+		if v, ok := value.(types.String); ok {
+			return strconv.Quote(string(v)), nil
+		}
+	case 'x':
+		switch v := value.(type) {
+		case types.String, types.Int, types.Uint:
+			return fmt.Sprintf(verb, v.Value()), nil
+		}
+	default:
+		return "", fmt.Errorf("format: unknown verb %s", verb)
+	}
+	return "", fmt.Errorf("format: %s can not format a %s", verb, value.Type())
+}
@@ -0,0 +1,60 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package library
+
+import (
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/types"
+	"github.com/google/cel-go/common/types/ref"
+	"github.com/google/cel-go/common/types/traits"
+)
+
+var valueFunctions = []Function{
+	{
+		Name: "default",
+		Description: "Returns the value, or the fallback if the value is missing, null or empty. " +
+			"Fields that are not set can be read without `has()`. For reading them in other " +
+			"expressions, CEL optionals like `spec.?replicas.orValue(1)` are also available.",
+		Examples: []Example{
+			{Expression: "default({'a': 1}.b, 2)", Result: "2"},
+			{Expression: "default('', 'none')", Result: "'none'"},
+			{Expression: "default('web', 'none')", Result: "'web'"},
+		},
+		Overloads: []Overload{
+			{ID: "default_T_T", Args: []*cel.Type{cel.TypeParamType("T"), cel.TypeParamType("T")}, Result: cel.TypeParamType("T"),
+				NonStrict: true,
+				Binding: cel.BinaryBinding(func(v, fallback ref.Val) ref.Val {
+					if isEmpty(v) {
+						return fallback
+					}
+					return v
+				})},
+		},
+	},
+}
+
+// isEmpty returns whether a value is an error, like a missing field, null or
+// an empty string, list or map
+func isEmpty(v ref.Val) bool {
+	switch v := v.(type) {
+	case types.Null:
+		return true
+	case types.String:
+		return v == ""
+	case traits.Sizer:
+		return v.Size() == types.IntZero
+	}
+	return types.IsUnknownOrError(v)
+}
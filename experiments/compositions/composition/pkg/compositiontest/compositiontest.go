@@ -0,0 +1,272 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package compositiontest runs golden file tests of a composition.
+//
+// A test directory holds the composition and one sub directory per case:
+//
+//	composition.yaml              Composition and the expander configs it references
+//	<case>/facade.yaml            facade instance
+//	<case>/context.yaml           Context object (optional)
+//	<case>/objects.yaml           objects read by the getter (optional)
+//	<case>/status.yaml            status of rendered objects read by later stages (optional)
+//	<case>/expected/<stage>.yaml  manifests or values of each stage
+//
+// A test directory with a facade.yaml is a single case.
+// Each case is rendered offline and compared with the expected files.
+package compositiontest
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/cloud-native-compositions/compositions/composition/pkg/render"
+	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+const expectedDir = "expected"
+
+// Options configure a test run
+type Options struct {
+	// Composition is the composition file. Defaults to composition.yaml in the test directory.
+	Composition string
+
+	// Configs are expander configs in addition to the ones in the composition file
+	Configs []*unstructured.Unstructured
+
+	// Expanders maps expander types to grpc expanders, see render.Options
+	Expanders map[string]string
+
+	// Update rewrites the expected files instead of comparing
+	Update bool
+
+	Logger logr.Logger
+}
+
+// Result is the result of a case
+type Result struct {
+	Name string
+	Dir  string
+
+	// Failures describe the stages that differ from the expected files
+	Failures []string
+
+	// Updated are the expected files written with Options.Update
+	Updated []string
+}
+
+// Passed is true when the case matches the expected files
+func (r *Result) Passed() bool {
+	return len(r.Failures) == 0
+}
+
+// Run runs the cases in dir
+func Run(ctx context.Context, dir string, opts Options) ([]*Result, error) {
+	compositionFile := opts.Composition
+	if compositionFile == "" {
+		compositionFile = filepath.Join(dir, "composition.yaml")
+	}
+	composition, configs, err := render.ReadComposition(compositionFile)
+	if err != nil {
+		return nil, err
+	}
+	renderOpts := render.Options{
+		Composition: composition,
+		Configs:     append(configs, opts.Configs...),
+		Expanders:   opts.Expanders,
+		Logger:      opts.Logger,
+	}
+
+	cases, err := findCases(dir)
+	if err != nil {
+		return nil, err
+	}
+	results := []*Result{}
+	for _, caseDir := range cases {
+		result, err := runCase(ctx, caseDir, renderOpts, opts.Update)
+		if err != nil {
+			return results, err
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+func findCases(dir string) ([]string, error) {
+	if exists(filepath.Join(dir, "facade.yaml")) {
+		return []string{dir}, nil
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	cases := []string{}
+	for _, entry := range entries {
+		caseDir := filepath.Join(dir, entry.Name())
+		if entry.IsDir() && exists(filepath.Join(caseDir, "facade.yaml")) {
+			cases = append(cases, caseDir)
+		}
+	}
+	if len(cases) == 0 {
+		return nil, fmt.Errorf("no test cases in %s: expected facade.yaml in %s or its sub directories", dir, dir)
+	}
+	return cases, nil
+}
+
+// runCase renders a case. Errors reading the inputs are returned, render
+// errors and differences are reported in the result.
+func runCase(ctx context.Context, dir string, opts render.Options, update bool) (*Result, error) {
+	result := &Result{Name: filepath.Base(dir), Dir: dir}
+
+	var err error
+	if opts.Facade, err = render.ReadObject(filepath.Join(dir, "facade.yaml")); err != nil {
+		return nil, err
+	}
+	if exists(filepath.Join(dir, "context.yaml")) {
+		if opts.Context, err = render.ReadObject(filepath.Join(dir, "context.yaml")); err != nil {
+			return nil, err
+		}
+	}
+	if exists(filepath.Join(dir, "objects.yaml")) {
+		if opts.Objects, err = render.ReadObjects(filepath.Join(dir, "objects.yaml")); err != nil {
+			return nil, err
+		}
+	}
+	if exists(filepath.Join(dir, "status.yaml")) {
+		if opts.Status, err = render.ReadObjects(filepath.Join(dir, "status.yaml")); err != nil {
+			return nil, err
+		}
+	}
+
+	stages, err := render.Render(ctx, opts)
+	if err != nil {
+		result.Failures = append(result.Failures, err.Error())
+		return result, nil
+	}
+
+	expected, err := expectedFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+	if update {
+		return result, writeExpected(result, stages, expected)
+	}
+
+	for _, stage := range stages {
+		file, ok := expected[stage.Name]
+		if !ok {
+			result.Failures = append(result.Failures,
+				fmt.Sprintf("stage %s: missing %s (run with --update to create it)", stage.Name, filepath.Join(expectedDir, stage.Name+".yaml")))
+			continue
+		}
+		delete(expected, stage.Name)
+		want, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		diff, err := Diff(stage, want)
+		if err != nil {
+			result.Failures = append(result.Failures, fmt.Sprintf("stage %s: %v", stage.Name, err))
+			continue
+		}
+		if diff != "" {
+			result.Failures = append(result.Failures, fmt.Sprintf("stage %s differs from %s (-want +got):\n%s", stage.Name, file, diff))
+		}
+	}
+	for _, name := range sortedKeys(expected) {
+		result.Failures = append(result.Failures, fmt.Sprintf("%s: the composition has no stage %s", expected[name], name))
+	}
+	return result, nil
+}
+
+// expectedFiles returns the expected files of a case by stage name
+func expectedFiles(dir string) (map[string]string, error) {
+	files := map[string]string{}
+	entries, err := os.ReadDir(filepath.Join(dir, expectedDir))
+	if os.IsNotExist(err) {
+		return files, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".yaml" {
+			continue
+		}
+		files[strings.TrimSuffix(entry.Name(), ".yaml")] = filepath.Join(dir, expectedDir, entry.Name())
+	}
+	return files, nil
+}
+
+// writeExpected writes the output of each stage and removes the files of
+// stages that no longer exist
+func writeExpected(result *Result, stages []*render.Stage, existing map[string]string) error {
+	if err := os.MkdirAll(filepath.Join(result.Dir, expectedDir), 0755); err != nil {
+		return err
+	}
+	for _, stage := range stages {
+		b, err := stage.YAML()
+		if err != nil {
+			return fmt.Errorf("stage %s: %w", stage.Name, err)
+		}
+		file := filepath.Join(result.Dir, expectedDir, stage.Name+".yaml")
+		// Keep the license header of an existing file
+		var header []byte
+		if old, err := os.ReadFile(file); err == nil {
+			header = leadingComments(old)
+		}
+		if err := os.WriteFile(file, append(header, b...), 0644); err != nil {
+			return err
+		}
+		result.Updated = append(result.Updated, file)
+		delete(existing, stage.Name)
+	}
+	for _, name := range sortedKeys(existing) {
+		if err := os.Remove(existing[name]); err != nil {
+			return err
+		}
+		result.Updated = append(result.Updated, existing[name])
+	}
+	return nil
+}
+
+func leadingComments(b []byte) []byte {
+	out := []byte{}
+	for _, line := range strings.SplitAfter(string(b), "\n") {
+		if !strings.HasPrefix(line, "#") && strings.TrimSpace(line) != "" {
+			break
+		}
+		out = append(out, line...)
+	}
+	return out
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := []string{}
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
@@ -0,0 +1,134 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compositiontest
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	celexpander "github.com/cloud-native-compositions/compositions/composition/pkg/expanders/cel"
+	"github.com/cloud-native-compositions/compositions/composition/pkg/expandersdk"
+	"github.com/cloud-native-compositions/compositions/composition/pkg/inproc"
+	"github.com/cloud-native-compositions/compositions/composition/pkg/render"
+	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func init() {
+	inproc.Register("cel", expandersdk.NewServer(celexpander.New()).V2())
+}
+
+func TestRun(t *testing.T) {
+	results, err := Run(context.Background(), "testdata", Options{Logger: logr.Discard()})
+	if err != nil {
+		t.Fatalf("Run() failed: %v", err)
+	}
+	if len(results) != 1 {
+		t.Fatalf("want 1 result, got %d", len(results))
+	}
+	if !results[0].Passed() {
+		t.Errorf("want case blue to pass, got failures:\n%s", strings.Join(results[0].Failures, "\n"))
+	}
+}
+
+func TestRunUpdate(t *testing.T) {
+	dir := t.TempDir()
+	copyFile(t, "testdata/composition.yaml", filepath.Join(dir, "composition.yaml"))
+	copyFile(t, "testdata/blue/facade.yaml", filepath.Join(dir, "blue", "facade.yaml"))
+	// A stale file of a stage that does not exist
+	copyFile(t, "testdata/blue/expected/page.yaml", filepath.Join(dir, "blue", "expected", "old.yaml"))
+
+	results, err := Run(context.Background(), dir, Options{Logger: logr.Discard()})
+	if err != nil {
+		t.Fatalf("Run() failed: %v", err)
+	}
+	if results[0].Passed() || len(results[0].Failures) != 2 {
+		t.Fatalf("want failures for the missing and the stale file, got %v", results[0].Failures)
+	}
+
+	if _, err := Run(context.Background(), dir, Options{Update: true, Logger: logr.Discard()}); err != nil {
+		t.Fatalf("Run() with update failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "blue", "expected", "old.yaml")); !os.IsNotExist(err) {
+		t.Errorf("want stale expected file removed, got %v", err)
+	}
+	results, err = Run(context.Background(), dir, Options{Logger: logr.Discard()})
+	if err != nil {
+		t.Fatalf("Run() failed: %v", err)
+	}
+	if !results[0].Passed() {
+		t.Errorf("want case to pass after update, got failures:\n%s", strings.Join(results[0].Failures, "\n"))
+	}
+}
+
+func TestDiff(t *testing.T) {
+	stage := &render.Stage{
+		Name: "page",
+		Objects: []*unstructured.Unstructured{
+			{Object: map[string]interface{}{
+				"apiVersion": "v1",
+				"kind":       "ConfigMap",
+				"metadata":   map[string]interface{}{"name": "blue-page"},
+				"data":       map[string]interface{}{"owner": "bob", "replicas": int64(1)},
+			}},
+		},
+	}
+	expected := `
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: blue-page
+  annotations: null
+data:
+  owner: alice
+  replicas: 1.0
+---
+apiVersion: v1
+kind: ServiceAccount
+metadata:
+  name: blue
+`
+	diff, err := Diff(stage, []byte(expected))
+	if err != nil {
+		t.Fatalf("Diff() failed: %v", err)
+	}
+	for _, want := range []string{"~ ConfigMap blue-page", `"alice"`, `"bob"`, "- ServiceAccount blue: missing"} {
+		if !strings.Contains(diff, want) {
+			t.Errorf("want %q in diff:\n%s", want, diff)
+		}
+	}
+	for _, line := range strings.Split(diff, "\n") {
+		changed := strings.HasPrefix(line, "-") || strings.HasPrefix(line, "+")
+		if changed && (strings.Contains(line, "replicas") || strings.Contains(line, "annotations")) {
+			t.Errorf("want numbers compared by value and null fields ignored, got diff:\n%s", diff)
+		}
+	}
+}
+
+func copyFile(t *testing.T, from, to string) {
+	b, err := os.ReadFile(from)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Dir(to), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(to, b, 0644); err != nil {
+		t.Fatal(err)
+	}
+}
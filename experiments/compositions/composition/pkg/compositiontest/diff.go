@@ -0,0 +1,163 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compositiontest
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/cloud-native-compositions/compositions/composition/pkg/render"
+	"github.com/google/go-cmp/cmp"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/yaml"
+)
+
+// Diff compares the output of a stage with the expected YAML and returns the
+// differences, or "" if there are none.
+//
+// Objects are matched by group, kind, namespace and name so their order does
+// not matter. Null and empty fields are dropped and numbers compared by value,
+// so an expected file need not spell out empty defaults.
+func Diff(stage *render.Stage, expected []byte) (string, error) {
+	if stage.Values != nil {
+		var want interface{}
+		if err := yaml.Unmarshal(expected, &want); err != nil {
+			return "", fmt.Errorf("parsing expected values: %w", err)
+		}
+		w, err := normalize(want)
+		if err != nil {
+			return "", err
+		}
+		g, err := normalize(stage.Values)
+		if err != nil {
+			return "", err
+		}
+		return cmp.Diff(w, g), nil
+	}
+
+	wantObjects, err := render.ParseObjects(expected)
+	if err != nil {
+		return "", fmt.Errorf("parsing expected objects: %w", err)
+	}
+	want, err := byKey(wantObjects)
+	if err != nil {
+		return "", err
+	}
+	got, err := byKey(stage.Objects)
+	if err != nil {
+		return "", err
+	}
+
+	keys := map[string]bool{}
+	for k := range want {
+		keys[k] = true
+	}
+	for k := range got {
+		keys[k] = true
+	}
+	sorted := []string{}
+	for k := range keys {
+		sorted = append(sorted, k)
+	}
+	sort.Strings(sorted)
+
+	var out strings.Builder
+	for _, k := range sorted {
+		w, inWant := want[k]
+		g, inGot := got[k]
+		switch {
+		case !inGot:
+			fmt.Fprintf(&out, "- %s: missing\n", k)
+		case !inWant:
+			fmt.Fprintf(&out, "+ %s: unexpected\n", k)
+		default:
+			if diff := cmp.Diff(w, g); diff != "" {
+				fmt.Fprintf(&out, "~ %s:\n%s", k, diff)
+			}
+		}
+	}
+	return out.String(), nil
+}
+
+// byKey returns the normalized objects keyed by kind.group namespace/name
+func byKey(objects []*unstructured.Unstructured) (map[string]interface{}, error) {
+	out := map[string]interface{}{}
+	for _, obj := range objects {
+		key := fmt.Sprintf("%s %s", obj.GroupVersionKind().GroupKind(), obj.GetName())
+		if obj.GetNamespace() != "" {
+			key = fmt.Sprintf("%s %s/%s", obj.GroupVersionKind().GroupKind(), obj.GetNamespace(), obj.GetName())
+		}
+		for i := 2; ; i++ {
+			if _, ok := out[key]; !ok {
+				break
+			}
+			key = fmt.Sprintf("%s #%d", strings.Split(key, " #")[0], i)
+		}
+		n, err := normalize(obj.Object)
+		if err != nil {
+			return nil, err
+		}
+		out[key] = n
+	}
+	return out, nil
+}
+
+// normalize converts v to plain json types and drops null and empty fields
+func normalize(v interface{}) (interface{}, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var out interface{}
+	if err := json.Unmarshal(b, &out); err != nil {
+		return nil, err
+	}
+	return prune(out), nil
+}
+
+func prune(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, f := range v {
+			f = prune(f)
+			if isEmpty(f) {
+				delete(v, k)
+				continue
+			}
+			v[k] = f
+		}
+		return v
+	case []interface{}:
+		for i := range v {
+			v[i] = prune(v[i])
+		}
+		return v
+	}
+	return v
+}
+
+func isEmpty(v interface{}) bool {
+	switch v := v.(type) {
+	case nil:
+		return true
+	case map[string]interface{}:
+		return len(v) == 0
+	case []interface{}:
+		return len(v) == 0
+	}
+	return false
+}
@@ -21,6 +21,7 @@ import (
 	"fmt"
 	"strings"
 
+	compositionv1alpha1 "github.com/cloud-native-compositions/compositions/composition/api/v1alpha1"
 	"github.com/go-logr/logr"
 	"github.com/gobuffalo/flect"
 	"k8s.io/apiextensions-apiserver/pkg/apis/apiextensions"
@@ -36,6 +37,8 @@ import (
 
 const (
 	FacadeGroup = "facade.compositions.google.com"
+	// FacadeLabel marks the facade CRDs created from a Composition schema
+	FacadeLabel = "compositions.google.com/facade"
 )
 
 var scheme = runtime.NewScheme()
@@ -85,7 +88,7 @@ func NewFacadeCRDInfo(
 	}
 
 	crd.Labels = map[string]string{
-		"compositions.google.com/facade": "yes",
+		FacadeLabel: "yes",
 	}
 	for k, v := range labels {
 		crd.Labels[k] = v
@@ -93,6 +96,38 @@ func NewFacadeCRDInfo(
 	return &crd
 }
 
+// FacadeCRDName returns the name of the CRD of the composition's facade
+func FacadeCRDName(c *compositionv1alpha1.Composition) string {
+	if c.Spec.InputAPIGroup != "" || c.Spec.Schema == nil {
+		return c.Spec.InputAPIGroup
+	}
+	gvk := schema.GroupVersionKind{Group: c.Spec.Schema.Group, Version: c.Spec.Schema.APIVersion, Kind: c.Spec.Schema.Kind}
+	return NewFacadeCRDInfo(gvk, "", nil, nil, nil).Name()
+}
+
+// FacadeSchema returns the plural and the OpenAPI v3 schema of the facade CRD
+// built from the schema of a composition, without installing it. The plural
+// is empty for compositions with an inputAPIGroup.
+func FacadeSchema(c *compositionv1alpha1.Composition) (string, *extv1.JSONSchemaProps, error) {
+	if c.Spec.InputAPIGroup != "" || c.Spec.Schema == nil {
+		return "", nil, nil
+	}
+	gvk := schema.GroupVersionKind{Group: c.Spec.Schema.Group, Version: c.Spec.Schema.APIVersion, Kind: c.Spec.Schema.Kind}
+	crdInfo := NewFacadeCRDInfo(gvk, "", nil, nil, nil)
+	specSchema, err := BuildSchema(c.Spec.Schema.Spec.Raw)
+	if err != nil {
+		return "", nil, err
+	}
+	if err := crdInfo.SetSpec(specSchema); err != nil {
+		return "", nil, err
+	}
+	out := &extv1.JSONSchemaProps{}
+	if err := extv1.Convert_apiextensions_JSONSchemaProps_To_v1_JSONSchemaProps(crdInfo.schema, out, nil); err != nil {
+		return "", nil, err
+	}
+	return crdInfo.Plural, out, nil
+}
+
 func (c *CRDInfo) SetCRDSchema(schema *apiextensions.JSONSchemaProps) {
 	c.schema = schema
 }
@@ -0,0 +1,200 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package crds
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/awslabs/kro/pkg/simpleschema"
+	extv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/util/yaml"
+)
+
+// BuildSchema converts simpleschema raw bytes to an OpenAPI schema.
+// schema is expected to be defined using the "SimpleSchema" format.
+func BuildSchema(raw []byte) (*extv1.JSONSchemaProps, error) {
+	// unmarshal the to a map[string]interface{} to
+	// make it easier to work with.
+	ssinstance := map[string]interface{}{}
+	err := yaml.UnmarshalStrict(raw, &ssinstance)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal simpleschema bytes: %w", err)
+	}
+
+	// generate openapi spec from simpleschema instance
+	openapiSchema, err := simpleschema.ToOpenAPISpec(ssinstance)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build OpenAPI schema for instance: %v", err)
+	}
+	return openapiSchema, nil
+}
+
+// SimpleSchemaFromJSONSchema derives the SimpleSchema of a facade spec from a
+// JSON schema, like the values.schema.json of a helm chart. The schema has to
+// be an object with properties. Fields SimpleSchema has no type for, like
+// objects without properties, arrays of objects and compositions of schemas,
+// are left out and returned as skipped with the reason.
+func SimpleSchemaFromJSONSchema(schema map[string]interface{}) (map[string]interface{}, []string, error) {
+	if jsonSchemaType(schema) != "object" || schema["properties"] == nil {
+		return nil, nil, fmt.Errorf("the schema is not an object with properties")
+	}
+	c := &simpleSchemaConverter{}
+	return c.object("", schema), c.skipped, nil
+}
+
+type simpleSchemaConverter struct {
+	skipped []string
+}
+
+func (c *simpleSchemaConverter) skip(path, reason string) {
+	c.skipped = append(c.skipped, fmt.Sprintf("%s: %s", path, reason))
+}
+
+// object converts the properties of an object schema
+func (c *simpleSchemaConverter) object(path string, schema map[string]interface{}) map[string]interface{} {
+	required := map[string]bool{}
+	if r, ok := schema["required"].([]interface{}); ok {
+		for _, name := range r {
+			if s, ok := name.(string); ok {
+				required[s] = true
+			}
+		}
+	}
+	properties, _ := schema["properties"].(map[string]interface{})
+	names := []string{}
+	for name := range properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	out := map[string]interface{}{}
+	for _, name := range names {
+		p := properties[name]
+		fieldPath := name
+		if path != "" {
+			fieldPath = path + "." + name
+		}
+		property, ok := p.(map[string]interface{})
+		if !ok {
+			c.skip(fieldPath, "not a schema")
+			continue
+		}
+		// SimpleSchema has no markers for objects
+		if jsonSchemaType(property) == "object" && property["properties"] != nil {
+			out[name] = c.object(fieldPath, property)
+			continue
+		}
+		typ, reason := simpleSchemaType(property)
+		if reason != "" {
+			c.skip(fieldPath, reason)
+			continue
+		}
+		out[name] = typ + simpleSchemaMarkers(property, required[name])
+	}
+	return out
+}
+
+// simpleSchemaType is the SimpleSchema type of an atomic, map or array schema,
+// or the reason it has none
+func simpleSchemaType(schema map[string]interface{}) (string, string) {
+	switch t := jsonSchemaType(schema); t {
+	case "string", "integer", "boolean":
+		return t, ""
+	case "number":
+		// SimpleSchema float is not an OpenAPI type the facade CRD can have
+		return "", "numbers have no SimpleSchema type"
+	case "array":
+		items, ok := schema["items"].(map[string]interface{})
+		if !ok {
+			return "", "arrays need an items schema"
+		}
+		typ, reason := simpleSchemaType(items)
+		if reason != "" {
+			return "", "items: " + reason
+		}
+		return "[]" + typ, ""
+	case "object":
+		if schema["properties"] != nil {
+			return "", "objects with properties are only supported as fields"
+		}
+		values, ok := schema["additionalProperties"].(map[string]interface{})
+		if !ok {
+			return "", "objects need properties or an additionalProperties schema"
+		}
+		typ, reason := simpleSchemaType(values)
+		if reason != "" {
+			return "", "additionalProperties: " + reason
+		}
+		return "map[string]" + typ, ""
+	case "":
+		return "", "no type"
+	default:
+		return "", fmt.Sprintf("unsupported type %s", t)
+	}
+}
+
+// jsonSchemaType is the type of the schema. A nullable type is the type, and
+// a schema with properties and no type an object.
+func jsonSchemaType(schema map[string]interface{}) string {
+	switch t := schema["type"].(type) {
+	case string:
+		return t
+	case []interface{}:
+		types := []string{}
+		for _, v := range t {
+			if s, ok := v.(string); ok && s != "null" {
+				types = append(types, s)
+			}
+		}
+		if len(types) == 1 {
+			return types[0]
+		}
+		return strings.Join(types, ",")
+	}
+	if schema["properties"] != nil {
+		return "object"
+	}
+	return ""
+}
+
+// simpleSchemaMarkers are the required, default and description markers of a
+// field. '|' separates the type from the markers, so values with one are left out.
+func simpleSchemaMarkers(schema map[string]interface{}, required bool) string {
+	markers := []string{}
+	if required {
+		markers = append(markers, "required=true")
+	}
+	if d, ok := schema["default"]; ok {
+		var value string
+		if s, ok := d.(string); ok {
+			value = strconv.Quote(s)
+		} else if j, err := json.Marshal(d); err == nil {
+			value = string(j)
+		}
+		if value != "" && !strings.Contains(value, "|") {
+			markers = append(markers, "default="+value)
+		}
+	}
+	if d, ok := schema["description"].(string); ok && d != "" && !strings.Contains(d, "|") {
+		markers = append(markers, "description="+strconv.Quote(strings.Join(strings.Fields(d), " ")))
+	}
+	if len(markers) == 0 {
+		return ""
+	}
+	return " | " + strings.Join(markers, " ")
+}
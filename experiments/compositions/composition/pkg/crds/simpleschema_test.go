@@ -0,0 +1,125 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package crds
+
+import (
+	"reflect"
+	"testing"
+
+	"sigs.k8s.io/yaml"
+)
+
+const valuesSchema = `
+type: object
+required: [name]
+properties:
+  name:
+    type: string
+    description: |
+      The name of
+      the database
+  replicas:
+    type: integer
+    default: 3
+  tier:
+    type: [string, "null"]
+    default: db-f1
+  ratio:
+    type: number
+  labels:
+    type: object
+    additionalProperties:
+      type: string
+  zones:
+    type: array
+    items:
+      type: string
+  users:
+    type: array
+    items:
+      type: object
+      properties:
+        name:
+          type: string
+  extra:
+    type: object
+  backup:
+    properties:
+      enabled:
+        type: boolean
+        default: false
+`
+
+func TestSimpleSchemaFromJSONSchema(t *testing.T) {
+	schema := map[string]interface{}{}
+	if err := yaml.Unmarshal([]byte(valuesSchema), &schema); err != nil {
+		t.Fatalf("failed to parse schema: %v", err)
+	}
+	spec, skipped, err := SimpleSchemaFromJSONSchema(schema)
+	if err != nil {
+		t.Fatalf("SimpleSchemaFromJSONSchema() failed: %v", err)
+	}
+
+	want := map[string]interface{}{
+		"name":     `string | required=true description="The name of the database"`,
+		"replicas": "integer | default=3",
+		"tier":     `string | default="db-f1"`,
+		"labels":   "map[string]string",
+		"zones":    "[]string",
+		"backup": map[string]interface{}{
+			"enabled": "boolean | default=false",
+		},
+	}
+	if !reflect.DeepEqual(spec, want) {
+		t.Errorf("\nwant: %v\ngot: %v", want, spec)
+	}
+	wantSkipped := []string{
+		"extra: objects need properties or an additionalProperties schema",
+		"ratio: numbers have no SimpleSchema type",
+		"users: items: objects with properties are only supported as fields",
+	}
+	if !reflect.DeepEqual(skipped, wantSkipped) {
+		t.Errorf("\nwant skipped: %v\ngot: %v", wantSkipped, skipped)
+	}
+
+	// The SimpleSchema builds the facade CRD schema
+	raw, err := yaml.Marshal(spec)
+	if err != nil {
+		t.Fatalf("failed to marshal SimpleSchema: %v", err)
+	}
+	props, err := BuildSchema(raw)
+	if err != nil {
+		t.Fatalf("BuildSchema() failed: %v", err)
+	}
+	if !reflect.DeepEqual(props.Required, []string{"name"}) {
+		t.Errorf("want name required, got %v", props.Required)
+	}
+	if d := props.Properties["name"].Description; d != "The name of the database" {
+		t.Errorf("want the description of name, got %q", d)
+	}
+	if d := props.Properties["tier"].Default; d == nil || string(d.Raw) != `"db-f1"` {
+		t.Errorf("want the default of tier, got %v", d)
+	}
+	if typ := props.Properties["zones"].Items.Schema.Type; typ != "string" {
+		t.Errorf("want zones of strings, got %s", typ)
+	}
+}
+
+func TestSimpleSchemaFromJSONSchemaNotAnObject(t *testing.T) {
+	_, _, err := SimpleSchemaFromJSONSchema(map[string]interface{}{"type": "string"})
+	if err == nil {
+		t.Fatalf("want an error for a schema without properties")
+	}
+}
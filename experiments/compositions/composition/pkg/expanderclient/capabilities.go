@@ -0,0 +1,81 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package expanderclient
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"time"
+
+	compositionv1alpha1 "github.com/cloud-native-compositions/compositions/composition/api/v1alpha1"
+	pbv2 "github.com/cloud-native-compositions/compositions/composition/proto/v2"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+)
+
+// GetCapabilities asks the expander what it supports, and negotiates the
+// protocol of the client from the protocol versions it reports.
+// Expanders that predate GetCapabilities are reported as v1 expanders that validate.
+func (c *Client) GetCapabilities(ctx context.Context) (*pbv2.Capabilities, error) {
+	if c.inproc != nil {
+		return c.inproc.GetCapabilities(ctx, &pbv2.GetCapabilitiesRequest{})
+	}
+	result, err := c.v2.GetCapabilities(ctx, &pbv2.GetCapabilitiesRequest{})
+	if isUnimplemented(err) {
+		result, err = &pbv2.Capabilities{
+			ProtocolVersions: []string{string(ProtocolV1)},
+			Validate:         true,
+		}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	p := ProtocolV1
+	if slices.Contains(result.ProtocolVersions, string(ProtocolV2)) {
+		p = ProtocolV2
+	}
+	negotiated.Store(c.uri, negotiation{protocol: p, at: time.Now()})
+	return result, nil
+}
+
+// ToAPICapabilities converts v2 capabilities into the API representation
+func ToAPICapabilities(capabilities *pbv2.Capabilities) (compositionv1alpha1.ExpanderCapabilities, error) {
+	out := compositionv1alpha1.ExpanderCapabilities{
+		ProtocolVersions: capabilities.ProtocolVersions,
+		Validate:         capabilities.Validate,
+	}
+	if capabilities.Config != nil && capabilities.Config.Kind != "" {
+		out.Config = &compositionv1alpha1.ExpanderConfigGVK{
+			Group:   capabilities.Config.Group,
+			Version: capabilities.Config.Version,
+			Kind:    capabilities.Config.Kind,
+		}
+	}
+	switch capabilities.ResultType {
+	case pbv2.ResultType_OBJECTS:
+		out.ResultType = compositionv1alpha1.ExpanderResultTypeManifests
+	case pbv2.ResultType_VALUES:
+		out.ResultType = compositionv1alpha1.ExpanderResultTypeValues
+	}
+	if len(capabilities.TemplateSchema) != 0 {
+		schema := &apiextensionsv1.JSONSchemaProps{}
+		if err := json.Unmarshal(capabilities.TemplateSchema, schema); err != nil {
+			return out, fmt.Errorf("invalid template schema: %w", err)
+		}
+		out.TemplateSchema = schema
+	}
+	return out, nil
+}
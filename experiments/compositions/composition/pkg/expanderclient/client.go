@@ -0,0 +1,218 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package expanderclient
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/cloud-native-compositions/compositions/composition/pkg/inproc"
+	pb "github.com/cloud-native-compositions/compositions/composition/proto"
+	pbv2 "github.com/cloud-native-compositions/compositions/composition/proto/v2"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+)
+
+type Protocol string
+
+const (
+	ProtocolV1 Protocol = "v1"
+	ProtocolV2 Protocol = "v2"
+)
+
+// negotiated caches the protocol version picked for each expander uri from
+// the protocol versions it reports in GetCapabilities
+var negotiated sync.Map
+
+// negotiationTTL bounds how long a negotiated protocol is used before the
+// capabilities are asked again, so that an expander upgraded behind the same
+// uri is not held to v1
+const negotiationTTL = 10 * time.Minute
+
+// negotiation is the protocol picked for an expander and when it was asked
+type negotiation struct {
+	protocol Protocol
+	at       time.Time
+}
+
+// Client calls an expander using the v2 protocol when its capabilities list
+// v2 and falls back to v1 otherwise. Results are always returned as v2 messages.
+// inproc:// uris are served by expanders registered in the inproc package.
+type Client struct {
+	uri    string
+	conn   *grpc.ClientConn
+	v1     pb.ExpanderClient
+	v2     pbv2.ExpanderClient
+	inproc pbv2.ExpanderServer
+}
+
+func New(uri string) (*Client, error) {
+	if name, ok := inproc.ParseURI(uri); ok {
+		e, found := inproc.Get(name)
+		if !found {
+			return nil, fmt.Errorf("inproc expander %q is not registered in the manager", name)
+		}
+		return &Client{uri: uri, inproc: e}, nil
+	}
+
+	conn, err := grpc.NewClient(uri, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return nil, err
+	}
+	return &Client{
+		uri:  uri,
+		conn: conn,
+		v1:   pb.NewExpanderClient(conn),
+		v2:   pbv2.NewExpanderClient(conn),
+	}, nil
+}
+
+// NewInProc returns a client calling e directly, without registering it
+func NewInProc(e pbv2.ExpanderServer) *Client {
+	return &Client{uri: "inproc", inproc: e}
+}
+
+func (c *Client) Close() error {
+	if c.conn == nil {
+		return nil
+	}
+	return c.conn.Close()
+}
+
+// Protocol returns the protocol negotiated with the expander so far.
+// ProtocolV2 is assumed until the capabilities of the expander are known, and
+// again once the negotiation is older than negotiationTTL.
+func (c *Client) Protocol() Protocol {
+	if c.inproc != nil {
+		return ProtocolV2
+	}
+	n, ok := negotiated.Load(c.uri)
+	if !ok || time.Since(n.(negotiation).at) > negotiationTTL {
+		return ProtocolV2
+	}
+	return n.(negotiation).protocol
+}
+
+// protocol returns the protocol to call the expander with, asking for its
+// capabilities when they are not known or the negotiation expired
+func (c *Client) protocol(ctx context.Context) (Protocol, error) {
+	if n, ok := negotiated.Load(c.uri); ok && time.Since(n.(negotiation).at) <= negotiationTTL {
+		return n.(negotiation).protocol, nil
+	}
+	if _, err := c.GetCapabilities(ctx); err != nil {
+		return "", err
+	}
+	return c.Protocol(), nil
+}
+
+func (c *Client) Validate(ctx context.Context, req *pbv2.ValidateRequest) (*pbv2.ValidateResult, error) {
+	if c.inproc != nil {
+		return c.inproc.Validate(ctx, req)
+	}
+	p, err := c.protocol(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if p == ProtocolV2 {
+		return c.v2.Validate(ctx, req)
+	}
+
+	result, err := c.v1.Validate(ctx, &pb.ValidateRequest{
+		Config:   req.Config,
+		Context:  req.Context,
+		Facade:   req.Facade,
+		Value:    req.Value,
+		Resource: req.Resource,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &pbv2.ValidateResult{
+		Status:      pbv2.Status(result.Status),
+		Diagnostics: diagnosticsFromV1(result.Status, result.Error),
+	}, nil
+}
+
+func (c *Client) Evaluate(ctx context.Context, req *pbv2.EvaluateRequest) (*pbv2.EvaluateResult, error) {
+	if c.inproc != nil {
+		return c.inproc.Evaluate(ctx, req)
+	}
+	p, err := c.protocol(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if p == ProtocolV2 {
+		return c.v2.Evaluate(ctx, req)
+	}
+
+	result, err := c.v1.Evaluate(ctx, &pb.EvaluateRequest{
+		Config:   req.Config,
+		Context:  req.Context,
+		Facade:   req.Facade,
+		Value:    req.Value,
+		Resource: req.Resource,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return evaluateResultFromV1(ctx, result)
+}
+
+func isUnimplemented(err error) bool {
+	return err != nil && status.Code(err) == codes.Unimplemented
+}
+
+func diagnosticsFromV1(s pb.Status, e *pb.Error) []*pbv2.Diagnostic {
+	if e == nil || e.Message == "" {
+		return nil
+	}
+	severity := pbv2.Severity_ERROR
+	if s == pb.Status_SUCCESS || s == pb.Status_EVALUATE_WAIT {
+		severity = pbv2.Severity_INFO
+	}
+	// v1 has no error codes
+	return []*pbv2.Diagnostic{{
+		Severity: severity,
+		Message:  e.Message,
+	}}
+}
+
+func evaluateResultFromV1(ctx context.Context, result *pb.EvaluateResult) (*pbv2.EvaluateResult, error) {
+	v2result := &pbv2.EvaluateResult{
+		Status:      pbv2.Status(result.Status),
+		Diagnostics: diagnosticsFromV1(result.Status, result.Error),
+		Values:      result.Values,
+	}
+	switch result.Type {
+	case pb.ResultType_MANIFESTS:
+		v2result.Type = pbv2.ResultType_OBJECTS
+	case pb.ResultType_VALUES:
+		v2result.Type = pbv2.ResultType_VALUES
+	}
+	if result.Status != pb.Status_SUCCESS || len(result.Manifests) == 0 {
+		return v2result, nil
+	}
+
+	objects, err := ObjectsFromManifests(ctx, string(result.Manifests))
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse manifests returned by expander: %w", err)
+	}
+	v2result.Objects = objects
+	return v2result, nil
+}
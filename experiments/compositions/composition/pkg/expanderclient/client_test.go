@@ -0,0 +1,334 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package expanderclient
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/cloud-native-compositions/compositions/composition/pkg/inproc"
+	pb "github.com/cloud-native-compositions/compositions/composition/proto"
+	pbv2 "github.com/cloud-native-compositions/compositions/composition/proto/v2"
+	"google.golang.org/grpc"
+)
+
+const configMapManifest = `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: demo
+  namespace: default
+data:
+  foo: bar
+`
+
+type v1Server struct {
+	pb.UnimplementedExpanderServer
+}
+
+func (s *v1Server) Validate(context.Context, *pb.ValidateRequest) (*pb.ValidateResult, error) {
+	return &pb.ValidateResult{
+		Status: pb.Status_VALIDATE_FAILED,
+		Error:  &pb.Error{Message: "bad template"},
+	}, nil
+}
+
+func (s *v1Server) Evaluate(context.Context, *pb.EvaluateRequest) (*pb.EvaluateResult, error) {
+	return &pb.EvaluateResult{
+		Status:    pb.Status_SUCCESS,
+		Type:      pb.ResultType_MANIFESTS,
+		Error:     &pb.Error{},
+		Manifests: []byte(configMapManifest),
+	}, nil
+}
+
+type v2Server struct {
+	pbv2.UnimplementedExpanderServer
+}
+
+func (s *v2Server) Evaluate(context.Context, *pbv2.EvaluateRequest) (*pbv2.EvaluateResult, error) {
+	return &pbv2.EvaluateResult{
+		Status: pbv2.Status_SUCCESS,
+		Type:   pbv2.ResultType_OBJECTS,
+		Diagnostics: []*pbv2.Diagnostic{{
+			Severity:  pbv2.Severity_WARNING,
+			Code:      "DeprecatedField",
+			Message:   "use spec.size",
+			FieldPath: "spec.replicas",
+		}},
+		Objects: []*pbv2.Object{{
+			ApiVersion: "v1",
+			Kind:       "ConfigMap",
+			Name:       "demo",
+			Json:       []byte(`{"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"demo"}}`),
+		}},
+	}, nil
+}
+
+func startServer(t *testing.T, register func(s *grpc.Server)) string {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	s := grpc.NewServer()
+	register(s)
+	go func() {
+		_ = s.Serve(lis)
+	}()
+	t.Cleanup(s.Stop)
+	return lis.Addr().String()
+}
+
+func TestFallbackToV1(t *testing.T) {
+	uri := startServer(t, func(s *grpc.Server) {
+		pb.RegisterExpanderServer(s, &v1Server{})
+	})
+	c, err := New(uri)
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	defer c.Close()
+
+	result, err := c.Evaluate(context.Background(), &pbv2.EvaluateRequest{})
+	if err != nil {
+		t.Fatalf("Evaluate() failed: %v", err)
+	}
+	if c.Protocol() != ProtocolV1 {
+		t.Errorf("want protocol %s, got %s", ProtocolV1, c.Protocol())
+	}
+	if result.Type != pbv2.ResultType_OBJECTS {
+		t.Errorf("want type OBJECTS, got %s", result.Type)
+	}
+	if len(result.Objects) != 1 || result.Objects[0].Kind != "ConfigMap" || result.Objects[0].Name != "demo" {
+		t.Fatalf("unexpected objects: %v", result.Objects)
+	}
+
+	vresult, err := c.Validate(context.Background(), &pbv2.ValidateRequest{})
+	if err != nil {
+		t.Fatalf("Validate() failed: %v", err)
+	}
+	if vresult.Status != pbv2.Status_VALIDATE_FAILED {
+		t.Errorf("want VALIDATE_FAILED, got %s", vresult.Status)
+	}
+	if got := ErrorMessage(vresult.Diagnostics, ""); got != "bad template" {
+		t.Errorf("want message %q, got %q", "bad template", got)
+	}
+}
+
+func TestV2(t *testing.T) {
+	uri := startServer(t, func(s *grpc.Server) {
+		pbv2.RegisterExpanderServer(s, &v2Server{})
+	})
+	c, err := New(uri)
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	defer c.Close()
+
+	result, err := c.Evaluate(context.Background(), &pbv2.EvaluateRequest{})
+	if err != nil {
+		t.Fatalf("Evaluate() failed: %v", err)
+	}
+	if c.Protocol() != ProtocolV2 {
+		t.Errorf("want protocol %s, got %s", ProtocolV2, c.Protocol())
+	}
+
+	diagnostics := ToAPIDiagnostics(result.Diagnostics)
+	if len(diagnostics) != 1 {
+		t.Fatalf("want 1 diagnostic, got %d", len(diagnostics))
+	}
+	want := "DeprecatedField: use spec.size (field: spec.replicas)"
+	if got := diagnostics[0].String(); got != want {
+		t.Errorf("want %q, got %q", want, got)
+	}
+
+	manifests, err := ManifestsFromObjects(result.Objects)
+	if err != nil {
+		t.Fatalf("ManifestsFromObjects() failed: %v", err)
+	}
+	wantManifests := "---\napiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: demo\n"
+	if manifests != wantManifests {
+		t.Errorf("want manifests:\n%s\ngot:\n%s", wantManifests, manifests)
+	}
+}
+
+func (s *v2Server) GetCapabilities(context.Context, *pbv2.GetCapabilitiesRequest) (*pbv2.Capabilities, error) {
+	return &pbv2.Capabilities{
+		ProtocolVersions: []string{"v1", "v2"},
+		Config:           &pbv2.ConfigGVK{Group: "composition.google.com", Version: "v1alpha1", Kind: "DemoConfiguration"},
+		Validate:         true,
+		ResultType:       pbv2.ResultType_OBJECTS,
+		TemplateSchema:   []byte(`{"type":"string"}`),
+	}, nil
+}
+
+// v1Capabilities serves both protocols but only reports v1
+type v1Capabilities struct {
+	v2Server
+}
+
+func (s *v1Capabilities) GetCapabilities(context.Context, *pbv2.GetCapabilitiesRequest) (*pbv2.Capabilities, error) {
+	return &pbv2.Capabilities{ProtocolVersions: []string{"v1"}}, nil
+}
+
+func TestProtocolFromCapabilities(t *testing.T) {
+	uri := startServer(t, func(s *grpc.Server) {
+		pb.RegisterExpanderServer(s, &v1Server{})
+		pbv2.RegisterExpanderServer(s, &v1Capabilities{})
+	})
+	c, err := New(uri)
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	defer c.Close()
+
+	result, err := c.Evaluate(context.Background(), &pbv2.EvaluateRequest{})
+	if err != nil {
+		t.Fatalf("Evaluate() failed: %v", err)
+	}
+	if c.Protocol() != ProtocolV1 {
+		t.Errorf("want protocol %s, got %s", ProtocolV1, c.Protocol())
+	}
+	// The v1 server has no diagnostics, the v2 server would return one
+	if len(result.Diagnostics) != 0 || len(result.Objects) != 1 {
+		t.Errorf("want the result of the v1 server, got %v", result)
+	}
+}
+
+func TestNegotiationExpires(t *testing.T) {
+	uri := startServer(t, func(s *grpc.Server) {
+		pbv2.RegisterExpanderServer(s, &v2Server{})
+	})
+	c, err := New(uri)
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	defer c.Close()
+
+	// The expander spoke v1 before it was upgraded behind the same uri
+	negotiated.Store(uri, negotiation{protocol: ProtocolV1, at: time.Now()})
+	if c.Protocol() != ProtocolV1 {
+		t.Fatalf("want protocol %s, got %s", ProtocolV1, c.Protocol())
+	}
+	negotiated.Store(uri, negotiation{protocol: ProtocolV1, at: time.Now().Add(-2 * negotiationTTL)})
+	if _, err := c.Evaluate(context.Background(), &pbv2.EvaluateRequest{}); err != nil {
+		t.Fatalf("Evaluate() failed: %v", err)
+	}
+	if c.Protocol() != ProtocolV2 {
+		t.Errorf("want protocol %s after the negotiation expired, got %s", ProtocolV2, c.Protocol())
+	}
+}
+
+func TestGetCapabilities(t *testing.T) {
+	v1uri := startServer(t, func(s *grpc.Server) {
+		pb.RegisterExpanderServer(s, &v1Server{})
+	})
+	v2uri := startServer(t, func(s *grpc.Server) {
+		pbv2.RegisterExpanderServer(s, &v2Server{})
+	})
+
+	for _, tc := range []struct {
+		uri        string
+		versions   int
+		resultType string
+		kind       string
+	}{
+		{uri: v1uri, versions: 1},
+		{uri: v2uri, versions: 2, resultType: "manifests", kind: "DemoConfiguration"},
+	} {
+		c, err := New(tc.uri)
+		if err != nil {
+			t.Fatalf("New() failed: %v", err)
+		}
+		defer c.Close()
+
+		result, err := c.GetCapabilities(context.Background())
+		if err != nil {
+			t.Fatalf("GetCapabilities() failed: %v", err)
+		}
+		capabilities, err := ToAPICapabilities(result)
+		if err != nil {
+			t.Fatalf("ToAPICapabilities() failed: %v", err)
+		}
+		if len(capabilities.ProtocolVersions) != tc.versions {
+			t.Errorf("want %d protocol versions, got %v", tc.versions, capabilities.ProtocolVersions)
+		}
+		if !capabilities.Validate {
+			t.Errorf("want validate true")
+		}
+		if string(capabilities.ResultType) != tc.resultType {
+			t.Errorf("want result type %q, got %q", tc.resultType, capabilities.ResultType)
+		}
+		if tc.kind == "" {
+			if capabilities.Config != nil {
+				t.Errorf("want no config, got %v", capabilities.Config)
+			}
+			continue
+		}
+		if capabilities.Config == nil || capabilities.Config.Kind != tc.kind {
+			t.Errorf("want config kind %q, got %v", tc.kind, capabilities.Config)
+		}
+		if capabilities.TemplateSchema == nil || capabilities.TemplateSchema.Type != "string" {
+			t.Errorf("want template schema of type string, got %v", capabilities.TemplateSchema)
+		}
+	}
+}
+
+func TestInProc(t *testing.T) {
+	inproc.Register("test-inproc", &v2Server{})
+
+	c, err := New(inproc.URI("test-inproc"))
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	defer c.Close()
+
+	result, err := c.Evaluate(context.Background(), &pbv2.EvaluateRequest{})
+	if err != nil {
+		t.Fatalf("Evaluate() failed: %v", err)
+	}
+	if c.Protocol() != ProtocolV2 {
+		t.Errorf("want protocol %s, got %s", ProtocolV2, c.Protocol())
+	}
+	if len(result.Objects) != 1 || result.Objects[0].Name != "demo" {
+		t.Errorf("unexpected objects: %v", result.Objects)
+	}
+
+	if _, err := New(inproc.URI("not-registered")); err == nil {
+		t.Errorf("want error for an unregistered inproc expander")
+	}
+}
+
+func TestRawFromObjects(t *testing.T) {
+	objects := []*pbv2.Object{{
+		ApiVersion: "v1",
+		Kind:       "ConfigMap",
+		Name:       "demo",
+		Json:       []byte(`{"kind": "ConfigMap", "apiVersion": "v1", "metadata": {"name": "demo"}, "data": {"size": 9007199254740993}}`),
+	}}
+	raw, err := RawFromObjects(objects)
+	if err != nil {
+		t.Fatalf("RawFromObjects() failed: %v", err)
+	}
+	if len(raw) != 1 {
+		t.Fatalf("want 1 object, got %d", len(raw))
+	}
+	// The keys are sorted and large numbers are kept as they are
+	want := `{"apiVersion":"v1","data":{"size":9007199254740993},"kind":"ConfigMap","metadata":{"name":"demo"}}`
+	if got := string(raw[0].Raw); got != want {
+		t.Errorf("want %s, got %s", want, got)
+	}
+}
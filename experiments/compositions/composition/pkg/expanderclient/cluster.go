@@ -0,0 +1,88 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package expanderclient
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	pbv2 "github.com/cloud-native-compositions/compositions/composition/proto/v2"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/discovery"
+)
+
+// ClusterTTL is how long a discovered cluster is reused. It is discovered
+// again after that so that the API versions of CRDs installed since are sent.
+const ClusterTTL = time.Minute
+
+// Cluster discovers the version and the API versions of the cluster sent in
+// Evaluate requests
+type Cluster struct {
+	discovery discovery.DiscoveryInterface
+
+	mu         sync.Mutex
+	cluster    *pbv2.Cluster
+	discovered time.Time
+}
+
+// NewCluster returns a Cluster discovering with the client
+func NewCluster(client discovery.DiscoveryInterface) *Cluster {
+	return &Cluster{discovery: client}
+}
+
+// Get returns the cluster, discovering it if it is older than ClusterTTL
+func (c *Cluster) Get() (*pbv2.Cluster, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.cluster != nil && time.Since(c.discovered) < ClusterTTL {
+		return c.cluster, nil
+	}
+
+	version, err := c.discovery.ServerVersion()
+	if err != nil {
+		return nil, fmt.Errorf("unable to discover the server version: %w", err)
+	}
+	_, lists, err := c.discovery.ServerGroupsAndResources()
+	// The groups that failed, for example of an unavailable aggregated API, are left out
+	if err != nil && !discovery.IsGroupDiscoveryFailedError(err) {
+		return nil, fmt.Errorf("unable to discover the API versions: %w", err)
+	}
+	c.cluster = &pbv2.Cluster{
+		KubeVersion: version.GitVersion,
+		ApiVersions: APIVersions(lists),
+	}
+	c.discovered = time.Now()
+	return c.cluster, nil
+}
+
+// APIVersions lists the group versions and the group version kinds of the
+// resources, as helm's .Capabilities.APIVersions has them. Subresources are
+// left out.
+func APIVersions(lists []*metav1.APIResourceList) []string {
+	versions := sets.New[string]()
+	for _, list := range lists {
+		versions.Insert(list.GroupVersion)
+		for _, resource := range list.APIResources {
+			if strings.Contains(resource.Name, "/") {
+				continue
+			}
+			versions.Insert(list.GroupVersion + "/" + resource.Kind)
+		}
+	}
+	return sets.List(versions)
+}
@@ -0,0 +1,58 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package expanderclient
+
+import (
+	"reflect"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/version"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	clienttesting "k8s.io/client-go/testing"
+)
+
+func TestCluster(t *testing.T) {
+	client := &fakediscovery.FakeDiscovery{
+		Fake: &clienttesting.Fake{
+			Resources: []*metav1.APIResourceList{
+				{
+					GroupVersion: "v1",
+					APIResources: []metav1.APIResource{{Name: "configmaps", Kind: "ConfigMap"}},
+				},
+				{
+					GroupVersion: "apps/v1",
+					APIResources: []metav1.APIResource{
+						{Name: "deployments", Kind: "Deployment"},
+						{Name: "deployments/scale", Kind: "Scale"},
+					},
+				},
+			},
+		},
+		FakedServerVersion: &version.Info{GitVersion: "v1.30.2"},
+	}
+
+	cluster, err := NewCluster(client).Get()
+	if err != nil {
+		t.Fatalf("Get() failed: %v", err)
+	}
+	if cluster.KubeVersion != "v1.30.2" {
+		t.Errorf("want kube version v1.30.2, got %s", cluster.KubeVersion)
+	}
+	want := []string{"apps/v1", "apps/v1/Deployment", "v1", "v1/ConfigMap"}
+	if !reflect.DeepEqual(cluster.ApiVersions, want) {
+		t.Errorf("want API versions %v, got %v", want, cluster.ApiVersions)
+	}
+}
@@ -0,0 +1,190 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package expanderclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	compositionv1alpha1 "github.com/cloud-native-compositions/compositions/composition/api/v1alpha1"
+	pbv2 "github.com/cloud-native-compositions/compositions/composition/proto/v2"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/kubebuilder-declarative-pattern/pkg/patterns/declarative/pkg/manifest"
+	"sigs.k8s.io/yaml"
+)
+
+// ObjectsFromManifests splits a yaml manifest stream into v2 objects
+func ObjectsFromManifests(ctx context.Context, manifests string) ([]*pbv2.Object, error) {
+	parsed, err := manifest.ParseObjects(ctx, manifests)
+	if err != nil {
+		return nil, err
+	}
+	objects := []*pbv2.Object{}
+	for _, item := range parsed.Items {
+		o, err := ObjectFromUnstructured(item.UnstructuredObject())
+		if err != nil {
+			return nil, err
+		}
+		objects = append(objects, o)
+	}
+	return objects, nil
+}
+
+// ObjectFromUnstructured converts an unstructured object to a v2 object
+func ObjectFromUnstructured(u *unstructured.Unstructured) (*pbv2.Object, error) {
+	j, err := u.MarshalJSON()
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal %s %s: %w", u.GetKind(), u.GetName(), err)
+	}
+	return &pbv2.Object{
+		ApiVersion: u.GetAPIVersion(),
+		Kind:       u.GetKind(),
+		Namespace:  u.GetNamespace(),
+		Name:       u.GetName(),
+		Json:       j,
+	}, nil
+}
+
+// RawFromObjects converts v2 objects into the objects of a Plan stage. The
+// JSON is compacted with sorted keys, as the API server returns it, so that
+// unchanged objects compare equal to the ones read back from the Plan.
+func RawFromObjects(objects []*pbv2.Object) ([]runtime.RawExtension, error) {
+	if len(objects) == 0 {
+		return nil, nil
+	}
+	out := []runtime.RawExtension{}
+	for _, o := range objects {
+		var v interface{}
+		d := json.NewDecoder(bytes.NewReader(o.Json))
+		d.UseNumber()
+		if err := d.Decode(&v); err != nil {
+			return nil, fmt.Errorf("failed to parse %s %s: %w", o.Kind, o.Name, err)
+		}
+		j, err := json.Marshal(v)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal %s %s: %w", o.Kind, o.Name, err)
+		}
+		out = append(out, runtime.RawExtension{Raw: j})
+	}
+	return out, nil
+}
+
+// ManifestsFromObjects joins v2 objects into a yaml manifest stream
+func ManifestsFromObjects(objects []*pbv2.Object) (string, error) {
+	docs := []string{}
+	for _, o := range objects {
+		y, err := yaml.JSONToYAML(o.Json)
+		if err != nil {
+			return "", fmt.Errorf("failed to convert %s %s to yaml: %w", o.Kind, o.Name, err)
+		}
+		docs = append(docs, string(y))
+	}
+	if len(docs) == 0 {
+		return "", nil
+	}
+	return "---\n" + strings.Join(docs, "---\n"), nil
+}
+
+var hookPhases = map[pbv2.HookPhase]compositionv1alpha1.HookPhase{
+	pbv2.HookPhase_PRE_INSTALL:  compositionv1alpha1.HookPreInstall,
+	pbv2.HookPhase_POST_INSTALL: compositionv1alpha1.HookPostInstall,
+	pbv2.HookPhase_PRE_UPGRADE:  compositionv1alpha1.HookPreUpgrade,
+	pbv2.HookPhase_POST_UPGRADE: compositionv1alpha1.HookPostUpgrade,
+	pbv2.HookPhase_PRE_DELETE:   compositionv1alpha1.HookPreDelete,
+	pbv2.HookPhase_POST_DELETE:  compositionv1alpha1.HookPostDelete,
+}
+
+var hookDeletePolicies = map[pbv2.HookDeletePolicy]compositionv1alpha1.HookDeletePolicy{
+	pbv2.HookDeletePolicy_BEFORE_HOOK_CREATION: compositionv1alpha1.HookBeforeHookCreation,
+	pbv2.HookDeletePolicy_HOOK_SUCCEEDED:       compositionv1alpha1.HookSucceeded,
+	pbv2.HookDeletePolicy_HOOK_FAILED:          compositionv1alpha1.HookFailed,
+}
+
+// HooksFromProto converts v2 hooks into the hooks of a Plan stage
+func HooksFromProto(hooks []*pbv2.Hook) ([]compositionv1alpha1.Hook, error) {
+	if len(hooks) == 0 {
+		return nil, nil
+	}
+	out := []compositionv1alpha1.Hook{}
+	for _, h := range hooks {
+		phase, ok := hookPhases[h.Phase]
+		if !ok {
+			return nil, fmt.Errorf("unknown hook phase %s", h.Phase)
+		}
+		hook := compositionv1alpha1.Hook{Phase: phase, Weight: h.Weight}
+		for _, p := range h.DeletePolicies {
+			policy, ok := hookDeletePolicies[p]
+			if !ok {
+				return nil, fmt.Errorf("unknown hook delete policy %s", p)
+			}
+			hook.DeletePolicies = append(hook.DeletePolicies, policy)
+		}
+		objects, err := RawFromObjects(h.Objects)
+		if err != nil {
+			return nil, err
+		}
+		hook.Objects = objects
+		out = append(out, hook)
+	}
+	return out, nil
+}
+
+// ToAPIDiagnostics converts v2 diagnostics into the API representation
+func ToAPIDiagnostics(diagnostics []*pbv2.Diagnostic) []compositionv1alpha1.Diagnostic {
+	if len(diagnostics) == 0 {
+		return nil
+	}
+	out := []compositionv1alpha1.Diagnostic{}
+	for _, d := range diagnostics {
+		ad := compositionv1alpha1.Diagnostic{
+			Code:      d.Code,
+			Message:   d.Message,
+			FieldPath: d.FieldPath,
+		}
+		switch d.Severity {
+		case pbv2.Severity_WARNING:
+			ad.Severity = compositionv1alpha1.DiagnosticSeverityWarning
+		case pbv2.Severity_INFO:
+			ad.Severity = compositionv1alpha1.DiagnosticSeverityInfo
+		default:
+			ad.Severity = compositionv1alpha1.DiagnosticSeverityError
+		}
+		if d.Source != nil {
+			ad.File = d.Source.File
+			ad.Line = d.Source.Line
+			ad.Column = d.Source.Column
+		}
+		out = append(out, ad)
+	}
+	return out
+}
+
+// ErrorMessage returns a single message for the error diagnostics, falling back to
+// the info diagnostics (used for WAIT) and then to fallback.
+func ErrorMessage(diagnostics []*pbv2.Diagnostic, fallback string) string {
+	apiDiagnostics := ToAPIDiagnostics(diagnostics)
+	message := compositionv1alpha1.DiagnosticsMessage(apiDiagnostics, compositionv1alpha1.DiagnosticSeverityError)
+	if message == "" {
+		message = compositionv1alpha1.DiagnosticsMessage(apiDiagnostics, compositionv1alpha1.DiagnosticSeverityInfo)
+	}
+	if message == "" {
+		return fallback
+	}
+	return message
+}
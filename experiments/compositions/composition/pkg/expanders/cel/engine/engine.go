@@ -0,0 +1,169 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package engine
+
+import (
+	"fmt"
+
+	"github.com/cloud-native-compositions/compositions/composition/pkg/cel/library"
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/types/ref"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	apiservercel "k8s.io/apiserver/pkg/cel"
+)
+
+type Engine struct {
+	resource string
+	values   map[string]interface{}
+	env      *cel.Env
+}
+
+// New returns an engine with the facade resource, fetched and context
+// variables. variables are further variables of any type, like the iterator
+// of a forEach, set with With.
+func New(resource string, values map[string]interface{}, variables ...string) (*Engine, error) {
+	e, err := newEngine(resource, values)
+	if err != nil {
+		return nil, err
+	}
+	opts := []cel.EnvOption{cel.Variable(resource, cel.MapType(cel.StringType, cel.DynType))}
+	for _, name := range variables {
+		opts = append(opts, cel.Variable(name, cel.DynType))
+	}
+	if e.env, err = e.env.Extend(opts...); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// NewTyped returns an engine type checking expressions against the
+// OpenAPI v3 schema of the facade, for finding unknown fields and type
+// mismatches before evaluating them. fetched and context stay untyped.
+func NewTyped(resource string, facadeSchema *apiextensionsv1.JSONSchemaProps) (*Engine, error) {
+	e, err := newEngine(resource, nil)
+	if err != nil {
+		return nil, err
+	}
+	facade := facadeDeclType(resource, facadeSchema)
+	opts, err := apiservercel.NewDeclTypeProvider(facade).EnvOptions(e.env.CELTypeProvider())
+	if err != nil {
+		return nil, err
+	}
+	opts = append(opts, cel.Variable(resource, facade.CelType()))
+	if e.env, err = e.env.Extend(opts...); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// newEngine returns an engine with the fetched and context variables and the
+// function library of compositions
+func newEngine(resource string, values map[string]interface{}) (*Engine, error) {
+	env, err := cel.NewEnv(
+		cel.Variable("fetched", cel.MapType(cel.StringType, cel.DynType)),
+		cel.Variable("context", cel.MapType(cel.StringType, cel.DynType)),
+		library.Lib(),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Engine{
+		resource: resource,
+		values:   values,
+		env:      env,
+	}, nil
+}
+
+// Extend returns an engine with a further variable of type t
+func (e *Engine) Extend(name string, t *cel.Type) (*Engine, error) {
+	env, err := e.env.Extend(cel.Variable(name, t))
+	if err != nil {
+		return nil, err
+	}
+	return &Engine{
+		resource: e.resource,
+		values:   e.values,
+		env:      env,
+	}, nil
+}
+
+// With returns an engine evaluating expressions with the variable name set
+// to value
+func (e *Engine) With(name string, value interface{}) *Engine {
+	values := make(map[string]interface{}, len(e.values)+1)
+	for k, v := range e.values {
+		values[k] = v
+	}
+	values[name] = value
+	return &Engine{
+		resource: e.resource,
+		values:   values,
+		env:      e.env,
+	}
+}
+
+// compile parses and type checks an expression
+func (e *Engine) compile(expression string) (*cel.Ast, error) {
+	if expression == "" {
+		return nil, fmt.Errorf("empty expression")
+	}
+	ast, issues := e.env.Compile(expression)
+	if issues != nil && issues.Err() != nil {
+		return nil, issues.Err()
+	}
+	return ast, nil
+}
+
+// Check parses and type checks an expression and returns the type of its
+// value
+func (e *Engine) Check(expression string) (*cel.Type, error) {
+	ast, err := e.compile(expression)
+	if err != nil {
+		return nil, err
+	}
+	return ast.OutputType(), nil
+}
+
+// References returns whether the expression uses the variable name
+func (e *Engine) References(expression string, name string) (bool, error) {
+	ast, err := e.compile(expression)
+	if err != nil {
+		return false, err
+	}
+	for _, ref := range ast.NativeRep().ReferenceMap() {
+		if ref.Name == name {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (e *Engine) Eval(expression string) (ref.Val, error) {
+	ast, err := e.compile(expression)
+	if err != nil {
+		return nil, err
+	}
+	prog, err := e.env.Program(ast)
+	if err != nil {
+		return nil, err
+	}
+	val, _, err := prog.Eval(e.values)
+	if err != nil {
+		return nil, err
+	}
+
+	return val, nil
+}
@@ -0,0 +1,94 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package engine
+
+import (
+	"regexp"
+
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	apiservercel "k8s.io/apiserver/pkg/cel"
+)
+
+// identifier matches the field names that can be selected with a dot
+var identifier = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// schemaDeclType returns the CEL type of the values of a schema, the way the
+// facade is decoded: numbers are ints or doubles and string formats are not
+// parsed, so they are dyn and string. Objects with properties are object
+// types, for selecting a field that is not in the schema to fail type
+// checking. Objects without a schema of their fields are maps of dyn.
+func schemaDeclType(s *apiextensionsv1.JSONSchemaProps) *apiservercel.DeclType {
+	if s == nil || s.XIntOrString {
+		return apiservercel.DynType
+	}
+	switch s.Type {
+	case "string":
+		return apiservercel.StringType
+	case "integer":
+		return apiservercel.IntType
+	case "boolean":
+		return apiservercel.BoolType
+	case "array":
+		if s.Items == nil || s.Items.Schema == nil {
+			return apiservercel.NewListType(apiservercel.DynType, -1)
+		}
+		return apiservercel.NewListType(schemaDeclType(s.Items.Schema), -1)
+	case "object":
+		if s.AdditionalProperties != nil && s.AdditionalProperties.Schema != nil {
+			return apiservercel.NewMapType(apiservercel.StringType, schemaDeclType(s.AdditionalProperties.Schema), -1)
+		}
+		if len(s.Properties) == 0 || (s.XPreserveUnknownFields != nil && *s.XPreserveUnknownFields) {
+			return apiservercel.NewMapType(apiservercel.StringType, apiservercel.DynType, -1)
+		}
+		required := map[string]bool{}
+		for _, name := range s.Required {
+			required[name] = true
+		}
+		fields := map[string]*apiservercel.DeclField{}
+		for name, prop := range s.Properties {
+			if !identifier.MatchString(name) {
+				// Only reachable with an index, like a map
+				return apiservercel.NewMapType(apiservercel.StringType, apiservercel.DynType, -1)
+			}
+			prop := prop
+			fields[name] = apiservercel.NewDeclField(name, schemaDeclType(&prop), required[name], nil, nil)
+		}
+		return apiservercel.NewObjectType("object", fields)
+	default:
+		// numbers and fields without a type
+		return apiservercel.DynType
+	}
+}
+
+// facadeDeclType returns the type of the facade, named facade.<resource>.
+// apiVersion, kind and metadata are always there, even if the schema does
+// not declare them.
+func facadeDeclType(resource string, s *apiextensionsv1.JSONSchemaProps) *apiservercel.DeclType {
+	root := s.DeepCopy()
+	root.Type = "object"
+	root.XPreserveUnknownFields = nil
+	if root.Properties == nil {
+		root.Properties = map[string]apiextensionsv1.JSONSchemaProps{}
+	}
+	for _, name := range []string{"apiVersion", "kind"} {
+		if _, ok := root.Properties[name]; !ok {
+			root.Properties[name] = apiextensionsv1.JSONSchemaProps{Type: "string"}
+		}
+	}
+	if _, ok := root.Properties["metadata"]; !ok {
+		root.Properties["metadata"] = apiextensionsv1.JSONSchemaProps{Type: "object"}
+	}
+	return schemaDeclType(root).MaybeAssignTypeName("facade." + resource)
+}
@@ -0,0 +1,358 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package cel implements the CEL expander.
+// It is served over grpc by the cel-expander binary and can be compiled into the
+// composition manager as an inproc expander.
+package cel
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	compositionv1alpha1 "github.com/cloud-native-compositions/compositions/composition/api/v1alpha1"
+	"github.com/cloud-native-compositions/compositions/composition/pkg/expanders/cel/engine"
+	"github.com/cloud-native-compositions/compositions/composition/pkg/expanders/cel/resource"
+	"github.com/cloud-native-compositions/compositions/composition/pkg/expandersdk"
+	"github.com/google/cel-go/common/types"
+)
+
+type Request = expandersdk.Request[compositionv1alpha1.CELConfiguration]
+
+// identifier matches the names of CEL variables
+var identifier = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+type Expander struct {
+	cel       *engine.Engine
+	resources []*template
+}
+
+// template is a resource of the configuration with its expressions
+type template struct {
+	name       string
+	definition []byte
+	resource   *resource.Resource
+	// cel declares the iterator of forEach
+	cel         *engine.Engine
+	includeWhen []string
+	forEach     string
+	iterator    string
+	// hashNames is set when the name of the resource does not use the
+	// iterator. The names of the objects get a hash of their item.
+	hashNames bool
+}
+
+func NewExpander(req *Request) (*Expander, error) {
+	var err error
+	e := &Expander{}
+
+	// Create a CEL engine
+	e.cel, err = engine.New(req.Resource, req.Inputs())
+	if err != nil {
+		return nil, fmt.Errorf("error creating CEL engine: %w", err)
+	}
+
+	// load resources
+	for _, rsrc := range req.Config.Spec.Resources {
+		t, err := e.newTemplate(req, rsrc)
+		if err != nil {
+			return nil, fmt.Errorf("error creating resource for %s, %w", rsrc.Name, err)
+		}
+		e.resources = append(e.resources, t)
+	}
+	return e, nil
+}
+
+func (e *Expander) newTemplate(req *Request, rsrc compositionv1alpha1.CELResource) (*template, error) {
+	r, err := resource.NewResourceFromRaw(rsrc.Name, rsrc.Definition.Raw)
+	if err != nil {
+		return nil, err
+	}
+	t := &template{name: rsrc.Name, definition: rsrc.Definition.Raw, resource: r, cel: e.cel}
+
+	if rsrc.ForEach != nil {
+		t.iterator = rsrc.ForEach.Iterator
+		switch t.iterator {
+		case req.Resource, "fetched", "context":
+			return nil, fmt.Errorf("forEach.iterator %q is already a variable", t.iterator)
+		}
+		if !identifier.MatchString(t.iterator) {
+			return nil, fmt.Errorf("forEach.iterator %q is not a valid variable name", t.iterator)
+		}
+		if t.forEach, err = resource.Expression(rsrc.ForEach.Expression); err != nil {
+			return nil, fmt.Errorf("forEach.expression: %w", err)
+		}
+		if t.cel, err = engine.New(req.Resource, req.Inputs(), t.iterator); err != nil {
+			return nil, fmt.Errorf("error creating CEL engine: %w", err)
+		}
+
+		t.hashNames = true
+		if field := r.Field("metadata.name"); field != nil {
+			for _, v := range field.Variables() {
+				// Expressions that do not compile are reported by Validate
+				if uses, err := t.cel.References(v.CELExpression, t.iterator); err == nil && uses {
+					t.hashNames = false
+				}
+			}
+		}
+	}
+
+	for i, when := range rsrc.IncludeWhen {
+		expression, err := resource.Expression(when)
+		if err != nil {
+			return nil, fmt.Errorf("includeWhen[%d]: %w", i, err)
+		}
+		t.includeWhen = append(t.includeWhen, expression)
+	}
+	return t, nil
+}
+
+// check type checks the expressions of the resources. With the schema of the
+// facade, it finds the fields that are not in the schema and the type
+// mismatches, otherwise only the syntax errors and unknown variables.
+func (e *Expander) check(req *Request) ([]*expandersdk.Diagnostic, error) {
+	eng := e.cel
+	if req.FacadeSchema != nil {
+		var err error
+		if eng, err = engine.NewTyped(req.Resource, req.FacadeSchema); err != nil {
+			return nil, fmt.Errorf("error creating CEL engine for the facade schema: %w", err)
+		}
+	}
+	diagnostics := []*expandersdk.Diagnostic{}
+	for _, t := range e.resources {
+		d, err := t.check(eng)
+		if err != nil {
+			return nil, err
+		}
+		diagnostics = append(diagnostics, d...)
+	}
+	return diagnostics, nil
+}
+
+// check type checks the expressions of a resource. The iterator of forEach
+// has the type of the items of the list.
+func (t *template) check(eng *engine.Engine) ([]*expandersdk.Diagnostic, error) {
+	diagnostics := []*expandersdk.Diagnostic{}
+	report := func(code, field, expression, format string, args ...interface{}) {
+		diagnostics = append(diagnostics, expandersdk.ErrorDiagnostic(code, "%s: %s: %s: %s",
+			t.name, field, expression, fmt.Sprintf(format, args...)))
+	}
+
+	if t.forEach != "" {
+		item := types.DynType
+		list, err := eng.Check(t.forEach)
+		switch {
+		case err != nil:
+			report(checkCode(err), "forEach.expression", t.forEach, "%v", err)
+		case list.Kind() == types.ListKind:
+			item = list.Parameters()[0]
+		case list.Kind() != types.DynKind:
+			report("TypeMismatch", "forEach.expression", t.forEach, "want a list, got %s", list)
+		}
+		if eng, err = eng.Extend(t.iterator, item); err != nil {
+			return nil, fmt.Errorf("error declaring the iterator of %s: %w", t.name, err)
+		}
+	}
+
+	for i, when := range t.includeWhen {
+		include, err := eng.Check(when)
+		switch {
+		case err != nil:
+			report(checkCode(err), fmt.Sprintf("includeWhen[%d]", i), when, "%v", err)
+		case include.Kind() != types.BoolKind && include.Kind() != types.DynKind:
+			report("TypeMismatch", fmt.Sprintf("includeWhen[%d]", i), when, "want a bool, got %s", include)
+		}
+	}
+
+	for _, field := range t.resource.Fields {
+		for _, v := range field.Variables() {
+			value, err := eng.Check(v.CELExpression)
+			if err != nil {
+				report(checkCode(err), field.FieldPath(), v.Expression, "%v", err)
+				continue
+			}
+			switch value.Kind() {
+			case types.MapKind, types.ListKind, types.StructKind:
+				if !field.Standalone {
+					report("TypeMismatch", field.FieldPath(), v.Expression,
+						"a map or list can only be the whole field, not part of a string")
+				}
+			}
+		}
+	}
+	return diagnostics, nil
+}
+
+// checkCode returns the diagnostic code of a CEL error
+func checkCode(err error) string {
+	switch message := err.Error(); {
+	case strings.Contains(message, "Syntax error"):
+		return "ExpressionSyntaxError"
+	case strings.Contains(message, "undefined field"):
+		return "UnknownField"
+	case strings.Contains(message, "undeclared reference"):
+		return "UndeclaredReference"
+	default:
+		return "TypeMismatch"
+	}
+}
+
+func Validate(ctx context.Context, req *Request) (*expandersdk.Result, error) {
+	e, err := NewExpander(req)
+	if err != nil {
+		return nil, fmt.Errorf("error processing inputs: %w", err)
+	}
+	diagnostics, err := e.check(req)
+	if err != nil {
+		return nil, err
+	}
+	if len(diagnostics) != 0 {
+		return nil, expandersdk.Failed(diagnostics...)
+	}
+	return nil, nil
+}
+
+func Evaluate(ctx context.Context, req *Request) (*expandersdk.Result, error) {
+	e, err := NewExpander(req)
+	if err != nil {
+		return nil, fmt.Errorf("error processing inputs: %w", err)
+	}
+
+	// Loop through resources
+	manifests := []byte{}
+	for _, t := range e.resources {
+		objects, err := t.expand()
+		if err != nil {
+			return nil, err
+		}
+		for _, rsrc := range objects {
+			manifests = append(manifests, []byte("\n---\n")...)
+			manifests = append(manifests, rsrc.Raw...)
+		}
+	}
+
+	return &expandersdk.Result{Manifests: manifests}, nil
+}
+
+// expand returns the objects of the resource, one for each item of forEach
+func (t *template) expand() ([]*resource.Resource, error) {
+	if t.forEach == "" {
+		rsrc, err := t.render(t.cel)
+		if err != nil || rsrc == nil {
+			return nil, err
+		}
+		return []*resource.Resource{rsrc}, nil
+	}
+
+	val, err := t.cel.Eval(t.forEach)
+	if err != nil {
+		return nil, fmt.Errorf("error Evaluating forEach of %s: %s, %w", t.name, t.forEach, err)
+	}
+	value, err := resource.NativeValue(val)
+	if err != nil {
+		return nil, fmt.Errorf("error Evaluating forEach of %s: %s, %w", t.name, t.forEach, err)
+	}
+	items, ok := value.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("forEach of %s: want a list, got %s", t.name, val.Type())
+	}
+
+	objects := []*resource.Resource{}
+	names := map[string]int{}
+	for i, item := range items {
+		rsrc, err := t.render(t.cel.With(t.iterator, item))
+		if err != nil {
+			return nil, fmt.Errorf("%s[%d]: %w", t.name, i, err)
+		}
+		if rsrc == nil {
+			continue
+		}
+		name := rsrc.ObjectName()
+		if name != "" && t.hashNames {
+			// The suffix only depends on the item, for the objects to keep
+			// their names when items are added, removed or reordered
+			name += "-" + itemHash(item)
+			if err := rsrc.SetObjectName(name); err != nil {
+				return nil, fmt.Errorf("%s[%d]: %w", t.name, i, err)
+			}
+		}
+		if j, ok := names[name]; ok && name != "" {
+			return nil, fmt.Errorf("forEach of %s: items %d and %d have the same name %s", t.name, j, i, name)
+		}
+		names[name] = i
+		objects = append(objects, rsrc)
+	}
+	return objects, nil
+}
+
+// render evaluates the expressions of the resource. It returns nil if an
+// includeWhen expression is false.
+func (t *template) render(eng *engine.Engine) (*resource.Resource, error) {
+	for _, when := range t.includeWhen {
+		val, err := eng.Eval(when)
+		if err != nil {
+			return nil, fmt.Errorf("error Evaluating includeWhen of %s: %s, %w", t.name, when, err)
+		}
+		include, ok := val.(types.Bool)
+		if !ok {
+			return nil, fmt.Errorf("includeWhen of %s: %s: want a bool, got %s", t.name, when, val.Type())
+		}
+		if !include {
+			return nil, nil
+		}
+	}
+
+	rsrc, err := resource.NewResourceFromRaw(t.name, t.definition)
+	if err != nil {
+		return nil, fmt.Errorf("error creating resource for %s, %w", t.name, err)
+	}
+	// loop through variables
+	for vindex := range rsrc.Variables {
+		// cel.eval()
+		result, err := eng.Eval(rsrc.Variables[vindex].CELExpression)
+		if err != nil {
+			// TODO: consume the error and mark result failed ?
+			return nil, fmt.Errorf("error Evaluating expression: %s, %w", rsrc.Variables[vindex].Expression, err)
+		}
+		rsrc.Variables[vindex].ResolvedValue = result
+	}
+	// Replace variables
+	if err := rsrc.ApplyResolvedVariables(); err != nil {
+		return nil, fmt.Errorf("error applying resolved variables for %s, %w", rsrc.Name, err)
+	}
+	return rsrc, nil
+}
+
+// itemHash returns a short hash of a forEach item
+func itemHash(item interface{}) string {
+	// json sorts the keys of maps
+	b, _ := json.Marshal(item)
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])[:8]
+}
+
+// New returns the CEL expander
+func New() *expandersdk.Expander[compositionv1alpha1.CELConfiguration] {
+	return &expandersdk.Expander[compositionv1alpha1.CELConfiguration]{
+		Name:     "cel",
+		Config:   compositionv1alpha1.GroupVersion.WithKind("CELConfiguration"),
+		Validate: Validate,
+		Evaluate: Evaluate,
+	}
+}
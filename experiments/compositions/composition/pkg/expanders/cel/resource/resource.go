@@ -0,0 +1,291 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resource
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/google/cel-go/common/types"
+	"github.com/google/cel-go/common/types/ref"
+	"github.com/google/cel-go/common/types/traits"
+	"sigs.k8s.io/yaml"
+)
+
+type Resource struct {
+	Name string
+	// Data is the resource with the resolved variables once they are applied
+	Data map[string]interface{}
+	// Raw is the YAML of Data
+	Raw []byte
+	// Fields are the string fields with expressions
+	Fields []*Field
+	// Variables are the expressions of all the fields, in order
+	Variables []*Variable
+}
+
+// NewResourceFromRaw parses the YAML or JSON of a resource and finds the
+// expressions in its string fields
+func NewResourceFromRaw(name string, raw []byte) (*Resource, error) {
+	var data map[string]interface{}
+	err := yaml.Unmarshal(raw, &data, func(d *json.Decoder) *json.Decoder {
+		// Numbers are kept as they are written
+		d.UseNumber()
+		return d
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	resource := &Resource{
+		Name: name,
+		Data: data,
+		Raw:  raw,
+	}
+	if err := resource.extractVariables(nil, data); err != nil {
+		return nil, err
+	}
+	return resource, nil
+}
+
+// extractVariables walks the resource and adds the string fields with expressions
+func (r *Resource) extractVariables(path []interface{}, value interface{}) error {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		// Sorted, for the expressions to be evaluated in the same order
+		keys := []string{}
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			if err := r.extractVariables(appendPath(path, key), v[key]); err != nil {
+				return err
+			}
+		}
+	case []interface{}:
+		for i, elem := range v {
+			if err := r.extractVariables(appendPath(path, i), elem); err != nil {
+				return err
+			}
+		}
+	case string:
+		segments, err := parseField(v)
+		if err != nil {
+			return fmt.Errorf("%s: %w", fieldPath(path), err)
+		}
+		field := &Field{Path: path, segments: segments}
+		for _, s := range segments {
+			if s.variable != nil {
+				field.Standalone = len(segments) == 1
+				r.Variables = append(r.Variables, s.variable)
+			}
+		}
+		if field.Standalone || len(segments) > 1 || v != joinText(segments) {
+			r.Fields = append(r.Fields, field)
+		}
+	}
+	return nil
+}
+
+// joinText joins the literal text of a field without expressions
+func joinText(segments []segment) string {
+	text := ""
+	for _, s := range segments {
+		text += s.text
+	}
+	return text
+}
+
+func appendPath(path []interface{}, elem interface{}) []interface{} {
+	return append(append([]interface{}{}, path...), elem)
+}
+
+// ApplyResolvedVariables replaces the fields with expressions. A field that is
+// a single expression gets the value of the expression, with its type. The
+// values of expressions in a larger string are formatted into the string.
+func (r *Resource) ApplyResolvedVariables() error {
+	for _, field := range r.Fields {
+		var value interface{}
+		if field.Standalone {
+			v, err := NativeValue(field.segments[0].variable.ResolvedValue)
+			if err != nil {
+				return fmt.Errorf("%s: %s: %w", field.FieldPath(), field.segments[0].variable.Expression, err)
+			}
+			value = v
+		} else {
+			s := ""
+			for _, segment := range field.segments {
+				if segment.variable == nil {
+					s += segment.text
+					continue
+				}
+				text, err := interpolate(segment.variable.ResolvedValue)
+				if err != nil {
+					return fmt.Errorf("%s: %s: %w", field.FieldPath(), segment.variable.Expression, err)
+				}
+				s += text
+			}
+			value = s
+		}
+		r.Data = setPath(r.Data, field.Path, value).(map[string]interface{})
+	}
+
+	raw, err := yaml.Marshal(r.Data)
+	if err != nil {
+		return err
+	}
+	r.Raw = raw
+	return nil
+}
+
+// Field returns the field with expressions at path, for example
+// metadata.name, or nil
+func (r *Resource) Field(path string) *Field {
+	for _, field := range r.Fields {
+		if field.FieldPath() == path {
+			return field
+		}
+	}
+	return nil
+}
+
+// ObjectName returns the metadata.name of the resource
+func (r *Resource) ObjectName() string {
+	metadata, _ := r.Data["metadata"].(map[string]interface{})
+	name, _ := metadata["name"].(string)
+	return name
+}
+
+// SetObjectName sets the metadata.name of the resource once the resolved
+// variables are applied
+func (r *Resource) SetObjectName(name string) error {
+	metadata, ok := r.Data["metadata"].(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("the resource has no metadata")
+	}
+	metadata["name"] = name
+	raw, err := yaml.Marshal(r.Data)
+	if err != nil {
+		return err
+	}
+	r.Raw = raw
+	return nil
+}
+
+// setPath sets the value at path in the maps and lists of obj
+func setPath(obj interface{}, path []interface{}, value interface{}) interface{} {
+	if len(path) == 0 {
+		return value
+	}
+	switch p := path[0].(type) {
+	case int:
+		list := obj.([]interface{})
+		list[p] = setPath(list[p], path[1:], value)
+		return list
+	default:
+		m := obj.(map[string]interface{})
+		m[p.(string)] = setPath(m[p.(string)], path[1:], value)
+		return m
+	}
+}
+
+// NativeValue converts a CEL value to the JSON types of a resource
+func NativeValue(value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case nil, types.Null:
+		return nil, nil
+	case types.String:
+		return string(v), nil
+	case types.Int:
+		return int64(v), nil
+	case types.Uint:
+		return uint64(v), nil
+	case types.Double:
+		return float64(v), nil
+	case types.Bool:
+		return bool(v), nil
+	case types.Bytes:
+		return base64.StdEncoding.EncodeToString(v), nil
+	case types.Timestamp:
+		return v.Time.Format(time.RFC3339Nano), nil
+	case types.Duration:
+		return v.Duration.String(), nil
+	case traits.Mapper:
+		out := map[string]interface{}{}
+		it := v.Iterator()
+		for it.HasNext() == types.True {
+			key := it.Next()
+			k, ok := key.(types.String)
+			if !ok {
+				return nil, fmt.Errorf("map keys must be strings, got %s", key.Type())
+			}
+			elem, err := NativeValue(v.Get(key))
+			if err != nil {
+				return nil, err
+			}
+			out[string(k)] = elem
+		}
+		return out, nil
+	case traits.Lister:
+		out := []interface{}{}
+		it := v.Iterator()
+		for it.HasNext() == types.True {
+			elem, err := NativeValue(it.Next())
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, elem)
+		}
+		return out, nil
+	case *types.Err:
+		return nil, v
+	case ref.Val:
+		return nil, fmt.Errorf("unsupported value type %s", v.Type())
+	default:
+		// Values that are not CEL values are already native
+		return v, nil
+	}
+}
+
+// interpolate formats the value of an expression in a larger string
+func interpolate(value interface{}) (string, error) {
+	v, err := NativeValue(value)
+	if err != nil {
+		return "", err
+	}
+	switch v := v.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case int64:
+		return strconv.FormatInt(v, 10), nil
+	case uint64:
+		return strconv.FormatUint(v, 10), nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case map[string]interface{}, []interface{}:
+		return "", fmt.Errorf("a map or list can only be the whole field, not part of a string")
+	default:
+		return fmt.Sprint(v), nil
+	}
+}
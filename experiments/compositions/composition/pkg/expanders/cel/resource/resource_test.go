@@ -0,0 +1,196 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resource
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/cloud-native-compositions/compositions/composition/pkg/expanders/cel/engine"
+)
+
+func TestParseField(t *testing.T) {
+	testcases := []struct {
+		field       string
+		expressions []string
+		err         string
+	}{
+		{field: "plain"},
+		{field: "${a.b}", expressions: []string{"a.b"}},
+		{field: "${x.items[0]}-${a + b}", expressions: []string{"x.items[0]", "a + b"}},
+		{field: "${ {'a': 1}['a'] }", expressions: []string{"{'a': 1}['a']"}},
+		{field: `${'}' + "{"}`, expressions: []string{`'}' + "{"`}},
+		{field: `${'it\'s}'}`, expressions: []string{`'it\'s}'`}},
+		{field: `${r'\'}`, expressions: []string{`r'\'`}},
+		{field: `${'''a'b}'''}`, expressions: []string{`'''a'b}'''`}},
+		{field: "echo $${HOME}"},
+		{field: "${a", err: `unterminated expression "${a"`},
+		{field: "${a)}", err: `unexpected ')' in expression "${a)}"`},
+		{field: "${'a}", err: `unterminated string in expression "${'a}"`},
+		{field: "${ }", err: `empty expression at "${ }"`},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.field, func(t *testing.T) {
+			segments, err := parseField(tc.field)
+			if tc.err != "" {
+				if err == nil || err.Error() != tc.err {
+					t.Fatalf("want error %q, got %v", tc.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			expressions := []string{}
+			for _, s := range segments {
+				if s.variable != nil {
+					expressions = append(expressions, s.variable.CELExpression)
+				}
+			}
+			if strings.Join(expressions, "\n") != strings.Join(tc.expressions, "\n") {
+				t.Errorf("want expressions %q, got %q", tc.expressions, expressions)
+			}
+		})
+	}
+}
+
+const definition = `
+apiVersion: v1
+kind: Service
+metadata:
+  name: ${sqls.metadata.name}-svc
+  labels: ${sqls.spec.labels}
+  annotations:
+    script: echo $${HOME}
+spec:
+  replicas: ${sqls.spec.replicas}
+  ratio: ${double(sqls.spec.replicas) / 2.0}
+  enabled: ${has(sqls.spec.labels)}
+  ports: "${sqls.spec.ports.map(p, {'port': p, 'name': 'p' + string(p)})}"
+  first: ${sqls.spec.ports[0]}
+  summary: ${sqls.spec.replicas} replicas, first port ${sqls.spec.ports[0]}
+  nothing: ${fetched.missing}
+  static: 3
+`
+
+func TestApplyResolvedVariables(t *testing.T) {
+	values := map[string]interface{}{
+		"sqls": map[string]interface{}{
+			"metadata": map[string]interface{}{"name": "db"},
+			"spec": map[string]interface{}{
+				"replicas": int64(3),
+				"labels":   map[string]interface{}{"team": "blue"},
+				"ports":    []interface{}{int64(80), int64(443)},
+			},
+		},
+		"fetched": map[string]interface{}{"missing": nil},
+		"context": map[string]interface{}{},
+	}
+	eng, err := engine.New("sqls", values)
+	if err != nil {
+		t.Fatalf("NewEngine() failed: %v", err)
+	}
+	r, err := NewResourceFromRaw("service", []byte(definition))
+	if err != nil {
+		t.Fatalf("NewResourceFromRaw() failed: %v", err)
+	}
+	for _, v := range r.Variables {
+		if v.ResolvedValue, err = eng.Eval(v.CELExpression); err != nil {
+			t.Fatalf("Eval(%s) failed: %v", v.CELExpression, err)
+		}
+	}
+	if err := r.ApplyResolvedVariables(); err != nil {
+		t.Fatalf("ApplyResolvedVariables() failed: %v", err)
+	}
+
+	want := `apiVersion: v1
+kind: Service
+metadata:
+  annotations:
+    script: echo ${HOME}
+  labels:
+    team: blue
+  name: db-svc
+spec:
+  enabled: true
+  first: 80
+  nothing: null
+  ports:
+  - name: p80
+    port: 80
+  - name: p443
+    port: 443
+  ratio: 1.5
+  replicas: 3
+  static: 3
+  summary: 3 replicas, first port 80
+`
+	if string(r.Raw) != want {
+		t.Errorf("\nwant:\n%s\ngot:\n%s", want, r.Raw)
+	}
+	if replicas := r.Data["spec"].(map[string]interface{})["replicas"]; !reflect.DeepEqual(replicas, int64(3)) {
+		t.Errorf("want replicas int64 3, got %T %v", replicas, replicas)
+	}
+}
+
+func TestApplyResolvedVariablesInterpolatedList(t *testing.T) {
+	eng, err := engine.New("sqls", map[string]interface{}{"sqls": map[string]interface{}{}})
+	if err != nil {
+		t.Fatalf("NewEngine() failed: %v", err)
+	}
+	r, err := NewResourceFromRaw("configmap", []byte("data:\n  list: ports ${[1, 2]}\n"))
+	if err != nil {
+		t.Fatalf("NewResourceFromRaw() failed: %v", err)
+	}
+	if r.Variables[0].ResolvedValue, err = eng.Eval(r.Variables[0].CELExpression); err != nil {
+		t.Fatalf("Eval() failed: %v", err)
+	}
+	err = r.ApplyResolvedVariables()
+	want := "data.list: ${[1, 2]}: a map or list can only be the whole field, not part of a string"
+	if err == nil || err.Error() != want {
+		t.Fatalf("want error %q, got %v", want, err)
+	}
+}
+
+func TestExpression(t *testing.T) {
+	testcases := []struct {
+		s          string
+		expression string
+		err        string
+	}{
+		{s: "a.b == 1", expression: "a.b == 1"},
+		{s: " ${a.b == 1} ", expression: "a.b == 1"},
+		{s: "${a}-${b}", err: `want a single expression, got "${a}-${b}"`},
+		{s: "${a", err: `unterminated expression "${a"`},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.s, func(t *testing.T) {
+			expression, err := Expression(tc.s)
+			if tc.err != "" {
+				if err == nil || err.Error() != tc.err {
+					t.Fatalf("want error %q, got %v", tc.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if expression != tc.expression {
+				t.Errorf("want %q, got %q", tc.expression, expression)
+			}
+		})
+	}
+}
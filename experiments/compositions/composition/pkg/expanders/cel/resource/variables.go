@@ -0,0 +1,189 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resource
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Variable is a ${} expression in a string field of a resource
+type Variable struct {
+	// Expression is the expression with the reference syntax, ${...}
+	Expression    string
+	CELExpression string
+	ResolvedValue interface{}
+}
+
+// segment is either literal text or a variable of a string field
+type segment struct {
+	text     string
+	variable *Variable
+}
+
+// Field is a string field of a resource with expressions
+type Field struct {
+	// Path is the path of the field, with map keys and list indexes
+	Path []interface{}
+	// Standalone is set if the field is a single expression. The field is
+	// replaced by the value of the expression, keeping its type.
+	Standalone bool
+	segments   []segment
+}
+
+// FieldPath returns the path of the field, for example spec.ports[0].name
+func (f *Field) FieldPath() string {
+	return fieldPath(f.Path)
+}
+
+// Variables returns the expressions of the field
+func (f *Field) Variables() []*Variable {
+	variables := []*Variable{}
+	for _, s := range f.segments {
+		if s.variable != nil {
+			variables = append(variables, s.variable)
+		}
+	}
+	return variables
+}
+
+func fieldPath(path []interface{}) string {
+	b := strings.Builder{}
+	for _, p := range path {
+		switch p := p.(type) {
+		case int:
+			fmt.Fprintf(&b, "[%d]", p)
+		default:
+			if b.Len() != 0 {
+				b.WriteByte('.')
+			}
+			fmt.Fprintf(&b, "%s", p)
+		}
+	}
+	return b.String()
+}
+
+// Expression returns the CEL expression of a field holding one, like
+// includeWhen, written with or without ${}
+func Expression(s string) (string, error) {
+	s = strings.TrimSpace(s)
+	if !strings.HasPrefix(s, "${") {
+		return s, nil
+	}
+	segments, err := parseField(s)
+	if err != nil {
+		return "", err
+	}
+	if len(segments) != 1 || segments[0].variable == nil {
+		return "", fmt.Errorf("want a single expression, got %q", s)
+	}
+	return segments[0].variable.CELExpression, nil
+}
+
+// parseField splits a string into literal text and ${} expressions. $${ is a
+// literal ${. Expressions end at the } closing them, which is found skipping
+// CEL string literals and nested brackets, so they can use the full CEL
+// syntax.
+func parseField(s string) ([]segment, error) {
+	segments := []segment{}
+	text := strings.Builder{}
+	for i := 0; i < len(s); {
+		if strings.HasPrefix(s[i:], "$${") {
+			text.WriteString("${")
+			i += 3
+			continue
+		}
+		if !strings.HasPrefix(s[i:], "${") {
+			text.WriteByte(s[i])
+			i++
+			continue
+		}
+		end, err := scanExpression(s, i+2)
+		if err != nil {
+			return nil, err
+		}
+		expression := strings.TrimSpace(s[i+2 : end])
+		if expression == "" {
+			return nil, fmt.Errorf("empty expression at %q", s[i:end+1])
+		}
+		if text.Len() != 0 {
+			segments = append(segments, segment{text: text.String()})
+			text.Reset()
+		}
+		segments = append(segments, segment{variable: &Variable{Expression: s[i : end+1], CELExpression: expression}})
+		i = end + 1
+	}
+	if text.Len() != 0 {
+		segments = append(segments, segment{text: text.String()})
+	}
+	return segments, nil
+}
+
+// scanExpression returns the index of the } ending the expression starting at start
+func scanExpression(s string, start int) (int, error) {
+	closing := []byte{}
+	for i := start; i < len(s); i++ {
+		switch c := s[i]; c {
+		case '(':
+			closing = append(closing, ')')
+		case '[':
+			closing = append(closing, ']')
+		case '{':
+			closing = append(closing, '}')
+		case ')', ']', '}':
+			if len(closing) == 0 {
+				if c == '}' {
+					return i, nil
+				}
+				return 0, fmt.Errorf("unexpected %q in expression %q", c, s[start-2:])
+			}
+			if closing[len(closing)-1] != c {
+				return 0, fmt.Errorf("unexpected %q in expression %q", c, s[start-2:])
+			}
+			closing = closing[:len(closing)-1]
+		case '\'', '"':
+			end, err := scanString(s, i)
+			if err != nil {
+				return 0, fmt.Errorf("%w in expression %q", err, s[start-2:])
+			}
+			i = end
+		}
+	}
+	return 0, fmt.Errorf("unterminated expression %q", s[start-2:])
+}
+
+// scanString returns the index of the quote ending the CEL string literal
+// starting at start. Literals are quoted with ' or ", or with three of them,
+// and raw literals with an r prefix, possibly along with b, have no escapes.
+func scanString(s string, start int) (int, error) {
+	quote := s[start : start+1]
+	if strings.HasPrefix(s[start:], strings.Repeat(quote, 3)) {
+		quote = strings.Repeat(quote, 3)
+	}
+	raw := false
+	for j := start - 1; j >= 0 && j >= start-2 && strings.IndexByte("rRbB", s[j]) >= 0; j-- {
+		raw = raw || s[j] == 'r' || s[j] == 'R'
+	}
+	for i := start + len(quote); i < len(s); i++ {
+		if s[i] == '\\' && !raw {
+			i++
+			continue
+		}
+		if strings.HasPrefix(s[i:], quote) {
+			return i + len(quote) - 1, nil
+		}
+	}
+	return 0, fmt.Errorf("unterminated string")
+}
@@ -0,0 +1,134 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package getter implements the getter expander.
+// It reads fields from objects in the facade namespace and returns them as values
+// for later stages.
+package getter
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	compositionv1alpha1 "github.com/cloud-native-compositions/compositions/composition/api/v1alpha1"
+	"github.com/cloud-native-compositions/compositions/composition/pkg/expandersdk"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+type Request = expandersdk.Request[compositionv1alpha1.GetterConfiguration]
+
+// New returns the getter expander reading objects with the given client
+func New(dynamicClient dynamic.Interface) *expandersdk.Expander[compositionv1alpha1.GetterConfiguration] {
+	return &expandersdk.Expander[compositionv1alpha1.GetterConfiguration]{
+		Name:           "getter",
+		Config:         compositionv1alpha1.GroupVersion.WithKind("GetterConfiguration"),
+		ReturnsValues:  true,
+		OptionalConfig: true,
+		Evaluate: func(ctx context.Context, req *Request) (*expandersdk.Result, error) {
+			g := &Getter{
+				ctx:    ctx,
+				client: dynamicClient,
+				getter: req.Config,
+				facade: req.Facade,
+				values: map[string]interface{}{},
+			}
+			if err := g.Fetch(); err != nil {
+				return nil, err
+			}
+			return &expandersdk.Result{Values: g.values}, nil
+		},
+	}
+}
+
+type Getter struct {
+	ctx    context.Context
+	client dynamic.Interface
+	getter *compositionv1alpha1.GetterConfiguration
+	facade *unstructured.Unstructured
+	values map[string]interface{}
+}
+
+func (g *Getter) updateValues(obj *unstructured.Unstructured, vf *compositionv1alpha1.ValuesFrom) error {
+	for index := range vf.FieldRef {
+		fr := &vf.FieldRef[index]
+		path := strings.Split(strings.TrimLeft(fr.Path, "."), ".")
+		identifier := ""
+		gvk := obj.GroupVersionKind()
+		if obj.GetNamespace() != "" {
+			identifier = fmt.Sprintf("%s.%s(%s)/%s/%s[%s]",
+				gvk.Kind, gvk.Group, gvk.Version,
+				obj.GetNamespace(), obj.GetName(), fr.Path)
+		} else {
+			identifier = fmt.Sprintf("%s.%s(%s)/%s[%s]",
+				gvk.Kind, gvk.Group, gvk.Version, obj.GetName(), fr.Path)
+		}
+		v, ok, err := unstructured.NestedFieldCopy(obj.Object, path...)
+		if err != nil {
+			return expandersdk.Failedf("Error traversing field path: %s", identifier)
+		}
+		if !ok {
+			return expandersdk.Wait("Field path not present in object yet: %s", identifier)
+		}
+		if g.values[vf.Name] == nil {
+			g.values[vf.Name] = map[string]interface{}{}
+		}
+		g.values[vf.Name].(map[string]interface{})[fr.As] = v
+	}
+	return nil
+}
+
+func (g *Getter) getObject(vf *compositionv1alpha1.ValuesFrom, name string) (*unstructured.Unstructured, error) {
+	gvr := schema.GroupVersionResource{
+		Group:    vf.ResourceRef.Group,
+		Version:  vf.ResourceRef.Version,
+		Resource: vf.ResourceRef.Resource,
+	}
+	namespace := g.facade.GetNamespace()
+	identifier := fmt.Sprintf("%s.%s(%s)/%s/%s", gvr.Resource, gvr.Group, gvr.Version, namespace, name)
+	log.Printf("Fetching :%s", identifier)
+	obj, err := g.client.Resource(gvr).Namespace(namespace).Get(g.ctx, name, metav1.GetOptions{})
+	if err != nil {
+		if client.IgnoreNotFound(err) != nil {
+			log.Printf("Error getting dependent object: %s", identifier)
+			return nil, err
+		}
+		return nil, expandersdk.Wait("Dependent object not found: GVR: %s", identifier)
+	}
+	return obj, nil
+}
+
+// Fetch reads all the ValuesFrom objects into values
+func (g *Getter) Fetch() error {
+	for index := range g.getter.Spec.ValuesFrom {
+		vf := &g.getter.Spec.ValuesFrom[index]
+		name := vf.ResourceRef.Name
+		if name == "" {
+			name = g.facade.GetName() + vf.ResourceRef.NameSuffix
+		}
+		obj, err := g.getObject(vf, name)
+		if err != nil {
+			return err
+		}
+		if err := g.updateValues(obj, vf); err != nil {
+			return err
+		}
+	}
+	return nil
+}
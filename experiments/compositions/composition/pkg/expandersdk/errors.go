@@ -0,0 +1,120 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package expandersdk
+
+import (
+	"fmt"
+	"strings"
+
+	pbv2 "github.com/cloud-native-compositions/compositions/composition/proto/v2"
+)
+
+type Severity int
+
+const (
+	SeverityError Severity = iota
+	SeverityWarning
+	SeverityInfo
+)
+
+// Diagnostic is an error, warning or informational message about the config or facade
+type Diagnostic struct {
+	Severity Severity
+	// Code is a machine readable code, for example TemplateSyntaxError
+	Code    string
+	Message string
+	// File, Line and Column locate the problem in the config, for example a template file
+	File   string
+	Line   int
+	Column int
+	// FieldPath is the facade field the diagnostic relates to, for example spec.replicas
+	FieldPath string
+}
+
+func (d *Diagnostic) String() string {
+	if d.Code == "" {
+		return d.Message
+	}
+	return d.Code + ": " + d.Message
+}
+
+// ErrorDiagnostic returns an error diagnostic with a code
+func ErrorDiagnostic(code string, format string, args ...interface{}) *Diagnostic {
+	return &Diagnostic{Severity: SeverityError, Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+// WaitError is returned by Evaluate when inputs are not ready yet.
+// The controller retries the stage later.
+type WaitError struct {
+	Message string
+}
+
+func (e *WaitError) Error() string {
+	return e.Message
+}
+
+// Wait returns a WaitError
+func Wait(format string, args ...interface{}) error {
+	return &WaitError{Message: fmt.Sprintf(format, args...)}
+}
+
+// FailedError is returned by Validate or Evaluate when the config or the facade
+// is invalid. It is reported as VALIDATE_FAILED or EVALUATE_FAILED along with
+// the diagnostics instead of as a grpc error.
+type FailedError struct {
+	Diagnostics []*Diagnostic
+}
+
+func (e *FailedError) Error() string {
+	messages := []string{}
+	for _, d := range e.Diagnostics {
+		messages = append(messages, d.String())
+	}
+	return strings.Join(messages, "; ")
+}
+
+// Failed returns a FailedError with the given diagnostics
+func Failed(diagnostics ...*Diagnostic) error {
+	return &FailedError{Diagnostics: diagnostics}
+}
+
+// Failedf returns a FailedError with a single error diagnostic
+func Failedf(format string, args ...interface{}) error {
+	return Failed(&Diagnostic{Severity: SeverityError, Message: fmt.Sprintf(format, args...)})
+}
+
+func toProtoDiagnostics(diagnostics []*Diagnostic) []*pbv2.Diagnostic {
+	out := []*pbv2.Diagnostic{}
+	for _, d := range diagnostics {
+		pd := &pbv2.Diagnostic{
+			Code:      d.Code,
+			Message:   d.Message,
+			FieldPath: d.FieldPath,
+		}
+		switch d.Severity {
+		case SeverityWarning:
+			pd.Severity = pbv2.Severity_WARNING
+		case SeverityInfo:
+			pd.Severity = pbv2.Severity_INFO
+		default:
+			pd.Severity = pbv2.Severity_ERROR
+		}
+		if d.File != "" || d.Line != 0 {
+			pd.Source = &pbv2.SourceLocation{File: d.File, Line: int32(d.Line), Column: int32(d.Column)}
+		}
+		out = append(out, pd)
+	}
+	return out
+}
@@ -0,0 +1,217 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package expandersdk helps writing expanders.
+//
+// An expander author fills in an Expander with a typed config and Validate and
+// Evaluate functions and calls Main. The SDK decodes the requests, serves both
+// versions of the expander protocol, reports capabilities and serves grpc health.
+//
+//	func main() {
+//		expandersdk.Main(&expandersdk.Expander[myv1alpha1.MyConfiguration]{
+//			Name:     "my",
+//			Config:   myv1alpha1.GroupVersion.WithKind("MyConfiguration"),
+//			Evaluate: evaluate,
+//		})
+//	}
+package expandersdk
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/kubebuilder-declarative-pattern/pkg/patterns/declarative/pkg/manifest"
+)
+
+// Expander describes an expander with a config of type C.
+//
+// C is usually the Go type of the expander configuration CRD. Use string or
+// []byte for expanders that take the inline template as is.
+type Expander[C any] struct {
+	// Name is used in logs
+	Name string
+
+	// Config is the GVK of the configuration CRD reported in the capabilities.
+	// Leave empty for expanders that only take inline templates.
+	Config schema.GroupVersionKind
+
+	// ReturnsValues is set for expanders that return values instead of objects
+	ReturnsValues bool
+
+	// TemplateSchema is a JSON encoded OpenAPI v3 schema of the inline template, if any
+	TemplateSchema []byte
+
+	// OptionalConfig allows calls without a config. Request.Config is then the zero value.
+	OptionalConfig bool
+
+	// DecodeConfig decodes the config sent by the controller. Optional, the
+	// config is unmarshalled from JSON if not set.
+	DecodeConfig func(raw []byte) (*C, error)
+
+	// Validate checks the config. Optional, Validate succeeds if not set.
+	Validate func(ctx context.Context, req *Request[C]) (*Result, error)
+
+	// Evaluate expands the config in the context of the facade
+	Evaluate func(ctx context.Context, req *Request[C]) (*Result, error)
+}
+
+// Request holds the decoded inputs of a Validate or Evaluate call.
+type Request[C any] struct {
+	// Resource is the facade resource name the facade is exposed as
+	Resource string
+
+	// Config is the decoded expander config
+	Config *C
+
+	// RawConfig is the config as sent by the controller
+	RawConfig []byte
+
+	// Facade is the facade object. May be nil for Validate calls.
+	Facade *unstructured.Unstructured
+
+	// FacadeSchema is the OpenAPI v3 schema of the facade CRD. Only set for
+	// Validate calls, and nil if the controller did not send it.
+	FacadeSchema *apiextensionsv1.JSONSchemaProps
+
+	// Context is the Context object of the facade namespace, if any
+	Context *unstructured.Unstructured
+
+	// Values are the values fetched by previous stages, if any
+	Values map[string]interface{}
+
+	// Cluster is the cluster the facade is reconciled in. Only set for
+	// Evaluate calls, and nil if the controller did not discover it.
+	Cluster *Cluster
+}
+
+// Cluster describes the cluster the facade is reconciled in
+type Cluster struct {
+	// KubeVersion is the server version, for example v1.30.2
+	KubeVersion string
+
+	// APIVersions are the group versions and group version kinds served, for
+	// example apps/v1 and apps/v1/Deployment
+	APIVersions []string
+}
+
+// DecodeFacade converts the facade into a typed object
+func (r *Request[C]) DecodeFacade(into interface{}) error {
+	if r.Facade == nil {
+		return fmt.Errorf("no facade in request")
+	}
+	return runtime.DefaultUnstructuredConverter.FromUnstructured(r.Facade.Object, into)
+}
+
+// DecodeContext converts the context into a typed object
+func (r *Request[C]) DecodeContext(into interface{}) error {
+	if r.Context == nil {
+		return fmt.Errorf("no context in request")
+	}
+	return runtime.DefaultUnstructuredConverter.FromUnstructured(r.Context.Object, into)
+}
+
+// DecodeValues converts the fetched values into a typed object
+func (r *Request[C]) DecodeValues(into interface{}) error {
+	if r.Values == nil {
+		return fmt.Errorf("no values in request")
+	}
+	return runtime.DefaultUnstructuredConverter.FromUnstructured(r.Values, into)
+}
+
+// Inputs returns the facade, context and values keyed the way expanders
+// conventionally expose them to templates: the facade under the resource name,
+// the context under "context" and the values under "fetched".
+func (r *Request[C]) Inputs() map[string]interface{} {
+	inputs := map[string]interface{}{}
+	if r.Facade != nil {
+		inputs[r.Resource] = r.Facade.Object
+	}
+	if r.Context != nil {
+		inputs["context"] = r.Context.Object
+	}
+	if r.Values != nil {
+		inputs["fetched"] = r.Values
+	}
+	return inputs
+}
+
+// Result is what Validate and Evaluate return on success.
+type Result struct {
+	// Objects returned by Evaluate
+	Objects []*unstructured.Unstructured
+
+	// Manifests is a YAML stream returned by Evaluate as an alternative to
+	// Objects. It is passed through unchanged to v1 clients.
+	Manifests []byte
+
+	// Hooks returned by Evaluate, applied by the controller before or after
+	// the Objects
+	Hooks []*Hook
+
+	// Values returned by Evaluate for expanders that return values
+	Values interface{}
+
+	// ReturnsValues makes this Evaluate call return Values, for expanders whose
+	// config chooses between returning objects and values
+	ReturnsValues bool
+
+	// Diagnostics are informational messages and warnings
+	Diagnostics []*Diagnostic
+}
+
+// Warn adds a warning to the result
+func (r *Result) Warn(code string, fieldPath string, message string) {
+	r.Diagnostics = append(r.Diagnostics, &Diagnostic{
+		Severity:  SeverityWarning,
+		Code:      code,
+		Message:   message,
+		FieldPath: fieldPath,
+	})
+}
+
+// objects returns Objects, parsing Manifests if needed
+func (r *Result) objects(ctx context.Context) ([]*unstructured.Unstructured, error) {
+	if len(r.Manifests) == 0 {
+		return r.Objects, nil
+	}
+	parsed, err := manifest.ParseObjects(ctx, string(r.Manifests))
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse manifests: %w", err)
+	}
+	objects := append([]*unstructured.Unstructured{}, r.Objects...)
+	for _, item := range parsed.Items {
+		objects = append(objects, item.UnstructuredObject())
+	}
+	return objects, nil
+}
+
+func decodeConfig[C any](raw []byte) (*C, error) {
+	config := new(C)
+	switch c := any(config).(type) {
+	case *string:
+		*c = string(raw)
+	case *[]byte:
+		*c = raw
+	default:
+		if err := json.Unmarshal(raw, config); err != nil {
+			return nil, err
+		}
+	}
+	return config, nil
+}
@@ -0,0 +1,206 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package expandersdk_test
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/cloud-native-compositions/compositions/composition/pkg/expandersdk"
+	"github.com/cloud-native-compositions/compositions/composition/pkg/expandersdk/expandertest"
+	pb "github.com/cloud-native-compositions/compositions/composition/proto"
+	pbv2 "github.com/cloud-native-compositions/compositions/composition/proto/v2"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// configMapConfig is the config of an expander that writes the facade spec into a ConfigMap
+type configMapConfig struct {
+	Spec struct {
+		Name string `json:"name"`
+	} `json:"spec"`
+}
+
+type facade struct {
+	Spec struct {
+		Data  map[string]string `json:"data"`
+		Ready bool              `json:"ready"`
+	} `json:"spec"`
+}
+
+var configMapExpander = &expandersdk.Expander[configMapConfig]{
+	Name:   "configmap",
+	Config: schema.GroupVersionKind{Group: "composition.google.com", Version: "v1alpha1", Kind: "ConfigMapConfiguration"},
+	Validate: func(ctx context.Context, req *expandersdk.Request[configMapConfig]) (*expandersdk.Result, error) {
+		if req.Config.Spec.Name == "" {
+			return nil, expandersdk.Failed(expandersdk.ErrorDiagnostic("MissingName", "spec.name is required"))
+		}
+		return nil, nil
+	},
+	Evaluate: func(ctx context.Context, req *expandersdk.Request[configMapConfig]) (*expandersdk.Result, error) {
+		f := facade{}
+		if err := req.DecodeFacade(&f); err != nil {
+			return nil, err
+		}
+		if !f.Spec.Ready {
+			return nil, expandersdk.Wait("facade is not ready")
+		}
+		cm := &unstructured.Unstructured{}
+		cm.SetAPIVersion("v1")
+		cm.SetKind("ConfigMap")
+		cm.SetName(req.Config.Spec.Name)
+		cm.SetNamespace(req.Facade.GetNamespace())
+		data := map[string]interface{}{}
+		for k, v := range f.Spec.Data {
+			data[k] = v
+		}
+		cm.Object["data"] = data
+
+		result := &expandersdk.Result{Objects: []*unstructured.Unstructured{cm}}
+		if len(f.Spec.Data) == 0 {
+			result.Warn("EmptyData", "spec.data", "no data in facade")
+		}
+		return result, nil
+	},
+}
+
+func TestGolden(t *testing.T) {
+	expandertest.Run(t, configMapExpander, "testdata")
+}
+
+func TestV1Manifests(t *testing.T) {
+	e := &expandersdk.Expander[string]{
+		Name: "passthrough",
+		Evaluate: func(ctx context.Context, req *expandersdk.Request[string]) (*expandersdk.Result, error) {
+			return &expandersdk.Result{Manifests: []byte(*req.Config)}, nil
+		},
+	}
+	manifests := "\n---\n# comment\napiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: demo\n"
+	result, err := expandersdk.NewServer(e).V1().Evaluate(context.Background(), &pb.EvaluateRequest{
+		Config: []byte(manifests),
+		Facade: []byte(`{"apiVersion":"v1","kind":"Foo","metadata":{"name":"foo","namespace":"default"}}`),
+	})
+	if err != nil {
+		t.Fatalf("Evaluate() failed: %v", err)
+	}
+	if result.Status != pb.Status_SUCCESS {
+		t.Fatalf("want SUCCESS, got %s: %s", result.Status, result.Error.Message)
+	}
+	if string(result.Manifests) != manifests {
+		t.Errorf("want manifests passed through unchanged, got %q", string(result.Manifests))
+	}
+}
+
+func TestResultReturnsValues(t *testing.T) {
+	e := &expandersdk.Expander[string]{
+		Name: "either",
+		Evaluate: func(ctx context.Context, req *expandersdk.Request[string]) (*expandersdk.Result, error) {
+			if *req.Config == "values" {
+				return &expandersdk.Result{Values: map[string]interface{}{"a": "b"}, ReturnsValues: true}, nil
+			}
+			return &expandersdk.Result{}, nil
+		},
+	}
+	facade := []byte(`{"apiVersion":"v1","kind":"Foo","metadata":{"name":"foo","namespace":"default"}}`)
+	for config, want := range map[string]pb.ResultType{"values": pb.ResultType_VALUES, "objects": pb.ResultType_MANIFESTS} {
+		result, err := expandersdk.NewServer(e).V1().Evaluate(context.Background(), &pb.EvaluateRequest{Config: []byte(config), Facade: facade})
+		if err != nil {
+			t.Fatalf("Evaluate() failed: %v", err)
+		}
+		if result.Type != want {
+			t.Errorf("config %s: want result type %s, got %s", config, want, result.Type)
+		}
+	}
+}
+
+func TestRequestCluster(t *testing.T) {
+	var got *expandersdk.Cluster
+	e := &expandersdk.Expander[string]{
+		Name: "cluster",
+		Evaluate: func(ctx context.Context, req *expandersdk.Request[string]) (*expandersdk.Result, error) {
+			got = req.Cluster
+			return &expandersdk.Result{}, nil
+		},
+	}
+	result, err := expandersdk.NewServer(e).V2().Evaluate(context.Background(), &pbv2.EvaluateRequest{
+		Config: []byte("config"),
+		Facade: []byte(`{"apiVersion":"v1","kind":"Foo","metadata":{"name":"foo","namespace":"default"}}`),
+		Cluster: &pbv2.Cluster{
+			KubeVersion: "v1.30.2",
+			ApiVersions: []string{"apps/v1", "apps/v1/Deployment"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Evaluate() failed: %v", err)
+	}
+	if result.Status != pbv2.Status_SUCCESS {
+		t.Fatalf("want SUCCESS, got %s", result.Status)
+	}
+	want := &expandersdk.Cluster{KubeVersion: "v1.30.2", APIVersions: []string{"apps/v1", "apps/v1/Deployment"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("want cluster %+v, got %+v", want, got)
+	}
+}
+
+func TestResultHooks(t *testing.T) {
+	job := &unstructured.Unstructured{}
+	job.SetAPIVersion("batch/v1")
+	job.SetKind("Job")
+	job.SetName("migrate")
+	e := &expandersdk.Expander[string]{
+		Name: "hooks",
+		Evaluate: func(ctx context.Context, req *expandersdk.Request[string]) (*expandersdk.Result, error) {
+			return &expandersdk.Result{Hooks: []*expandersdk.Hook{{
+				Phase:          expandersdk.HookPreUpgrade,
+				Weight:         -5,
+				DeletePolicies: []expandersdk.HookDeletePolicy{expandersdk.HookSucceeded},
+				Objects:        []*unstructured.Unstructured{job},
+			}}}, nil
+		},
+	}
+	request := &pbv2.EvaluateRequest{
+		Config: []byte("config"),
+		Facade: []byte(`{"apiVersion":"v1","kind":"Foo","metadata":{"name":"foo","namespace":"default"}}`),
+	}
+	result, err := expandersdk.NewServer(e).V2().Evaluate(context.Background(), request)
+	if err != nil {
+		t.Fatalf("Evaluate() failed: %v", err)
+	}
+	if len(result.Hooks) != 1 {
+		t.Fatalf("want 1 hook, got %v", result.Hooks)
+	}
+	hook := result.Hooks[0]
+	if hook.Phase != pbv2.HookPhase_PRE_UPGRADE || hook.Weight != -5 ||
+		!reflect.DeepEqual(hook.DeletePolicies, []pbv2.HookDeletePolicy{pbv2.HookDeletePolicy_HOOK_SUCCEEDED}) {
+		t.Errorf("want a pre-upgrade hook with weight -5 deleted when it succeeds, got %v", hook)
+	}
+	if len(hook.Objects) != 1 || hook.Objects[0].Kind != "Job" || hook.Objects[0].Name != "migrate" {
+		t.Errorf("want the migrate Job, got %v", hook.Objects)
+	}
+
+	// v1 has no hooks, so the evaluation fails instead of applying without them
+	v1result, err := expandersdk.NewServer(e).V1().Evaluate(context.Background(), &pb.EvaluateRequest{Config: request.Config, Facade: request.Facade})
+	if err != nil {
+		t.Fatalf("Evaluate() failed: %v", err)
+	}
+	if v1result.Status != pb.Status_EVALUATE_FAILED || !strings.Contains(v1result.Error.Message, "HooksNeedV2") {
+		t.Errorf("want EVALUATE_FAILED with HooksNeedV2, got %v", v1result)
+	}
+	if len(v1result.Manifests) != 0 {
+		t.Errorf("want no manifests, got %s", v1result.Manifests)
+	}
+}
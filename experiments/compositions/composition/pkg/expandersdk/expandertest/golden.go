@@ -0,0 +1,302 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package expandertest runs golden file tests against an expander.
+//
+// Each sub directory of the test directory is a case:
+//
+//	config.yaml    expander config CR, sent as JSON (optional, no config is sent without it)
+//	template       inline template, sent as is (instead of config.yaml)
+//	facade.yaml    facade object (optional)
+//	context.yaml   Context object (optional)
+//	values.yaml    values fetched by previous stages (optional)
+//	resource       facade resource name (optional, defaults to lowercase kind + "s")
+//	schema.yaml    OpenAPI v3 schema of the facade, sent to Validate (optional)
+//	expected.yaml  Validate and Evaluate results
+//
+// facade.yaml, context.yaml, values.yaml, resource and schema.yaml in the test
+// directory itself are shared by the cases without their own. A case leaves a
+// shared input out with an empty file, or one with only comments.
+//
+// Errors returned by Validate and Evaluate are recorded in expected.yaml as
+// well. Run the tests with -update-golden to write expected.yaml, and with
+// -addr to run the cases against an expander serving grpc instead of
+// in-process.
+package expandertest
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/cloud-native-compositions/compositions/composition/pkg/expandersdk"
+	pbv2 "github.com/cloud-native-compositions/compositions/composition/proto/v2"
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"sigs.k8s.io/yaml"
+)
+
+var (
+	update = flag.Bool("update-golden", false, "write expected.yaml files instead of comparing")
+	addr   = flag.String("addr", "", "the address of the expander to test. The expander is served in-process if empty")
+)
+
+type diagnostic struct {
+	Severity  string `json:"severity"`
+	Code      string `json:"code,omitempty"`
+	Message   string `json:"message"`
+	File      string `json:"file,omitempty"`
+	Line      int32  `json:"line,omitempty"`
+	Column    int32  `json:"column,omitempty"`
+	FieldPath string `json:"fieldPath,omitempty"`
+}
+
+type validateResult struct {
+	Error       string       `json:"error,omitempty"`
+	Status      string       `json:"status,omitempty"`
+	Diagnostics []diagnostic `json:"diagnostics,omitempty"`
+}
+
+type evaluateResult struct {
+	Error       string                   `json:"error,omitempty"`
+	Status      string                   `json:"status,omitempty"`
+	Diagnostics []diagnostic             `json:"diagnostics,omitempty"`
+	Objects     []map[string]interface{} `json:"objects,omitempty"`
+	Values      interface{}              `json:"values,omitempty"`
+}
+
+type golden struct {
+	Validate validateResult `json:"validate"`
+	Evaluate evaluateResult `json:"evaluate"`
+}
+
+// expander is the part of the v2 service the cases call, served in-process
+// or over grpc
+type expander interface {
+	Validate(ctx context.Context, req *pbv2.ValidateRequest) (*pbv2.ValidateResult, error)
+	Evaluate(ctx context.Context, req *pbv2.EvaluateRequest) (*pbv2.EvaluateResult, error)
+}
+
+type client struct {
+	c pbv2.ExpanderClient
+}
+
+func (c *client) Validate(ctx context.Context, req *pbv2.ValidateRequest) (*pbv2.ValidateResult, error) {
+	return c.c.Validate(ctx, req)
+}
+
+func (c *client) Evaluate(ctx context.Context, req *pbv2.EvaluateRequest) (*pbv2.EvaluateResult, error) {
+	return c.c.Evaluate(ctx, req)
+}
+
+// Run runs every case in dir as a sub test
+func Run[C any](t *testing.T, e *expandersdk.Expander[C], dir string) {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("reading test dir %s: %v", dir, err)
+	}
+	var server expander = expandersdk.NewServer(e)
+	if *addr != "" {
+		conn, err := grpc.NewClient(*addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+		if err != nil {
+			t.Fatalf("connecting to %s: %v", *addr, err)
+		}
+		defer conn.Close()
+		server = &client{c: pbv2.NewExpanderClient(conn)}
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		caseDir := filepath.Join(dir, entry.Name())
+		t.Run(entry.Name(), func(t *testing.T) {
+			runCase(t, server, dir, caseDir)
+		})
+	}
+}
+
+func runCase(t *testing.T, server expander, sharedDir string, dir string) {
+	ctx := context.Background()
+
+	config := readOptional(t, filepath.Join(dir, "template"))
+	if config == nil {
+		config = readYAMLAsJSON(t, filepath.Join(dir, "config.yaml"))
+	}
+	input := func(name string) []byte {
+		if readOptional(t, filepath.Join(dir, name)) != nil {
+			return readYAMLAsJSON(t, filepath.Join(dir, name))
+		}
+		return readYAMLAsJSON(t, filepath.Join(sharedDir, name))
+	}
+	facade := input("facade.yaml")
+	contextBytes := input("context.yaml")
+	values := input("values.yaml")
+	facadeSchema := input("schema.yaml")
+
+	resource := strings.TrimSpace(string(readOptional(t, filepath.Join(dir, "resource"))))
+	if resource == "" {
+		resource = strings.TrimSpace(string(readOptional(t, filepath.Join(sharedDir, "resource"))))
+	}
+	if resource == "" && facade != nil {
+		f := struct {
+			Kind string `json:"kind"`
+		}{}
+		if err := json.Unmarshal(facade, &f); err != nil {
+			t.Fatalf("reading facade kind: %v", err)
+		}
+		resource = strings.ToLower(f.Kind) + "s"
+	}
+
+	got := golden{}
+	vresult, err := server.Validate(ctx, &pbv2.ValidateRequest{
+		Config:       config,
+		Facade:       facade,
+		Context:      contextBytes,
+		Value:        values,
+		Resource:     resource,
+		FacadeSchema: facadeSchema,
+	})
+	if err != nil {
+		got.Validate.Error = status.Convert(err).Message()
+	} else {
+		got.Validate = validateResult{
+			Status:      vresult.Status.String(),
+			Diagnostics: toDiagnostics(vresult.Diagnostics),
+		}
+	}
+
+	eresult, err := server.Evaluate(ctx, &pbv2.EvaluateRequest{
+		Config:   config,
+		Facade:   facade,
+		Context:  contextBytes,
+		Value:    values,
+		Resource: resource,
+	})
+	if err != nil {
+		got.Evaluate.Error = status.Convert(err).Message()
+	} else {
+		got.Evaluate = evaluateResult{
+			Status:      eresult.Status.String(),
+			Diagnostics: toDiagnostics(eresult.Diagnostics),
+		}
+		for _, o := range eresult.Objects {
+			obj := map[string]interface{}{}
+			if err := json.Unmarshal(o.Json, &obj); err != nil {
+				t.Fatalf("unmarshalling object %s %s: %v", o.Kind, o.Name, err)
+			}
+			got.Evaluate.Objects = append(got.Evaluate.Objects, obj)
+		}
+		if len(eresult.Values) != 0 {
+			if err := json.Unmarshal(eresult.Values, &got.Evaluate.Values); err != nil {
+				t.Fatalf("unmarshalling values: %v", err)
+			}
+		}
+	}
+
+	gotYAML, err := yaml.Marshal(got)
+	if err != nil {
+		t.Fatalf("marshalling result: %v", err)
+	}
+
+	expectedFile := filepath.Join(dir, "expected.yaml")
+	if *update {
+		// Keep the license header of an existing file
+		header := leadingComments(readOptional(t, expectedFile))
+		if err := os.WriteFile(expectedFile, append(header, gotYAML...), 0644); err != nil {
+			t.Fatalf("writing %s: %v", expectedFile, err)
+		}
+		return
+	}
+
+	expectedYAML, err := os.ReadFile(expectedFile)
+	if err != nil {
+		t.Fatalf("reading %s (run with -update-golden to create it): %v", expectedFile, err)
+	}
+	// Compare semantically so that formatting and key order do not matter
+	var want, have interface{}
+	if err := yaml.Unmarshal(expectedYAML, &want); err != nil {
+		t.Fatalf("parsing %s: %v", expectedFile, err)
+	}
+	if err := yaml.Unmarshal(gotYAML, &have); err != nil {
+		t.Fatalf("parsing result: %v", err)
+	}
+	if !reflect.DeepEqual(want, have) {
+		t.Errorf("result differs from %s (-want +got):\n%s", expectedFile, cmp.Diff(want, have))
+	}
+}
+
+func toDiagnostics(in []*pbv2.Diagnostic) []diagnostic {
+	out := []diagnostic{}
+	for _, d := range in {
+		o := diagnostic{
+			Severity:  d.Severity.String(),
+			Code:      d.Code,
+			Message:   d.Message,
+			FieldPath: d.FieldPath,
+		}
+		if d.Source != nil {
+			o.File = d.Source.File
+			o.Line = d.Source.Line
+			o.Column = d.Source.Column
+		}
+		out = append(out, o)
+	}
+	return out
+}
+
+func readOptional(t *testing.T, path string) []byte {
+	b, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		t.Fatalf("reading %s: %v", path, err)
+	}
+	return b
+}
+
+// readYAMLAsJSON returns nil for missing files and files without a document
+func readYAMLAsJSON(t *testing.T, path string) []byte {
+	b := readOptional(t, path)
+	if b == nil {
+		return nil
+	}
+	j, err := yaml.YAMLToJSON(b)
+	if err != nil {
+		t.Fatalf("converting %s to json: %v", path, err)
+	}
+	if string(j) == "null" {
+		return nil
+	}
+	return j
+}
+
+func leadingComments(b []byte) []byte {
+	out := []byte{}
+	for _, line := range strings.SplitAfter(string(b), "\n") {
+		if !strings.HasPrefix(line, "#") && strings.TrimSpace(line) != "" {
+			break
+		}
+		out = append(out, line...)
+	}
+	return out
+}
@@ -0,0 +1,96 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package expandersdk
+
+import (
+	"fmt"
+
+	pbv2 "github.com/cloud-native-compositions/compositions/composition/proto/v2"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// HookPhase is when the objects of a Hook are applied
+type HookPhase string
+
+const (
+	HookPreInstall  HookPhase = "pre-install"
+	HookPostInstall HookPhase = "post-install"
+	HookPreUpgrade  HookPhase = "pre-upgrade"
+	HookPostUpgrade HookPhase = "post-upgrade"
+	HookPreDelete   HookPhase = "pre-delete"
+	HookPostDelete  HookPhase = "post-delete"
+)
+
+var hookPhases = map[HookPhase]pbv2.HookPhase{
+	HookPreInstall:  pbv2.HookPhase_PRE_INSTALL,
+	HookPostInstall: pbv2.HookPhase_POST_INSTALL,
+	HookPreUpgrade:  pbv2.HookPhase_PRE_UPGRADE,
+	HookPostUpgrade: pbv2.HookPhase_POST_UPGRADE,
+	HookPreDelete:   pbv2.HookPhase_PRE_DELETE,
+	HookPostDelete:  pbv2.HookPhase_POST_DELETE,
+}
+
+// HookDeletePolicy is when the objects of a Hook are deleted
+type HookDeletePolicy string
+
+const (
+	HookBeforeHookCreation HookDeletePolicy = "before-hook-creation"
+	HookSucceeded          HookDeletePolicy = "hook-succeeded"
+	HookFailed             HookDeletePolicy = "hook-failed"
+)
+
+var hookDeletePolicies = map[HookDeletePolicy]pbv2.HookDeletePolicy{
+	HookBeforeHookCreation: pbv2.HookDeletePolicy_BEFORE_HOOK_CREATION,
+	HookSucceeded:          pbv2.HookDeletePolicy_HOOK_SUCCEEDED,
+	HookFailed:             pbv2.HookDeletePolicy_HOOK_FAILED,
+}
+
+// Hook is a group of objects the controller applies in a phase of the stage
+// instead of with the other objects. Hooks are only returned to v2 clients.
+type Hook struct {
+	Phase HookPhase
+	// Hooks of a phase run in order of Weight
+	Weight int32
+	// DeletePolicies default to before-hook-creation
+	DeletePolicies []HookDeletePolicy
+	Objects        []*unstructured.Unstructured
+}
+
+func toProtoHooks(hooks []*Hook) ([]*pbv2.Hook, error) {
+	out := []*pbv2.Hook{}
+	for _, h := range hooks {
+		phase, ok := hookPhases[h.Phase]
+		if !ok {
+			return nil, fmt.Errorf("unknown hook phase %q", h.Phase)
+		}
+		ph := &pbv2.Hook{Phase: phase, Weight: h.Weight}
+		for _, p := range h.DeletePolicies {
+			policy, ok := hookDeletePolicies[p]
+			if !ok {
+				return nil, fmt.Errorf("unknown hook delete policy %q", p)
+			}
+			ph.DeletePolicies = append(ph.DeletePolicies, policy)
+		}
+		for _, u := range h.Objects {
+			o, err := toProtoObject(u)
+			if err != nil {
+				return nil, err
+			}
+			ph.Objects = append(ph.Objects, o)
+		}
+		out = append(out, ph)
+	}
+	return out, nil
+}
@@ -0,0 +1,404 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package expandersdk
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net"
+	"strings"
+
+	pb "github.com/cloud-native-compositions/compositions/composition/proto"
+	pbv2 "github.com/cloud-native-compositions/compositions/composition/proto/v2"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/yaml"
+)
+
+// Server serves an Expander over both versions of the expander protocol
+type Server[C any] struct {
+	expander *Expander[C]
+}
+
+func NewServer[C any](e *Expander[C]) *Server[C] {
+	return &Server[C]{expander: e}
+}
+
+// Register registers the v1 and v2 expander services and the health service
+func (s *Server[C]) Register(gs *grpc.Server) {
+	pb.RegisterExpanderServer(gs, s.V1())
+	pbv2.RegisterExpanderServer(gs, s.V2())
+	healthServer := health.NewServer()
+	healthServer.SetServingStatus("", healthpb.HealthCheckResponse_SERVING)
+	healthpb.RegisterHealthServer(gs, healthServer)
+}
+
+// Main parses flags and serves the expander until the process exits
+func Main[C any](e *Expander[C]) {
+	port := flag.Int("port", 8443, "The server port")
+	flag.Parse()
+
+	lis, err := net.Listen("tcp", fmt.Sprintf(":%d", *port))
+	if err != nil {
+		log.Fatalf("failed to listen: %v", err)
+	}
+	s := grpc.NewServer()
+	NewServer(e).Register(s)
+	log.Printf("%s expander listening at %v", e.Name, lis.Addr())
+	if err := s.Serve(lis); err != nil {
+		log.Fatalf("failed to serve: %v", err)
+	}
+}
+
+// decode builds a Request from the raw inputs.
+// A non-empty failed message means the inputs are invalid.
+func decode[C any](e *Expander[C], config, facade, context, values []byte, resource string, cluster *pbv2.Cluster, evaluate bool) (*Request[C], string, error) {
+	if evaluate && len(facade) == 0 {
+		return nil, "", fmt.Errorf("Empty Facade for an Evaluate call")
+	}
+	if len(config) == 0 && !e.OptionalConfig {
+		return nil, "empty Config passed", nil
+	}
+
+	req := &Request[C]{
+		Resource:  resource,
+		RawConfig: config,
+		Config:    new(C),
+	}
+	if len(config) != 0 {
+		var err error
+		if e.DecodeConfig != nil {
+			req.Config, err = e.DecodeConfig(config)
+		} else {
+			req.Config, err = decodeConfig[C](config)
+		}
+		if err != nil {
+			return nil, "", fmt.Errorf("error unmarshalling req.Config: %w", err)
+		}
+	}
+
+	if len(context) != 0 {
+		req.Context = &unstructured.Unstructured{}
+		if err := req.Context.UnmarshalJSON(context); err != nil {
+			return nil, "", fmt.Errorf("error unmarshalling req.Context: %w", err)
+		}
+	}
+
+	if len(values) != 0 {
+		req.Values = map[string]interface{}{}
+		if err := json.Unmarshal(values, &req.Values); err != nil {
+			return nil, "", fmt.Errorf("error unmarshalling req.Value: %w", err)
+		}
+	}
+
+	if len(facade) != 0 {
+		req.Facade = &unstructured.Unstructured{}
+		if err := req.Facade.UnmarshalJSON(facade); err != nil {
+			return nil, "", fmt.Errorf("error unmarshalling req.Facade into Unstructured: %w", err)
+		}
+		if req.Facade.GetNamespace() == "" {
+			return nil, "missing namespace in req.Facade object", nil
+		}
+	}
+
+	if cluster != nil {
+		req.Cluster = &Cluster{
+			KubeVersion: cluster.KubeVersion,
+			APIVersions: cluster.ApiVersions,
+		}
+	}
+	return req, "", nil
+}
+
+// Capabilities returns what the expander supports
+func (s *Server[C]) Capabilities() *pbv2.Capabilities {
+	c := &pbv2.Capabilities{
+		ProtocolVersions: []string{"v1", "v2"},
+		Validate:         s.expander.Validate != nil,
+		ResultType:       pbv2.ResultType_OBJECTS,
+		TemplateSchema:   s.expander.TemplateSchema,
+	}
+	if s.expander.ReturnsValues {
+		c.ResultType = pbv2.ResultType_VALUES
+	}
+	if s.expander.Config.Kind != "" {
+		c.Config = &pbv2.ConfigGVK{
+			Group:   s.expander.Config.Group,
+			Version: s.expander.Config.Version,
+			Kind:    s.expander.Config.Kind,
+		}
+	}
+	return c
+}
+
+func (s *Server[C]) Validate(ctx context.Context, req *pbv2.ValidateRequest) (*pbv2.ValidateResult, error) {
+	log.Printf("Validate called")
+	result := &pbv2.ValidateResult{Status: pbv2.Status_SUCCESS}
+
+	r, failedMessage, err := decode(s.expander, req.Config, req.Facade, req.Context, req.Value, req.Resource, nil, false)
+	if err != nil {
+		return nil, err
+	}
+	if failedMessage != "" {
+		result.Status = pbv2.Status_VALIDATE_FAILED
+		result.Diagnostics = toProtoDiagnostics([]*Diagnostic{{Message: failedMessage}})
+		return result, nil
+	}
+	if len(req.FacadeSchema) != 0 {
+		r.FacadeSchema = &apiextensionsv1.JSONSchemaProps{}
+		if err := json.Unmarshal(req.FacadeSchema, r.FacadeSchema); err != nil {
+			return nil, fmt.Errorf("error unmarshalling req.FacadeSchema: %w", err)
+		}
+	}
+	if s.expander.Validate == nil {
+		return result, nil
+	}
+
+	out, err := s.expander.Validate(ctx, r)
+	var failed *FailedError
+	if errors.As(err, &failed) {
+		log.Printf("validate failed: %v", failed)
+		result.Status = pbv2.Status_VALIDATE_FAILED
+		result.Diagnostics = toProtoDiagnostics(failed.Diagnostics)
+		return result, nil
+	}
+	if err != nil {
+		log.Printf("validate error: %v", err)
+		return nil, err
+	}
+	if out != nil {
+		result.Diagnostics = toProtoDiagnostics(out.Diagnostics)
+	}
+	return result, nil
+}
+
+func (s *Server[C]) Evaluate(ctx context.Context, req *pbv2.EvaluateRequest) (*pbv2.EvaluateResult, error) {
+	result, _, err := s.evaluate(ctx, req)
+	return result, err
+}
+
+// evaluate also returns the raw Result so that v1 can pass manifests through unchanged
+func (s *Server[C]) evaluate(ctx context.Context, req *pbv2.EvaluateRequest) (*pbv2.EvaluateResult, *Result, error) {
+	log.Printf("Evaluate called")
+	result := &pbv2.EvaluateResult{
+		Status: pbv2.Status_SUCCESS,
+		Type:   pbv2.ResultType_OBJECTS,
+	}
+	if s.expander.ReturnsValues {
+		result.Type = pbv2.ResultType_VALUES
+	}
+
+	r, failedMessage, err := decode(s.expander, req.Config, req.Facade, req.Context, req.Value, req.Resource, req.Cluster, true)
+	if err != nil {
+		return nil, nil, err
+	}
+	if failedMessage != "" {
+		result.Status = pbv2.Status_EVALUATE_FAILED
+		result.Diagnostics = toProtoDiagnostics([]*Diagnostic{{Message: failedMessage}})
+		return result, nil, nil
+	}
+
+	out, err := s.expander.Evaluate(ctx, r)
+	var failed *FailedError
+	var wait *WaitError
+	switch {
+	case errors.As(err, &wait):
+		log.Printf("evaluate wait: %v", wait)
+		result.Status = pbv2.Status_EVALUATE_WAIT
+		result.Diagnostics = toProtoDiagnostics([]*Diagnostic{{Severity: SeverityInfo, Message: wait.Message}})
+		return result, nil, nil
+	case errors.As(err, &failed):
+		log.Printf("evaluate failed: %v", failed)
+		result.Status = pbv2.Status_EVALUATE_FAILED
+		result.Diagnostics = toProtoDiagnostics(failed.Diagnostics)
+		return result, nil, nil
+	case err != nil:
+		log.Printf("evaluate error: %v", err)
+		return nil, nil, err
+	}
+	if out == nil {
+		out = &Result{}
+	}
+	result.Diagnostics = toProtoDiagnostics(out.Diagnostics)
+
+	if s.expander.ReturnsValues || out.ReturnsValues {
+		result.Type = pbv2.ResultType_VALUES
+		result.Values, err = json.Marshal(out.Values)
+		if err != nil {
+			return nil, nil, fmt.Errorf("error marshalling values: %w", err)
+		}
+		return result, out, nil
+	}
+
+	objects, err := out.objects(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, u := range objects {
+		o, err := toProtoObject(u)
+		if err != nil {
+			return nil, nil, err
+		}
+		result.Objects = append(result.Objects, o)
+	}
+	if len(out.Hooks) != 0 {
+		result.Hooks, err = toProtoHooks(out.Hooks)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	return result, out, nil
+}
+
+func toProtoObject(u *unstructured.Unstructured) (*pbv2.Object, error) {
+	j, err := u.MarshalJSON()
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal %s %s: %w", u.GetKind(), u.GetName(), err)
+	}
+	return &pbv2.Object{
+		ApiVersion: u.GetAPIVersion(),
+		Kind:       u.GetKind(),
+		Namespace:  u.GetNamespace(),
+		Name:       u.GetName(),
+		Json:       j,
+	}, nil
+}
+
+// ------------- v2 -------------
+
+// V2 returns the v2 expander service
+func (s *Server[C]) V2() pbv2.ExpanderServer {
+	return &v2Server[C]{s: s}
+}
+
+type v2Server[C any] struct {
+	pbv2.UnimplementedExpanderServer
+	s *Server[C]
+}
+
+func (v *v2Server[C]) Validate(ctx context.Context, req *pbv2.ValidateRequest) (*pbv2.ValidateResult, error) {
+	return v.s.Validate(ctx, req)
+}
+
+func (v *v2Server[C]) Evaluate(ctx context.Context, req *pbv2.EvaluateRequest) (*pbv2.EvaluateResult, error) {
+	return v.s.Evaluate(ctx, req)
+}
+
+func (v *v2Server[C]) GetCapabilities(context.Context, *pbv2.GetCapabilitiesRequest) (*pbv2.Capabilities, error) {
+	return v.s.Capabilities(), nil
+}
+
+// ------------- v1 -------------
+
+// V1 returns the v1 expander service
+func (s *Server[C]) V1() pb.ExpanderServer {
+	return &v1Server[C]{s: s}
+}
+
+type v1Server[C any] struct {
+	pb.UnimplementedExpanderServer
+	s *Server[C]
+}
+
+func (v *v1Server[C]) Validate(ctx context.Context, req *pb.ValidateRequest) (*pb.ValidateResult, error) {
+	result, err := v.s.Validate(ctx, &pbv2.ValidateRequest{
+		Config:   req.Config,
+		Context:  req.Context,
+		Facade:   req.Facade,
+		Value:    req.Value,
+		Resource: req.Resource,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &pb.ValidateResult{
+		Status: pb.Status(result.Status),
+		Error:  &pb.Error{Message: v1Message(result.Status, result.Diagnostics)},
+	}, nil
+}
+
+func (v *v1Server[C]) Evaluate(ctx context.Context, req *pb.EvaluateRequest) (*pb.EvaluateResult, error) {
+	result, out, err := v.s.evaluate(ctx, &pbv2.EvaluateRequest{
+		Config:   req.Config,
+		Context:  req.Context,
+		Facade:   req.Facade,
+		Value:    req.Value,
+		Resource: req.Resource,
+	})
+	if err != nil {
+		return nil, err
+	}
+	v1result := &pb.EvaluateResult{
+		Status: pb.Status(result.Status),
+		Type:   pb.ResultType_MANIFESTS,
+		Error:  &pb.Error{Message: v1Message(result.Status, result.Diagnostics)},
+		Values: result.Values,
+	}
+	if result.Type == pbv2.ResultType_VALUES {
+		v1result.Type = pb.ResultType_VALUES
+	}
+	if out == nil || result.Type == pbv2.ResultType_VALUES {
+		return v1result, nil
+	}
+	if len(out.Hooks) != 0 {
+		// Applying the hooks as plain objects would run them at the wrong
+		// time, and dropping them would apply the rest without them
+		v1result.Status = pb.Status_EVALUATE_FAILED
+		v1result.Error = &pb.Error{Message: v1Message(pbv2.Status_EVALUATE_FAILED, []*pbv2.Diagnostic{{
+			Severity: pbv2.Severity_ERROR,
+			Code:     "HooksNeedV2",
+			Message:  fmt.Sprintf("the expander returned %d hooks, which are only supported by v2 clients", len(out.Hooks)),
+		}})}
+		return v1result, nil
+	}
+
+	if len(out.Objects) == 0 {
+		v1result.Manifests = out.Manifests
+		return v1result, nil
+	}
+	manifests := []byte{}
+	for _, o := range result.Objects {
+		y, err := yaml.JSONToYAML(o.Json)
+		if err != nil {
+			return nil, fmt.Errorf("failed to convert %s %s to yaml: %w", o.Kind, o.Name, err)
+		}
+		manifests = append(manifests, []byte("---\n")...)
+		manifests = append(manifests, y...)
+	}
+	v1result.Manifests = manifests
+	return v1result, nil
+}
+
+// v1Message flattens diagnostics into the single v1 error message
+func v1Message(status pbv2.Status, diagnostics []*pbv2.Diagnostic) string {
+	messages := []string{}
+	for _, d := range diagnostics {
+		if status != pbv2.Status_SUCCESS || d.Severity == pbv2.Severity_WARNING {
+			m := d.Message
+			if d.Code != "" {
+				m = d.Code + ": " + m
+			}
+			messages = append(messages, m)
+		}
+	}
+	return strings.Join(messages, "; ")
+}
@@ -0,0 +1,73 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package inproc is the registry of expanders compiled into the manager.
+// ExpanderVersions of type inproc resolve to inproc://<name> and are called
+// without leaving the process.
+package inproc
+
+import (
+	"sort"
+	"strings"
+	"sync"
+
+	pbv2 "github.com/cloud-native-compositions/compositions/composition/proto/v2"
+)
+
+const Scheme = "inproc://"
+
+var (
+	mu        sync.RWMutex
+	expanders = map[string]pbv2.ExpanderServer{}
+)
+
+// Register adds an expander under name, replacing any previous registration
+func Register(name string, e pbv2.ExpanderServer) {
+	mu.Lock()
+	defer mu.Unlock()
+	expanders[name] = e
+}
+
+// Get returns the expander registered under name
+func Get(name string) (pbv2.ExpanderServer, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+	e, ok := expanders[name]
+	return e, ok
+}
+
+// Names returns the registered expander names in sorted order
+func Names() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+	names := []string{}
+	for name := range expanders {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// URI returns the uri recorded in the ExpanderVersion status for name
+func URI(name string) string {
+	return Scheme + name
+}
+
+// ParseURI returns the expander name if uri is an inproc uri
+func ParseURI(uri string) (string, bool) {
+	if !strings.HasPrefix(uri, Scheme) {
+		return "", false
+	}
+	return strings.TrimPrefix(uri, Scheme), true
+}
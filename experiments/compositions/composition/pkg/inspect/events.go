@@ -0,0 +1,91 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package inspect
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	compositionv1alpha1 "github.com/cloud-native-compositions/compositions/composition/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Events returns the events of the facade, its Plan and the resources applied
+// for it, oldest first.
+func Events(ctx context.Context, c client.Client, report *Report) ([]corev1.Event, error) {
+	type key struct {
+		group, kind, namespace, name string
+	}
+	objectKey := func(gvk schema.GroupVersionKind, namespace, name string) key {
+		return key{gvk.Group, gvk.Kind, namespace, name}
+	}
+
+	involved := map[key]bool{}
+	namespaces := map[string]bool{}
+	add := func(gvk schema.GroupVersionKind, namespace, name string) {
+		involved[objectKey(gvk, namespace, name)] = true
+		// Events of cluster scoped objects are in the default namespace
+		if namespace == "" {
+			namespace = "default"
+		}
+		namespaces[namespace] = true
+	}
+
+	add(report.Facade.GroupVersionKind(), report.Facade.GetNamespace(), report.Facade.GetName())
+	if report.Plan != nil {
+		add(compositionv1alpha1.GroupVersion.WithKind("Plan"), report.Plan.Namespace, report.Plan.Name)
+	}
+	for _, stage := range report.Stages {
+		for _, r := range stage.Resources {
+			add(schema.GroupVersionKind{Group: r.Group, Kind: r.Kind}, r.Namespace, r.Name)
+		}
+	}
+
+	events := []corev1.Event{}
+	for namespace := range namespaces {
+		list := &corev1.EventList{}
+		if err := c.List(ctx, list, client.InNamespace(namespace)); err != nil {
+			return nil, fmt.Errorf("listing events in namespace %s: %w", namespace, err)
+		}
+		for _, event := range list.Items {
+			o := event.InvolvedObject
+			gvk := schema.FromAPIVersionAndKind(o.APIVersion, o.Kind)
+			if involved[objectKey(gvk, o.Namespace, o.Name)] {
+				events = append(events, event)
+			}
+		}
+	}
+	sort.SliceStable(events, func(i, j int) bool {
+		return EventTime(events[i]).Before(EventTime(events[j]))
+	})
+	return events, nil
+}
+
+// EventTime returns when the event was last seen
+func EventTime(event corev1.Event) time.Time {
+	switch {
+	case event.Series != nil:
+		return event.Series.LastObservedTime.Time
+	case !event.LastTimestamp.IsZero():
+		return event.LastTimestamp.Time
+	case !event.EventTime.IsZero():
+		return event.EventTime.Time
+	}
+	return event.FirstTimestamp.Time
+}
@@ -0,0 +1,247 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package inspect collects the state of a facade instance, its Plan, the stages
+// of its Composition and the resources they applied. It backs the
+// kubectl-composition plugin.
+package inspect
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	compositionv1alpha1 "github.com/cloud-native-compositions/compositions/composition/api/v1alpha1"
+	"github.com/cloud-native-compositions/compositions/composition/pkg/crds"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// stagesAnnotation is set on the Plan by the controller with the ordered stage names
+const stagesAnnotation = "compositions.google.com/expander-stages"
+
+// Phase is the evaluation or apply state of a stage
+type Phase string
+
+const (
+	// PhasePending - the stage was not reached yet
+	PhasePending Phase = "Pending"
+	// PhaseWaiting - the stage waits for inputs or for applied resources to become healthy
+	PhaseWaiting Phase = "Waiting"
+	// PhaseFailed - the stage returned an error
+	PhaseFailed Phase = "Failed"
+	// PhaseEvaluated - the expander returned the manifests or values of the stage
+	PhaseEvaluated Phase = "Evaluated"
+	// PhaseReady - all resources of the stage were applied and are healthy
+	PhaseReady Phase = "Ready"
+	// PhaseSkipped - the stage returned values and has nothing to apply
+	PhaseSkipped Phase = "Skipped"
+)
+
+// Report is the state of a facade instance and everything derived from it
+type Report struct {
+	Facade *unstructured.Unstructured
+	// Resource is the plural resource name of the facade kind
+	Resource string
+	// Composition is nil if no Composition for the facade kind was found
+	Composition *compositionv1alpha1.Composition
+	// Plan is nil if the controller did not create one yet
+	Plan   *compositionv1alpha1.Plan
+	Stages []Stage
+}
+
+// Stage is the state of a single stage of the Composition
+type Stage struct {
+	Name    string
+	Type    string
+	Version string
+
+	Validation        compositionv1alpha1.ValidationStatus
+	ValidationMessage string
+	Evaluation        Phase
+	Apply             Phase
+	// Message explains a Waiting or Failed phase
+	Message string
+
+	ResourceCount int
+	AppliedCount  int
+	Resources     []compositionv1alpha1.ResourceStatus
+	Diagnostics   []compositionv1alpha1.Diagnostic
+}
+
+// ParseFacadeArgs parses the facade given as "<type>/<name>" or "<type> <name>".
+// The type is a resource, a kind or a resource.group, like kubectl get.
+func ParseFacadeArgs(args []string) (schema.GroupResource, string, error) {
+	var kind, name string
+	switch {
+	case len(args) == 1 && strings.Count(args[0], "/") == 1:
+		kind, name, _ = strings.Cut(args[0], "/")
+	case len(args) == 2 && !strings.Contains(args[0], "/"):
+		kind, name = args[0], args[1]
+	default:
+		return schema.GroupResource{}, "", fmt.Errorf("expected <type>/<name> or <type> <name>, got %q", strings.Join(args, " "))
+	}
+	if kind == "" || name == "" {
+		return schema.GroupResource{}, "", fmt.Errorf("expected <type>/<name> or <type> <name>, got %q", strings.Join(args, " "))
+	}
+	return schema.ParseGroupResource(strings.ToLower(kind)), name, nil
+}
+
+// Inspect reads the facade, its Composition and Plan and derives the state of each stage
+func Inspect(ctx context.Context, c client.Client, gr schema.GroupResource, namespace, name string) (*Report, error) {
+	gvk, err := c.RESTMapper().KindFor(gr.WithVersion(""))
+	if err != nil {
+		return nil, fmt.Errorf("resource type %q: %w", gr, err)
+	}
+	mapping, err := c.RESTMapper().RESTMapping(gvk.GroupKind(), gvk.Version)
+	if err != nil {
+		return nil, fmt.Errorf("resource type %q: %w", gr, err)
+	}
+	if mapping.Scope.Name() != meta.RESTScopeNameNamespace {
+		namespace = ""
+	}
+
+	report := &Report{Facade: &unstructured.Unstructured{}, Resource: mapping.Resource.Resource}
+	report.Facade.SetGroupVersionKind(gvk)
+	if err := c.Get(ctx, types.NamespacedName{Namespace: namespace, Name: name}, report.Facade); err != nil {
+		return nil, err
+	}
+
+	if report.Composition, err = findComposition(ctx, c, mapping); err != nil {
+		return nil, err
+	}
+
+	// The Plan is named <resource>-<name>, see ExpanderReconciler.getPlanForInputCR
+	plan := &compositionv1alpha1.Plan{}
+	planNN := types.NamespacedName{Namespace: namespace, Name: report.Resource + "-" + name}
+	if err := c.Get(ctx, planNN, plan); err != nil {
+		if client.IgnoreNotFound(err) != nil {
+			return nil, err
+		}
+	} else {
+		report.Plan = plan
+	}
+
+	report.Stages = stages(report.Composition, report.Plan)
+	return report, nil
+}
+
+// findComposition returns the Composition whose facade is the given resource
+func findComposition(ctx context.Context, c client.Client, mapping *meta.RESTMapping) (*compositionv1alpha1.Composition, error) {
+	compositions := &compositionv1alpha1.CompositionList{}
+	if err := c.List(ctx, compositions); err != nil {
+		return nil, fmt.Errorf("listing Compositions: %w", err)
+	}
+	crdName := mapping.Resource.Resource + "." + mapping.Resource.Group
+	for i := range compositions.Items {
+		if crds.FacadeCRDName(&compositions.Items[i]) == crdName {
+			return &compositions.Items[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// stages derives the state of each stage from the Composition and the Plan.
+// The controller evaluates and applies the stages in order and stops at the
+// first one that waits or fails, so the stages after it are pending.
+func stages(composition *compositionv1alpha1.Composition, plan *compositionv1alpha1.Plan) []Stage {
+	out := []Stage{}
+	if composition != nil {
+		for _, expander := range composition.Spec.Expanders {
+			stage := Stage{Name: expander.Name, Type: expander.Type, Version: expander.Version, Validation: compositionv1alpha1.ValidationStatusUnknown}
+			if status, ok := composition.Status.Stages[expander.Name]; ok {
+				stage.Validation = status.ValidationStatus
+				stage.ValidationMessage = status.Message
+				stage.Diagnostics = status.Diagnostics
+			}
+			out = append(out, stage)
+		}
+	} else if plan != nil && plan.Annotations[stagesAnnotation] != "" {
+		for _, name := range strings.Split(plan.Annotations[stagesAnnotation], ",") {
+			out = append(out, Stage{Name: name, Validation: compositionv1alpha1.ValidationStatusUnknown})
+		}
+	}
+
+	blocked := false
+	for i := range out {
+		stage := &out[i]
+		stage.Evaluation = PhasePending
+		stage.Apply = PhasePending
+		if plan == nil || blocked {
+			continue
+		}
+		planStage, evaluated := plan.Spec.Stages[stage.Name]
+		if evaluated {
+			stage.Evaluation = PhaseEvaluated
+		}
+		if status := plan.Status.Stages[stage.Name]; status != nil {
+			stage.ResourceCount = status.ResourceCount
+			stage.AppliedCount = status.AppliedCount
+			stage.Resources = status.LastApplied
+			if len(status.Diagnostics) != 0 {
+				stage.Diagnostics = status.Diagnostics
+			}
+			stage.Apply = PhaseReady
+			for _, resource := range status.LastApplied {
+				if resource.Health != compositionv1alpha1.Healthy {
+					stage.Apply = PhaseWaiting
+				}
+			}
+		} else if evaluated && planStage.Values != "" {
+			stage.Apply = PhaseSkipped
+		}
+
+		if condition, message := stageCondition(plan, stage.Name); condition != nil {
+			blocked = true
+			stage.Message = message
+			phase := PhaseWaiting
+			if condition.Type == string(compositionv1alpha1.Error) {
+				phase = PhaseFailed
+			}
+			if applyReasons[condition.Reason] {
+				stage.Apply = phase
+			} else {
+				stage.Evaluation = phase
+				stage.Apply = PhasePending
+			}
+		}
+	}
+	return out
+}
+
+// applyReasons are the condition reasons the controller uses for failures after evaluation
+var applyReasons = map[string]bool{
+	"FailedLoadingManifestsFromPlan":   true,
+	"FailedApplyingManifests":          true,
+	"FailedWaitingForAppliedResources": true,
+	"WaitingForAppliedResources":       true,
+}
+
+// stageCondition returns the Error or Waiting condition of the Plan for the stage.
+// The controller sets the message to "Expander: <stage>, Message: <message>".
+func stageCondition(plan *compositionv1alpha1.Plan, stage string) (*metav1.Condition, string) {
+	prefix := fmt.Sprintf("Expander: %s, Message: ", stage)
+	for _, conditionType := range []compositionv1alpha1.ConditionType{compositionv1alpha1.Error, compositionv1alpha1.Waiting} {
+		condition := meta.FindStatusCondition(plan.Status.Conditions, string(conditionType))
+		if condition == nil || condition.Status != metav1.ConditionTrue || !strings.HasPrefix(condition.Message, prefix) {
+			continue
+		}
+		return condition, strings.TrimPrefix(condition.Message, prefix)
+	}
+	return nil, ""
+}
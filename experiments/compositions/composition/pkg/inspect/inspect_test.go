@@ -0,0 +1,280 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package inspect
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	compositionv1alpha1 "github.com/cloud-native-compositions/compositions/composition/api/v1alpha1"
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+var teamGVK = schema.GroupVersionKind{Group: "facade.compositions.google.com", Version: "v1", Kind: "Team"}
+
+var now = time.Date(2024, 8, 1, 12, 0, 0, 0, time.UTC)
+
+func newClient(t *testing.T, objects ...client.Object) client.Client {
+	scheme := runtime.NewScheme()
+	if err := compositionv1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := corev1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	mapper := meta.NewDefaultRESTMapper(nil)
+	mapper.Add(teamGVK, meta.RESTScopeNamespace)
+
+	facade := &unstructured.Unstructured{}
+	facade.SetGroupVersionKind(teamGVK)
+	facade.SetNamespace("default")
+	facade.SetName("blue")
+
+	composition := &compositionv1alpha1.Composition{
+		ObjectMeta: metav1.ObjectMeta{Name: "team", Generation: 3, UID: "composition-uid"},
+		Spec: compositionv1alpha1.CompositionSpec{
+			Schema: &compositionv1alpha1.Schema{Kind: "Team", APIVersion: "v1"},
+			Expanders: []compositionv1alpha1.Expander{
+				{Name: "db", Type: "cel"},
+				{Name: "fetch", Type: "getter"},
+				{Name: "page", Type: "jinja2"},
+			},
+		},
+		Status: compositionv1alpha1.CompositionStatus{
+			Stages: map[string]compositionv1alpha1.StageValidationStatus{
+				"db":    {ValidationStatus: compositionv1alpha1.ValidationStatusSuccess},
+				"fetch": {ValidationStatus: compositionv1alpha1.ValidationStatusSuccess},
+			},
+		},
+	}
+
+	return fake.NewClientBuilder().WithScheme(scheme).WithRESTMapper(mapper).
+		WithObjects(append(objects, facade, composition)...).Build()
+}
+
+// waitingPlan has applied the db stage and waits in the fetch stage
+func waitingPlan() *compositionv1alpha1.Plan {
+	return &compositionv1alpha1.Plan{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "teams-blue", UID: "plan-uid"},
+		Spec: compositionv1alpha1.PlanSpec{Stages: map[string]compositionv1alpha1.Stage{
+			"db":    {Manifest: "..."},
+			"fetch": {Values: "{}"},
+			"page":  {Manifest: "..."},
+		}},
+		Status: compositionv1alpha1.PlanStatus{
+			CompositionGeneration: 2,
+			CompositionUID:        "composition-uid",
+			Conditions: []metav1.Condition{
+				{Type: "Ready", Status: metav1.ConditionFalse, Reason: "PendingStages", Message: "Applied stages: db"},
+				{Type: "Waiting", Status: metav1.ConditionTrue, Reason: "EvaluateWait", Message: "Expander: fetch, Message: SQLInstance bluedb has no connectionName"},
+			},
+			Stages: map[string]*compositionv1alpha1.StageStatus{
+				"db": {ResourceCount: 2, AppliedCount: 2, LastApplied: []compositionv1alpha1.ResourceStatus{
+					{Group: "sql.cnrm.cloud.google.com", Version: "v1beta1", Kind: "SQLInstance", Namespace: "default", Name: "bluedb", Health: compositionv1alpha1.Healthy},
+					{Version: "v1", Kind: "ServiceAccount", Namespace: "default", Name: "blue", Health: compositionv1alpha1.Unhealthy, Status: "created"},
+				}},
+			},
+		},
+	}
+}
+
+func TestParseFacadeArgs(t *testing.T) {
+	tests := []struct {
+		args  []string
+		want  schema.GroupResource
+		name  string
+		isErr bool
+	}{
+		{args: []string{"teams/blue"}, want: schema.GroupResource{Resource: "teams"}, name: "blue"},
+		{args: []string{"Team", "blue"}, want: schema.GroupResource{Resource: "team"}, name: "blue"},
+		{args: []string{"teams.facade.compositions.google.com/blue"}, want: schema.GroupResource{Group: "facade.compositions.google.com", Resource: "teams"}, name: "blue"},
+		{args: []string{"teams"}, isErr: true},
+		{args: []string{"teams/"}, isErr: true},
+		{args: []string{"teams/blue", "red"}, isErr: true},
+	}
+	for _, tc := range tests {
+		gr, name, err := ParseFacadeArgs(tc.args)
+		if tc.isErr {
+			if err == nil {
+				t.Errorf("ParseFacadeArgs(%q) want error", tc.args)
+			}
+			continue
+		}
+		if err != nil || gr != tc.want || name != tc.name {
+			t.Errorf("ParseFacadeArgs(%q) = %v, %q, %v; want %v, %q", tc.args, gr, name, err, tc.want, tc.name)
+		}
+	}
+}
+
+func TestInspect(t *testing.T) {
+	c := newClient(t, waitingPlan())
+	report, err := Inspect(context.Background(), c, schema.GroupResource{Resource: "team"}, "default", "blue")
+	if err != nil {
+		t.Fatalf("Inspect() failed: %v", err)
+	}
+	if report.Composition == nil || report.Composition.Name != "team" {
+		t.Fatalf("want Composition team, got %v", report.Composition)
+	}
+	if report.Plan == nil {
+		t.Fatalf("want Plan teams-blue")
+	}
+
+	type phases struct {
+		Name       string
+		Validation compositionv1alpha1.ValidationStatus
+		Evaluation Phase
+		Apply      Phase
+		Message    string
+	}
+	got := []phases{}
+	for _, s := range report.Stages {
+		got = append(got, phases{s.Name, s.Validation, s.Evaluation, s.Apply, s.Message})
+	}
+	want := []phases{
+		{"db", "success", PhaseEvaluated, PhaseWaiting, ""},
+		{"fetch", "success", PhaseWaiting, PhasePending, "SQLInstance bluedb has no connectionName"},
+		{"page", "unknown", PhasePending, PhasePending, ""},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("unexpected stages (-want +got):\n%s", diff)
+	}
+}
+
+func TestInspectNoPlan(t *testing.T) {
+	c := newClient(t)
+	report, err := Inspect(context.Background(), c, schema.GroupResource{Resource: "teams"}, "default", "blue")
+	if err != nil {
+		t.Fatalf("Inspect() failed: %v", err)
+	}
+	if report.Plan != nil {
+		t.Errorf("want no Plan, got %v", report.Plan)
+	}
+	for _, s := range report.Stages {
+		if s.Evaluation != PhasePending || s.Apply != PhasePending {
+			t.Errorf("stage %s: want Pending, got %s/%s", s.Name, s.Evaluation, s.Apply)
+		}
+	}
+
+	if _, err := Inspect(context.Background(), c, schema.GroupResource{Resource: "teams"}, "default", "red"); err == nil {
+		t.Errorf("want error for missing facade")
+	}
+}
+
+func TestPrint(t *testing.T) {
+	c := newClient(t, waitingPlan())
+	report, err := Inspect(context.Background(), c, schema.GroupResource{Resource: "teams"}, "default", "blue")
+	if err != nil {
+		t.Fatalf("Inspect() failed: %v", err)
+	}
+
+	var out bytes.Buffer
+	if err := PrintStatus(&out, report); err != nil {
+		t.Fatal(err)
+	}
+	want := `Facade:       Team default/blue
+Ready:        False (PendingStages) Applied stages: db
+Composition:  team generation 3, plan reconciled generation 2 (outdated)
+Plan:         default/teams-blue
+
+Stages:
+  NAME   TYPE    VALIDATION  EVALUATION  APPLY    RESOURCES
+  db     cel     success     Evaluated   Waiting  2/2
+  fetch  getter  success     Waiting     Pending  -
+  page   jinja2  unknown     Pending     Pending  -
+  fetch: SQLInstance bluedb has no connectionName
+
+Resources:
+  STAGE  KIND                                   NAME            HEALTH     STATUS
+  db     SQLInstance.sql.cnrm.cloud.google.com  default/bluedb  Healthy    -
+  db     ServiceAccount                         default/blue    Unhealthy  created
+`
+	if diff := cmp.Diff(want, out.String()); diff != "" {
+		t.Errorf("unexpected status (-want +got):\n%s", diff)
+	}
+
+	out.Reset()
+	PrintTree(&out, report, false)
+	want = `Team/blue False (PendingStages) Applied stages: db
+└── Plan/teams-blue  composition team generation 3, plan reconciled generation 2 (outdated)
+    ├── Stage/db [cel]  validation: success  evaluation: Evaluated  apply: Waiting (2/2)
+    │   ├── SQLInstance.sql.cnrm.cloud.google.com/bluedb  Healthy
+    │   └── ServiceAccount/blue  Unhealthy  created
+    ├── Stage/fetch [getter]  validation: success  evaluation: Waiting  apply: Pending
+    │   └── SQLInstance bluedb has no connectionName
+    └── Stage/page [jinja2]  validation: unknown  evaluation: Pending  apply: Pending
+`
+	if diff := cmp.Diff(want, out.String()); diff != "" {
+		t.Errorf("unexpected tree (-want +got):\n%s", diff)
+	}
+
+	out.Reset()
+	PrintTree(&out, report, true)
+	if !bytes.Contains(out.Bytes(), []byte(colorRed+"Unhealthy"+colorReset)) {
+		t.Errorf("want colored health in tree, got:\n%s", out.String())
+	}
+}
+
+func TestEvents(t *testing.T) {
+	event := func(name, apiVersion, kind, object, reason string, age time.Duration) *corev1.Event {
+		return &corev1.Event{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: name},
+			InvolvedObject: corev1.ObjectReference{
+				APIVersion: apiVersion, Kind: kind, Namespace: "default", Name: object,
+			},
+			Type:          corev1.EventTypeNormal,
+			Reason:        reason,
+			Message:       reason + " " + object,
+			LastTimestamp: metav1.NewTime(now.Add(-age)),
+		}
+	}
+	c := newClient(t, waitingPlan(),
+		event("e1", "facade.compositions.google.com/v1", "Team", "blue", "ResourcesApplied", time.Minute),
+		event("e2", "facade.compositions.google.com/v1", "Team", "red", "ResourcesApplied", time.Minute),
+		event("e3", "sql.cnrm.cloud.google.com/v1beta1", "SQLInstance", "bluedb", "Updating", 30*time.Second),
+		event("e4", "v1", "ServiceAccount", "bluedb", "Created", time.Second),
+		event("e5", "composition.google.com/v1alpha1", "Plan", "teams-blue", "Evaluated", 2*time.Minute),
+	)
+	report, err := Inspect(context.Background(), c, schema.GroupResource{Resource: "teams"}, "default", "blue")
+	if err != nil {
+		t.Fatalf("Inspect() failed: %v", err)
+	}
+	events, err := Events(context.Background(), c, report)
+	if err != nil {
+		t.Fatalf("Events() failed: %v", err)
+	}
+
+	var out bytes.Buffer
+	if err := PrintEvents(&out, events, now); err != nil {
+		t.Fatal(err)
+	}
+	want := `LAST SEEN  TYPE    REASON            OBJECT              MESSAGE
+2m         Normal  Evaluated         plan/teams-blue     Evaluated teams-blue
+60s        Normal  ResourcesApplied  team/blue           ResourcesApplied blue
+30s        Normal  Updating          sqlinstance/bluedb  Updating bluedb
+`
+	if diff := cmp.Diff(want, out.String()); diff != "" {
+		t.Errorf("unexpected events (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,279 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package inspect
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	compositionv1alpha1 "github.com/cloud-native-compositions/compositions/composition/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/util/duration"
+)
+
+// ANSI colors used by PrintTree
+const (
+	colorReset  = "\x1b[0m"
+	colorRed    = "\x1b[31m"
+	colorGreen  = "\x1b[32m"
+	colorYellow = "\x1b[33m"
+	colorBold   = "\x1b[1m"
+)
+
+// PrintStatus writes the facade, its composition and the stages and resources as tables
+func PrintStatus(w io.Writer, r *Report) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintf(tw, "Facade:\t%s %s\n", r.Facade.GetKind(), objectName(r.Facade.GetNamespace(), r.Facade.GetName()))
+	fmt.Fprintf(tw, "Ready:\t%s\n", r.ready())
+	fmt.Fprintf(tw, "Composition:\t%s\n", r.revision())
+	if r.Plan != nil {
+		fmt.Fprintf(tw, "Plan:\t%s\n", objectName(r.Plan.Namespace, r.Plan.Name))
+	} else {
+		fmt.Fprintf(tw, "Plan:\t<not created yet>\n")
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	if len(r.Stages) == 0 {
+		return nil
+	}
+	fmt.Fprintf(w, "\nStages:\n")
+	tw = tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintf(tw, "  NAME\tTYPE\tVALIDATION\tEVALUATION\tAPPLY\tRESOURCES\n")
+	for _, s := range r.Stages {
+		fmt.Fprintf(tw, "  %s\t%s\t%s\t%s\t%s\t%s\n", s.Name, s.Type, s.Validation, s.Evaluation, s.Apply, orDash(s.resourceCount()))
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	for _, s := range r.Stages {
+		for _, message := range s.messages() {
+			fmt.Fprintf(w, "  %s: %s\n", s.Name, message)
+		}
+	}
+
+	if !r.hasResources() {
+		return nil
+	}
+	fmt.Fprintf(w, "\nResources:\n")
+	tw = tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintf(tw, "  STAGE\tKIND\tNAME\tHEALTH\tSTATUS\n")
+	for _, s := range r.Stages {
+		for _, resource := range s.Resources {
+			fmt.Fprintf(tw, "  %s\t%s\t%s\t%s\t%s\n", s.Name, resourceKind(resource), objectName(resource.Namespace, resource.Name),
+				resource.Health, orDash(resource.Status))
+		}
+	}
+	return tw.Flush()
+}
+
+// PrintTree writes the facade, Plan, stages and resources as a tree. With color
+// the state of each node is highlighted.
+func PrintTree(w io.Writer, r *Report, color bool) {
+	paint := func(c, s string) string {
+		if !color || c == "" {
+			return s
+		}
+		return c + s + colorReset
+	}
+
+	fmt.Fprintf(w, "%s %s\n", paint(colorBold, r.Facade.GetKind()+"/"+r.Facade.GetName()), r.ready())
+	planName := "Plan <not created yet>"
+	if r.Plan != nil {
+		planName = "Plan/" + r.Plan.Name
+	}
+	fmt.Fprintf(w, "└── %s  composition %s\n", paint(colorBold, planName), r.revision())
+	for i, s := range r.Stages {
+		branch, indent := "├── ", "│   "
+		if i == len(r.Stages)-1 {
+			branch, indent = "└── ", "    "
+		}
+		fmt.Fprintf(w, "    %s%s", branch, paint(colorBold, "Stage/"+s.Name))
+		if s.Type != "" {
+			fmt.Fprintf(w, " [%s]", s.Type)
+		}
+		fmt.Fprintf(w, "  validation: %s  evaluation: %s  apply: %s",
+			paint(validationColor(s.Validation), string(s.Validation)),
+			paint(phaseColor(s.Evaluation), string(s.Evaluation)),
+			paint(phaseColor(s.Apply), string(s.Apply)))
+		if count := s.resourceCount(); count != "" {
+			fmt.Fprintf(w, " (%s)", count)
+		}
+		fmt.Fprintln(w)
+
+		children := []string{}
+		for _, message := range s.messages() {
+			children = append(children, paint(colorYellow, message))
+		}
+		for _, resource := range s.Resources {
+			healthColor := colorGreen
+			if resource.Health != compositionv1alpha1.Healthy {
+				healthColor = colorRed
+			}
+			line := fmt.Sprintf("%s/%s  %s", resourceKind(resource), resource.Name, paint(healthColor, string(resource.Health)))
+			if resource.Status != "" {
+				line += "  " + resource.Status
+			}
+			children = append(children, line)
+		}
+		for j, child := range children {
+			branch := "├── "
+			if j == len(children)-1 {
+				branch = "└── "
+			}
+			fmt.Fprintf(w, "    %s%s%s\n", indent, branch, child)
+		}
+	}
+}
+
+// PrintEvents writes the events as a table like kubectl get events
+func PrintEvents(w io.Writer, events []corev1.Event, now time.Time) error {
+	if len(events) == 0 {
+		fmt.Fprintf(w, "No events found.\n")
+		return nil
+	}
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintf(tw, "LAST SEEN\tTYPE\tREASON\tOBJECT\tMESSAGE\n")
+	for _, event := range events {
+		lastSeen := "<unknown>"
+		if t := EventTime(event); !t.IsZero() {
+			lastSeen = duration.HumanDuration(now.Sub(t))
+		}
+		o := event.InvolvedObject
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", lastSeen, event.Type, event.Reason,
+			strings.ToLower(o.Kind)+"/"+o.Name, strings.TrimSpace(event.Message))
+	}
+	return tw.Flush()
+}
+
+// ready summarizes the Ready condition of the Plan
+func (r *Report) ready() string {
+	if r.Plan == nil {
+		return "Unknown"
+	}
+	condition := meta.FindStatusCondition(r.Plan.Status.Conditions, string(compositionv1alpha1.Ready))
+	if condition == nil {
+		return "Unknown"
+	}
+	s := string(condition.Status)
+	if condition.Reason != "" {
+		s += " (" + condition.Reason + ")"
+	}
+	if condition.Message != "" {
+		s += " " + condition.Message
+	}
+	return s
+}
+
+// revision describes the Composition generation and the one the Plan was reconciled with
+func (r *Report) revision() string {
+	if r.Composition == nil {
+		return "<not found>"
+	}
+	s := fmt.Sprintf("%s generation %d", r.Composition.Name, r.Composition.Generation)
+	if r.Plan == nil || r.Plan.Status.CompositionGeneration == 0 {
+		return s
+	}
+	if r.Plan.Status.CompositionUID != "" && r.Plan.Status.CompositionUID != r.Composition.UID {
+		return s + ", plan reconciled a different composition"
+	}
+	s += fmt.Sprintf(", plan reconciled generation %d", r.Plan.Status.CompositionGeneration)
+	if r.Plan.Status.CompositionGeneration != r.Composition.Generation {
+		s += " (outdated)"
+	}
+	return s
+}
+
+func (r *Report) hasResources() bool {
+	for _, s := range r.Stages {
+		if len(s.Resources) != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// resourceCount returns applied/total resources, or "" if nothing was applied
+func (s *Stage) resourceCount() string {
+	if s.ResourceCount == 0 && len(s.Resources) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%d/%d", s.AppliedCount, s.ResourceCount)
+}
+
+// messages returns the phase message and the diagnostics of the stage
+func (s *Stage) messages() []string {
+	messages := []string{}
+	if s.Validation == compositionv1alpha1.ValidationStatusFailed || s.Validation == compositionv1alpha1.ValidationStatusError {
+		if s.ValidationMessage != "" {
+			messages = append(messages, "validation: "+s.ValidationMessage)
+		}
+	}
+	if s.Message != "" {
+		messages = append(messages, s.Message)
+	}
+	for _, d := range s.Diagnostics {
+		messages = append(messages, fmt.Sprintf("%s: %s", d.Severity, d.String()))
+	}
+	return messages
+}
+
+func phaseColor(phase Phase) string {
+	switch phase {
+	case PhaseReady, PhaseEvaluated, PhaseSkipped:
+		return colorGreen
+	case PhaseFailed:
+		return colorRed
+	case PhaseWaiting:
+		return colorYellow
+	}
+	return ""
+}
+
+func validationColor(status compositionv1alpha1.ValidationStatus) string {
+	switch status {
+	case compositionv1alpha1.ValidationStatusSuccess:
+		return colorGreen
+	case compositionv1alpha1.ValidationStatusFailed, compositionv1alpha1.ValidationStatusError:
+		return colorRed
+	}
+	return ""
+}
+
+func resourceKind(r compositionv1alpha1.ResourceStatus) string {
+	if r.Group == "" {
+		return r.Kind
+	}
+	return r.Kind + "." + r.Group
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+func objectName(namespace, name string) string {
+	if namespace == "" {
+		return name
+	}
+	return namespace + "/" + name
+}
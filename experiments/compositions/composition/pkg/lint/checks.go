@@ -0,0 +1,227 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lint
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	compositionv1alpha1 "github.com/cloud-native-compositions/compositions/composition/api/v1alpha1"
+	"github.com/cloud-native-compositions/compositions/composition/pkg/cel"
+	"github.com/cloud-native-compositions/compositions/composition/pkg/crds"
+	"github.com/cloud-native-compositions/compositions/composition/pkg/render"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func expanderType(expanderVersionName string) string {
+	return strings.TrimPrefix(expanderVersionName, "composition-")
+}
+
+// StageNames checks that stage names are set and unique
+func StageNames(c *compositionv1alpha1.Composition) []Finding {
+	findings := []Finding{}
+	seen := map[string]int{}
+	for i, expander := range c.Spec.Expanders {
+		path := expanderPath(i) + ".name"
+		if expander.Name == "" {
+			findings = append(findings, compositionFinding(c, SeverityError, "EmptyStageName", path, "stage name is empty"))
+			continue
+		}
+		if first, ok := seen[expander.Name]; ok {
+			findings = append(findings, compositionFinding(c, SeverityError, "DuplicateStageName", path,
+				"stage name %q is also used by %s", expander.Name, expanderPath(first)))
+			continue
+		}
+		seen[expander.Name] = i
+	}
+	return findings
+}
+
+// ExpanderTypes checks that the expander type and version of each stage are installed
+func ExpanderTypes(c *compositionv1alpha1.Composition, expanderVersions map[string]*compositionv1alpha1.ExpanderVersion) []Finding {
+	findings := []Finding{}
+	for i, expander := range c.Spec.Expanders {
+		ev, ok := expanderVersions[expander.Type]
+		if !ok {
+			types := []string{}
+			for t := range expanderVersions {
+				types = append(types, t)
+			}
+			sort.Strings(types)
+			findings = append(findings, compositionFinding(c, SeverityError, "UnknownExpanderType", expanderPath(i)+".type",
+				"unknown expander type %q, known types: %s", expander.Type, strings.Join(types, ", ")))
+			continue
+		}
+		if !validVersion(ev, expander.Version) {
+			findings = append(findings, compositionFinding(c, SeverityError, "UnknownExpanderVersion", expanderPath(i)+".version",
+				"version %q is not a valid version of expander %s, valid versions: latest, %s",
+				expander.Version, expander.Type, strings.Join(ev.Spec.ValidVersions, ", ")))
+		}
+	}
+	return findings
+}
+
+func validVersion(ev *compositionv1alpha1.ExpanderVersion, version string) bool {
+	if version == "latest" {
+		return len(ev.Spec.ValidVersions) != 0
+	}
+	for _, v := range ev.Spec.ValidVersions {
+		if v == version {
+			return true
+		}
+	}
+	return false
+}
+
+// Readiness checks that the readyIf rules compile
+func Readiness(c *compositionv1alpha1.Composition) []Finding {
+	findings := []Finding{}
+	for i, readiness := range c.Spec.Readiness {
+		path := fmt.Sprintf("spec.readiness[%d].readyIf", i)
+		if err := cel.Compile(readiness.Ready); err != nil {
+			findings = append(findings, compositionFinding(c, SeverityError, "InvalidReadyIf", path,
+				"readyIf rule does not compile: %v", err))
+		}
+	}
+	return findings
+}
+
+// Schema checks that the facade API is set and that a SimpleSchema builds a CRD
+func Schema(c *compositionv1alpha1.Composition) []Finding {
+	if c.Spec.InputAPIGroup != "" {
+		return nil
+	}
+	if c.Spec.Schema == nil {
+		return []Finding{compositionFinding(c, SeverityError, "MissingSchema", "spec",
+			"one of spec.inputAPIGroup or spec.schema is required")}
+	}
+	findings := []Finding{}
+	if c.Spec.Schema.Kind == "" {
+		findings = append(findings, compositionFinding(c, SeverityError, "MissingSchemaKind", "spec.schema.kind",
+			"the facade kind is required"))
+	}
+	specSchema, err := crds.BuildSchema(c.Spec.Schema.Spec.Raw)
+	if err != nil {
+		return append(findings, compositionFinding(c, SeverityError, "InvalidSchema", "spec.schema.spec", "%v", err))
+	}
+	gvk := schema.GroupVersionKind{Group: c.Spec.Schema.Group, Version: c.Spec.Schema.APIVersion, Kind: c.Spec.Schema.Kind}
+	if err := crds.NewFacadeCRDInfo(gvk, "", nil, nil, nil).SetSpec(specSchema); err != nil {
+		findings = append(findings, compositionFinding(c, SeverityError, "InvalidSchema", "spec.schema.spec",
+			"unable to set CRD spec from schema: %v", err))
+	}
+	return findings
+}
+
+// ConfigRefs checks that the configs referenced by the stages exist
+func ConfigRefs(c *compositionv1alpha1.Composition, configs []*unstructured.Unstructured,
+	expanderVersions map[string]*compositionv1alpha1.ExpanderVersion) []Finding {
+	findings := []Finding{}
+	for i, expander := range c.Spec.Expanders {
+		if expander.ConfigRef == nil {
+			continue
+		}
+		kind := configKind(expander, expanderVersions)
+		if _, err := render.FindConfig(configs, expander.ConfigRef, kind); err != nil {
+			findings = append(findings, compositionFinding(c, SeverityError, "MissingConfig", expanderPath(i)+".configref", "%v", err))
+		}
+	}
+	return findings
+}
+
+func configKind(expander compositionv1alpha1.Expander, expanderVersions map[string]*compositionv1alpha1.ExpanderVersion) string {
+	ev, ok := expanderVersions[expander.Type]
+	if !ok {
+		return ""
+	}
+	return ev.ConfigGVK(expander.Version).Kind
+}
+
+// clusterScopedKinds are well known kinds that have no namespace
+var clusterScopedKinds = map[string]bool{
+	"Namespace":                      true,
+	"ClusterRole":                    true,
+	"ClusterRoleBinding":             true,
+	"CustomResourceDefinition":       true,
+	"PersistentVolume":               true,
+	"StorageClass":                   true,
+	"PriorityClass":                  true,
+	"ValidatingWebhookConfiguration": true,
+	"MutatingWebhookConfiguration":   true,
+}
+
+var (
+	documentSeparator = regexp.MustCompile(`(?m)^---.*$`)
+	kindLine          = regexp.MustCompile(`(?m)^kind:\s*["']?([A-Za-z0-9]+)`)
+	namespaceLine     = regexp.MustCompile(`(?m)^\s+namespace:`)
+)
+
+// NamespaceMode checks that objects set their namespace when the composition
+// uses namespaceMode explicit. Inline templates are scanned for documents
+// without a namespace field. The resources of CEL configs are checked exactly.
+// Both only know the well known cluster scoped kinds, so the findings are
+// warnings wherever the template lives.
+func NamespaceMode(c *compositionv1alpha1.Composition, configs []*unstructured.Unstructured,
+	expanderVersions map[string]*compositionv1alpha1.ExpanderVersion) []Finding {
+	if c.Spec.NamespaceMode != compositionv1alpha1.NamespaceModeExplicit {
+		return nil
+	}
+	findings := []Finding{}
+	for i, expander := range c.Spec.Expanders {
+		if expander.ConfigRef == nil {
+			for n, doc := range documentSeparator.Split(expander.Template, -1) {
+				kind := kindLine.FindStringSubmatch(doc)
+				if kind == nil || clusterScopedKinds[kind[1]] || namespaceLine.MatchString(doc) {
+					continue
+				}
+				findings = append(findings, compositionFinding(c, SeverityWarning, "MissingNamespace", expanderPath(i)+".template",
+					"namespaceMode is explicit but document %d of the template (kind %s) does not set metadata.namespace", n, kind[1]))
+			}
+			continue
+		}
+
+		config, err := render.FindConfig(configs, expander.ConfigRef, configKind(expander, expanderVersions))
+		if err != nil {
+			// Reported by ConfigRefs
+			continue
+		}
+		resources, _, _ := unstructured.NestedSlice(config.Object, "spec", "resources")
+		for r, resource := range resources {
+			definition, _, _ := unstructured.NestedMap(asMap(resource), "definition")
+			kind, _, _ := unstructured.NestedString(definition, "kind")
+			if definition == nil || clusterScopedKinds[kind] {
+				continue
+			}
+			if namespace, _, _ := unstructured.NestedString(definition, "metadata", "namespace"); namespace == "" {
+				findings = append(findings, Finding{
+					Severity: SeverityWarning,
+					Code:     "MissingNamespace",
+					Message: fmt.Sprintf("namespaceMode of Composition %s is explicit but the %s resource does not set metadata.namespace",
+						c.Name, kind),
+					Object: objectName(config.GetKind(), config.GetName()),
+					Path:   fmt.Sprintf("spec.resources[%d].definition.metadata", r),
+				})
+			}
+		}
+	}
+	return findings
+}
+
+func asMap(v interface{}) map[string]interface{} {
+	m, _ := v.(map[string]interface{})
+	return m
+}
@@ -0,0 +1,130 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lint
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	sigsyaml "sigs.k8s.io/yaml"
+)
+
+// Document is an object read from a file, kept with its YAML node for line numbers
+type Document struct {
+	File   string
+	Object *unstructured.Unstructured
+	node   *yaml.Node
+}
+
+// ReadFile reads the objects in a YAML file with one or more documents
+func ReadFile(path string) ([]*Document, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	documents := []*Document{}
+	decoder := yaml.NewDecoder(bytes.NewReader(b))
+	for {
+		node := &yaml.Node{}
+		if err := decoder.Decode(node); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, fmt.Errorf("parsing %s: %w", path, err)
+		}
+		// Convert through json like the api clients do, for int64 and float64 numbers
+		raw, err := yaml.Marshal(node)
+		if err != nil {
+			return nil, fmt.Errorf("parsing %s: %w", path, err)
+		}
+		obj := map[string]interface{}{}
+		if err := sigsyaml.Unmarshal(raw, &obj); err != nil {
+			return nil, fmt.Errorf("parsing %s: %w", path, err)
+		}
+		if len(obj) == 0 {
+			continue
+		}
+		documents = append(documents, &Document{File: path, Object: &unstructured.Unstructured{Object: obj}, node: node})
+	}
+	return documents, nil
+}
+
+// Locate sets the file and line of findings about the objects in documents
+func Locate(findings []Finding, documents []*Document) {
+	for i := range findings {
+		for _, doc := range documents {
+			if objectName(doc.Object.GetKind(), doc.Object.GetName()) != findings[i].Object {
+				continue
+			}
+			findings[i].File = doc.File
+			findings[i].Line = line(doc.node, findings[i].Path)
+			break
+		}
+	}
+}
+
+var pathSegment = regexp.MustCompile(`^([^\[]*)((?:\[\d+\])*)$`)
+var pathIndex = regexp.MustCompile(`\[(\d+)\]`)
+
+// line returns the line of the field at path, or of its closest parent that exists
+func line(node *yaml.Node, path string) int {
+	if node.Kind == yaml.DocumentNode && len(node.Content) != 0 {
+		node = node.Content[0]
+	}
+	current := node.Line
+	for _, segment := range strings.Split(path, ".") {
+		m := pathSegment.FindStringSubmatch(segment)
+		if m == nil {
+			return current
+		}
+		if m[1] != "" {
+			next := mappingValue(node, m[1])
+			if next == nil {
+				return current
+			}
+			node = next
+			current = node.Line
+		}
+		for _, index := range pathIndex.FindAllStringSubmatch(m[2], -1) {
+			i, _ := strconv.Atoi(index[1])
+			if node.Kind != yaml.SequenceNode || i >= len(node.Content) {
+				return current
+			}
+			node = node.Content[i]
+			current = node.Line
+		}
+	}
+	return current
+}
+
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	if node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
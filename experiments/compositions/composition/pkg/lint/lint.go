@@ -0,0 +1,159 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package lint runs static checks on Compositions.
+//
+// The checks catch mistakes that are otherwise only reported in the status of
+// an installed Composition. Findings carry a path into the object they are
+// about, for example spec.expanders[1].name.
+package lint
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	compositionv1alpha1 "github.com/cloud-native-compositions/compositions/composition/api/v1alpha1"
+	"github.com/cloud-native-compositions/compositions/composition/pkg/expanderclient"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+type Severity string
+
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+	SeverityInfo    Severity = "info"
+)
+
+// Finding is a problem found by a check
+type Finding struct {
+	Severity Severity `json:"severity"`
+	// Code is a machine readable identifier of the check, for example DuplicateStageName
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	// Object is the object the finding is about as <kind>/<name>
+	Object string `json:"object"`
+	// Path is the field of the object, for example spec.expanders[1].name
+	Path string `json:"path"`
+	// File and Line locate the field in the linted files, if known
+	File string `json:"file,omitempty"`
+	Line int    `json:"line,omitempty"`
+}
+
+func (f Finding) String() string {
+	location := ""
+	if f.File != "" {
+		location = f.File
+		if f.Line != 0 {
+			location = fmt.Sprintf("%s:%d", f.File, f.Line)
+		}
+		location += ": "
+	}
+	return fmt.Sprintf("%s%s %s %s %s: %s", location, f.Severity, f.Code, f.Object, f.Path, f.Message)
+}
+
+// Options are the context the checks run in
+type Options struct {
+	// ExpanderVersions are the installed expanders. The expander types and
+	// versions are not checked if there are none.
+	ExpanderVersions []*compositionv1alpha1.ExpanderVersion
+
+	// Configs are the expander configs referenced by configref
+	Configs []*unstructured.Unstructured
+
+	// Client returns a client for the expander type and version, or nil if
+	// there is no reachable expander. Validate is skipped if Client is nil.
+	Client func(expanderType, version string) (*expanderclient.Client, error)
+
+	// ValidateTimeout bounds each Validate call, 10s if not set
+	ValidateTimeout time.Duration
+}
+
+// Composition runs all the checks on a composition
+func Composition(ctx context.Context, c *compositionv1alpha1.Composition, opts Options) []Finding {
+	findings := []Finding{}
+	findings = append(findings, StageNames(c)...)
+	findings = append(findings, Schema(c)...)
+	findings = append(findings, Readiness(c)...)
+	expanderVersions := byType(opts.ExpanderVersions)
+	if len(opts.ExpanderVersions) != 0 {
+		findings = append(findings, ExpanderTypes(c, expanderVersions)...)
+	} else {
+		findings = append(findings, compositionFinding(c, SeverityInfo, "ExpanderTypesNotChecked", "spec.expanders",
+			"no ExpanderVersions given, expander types and versions are not checked"))
+	}
+	findings = append(findings, ConfigRefs(c, opts.Configs, expanderVersions)...)
+	findings = append(findings, NamespaceMode(c, opts.Configs, expanderVersions)...)
+	if opts.Client != nil {
+		findings = append(findings, Validate(ctx, c, opts)...)
+	}
+	return findings
+}
+
+// HasErrors is true if any finding is an error
+func HasErrors(findings []Finding) bool {
+	for _, f := range findings {
+		if f.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+// Sort orders findings by file, line, object and path
+func Sort(findings []Finding) {
+	sort.SliceStable(findings, func(i, j int) bool {
+		a, b := findings[i], findings[j]
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		if a.Object != b.Object {
+			return a.Object < b.Object
+		}
+		return a.Path < b.Path
+	})
+}
+
+func objectName(kind, name string) string {
+	return kind + "/" + name
+}
+
+func compositionFinding(c *compositionv1alpha1.Composition, severity Severity, code, path, format string, args ...interface{}) Finding {
+	return Finding{
+		Severity: severity,
+		Code:     code,
+		Message:  fmt.Sprintf(format, args...),
+		Object:   objectName("Composition", c.Name),
+		Path:     path,
+	}
+}
+
+// byType keys ExpanderVersions by expander type, the name without the
+// composition- prefix the controller adds
+func byType(evs []*compositionv1alpha1.ExpanderVersion) map[string]*compositionv1alpha1.ExpanderVersion {
+	out := map[string]*compositionv1alpha1.ExpanderVersion{}
+	for _, ev := range evs {
+		out[expanderType(ev.Name)] = ev
+	}
+	return out
+}
+
+func expanderPath(i int) string {
+	return fmt.Sprintf("spec.expanders[%d]", i)
+}
@@ -0,0 +1,145 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lint
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+
+	compositionv1alpha1 "github.com/cloud-native-compositions/compositions/composition/api/v1alpha1"
+	"github.com/cloud-native-compositions/compositions/composition/pkg/expanderclient"
+	"github.com/cloud-native-compositions/compositions/composition/pkg/expandersdk"
+	"github.com/cloud-native-compositions/compositions/composition/pkg/render"
+	"github.com/google/go-cmp/cmp"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// lintFiles lints the Compositions in testdata/expanderversions.yaml and file
+func lintFiles(t *testing.T, file string, client func(string, string) (*expanderclient.Client, error)) []Finding {
+	documents := []*Document{}
+	for _, f := range []string{"testdata/expanderversions.yaml", file} {
+		docs, err := ReadFile(f)
+		if err != nil {
+			t.Fatalf("ReadFile(%s) failed: %v", f, err)
+		}
+		documents = append(documents, docs...)
+	}
+
+	opts := Options{Client: client}
+	compositions := []*compositionv1alpha1.Composition{}
+	for _, doc := range documents {
+		switch doc.Object.GetKind() {
+		case "Composition":
+			c, err := render.DecodeComposition(doc.Object)
+			if err != nil {
+				t.Fatalf("DecodeComposition() failed: %v", err)
+			}
+			compositions = append(compositions, c)
+		case "ExpanderVersion":
+			ev := &compositionv1alpha1.ExpanderVersion{}
+			if err := runtime.DefaultUnstructuredConverter.FromUnstructured(doc.Object.Object, ev); err != nil {
+				t.Fatalf("decoding ExpanderVersion failed: %v", err)
+			}
+			opts.ExpanderVersions = append(opts.ExpanderVersions, ev)
+		default:
+			opts.Configs = append(opts.Configs, doc.Object)
+		}
+	}
+
+	findings := []Finding{}
+	for _, c := range compositions {
+		findings = append(findings, Composition(context.Background(), c, opts)...)
+	}
+	Locate(findings, documents)
+	Sort(findings)
+	return findings
+}
+
+func summary(findings []Finding) []string {
+	out := []string{}
+	for _, f := range findings {
+		out = append(out, fmt.Sprintf("%d %s %s %s %s", f.Line, f.Severity, f.Code, f.Object, f.Path))
+	}
+	return out
+}
+
+func TestInvalid(t *testing.T) {
+	findings := lintFiles(t, "testdata/invalid.yaml", nil)
+	want := []string{
+		"27 warning MissingNamespace CELConfiguration/database spec.resources[0].definition.metadata",
+		"45 error InvalidSchema Composition/invalid spec.schema.spec",
+		"54 error UnknownExpanderVersion Composition/invalid spec.expanders[1].version",
+		"55 error DuplicateStageName Composition/invalid spec.expanders[1].name",
+		"56 warning MissingNamespace Composition/invalid spec.expanders[1].template",
+		"61 error UnknownExpanderType Composition/invalid spec.expanders[2].type",
+		"62 error EmptyStageName Composition/invalid spec.expanders[2].name",
+		"64 error MissingConfig Composition/invalid spec.expanders[2].configref",
+		"68 error InvalidReadyIf Composition/invalid spec.readiness[0].readyIf",
+	}
+	if diff := cmp.Diff(want, summary(findings)); diff != "" {
+		t.Errorf("unexpected findings (-want +got):\n%s", diff)
+	}
+	if !HasErrors(findings) {
+		t.Errorf("want HasErrors true")
+	}
+}
+
+func TestValid(t *testing.T) {
+	findings := lintFiles(t, "testdata/valid.yaml", nil)
+	got := []string{}
+	for _, f := range findings {
+		if f.Object == "Composition/valid" {
+			got = append(got, f.String())
+		}
+	}
+	if len(got) != 0 {
+		t.Errorf("want no findings, got:\n%s", strings.Join(got, "\n"))
+	}
+}
+
+func TestValidate(t *testing.T) {
+	server := expandersdk.NewServer(&expandersdk.Expander[string]{
+		Name: "fake",
+		Validate: func(ctx context.Context, req *expandersdk.Request[string]) (*expandersdk.Result, error) {
+			return nil, expandersdk.Failed(&expandersdk.Diagnostic{
+				Severity: expandersdk.SeverityError,
+				Code:     "TemplateSyntaxError",
+				Message:  "unexpected end of template",
+				Line:     4,
+			})
+		},
+	}).V2()
+	client := func(expanderType, version string) (*expanderclient.Client, error) {
+		if expanderType != "jinja2" {
+			return nil, nil
+		}
+		return expanderclient.NewInProc(server), nil
+	}
+
+	findings := []string{}
+	for _, f := range lintFiles(t, "testdata/valid.yaml", client) {
+		if f.Code == "TemplateSyntaxError" {
+			findings = append(findings, fmt.Sprintf("%s %s %s", f.Object, f.Path, f.Message))
+		}
+	}
+	sort.Strings(findings)
+	want := []string{"Composition/valid spec.expanders[1].template stage page: TemplateSyntaxError: unexpected end of template"}
+	if diff := cmp.Diff(want, findings); diff != "" {
+		t.Errorf("unexpected findings (-want +got):\n%s", diff)
+	}
+}
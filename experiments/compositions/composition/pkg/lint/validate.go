@@ -0,0 +1,127 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lint
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	compositionv1alpha1 "github.com/cloud-native-compositions/compositions/composition/api/v1alpha1"
+	"github.com/cloud-native-compositions/compositions/composition/pkg/crds"
+	"github.com/cloud-native-compositions/compositions/composition/pkg/expanderclient"
+	"github.com/cloud-native-compositions/compositions/composition/pkg/render"
+	pbv2 "github.com/cloud-native-compositions/compositions/composition/proto/v2"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// defaultValidateTimeout bounds each Validate call if Options.ValidateTimeout is not set
+const defaultValidateTimeout = 10 * time.Second
+
+// Validate calls the Validate rpc of the expanders that are reachable with opts.Client
+func Validate(ctx context.Context, c *compositionv1alpha1.Composition, opts Options) []Finding {
+	expanderVersions := byType(opts.ExpanderVersions)
+	timeout := opts.ValidateTimeout
+	if timeout == 0 {
+		timeout = defaultValidateTimeout
+	}
+	findings := []Finding{}
+	for i, expander := range c.Spec.Expanders {
+		path := expanderPath(i)
+		expanderClient, err := opts.Client(expander.Type, expander.Version)
+		if err != nil {
+			findings = append(findings, compositionFinding(c, SeverityWarning, "ExpanderUnreachable", path,
+				"unable to call expander %s: %v", expander.Type, err))
+			continue
+		}
+		if expanderClient == nil {
+			continue
+		}
+		findings = append(findings, validateStage(ctx, c, i, expander, opts.Configs, expanderVersions, expanderClient, timeout)...)
+		expanderClient.Close()
+	}
+	return findings
+}
+
+func validateStage(ctx context.Context, c *compositionv1alpha1.Composition, i int, expander compositionv1alpha1.Expander,
+	configs []*unstructured.Unstructured, expanderVersions map[string]*compositionv1alpha1.ExpanderVersion,
+	expanderClient *expanderclient.Client, timeout time.Duration) []Finding {
+	path := expanderPath(i) + ".template"
+	object := objectName("Composition", c.Name)
+	configBytes := []byte(expander.Template)
+	if expander.ConfigRef != nil {
+		config, err := render.FindConfig(configs, expander.ConfigRef, configKind(expander, expanderVersions))
+		if err != nil {
+			// Reported by ConfigRefs
+			return nil
+		}
+		if configBytes, err = json.Marshal(config.Object); err != nil {
+			return []Finding{compositionFinding(c, SeverityError, "ValidateFailed", expanderPath(i), "%v", err)}
+		}
+		object = objectName(config.GetKind(), config.GetName())
+		path = "spec"
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	req := &pbv2.ValidateRequest{Config: configBytes}
+	// The facade CRD of a composition with an inputAPIGroup is not known offline
+	if resource, facadeSchema, err := crds.FacadeSchema(c); err == nil && facadeSchema != nil {
+		req.Resource = resource
+		if req.FacadeSchema, err = json.Marshal(facadeSchema); err != nil {
+			return []Finding{compositionFinding(c, SeverityError, "ValidateFailed", expanderPath(i), "%v", err)}
+		}
+	}
+	result, err := expanderClient.Validate(ctx, req)
+	if err != nil {
+		return []Finding{compositionFinding(c, SeverityWarning, "ExpanderUnreachable", expanderPath(i),
+			"expander %s Validate failed: %v", expander.Type, err)}
+	}
+
+	findings := []Finding{}
+	hasError := false
+	for _, d := range expanderclient.ToAPIDiagnostics(result.Diagnostics) {
+		severity := SeverityError
+		switch d.Severity {
+		case compositionv1alpha1.DiagnosticSeverityWarning:
+			severity = SeverityWarning
+		case compositionv1alpha1.DiagnosticSeverityInfo:
+			severity = SeverityInfo
+		}
+		hasError = hasError || severity == SeverityError
+		code := d.Code
+		if code == "" {
+			code = "ValidateFailed"
+		}
+		findings = append(findings, Finding{
+			Severity: severity,
+			Code:     code,
+			Message:  fmt.Sprintf("stage %s: %s", expander.Name, d.String()),
+			Object:   object,
+			Path:     path,
+		})
+	}
+	if result.Status != pbv2.Status_SUCCESS && !hasError {
+		findings = append(findings, Finding{
+			Severity: SeverityError,
+			Code:     "ValidateFailed",
+			Message:  fmt.Sprintf("stage %s: %s", expander.Name, expanderclient.ErrorMessage(result.Diagnostics, result.Status.String())),
+			Object:   object,
+			Path:     path,
+		})
+	}
+	return findings
+}
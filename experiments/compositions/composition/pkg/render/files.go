@@ -0,0 +1,114 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package render
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	compositionv1alpha1 "github.com/cloud-native-compositions/compositions/composition/api/v1alpha1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+)
+
+// ReadObjects reads the objects in a YAML or JSON file with one or more documents
+func ReadObjects(path string) ([]*unstructured.Unstructured, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	objects, err := ParseObjects(b)
+	if err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	return objects, nil
+}
+
+// ParseObjects parses a YAML or JSON stream. Empty documents are skipped.
+func ParseObjects(b []byte) ([]*unstructured.Unstructured, error) {
+	objects := []*unstructured.Unstructured{}
+	decoder := utilyaml.NewYAMLOrJSONDecoder(bytes.NewReader(b), 4096)
+	for {
+		obj := map[string]interface{}{}
+		if err := decoder.Decode(&obj); err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, err
+		}
+		if len(obj) == 0 {
+			continue
+		}
+		objects = append(objects, &unstructured.Unstructured{Object: obj})
+	}
+	return objects, nil
+}
+
+// ReadObject reads a file with exactly one object
+func ReadObject(path string) (*unstructured.Unstructured, error) {
+	objects, err := ReadObjects(path)
+	if err != nil {
+		return nil, err
+	}
+	if len(objects) != 1 {
+		return nil, fmt.Errorf("expected one object in %s, found %d", path, len(objects))
+	}
+	return objects[0], nil
+}
+
+// ReadComposition reads a file with a Composition. Other objects in the file,
+// like the expander configs of the composition, are returned along with it.
+func ReadComposition(path string) (*compositionv1alpha1.Composition, []*unstructured.Unstructured, error) {
+	objects, err := ReadObjects(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	var composition *compositionv1alpha1.Composition
+	others := []*unstructured.Unstructured{}
+	for _, obj := range objects {
+		if obj.GetKind() != "Composition" {
+			others = append(others, obj)
+			continue
+		}
+		if composition != nil {
+			return nil, nil, fmt.Errorf("more than one Composition in %s", path)
+		}
+		if composition, err = DecodeComposition(obj); err != nil {
+			return nil, nil, fmt.Errorf("parsing Composition in %s: %w", path, err)
+		}
+	}
+	if composition == nil {
+		return nil, nil, fmt.Errorf("no Composition in %s", path)
+	}
+	return composition, others, nil
+}
+
+// DecodeComposition converts an object to a Composition
+func DecodeComposition(obj *unstructured.Unstructured) (*compositionv1alpha1.Composition, error) {
+	// Decode from json like the api clients do. The unstructured converter does
+	// not inline the embedded ExpanderConfig.
+	b, err := obj.MarshalJSON()
+	if err != nil {
+		return nil, err
+	}
+	composition := &compositionv1alpha1.Composition{}
+	if err := json.Unmarshal(b, composition); err != nil {
+		return nil, err
+	}
+	return composition, nil
+}
@@ -0,0 +1,413 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package render expands a composition offline, without a cluster.
+//
+// The stages are evaluated in order and values are passed between them the
+// way the expander reconciler does: values returned by a stage are merged into
+// the values of later stages and the objects of a stage are added by the
+// implicit getter. Objects are assumed to become ready as soon as they are
+// rendered. Their status can be stubbed for stages that read it.
+package render
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	compositionv1alpha1 "github.com/cloud-native-compositions/compositions/composition/api/v1alpha1"
+	"github.com/cloud-native-compositions/compositions/composition/pkg/expanderclient"
+	"github.com/cloud-native-compositions/compositions/composition/pkg/expanders/getter"
+	"github.com/cloud-native-compositions/compositions/composition/pkg/expandersdk"
+	"github.com/cloud-native-compositions/compositions/composition/pkg/inproc"
+	"github.com/cloud-native-compositions/compositions/composition/pkg/stagevalues"
+	pbv2 "github.com/cloud-native-compositions/compositions/composition/proto/v2"
+	"github.com/go-logr/logr"
+	"github.com/gobuffalo/flect"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"sigs.k8s.io/yaml"
+)
+
+// Options are the inputs of a render
+type Options struct {
+	Composition *compositionv1alpha1.Composition
+	Facade      *unstructured.Unstructured
+
+	// FacadeCRD is the CRD of the facade, if known. Expanders are sent the
+	// plural in its spec.names as the resource of the facade. Without it the
+	// plural is guessed from the kind.
+	FacadeCRD *unstructured.Unstructured
+
+	// Context is the Context object of the facade namespace, if any
+	Context *unstructured.Unstructured
+
+	// Configs are the expander config CRs referenced by configref
+	Configs []*unstructured.Unstructured
+
+	// Objects are read by the getter in addition to the rendered objects,
+	// for example objects created outside of the composition
+	Objects []*unstructured.Unstructured
+
+	// Status stubs the status of rendered objects. The status of an object
+	// matching the kind, name and (if set) namespace of a stub is replaced.
+	Status []*unstructured.Unstructured
+
+	// Expanders maps an expander type to the uri of a grpc expander or an
+	// inproc:// uri. Types not listed use the getter reading the rendered
+	// objects or the expander registered in the inproc package.
+	Expanders map[string]string
+
+	// Stages limits the render to the first stages. All stages are rendered if 0.
+	Stages int
+
+	// Cluster is the server version and the API versions sent to the
+	// expanders, if any
+	Cluster *pbv2.Cluster
+
+	Logger logr.Logger
+}
+
+// Stage is the result of a stage
+type Stage struct {
+	Name string
+	Type string
+
+	// Objects and Manifest are set for stages returning objects
+	Objects  []*unstructured.Unstructured
+	Manifest string
+
+	// Hooks are the hooks the controller would apply around Objects. They are
+	// not applied to the stand-in cluster read by the getter.
+	Hooks []compositionv1alpha1.Hook
+
+	// Values are set for stages returning values
+	Values map[string]interface{}
+
+	Diagnostics []compositionv1alpha1.Diagnostic
+}
+
+// YAML returns the manifests of the stage, or its values as a YAML document
+func (s *Stage) YAML() ([]byte, error) {
+	if s.Values != nil {
+		return yaml.Marshal(s.Values)
+	}
+	return []byte(s.Manifest), nil
+}
+
+// HooksYAML returns the manifests of the hooks of the stage, each preceded by
+// a comment with its phase and weight
+func (s *Stage) HooksYAML() []byte {
+	b := []byte{}
+	for _, h := range s.Hooks {
+		b = append(b, fmt.Sprintf("# Hook: %s, weight: %d\n", h.Phase, h.Weight)...)
+		b = append(b, h.Manifest...)
+	}
+	return b
+}
+
+// WaitError is returned when an expander returns WAIT.
+// The stages rendered until then are returned along with it.
+type WaitError struct {
+	Stage   string
+	Message string
+}
+
+func (e *WaitError) Error() string {
+	return fmt.Sprintf("stage %s: Expander returned WAIT: %s", e.Stage, e.Message)
+}
+
+// EvaluateError is returned when an expander fails to evaluate a stage, as
+// opposed to errors reaching the expander or reading its inputs.
+type EvaluateError struct {
+	Stage   string
+	Message string
+}
+
+func (e *EvaluateError) Error() string {
+	return fmt.Sprintf("stage %s: Evaluate Failed: %s", e.Stage, e.Message)
+}
+
+type renderer struct {
+	Options
+	cluster   *dynamicfake.FakeDynamicClient
+	getter    pbv2.ExpanderServer
+	namespace string
+	resource  string
+}
+
+// facadeResource returns the plural of the facade CRD, or the lowercase plural
+// of the facade kind when the CRD is not known
+func facadeResource(opts Options) string {
+	if opts.FacadeCRD != nil {
+		plural, _, _ := unstructured.NestedString(opts.FacadeCRD.Object, "spec", "names", "plural")
+		if plural != "" {
+			return plural
+		}
+	}
+	return strings.ToLower(flect.Pluralize(opts.Facade.GetKind()))
+}
+
+// Render evaluates the stages of the composition in order
+func Render(ctx context.Context, opts Options) ([]*Stage, error) {
+	if opts.Composition == nil || opts.Facade == nil {
+		return nil, fmt.Errorf("a composition and a facade are required")
+	}
+	r := &renderer{
+		Options:  opts,
+		cluster:  dynamicfake.NewSimpleDynamicClient(runtime.NewScheme()),
+		resource: facadeResource(opts),
+	}
+	r.getter = expandersdk.NewServer(getter.New(r.cluster)).V2()
+	if r.Facade.GetNamespace() == "" {
+		r.Facade = r.Facade.DeepCopy()
+		r.Facade.SetNamespace("default")
+	}
+	if r.Composition.Spec.NamespaceMode != compositionv1alpha1.NamespaceModeExplicit {
+		r.namespace = r.Facade.GetNamespace()
+	}
+	for _, obj := range r.Objects {
+		if err := r.track(obj); err != nil {
+			return nil, err
+		}
+	}
+
+	stages := []*Stage{}
+	values := map[string]interface{}{}
+	for i, expander := range r.Composition.Spec.Expanders {
+		if r.Stages != 0 && i == r.Stages {
+			break
+		}
+		stage, err := r.evaluate(ctx, expander, values)
+		if err != nil {
+			return stages, err
+		}
+		stages = append(stages, stage)
+
+		if stage.Values != nil {
+			if err := stagevalues.Merge(values, stage.Values); err != nil {
+				return stages, fmt.Errorf("stage %s: %w", stage.Name, err)
+			}
+			continue
+		}
+
+		applied := []*unstructured.Unstructured{}
+		for _, obj := range stage.Objects {
+			obj = r.applied(obj)
+			if err := r.track(obj); err != nil {
+				return stages, fmt.Errorf("stage %s: %w", stage.Name, err)
+			}
+			applied = append(applied, obj)
+		}
+		// Implicit getter
+		values = stagevalues.AddObjects(r.Logger, values, applied)
+	}
+	return stages, nil
+}
+
+func (r *renderer) evaluate(ctx context.Context, expander compositionv1alpha1.Expander, values map[string]interface{}) (*Stage, error) {
+	stage := &Stage{Name: expander.Name, Type: expander.Type}
+
+	expanderClient, err := r.client(expander.Type)
+	if err != nil {
+		return nil, fmt.Errorf("stage %s: %w", expander.Name, err)
+	}
+	defer expanderClient.Close()
+
+	var contextBytes []byte
+	if r.Context != nil {
+		contextBytes, err = json.Marshal(r.Context.Object)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal Context Object: %w", err)
+		}
+	}
+	facadeBytes, err := json.Marshal(r.Facade.Object)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshall Facade Object: %w", err)
+	}
+
+	var configBytes []byte
+	if expander.ConfigRef != nil {
+		config, err := r.config(ctx, expanderClient, expander)
+		if err != nil {
+			return nil, fmt.Errorf("stage %s: %w", expander.Name, err)
+		}
+		configBytes, err = json.Marshal(config.Object)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal ExpanderConfig Object: %w", err)
+		}
+	} else {
+		configBytes = []byte(expander.Template)
+	}
+
+	valuesBytes, err := json.Marshal(values)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshall Getter Values: %w", err)
+	}
+
+	result, err := expanderClient.Evaluate(ctx, &pbv2.EvaluateRequest{
+		Config:   configBytes,
+		Context:  contextBytes,
+		Facade:   facadeBytes,
+		Resource: r.resource,
+		Value:    valuesBytes,
+		Cluster:  r.Cluster,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("stage %s: expander.Evaluate() failed: %w", expander.Name, err)
+	}
+	stage.Diagnostics = expanderclient.ToAPIDiagnostics(result.Diagnostics)
+	if result.Status == pbv2.Status_EVALUATE_WAIT {
+		return nil, &WaitError{Stage: expander.Name, Message: expanderclient.ErrorMessage(result.Diagnostics, "")}
+	}
+	if result.Status != pbv2.Status_SUCCESS {
+		return nil, &EvaluateError{Stage: expander.Name, Message: expanderclient.ErrorMessage(result.Diagnostics, result.Status.String())}
+	}
+
+	if result.Type == pbv2.ResultType_OBJECTS {
+		stage.Manifest, err = expanderclient.ManifestsFromObjects(result.Objects)
+		if err != nil {
+			return nil, fmt.Errorf("stage %s: unable to convert expanded objects to manifests: %w", expander.Name, err)
+		}
+		for _, o := range result.Objects {
+			obj := &unstructured.Unstructured{}
+			if err := obj.UnmarshalJSON(o.Json); err != nil {
+				return nil, fmt.Errorf("stage %s: unable to parse object %s %s: %w", expander.Name, o.Kind, o.Name, err)
+			}
+			stage.Objects = append(stage.Objects, obj)
+		}
+		stage.Hooks, err = expanderclient.HooksFromProto(result.Hooks)
+		if err != nil {
+			return nil, fmt.Errorf("stage %s: unable to convert expanded hooks: %w", expander.Name, err)
+		}
+		// Hooks are printed as manifests, like the objects of the stage
+		for i, h := range result.Hooks {
+			stage.Hooks[i].Manifest, err = expanderclient.ManifestsFromObjects(h.Objects)
+			if err != nil {
+				return nil, fmt.Errorf("stage %s: unable to convert expanded hooks to manifests: %w", expander.Name, err)
+			}
+			stage.Hooks[i].Objects = nil
+		}
+		return stage, nil
+	}
+
+	stage.Values = map[string]interface{}{}
+	if err := json.Unmarshal(result.Values, &stage.Values); err != nil {
+		return nil, fmt.Errorf("stage %s: Failed unmarshalling response.Values field: %w", expander.Name, err)
+	}
+	return stage, nil
+}
+
+// client returns the expander for the type. See Options.Expanders.
+func (r *renderer) client(expanderType string) (*expanderclient.Client, error) {
+	if uri, ok := r.Expanders[expanderType]; ok {
+		return expanderclient.New(uri)
+	}
+	if expanderType == "getter" {
+		return expanderclient.NewInProc(r.getter), nil
+	}
+	if _, ok := inproc.Get(expanderType); ok {
+		return expanderclient.New(inproc.URI(expanderType))
+	}
+	return nil, fmt.Errorf("no expander for type %q, pass its grpc address with --expander %s=<host:port>", expanderType, expanderType)
+}
+
+// config finds the config CR referenced by the stage. The kind reported in the
+// expander capabilities is used to tell configs with the same name apart.
+func (r *renderer) config(ctx context.Context, c *expanderclient.Client, expander compositionv1alpha1.Expander) (*unstructured.Unstructured, error) {
+	ref := expander.ConfigRef
+	kind := ""
+	if result, err := c.GetCapabilities(ctx); err == nil {
+		if capabilities, err := expanderclient.ToAPICapabilities(result); err == nil && capabilities.Config != nil {
+			kind = capabilities.Config.Kind
+		}
+	}
+
+	return FindConfig(r.Configs, ref, kind)
+}
+
+// applied returns obj the way the applier would apply it, with the namespace
+// set in inherit mode and the status stubbed
+func (r *renderer) applied(obj *unstructured.Unstructured) *unstructured.Unstructured {
+	obj = obj.DeepCopy()
+	if r.namespace != "" {
+		obj.SetNamespace(r.namespace)
+	}
+	for _, stub := range r.Status {
+		if stub.GroupVersionKind().GroupKind() != obj.GroupVersionKind().GroupKind() || stub.GetName() != obj.GetName() {
+			continue
+		}
+		if stub.GetNamespace() != "" && stub.GetNamespace() != obj.GetNamespace() {
+			continue
+		}
+		if status, ok := stub.Object["status"]; ok {
+			obj.Object["status"] = runtime.DeepCopyJSONValue(status)
+		}
+	}
+	return obj
+}
+
+// track adds or updates obj in the objects read by the getter
+func (r *renderer) track(obj *unstructured.Unstructured) error {
+	gvr, _ := meta.UnsafeGuessKindToResource(obj.GroupVersionKind())
+	err := r.cluster.Tracker().Create(gvr, obj, obj.GetNamespace())
+	if apierrors.IsAlreadyExists(err) {
+		err = r.cluster.Tracker().Update(gvr, obj, obj.GetNamespace())
+	}
+	return err
+}
+
+// FindConfig returns the config referenced by ref. Configs of other kinds are
+// ignored if kind is set.
+func FindConfig(configs []*unstructured.Unstructured, ref *compositionv1alpha1.ConfigReference, kind string) (*unstructured.Unstructured, error) {
+	var found *unstructured.Unstructured
+	for _, config := range configs {
+		if config.GetName() != ref.Name {
+			continue
+		}
+		// Configs without a namespace end up in the default namespace when applied with kubectl
+		namespace := config.GetNamespace()
+		if namespace == "" {
+			namespace = "default"
+		}
+		if ref.Namespace != "" && namespace != ref.Namespace {
+			continue
+		}
+		if kind != "" && config.GetKind() != kind {
+			continue
+		}
+		if found != nil {
+			return nil, fmt.Errorf("more than one config matches configref %s", configRefName(ref))
+		}
+		found = config
+	}
+	if found == nil {
+		if kind == "" {
+			kind = "config"
+		}
+		return nil, fmt.Errorf("%s for configref %s not found", kind, configRefName(ref))
+	}
+	return found, nil
+}
+
+func configRefName(ref *compositionv1alpha1.ConfigReference) string {
+	if ref.Namespace == "" {
+		return ref.Name
+	}
+	return ref.Namespace + "/" + ref.Name
+}
@@ -0,0 +1,138 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package render
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	celexpander "github.com/cloud-native-compositions/compositions/composition/pkg/expanders/cel"
+	"github.com/cloud-native-compositions/compositions/composition/pkg/expandersdk"
+	"github.com/cloud-native-compositions/compositions/composition/pkg/inproc"
+	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func testOptions(t *testing.T) Options {
+	inproc.Register("cel", expandersdk.NewServer(celexpander.New()).V2())
+
+	composition, _, err := ReadComposition("testdata/composition.yaml")
+	if err != nil {
+		t.Fatalf("ReadComposition() failed: %v", err)
+	}
+	facade, err := ReadObject("testdata/facade.yaml")
+	if err != nil {
+		t.Fatalf("ReadObject() failed: %v", err)
+	}
+	configs, err := ReadObjects("testdata/configs.yaml")
+	if err != nil {
+		t.Fatalf("ReadObjects() failed: %v", err)
+	}
+	return Options{
+		Composition: composition,
+		Facade:      facade,
+		Configs:     configs,
+		Logger:      logr.Discard(),
+	}
+}
+
+func TestRender(t *testing.T) {
+	opts := testOptions(t)
+	status, err := ReadObjects("testdata/status.yaml")
+	if err != nil {
+		t.Fatalf("ReadObjects() failed: %v", err)
+	}
+	opts.Status = status
+
+	stages, err := Render(context.Background(), opts)
+	if err != nil {
+		t.Fatalf("Render() failed: %v", err)
+	}
+	if len(stages) != 3 {
+		t.Fatalf("want 3 stages, got %d", len(stages))
+	}
+
+	database := stages[0].Objects
+	if len(database) != 1 || database[0].GetName() != "bluedb" {
+		t.Fatalf("unexpected objects in stage database: %v", database)
+	}
+	// The objects are returned as expanded, the namespace is only set on the applied copy
+	if database[0].GetNamespace() != "" {
+		t.Errorf("want no namespace in the rendered object, got %q", database[0].GetNamespace())
+	}
+
+	// The getter reads the stubbed status
+	connection, _, _ := unstructured.NestedString(stages[1].Values, "database", "connection")
+	if connection != "project:us-central1:bluedb" {
+		t.Errorf("want fetched connection project:us-central1:bluedb, got %q", connection)
+	}
+
+	// The implicit getter passes the applied objects to later stages
+	page := stages[2].Objects
+	if len(page) != 1 {
+		t.Fatalf("want 1 object in stage page, got %d", len(page))
+	}
+	data, _, _ := unstructured.NestedStringMap(page[0].Object, "data")
+	if data["connection"] != "project:us-central1:bluedb" || data["namespace"] != "team-blue" {
+		t.Errorf("unexpected data in stage page: %v", data)
+	}
+}
+
+func TestRenderWait(t *testing.T) {
+	opts := testOptions(t)
+
+	// Without a status stub the getter waits for status.connectionName
+	stages, err := Render(context.Background(), opts)
+	var waitErr *WaitError
+	if !errors.As(err, &waitErr) {
+		t.Fatalf("want WaitError, got %v", err)
+	}
+	if waitErr.Stage != "fetch" {
+		t.Errorf("want stage fetch waiting, got %s", waitErr.Stage)
+	}
+	if len(stages) != 1 {
+		t.Errorf("want 1 stage rendered before waiting, got %d", len(stages))
+	}
+}
+
+func TestRenderStages(t *testing.T) {
+	opts := testOptions(t)
+	opts.Stages = 1
+
+	// The getter stage is not reached, so it does not wait
+	stages, err := Render(context.Background(), opts)
+	if err != nil {
+		t.Fatalf("Render() failed: %v", err)
+	}
+	if len(stages) != 1 || stages[0].Name != "database" {
+		t.Errorf("want only stage database, got %d stages", len(stages))
+	}
+}
+
+func TestFacadeResource(t *testing.T) {
+	facade := &unstructured.Unstructured{}
+	facade.SetKind("Cactus")
+	crd := &unstructured.Unstructured{Object: map[string]interface{}{
+		"spec": map[string]interface{}{"names": map[string]interface{}{"plural": "cactuses"}},
+	}}
+
+	if got := facadeResource(Options{Facade: facade}); got != "cacti" {
+		t.Errorf("want the plural of the kind without a CRD, got %q", got)
+	}
+	if got := facadeResource(Options{Facade: facade, FacadeCRD: crd}); got != "cactuses" {
+		t.Errorf("want the plural of the CRD, got %q", got)
+	}
+}
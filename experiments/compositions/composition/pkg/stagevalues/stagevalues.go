@@ -0,0 +1,103 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package stagevalues implements how values are passed between the stages of a
+// composition. It is shared by the expander reconciler and the offline renderer.
+package stagevalues
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// Merge adds the values returned by a stage to values.
+// A key may only be returned by one stage.
+func Merge(values map[string]interface{}, stageValues map[string]interface{}) error {
+	for k := range stageValues {
+		_, ok := values[k]
+		if ok {
+			return fmt.Errorf("values[%s] already exists from one of the previous stages.", k)
+		}
+		values[k] = stageValues[k]
+	}
+	return nil
+}
+
+// AddObjects is the implicit getter. It adds the objects applied by a stage
+// to values as values.<kind>.<name>.
+func AddObjects(logger logr.Logger, values map[string]interface{}, objects []*unstructured.Unstructured) map[string]interface{} {
+	for _, obj := range objects {
+		name := obj.GetName()
+		gvk := obj.GroupVersionKind()
+		kind := strings.ToLower(gvk.Kind)
+
+		// short path: values.<kind>.<name>. May clash
+		_, ok := values[kind]
+		if !ok {
+			values[kind] = map[string]interface{}{}
+		}
+		ref := values[kind].(map[string]interface{})
+
+		_, ok = ref[name]
+		if ok {
+			// Clash !! We will ignore
+			logger.Info("Clash when adding applied objects to values.", "kind", kind, "name", name)
+		} else {
+			ref[name] = obj.Object
+		}
+
+		// long path: values.<group>.<kind>.<namespace>.<name> will not clash
+		// Long path may not be practical since the namespace is not part of the composition and
+		// most templating languages dont support nested templatable variable.
+		// ex: {{ values.deployment.teampage.status.something  }} will work in jinja2
+		// but this {{ values.apps.deployment.{{teampage.metadata.namespace}}.status.something }} wont work
+		// So leaving this code commented for now.
+		/*
+			group := strings.ReplaceAll(strings.ToLower(gvk.Group), ".", "_")
+			namespace := obj.GetNamespace()
+
+			if group == "" {
+				group = "core"
+			}
+			_, ok = values[group]
+			if !ok {
+				values[group] = map[string]interface{}{}
+			}
+			ref = values[group].(map[string]interface{})
+
+			_, ok = ref[kind]
+			if !ok {
+				ref[kind] = map[string]interface{}{}
+			}
+			ref = ref[kind].(map[string]interface{})
+
+			_, ok = ref[namespace]
+			if !ok {
+				ref[namespace] = map[string]interface{}{}
+			}
+			ref = ref[namespace].(map[string]interface{})
+
+			_, ok = ref[name]
+			if !ok {
+				ref[name] = map[string]interface{}{}
+			}
+			ref[name] = obj.Object
+		*/
+
+	}
+	return values
+}
@@ -0,0 +1,262 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package webhookcert provisions the serving certificate of the manager's
+// admission webhooks without cert-manager.
+//
+// A self signed CA and a certificate for the webhook Service are kept in a
+// Secret, so all replicas of the manager serve the same certificate. The CA
+// is injected into the caBundle of the ValidatingWebhookConfigurations.
+package webhookcert
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"time"
+
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// CAKey is the Secret key of the CA certificate
+	CAKey = "ca.crt"
+
+	validity = 10 * 365 * 24 * time.Hour
+	// renewBefore regenerates certificates that expire sooner than this
+	renewBefore = 30 * 24 * time.Hour
+)
+
+// Options describe where the certificate is stored and served
+type Options struct {
+	// Client must not be cached, Ensure runs before the manager starts
+	Client client.Client
+	// Namespace of the Secret and the webhook Service
+	Namespace   string
+	SecretName  string
+	ServiceName string
+	// CertDir is where the webhook server reads tls.crt and tls.key from
+	CertDir string
+	// WebhookConfigurations are the ValidatingWebhookConfigurations whose caBundle is set
+	WebhookConfigurations []string
+}
+
+// DNSNames returns the names the webhook Service is reached by
+func DNSNames(service, namespace string) []string {
+	return []string{
+		service,
+		service + "." + namespace,
+		service + "." + namespace + ".svc",
+		service + "." + namespace + ".svc.cluster.local",
+	}
+}
+
+//+kubebuilder:rbac:groups="",resources=secrets,verbs=get;create;update
+//+kubebuilder:rbac:groups=admissionregistration.k8s.io,resources=validatingwebhookconfigurations,verbs=get;update
+
+// Ensure makes sure the Secret holds a valid certificate for the Service,
+// writes it to CertDir and injects the CA into the webhook configurations.
+// It returns the PEM encoded CA.
+func Ensure(ctx context.Context, opts Options) ([]byte, error) {
+	secret, err := ensureSecret(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(opts.CertDir, 0o700); err != nil {
+		return nil, fmt.Errorf("creating cert dir: %w", err)
+	}
+	for _, key := range []string{corev1.TLSCertKey, corev1.TLSPrivateKeyKey} {
+		if err := os.WriteFile(filepath.Join(opts.CertDir, key), secret.Data[key], 0o600); err != nil {
+			return nil, fmt.Errorf("writing %s: %w", key, err)
+		}
+	}
+
+	ca := secret.Data[CAKey]
+	for _, name := range opts.WebhookConfigurations {
+		if err := InjectCABundle(ctx, opts.Client, name, ca); err != nil {
+			return nil, err
+		}
+	}
+	return ca, nil
+}
+
+// ensureSecret returns the certificate Secret, creating or renewing it as needed
+func ensureSecret(ctx context.Context, opts Options) (*corev1.Secret, error) {
+	secret := &corev1.Secret{}
+	nn := types.NamespacedName{Namespace: opts.Namespace, Name: opts.SecretName}
+	err := opts.Client.Get(ctx, nn, secret)
+	if err != nil && !apierrors.IsNotFound(err) {
+		return nil, fmt.Errorf("getting Secret %s: %w", nn, err)
+	}
+	exists := err == nil
+	dnsNames := DNSNames(opts.ServiceName, opts.Namespace)
+	if exists && valid(secret.Data, dnsNames) {
+		return secret, nil
+	}
+
+	data, err := Generate(dnsNames)
+	if err != nil {
+		return nil, err
+	}
+	if exists {
+		secret.Data = data
+		if err := opts.Client.Update(ctx, secret); err != nil {
+			return nil, fmt.Errorf("updating Secret %s: %w", nn, err)
+		}
+		return secret, nil
+	}
+
+	secret = &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: opts.Namespace, Name: opts.SecretName},
+		Type:       corev1.SecretTypeTLS,
+		Data:       data,
+	}
+	if err := opts.Client.Create(ctx, secret); err != nil {
+		if !apierrors.IsAlreadyExists(err) {
+			return nil, fmt.Errorf("creating Secret %s: %w", nn, err)
+		}
+		// Another replica created it first
+		if err := opts.Client.Get(ctx, nn, secret); err != nil {
+			return nil, fmt.Errorf("getting Secret %s: %w", nn, err)
+		}
+	}
+	return secret, nil
+}
+
+// valid is true if data holds a key pair for dnsNames signed by the CA that does not expire soon
+func valid(data map[string][]byte, dnsNames []string) bool {
+	if _, err := tls.X509KeyPair(data[corev1.TLSCertKey], data[corev1.TLSPrivateKeyKey]); err != nil {
+		return false
+	}
+	cert, err := parseCertificate(data[corev1.TLSCertKey])
+	if err != nil {
+		return false
+	}
+	roots := x509.NewCertPool()
+	if !roots.AppendCertsFromPEM(data[CAKey]) {
+		return false
+	}
+	for _, name := range dnsNames {
+		_, err := cert.Verify(x509.VerifyOptions{
+			DNSName:     name,
+			Roots:       roots,
+			CurrentTime: time.Now().Add(renewBefore),
+		})
+		if err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// Generate returns a new CA and a serving certificate for dnsNames as Secret data
+func Generate(dnsNames []string) (map[string][]byte, error) {
+	now := time.Now()
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "composition-webhook-ca"},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(validity),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	if err != nil {
+		return nil, fmt.Errorf("creating CA certificate: %w", err)
+	}
+	caCert, err := x509.ParseCertificate(caDER)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: dnsNames[0]},
+		DNSNames:     dnsNames,
+		NotBefore:    now.Add(-time.Hour),
+		NotAfter:     now.Add(validity),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	certDER, err := x509.CreateCertificate(rand.Reader, template, caCert, &key.PublicKey, caKey)
+	if err != nil {
+		return nil, fmt.Errorf("creating serving certificate: %w", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string][]byte{
+		CAKey:                   pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: caDER}),
+		corev1.TLSCertKey:       pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certDER}),
+		corev1.TLSPrivateKeyKey: pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}),
+	}, nil
+}
+
+func parseCertificate(b []byte) (*x509.Certificate, error) {
+	block, _ := pem.Decode(b)
+	if block == nil || block.Type != "CERTIFICATE" {
+		return nil, fmt.Errorf("no PEM certificate found")
+	}
+	return x509.ParseCertificate(block.Bytes)
+}
+
+// InjectCABundle sets the caBundle of all webhooks of the ValidatingWebhookConfiguration.
+// A missing configuration is not an error, the webhooks may not be installed.
+func InjectCABundle(ctx context.Context, c client.Client, name string, ca []byte) error {
+	config := &admissionregistrationv1.ValidatingWebhookConfiguration{}
+	if err := c.Get(ctx, types.NamespacedName{Name: name}, config); err != nil {
+		return client.IgnoreNotFound(err)
+	}
+	changed := false
+	for i := range config.Webhooks {
+		if !bytes.Equal(config.Webhooks[i].ClientConfig.CABundle, ca) {
+			config.Webhooks[i].ClientConfig.CABundle = ca
+			changed = true
+		}
+	}
+	if !changed {
+		return nil
+	}
+	if err := c.Update(ctx, config); err != nil {
+		return fmt.Errorf("setting caBundle of ValidatingWebhookConfiguration %s: %w", name, err)
+	}
+	return nil
+}
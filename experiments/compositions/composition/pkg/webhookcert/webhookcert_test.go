@@ -0,0 +1,124 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package webhookcert
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestGenerate(t *testing.T) {
+	dnsNames := DNSNames("webhook-service", "composition-system")
+	data, err := Generate(dnsNames)
+	if err != nil {
+		t.Fatalf("Generate() failed: %v", err)
+	}
+	if !valid(data, dnsNames) {
+		t.Errorf("generated certificate is not valid for %v", dnsNames)
+	}
+	if valid(data, DNSNames("other-service", "composition-system")) {
+		t.Errorf("generated certificate is valid for another service")
+	}
+
+	other, err := Generate(dnsNames)
+	if err != nil {
+		t.Fatalf("Generate() failed: %v", err)
+	}
+	data[CAKey] = other[CAKey]
+	if valid(data, dnsNames) {
+		t.Errorf("certificate is valid with a different CA")
+	}
+}
+
+func TestEnsure(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := corev1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := admissionregistrationv1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	config := &admissionregistrationv1.ValidatingWebhookConfiguration{
+		ObjectMeta: metav1.ObjectMeta{Name: "composition-validating-webhook-configuration"},
+		Webhooks: []admissionregistrationv1.ValidatingWebhook{
+			{Name: "vcomposition.composition.google.com"},
+		},
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(config).Build()
+
+	opts := Options{
+		Client:                c,
+		Namespace:             "composition-system",
+		SecretName:            "composition-webhook-server-cert",
+		ServiceName:           "composition-webhook-service",
+		CertDir:               t.TempDir(),
+		WebhookConfigurations: []string{config.Name, "not-installed"},
+	}
+	ca, err := Ensure(context.Background(), opts)
+	if err != nil {
+		t.Fatalf("Ensure() failed: %v", err)
+	}
+
+	secret := &corev1.Secret{}
+	if err := c.Get(context.Background(), types.NamespacedName{Namespace: opts.Namespace, Name: opts.SecretName}, secret); err != nil {
+		t.Fatalf("want Secret: %v", err)
+	}
+	if !bytes.Equal(secret.Data[CAKey], ca) {
+		t.Errorf("returned CA does not match the Secret")
+	}
+	for _, key := range []string{corev1.TLSCertKey, corev1.TLSPrivateKeyKey} {
+		b, err := os.ReadFile(filepath.Join(opts.CertDir, key))
+		if err != nil || !bytes.Equal(b, secret.Data[key]) {
+			t.Errorf("%s not written to cert dir: %v", key, err)
+		}
+	}
+	if err := c.Get(context.Background(), client.ObjectKeyFromObject(config), config); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(config.Webhooks[0].ClientConfig.CABundle, ca) {
+		t.Errorf("caBundle not injected")
+	}
+
+	// A second replica reuses the certificate
+	opts.CertDir = t.TempDir()
+	again, err := Ensure(context.Background(), opts)
+	if err != nil {
+		t.Fatalf("Ensure() failed: %v", err)
+	}
+	if !bytes.Equal(ca, again) {
+		t.Errorf("want the CA to be reused")
+	}
+
+	// A certificate for another service is replaced
+	opts.ServiceName = "renamed-service"
+	renewed, err := Ensure(context.Background(), opts)
+	if err != nil {
+		t.Fatalf("Ensure() failed: %v", err)
+	}
+	if bytes.Equal(ca, renewed) {
+		t.Errorf("want a new CA for a renamed service")
+	}
+}
@@ -0,0 +1,310 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main_test
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	pb "github.com/cloud-native-compositions/compositions/composition/proto"
+)
+
+// resourcesConfig is a CELConfiguration with the given resources
+func resourcesConfig(resources string) string {
+	return fmt.Sprintf(`apiVersion: composition.google.com/v1alpha1
+kind: CELConfiguration
+metadata:
+  name: foobar
+  namespace: config-control
+spec:
+  resources:
+%s`, resources)
+}
+
+const portsFacade = `apiVersion: facade.foobar.com/v1alpha1
+kind: Foo
+metadata:
+  name: appteam-sample
+  namespace: default
+spec:
+  foo: bar
+  car: sedan
+  ports:
+  - name: http
+    port: 80
+  - name: https
+    port: 443
+  environments: [dev, prod]
+`
+
+func TestEvaluateIncludeWhenForEach(t *testing.T) {
+	testcases := []struct {
+		name      string
+		resources string
+		manifests string
+		err       string
+	}{
+		{
+			name: "includeWhen",
+			resources: `  - name: included
+    includeWhen:
+    - ${sqls.spec.car == 'sedan'}
+    - has(sqls.spec.foo)
+    definition:
+      apiVersion: v1
+      kind: ConfigMap
+      metadata:
+        name: included
+  - name: excluded
+    includeWhen: ["sqls.spec.car == 'truck'"]
+    definition:
+      apiVersion: v1
+      kind: ConfigMap
+      metadata:
+        name: excluded
+`,
+			manifests: `
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: included
+`,
+		},
+		{
+			name: "includeWhen not a bool",
+			resources: `  - name: configmap
+    includeWhen: [sqls.spec.car]
+    definition:
+      apiVersion: v1
+      kind: ConfigMap
+      metadata:
+        name: demo
+`,
+			err: "includeWhen of configmap: sqls.spec.car: want a bool, got string",
+		},
+		{
+			name: "forEach",
+			resources: `  - name: services
+    forEach:
+      expression: ${sqls.spec.ports}
+      iterator: port
+    includeWhen: ["port.port != 443 || sqls.spec.car == 'sedan'"]
+    definition:
+      apiVersion: v1
+      kind: Service
+      metadata:
+        name: ${sqls.metadata.name}-${port.name}
+      spec:
+        ports:
+        - port: ${port.port}
+`,
+			manifests: `
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: appteam-sample-http
+spec:
+  ports:
+  - port: 80
+
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: appteam-sample-https
+spec:
+  ports:
+  - port: 443
+`,
+		},
+		{
+			name: "forEach name without iterator",
+			resources: `  - name: buckets
+    forEach:
+      expression: sqls.spec.environments
+      iterator: env
+    definition:
+      apiVersion: v1
+      kind: ConfigMap
+      metadata:
+        name: bucket
+      data:
+        environment: ${env}
+`,
+			manifests: `
+---
+apiVersion: v1
+data:
+  environment: dev
+kind: ConfigMap
+metadata:
+  name: bucket-8a17302d
+
+---
+apiVersion: v1
+data:
+  environment: prod
+kind: ConfigMap
+metadata:
+  name: bucket-4120bf0e
+`,
+		},
+		{
+			name: "forEach same names",
+			resources: `  - name: buckets
+    forEach:
+      expression: "[1, 2]"
+      iterator: i
+    definition:
+      apiVersion: v1
+      kind: ConfigMap
+      metadata:
+        name: ${string(i % 1)}
+`,
+			err: "forEach of buckets: items 0 and 1 have the same name 0",
+		},
+		{
+			name: "forEach not a list",
+			resources: `  - name: buckets
+    forEach:
+      expression: sqls.spec.car
+      iterator: i
+    definition:
+      apiVersion: v1
+      kind: ConfigMap
+      metadata:
+        name: bucket
+`,
+			err: "forEach of buckets: want a list, got string",
+		},
+		{
+			name: "forEach iterator",
+			resources: `  - name: buckets
+    forEach:
+      expression: sqls.spec.environments
+      iterator: fetched
+    definition:
+      apiVersion: v1
+      kind: ConfigMap
+      metadata:
+        name: bucket
+`,
+			err: `forEach.iterator "fetched" is already a variable`,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			r, err := expanderClient.Evaluate(context.Background(),
+				&pb.EvaluateRequest{
+					Resource: "sqls",
+					Config:   configFrom(t, resourcesConfig(tc.resources)),
+					Context:  testContext(t),
+					Facade:   testFacade(t, portsFacade),
+					Value:    dummyValues(t),
+				})
+			if tc.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.err) {
+					t.Fatalf("want error %q, got %v", tc.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if r.GetStatus() != pb.Status_SUCCESS {
+				t.Fatalf("want SUCCESS, got: %s", r)
+			}
+			if string(r.Manifests) != tc.manifests {
+				t.Errorf("\nwant:\n%s\ngot:\n%s", tc.manifests, r.Manifests)
+			}
+		})
+	}
+}
+
+func TestValidateIncludeWhenForEach(t *testing.T) {
+	testcases := []struct {
+		name      string
+		resources string
+		err       string
+	}{
+		{
+			name: "valid",
+			resources: `  - name: services
+    forEach:
+      expression: sqls.spec.ports
+      iterator: port
+    includeWhen: [port.port > 0]
+    definition:
+      metadata:
+        name: ${port.name}
+`,
+		},
+		{
+			name: "iterator name",
+			resources: `  - name: services
+    forEach:
+      expression: sqls.spec.ports
+      iterator: a-port
+    definition:
+      metadata:
+        name: svc
+`,
+			err: `forEach.iterator "a-port" is not a valid variable name`,
+		},
+		{
+			name: "undeclared variable",
+			resources: `  - name: services
+    includeWhen: [port.port > 0]
+    definition:
+      metadata:
+        name: svc
+`,
+			err: "services: includeWhen[0]: port.port > 0: ERROR: <input>:1:1: undeclared reference to 'port'",
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			r, err := expanderClient.Validate(context.Background(),
+				&pb.ValidateRequest{
+					Config:   configFrom(t, resourcesConfig(tc.resources)),
+					Resource: "sqls",
+					Context:  testContext(t),
+					Value:    dummyValues(t),
+				})
+			if tc.err == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if r.GetStatus() != pb.Status_SUCCESS {
+					t.Fatalf("want SUCCESS, got: %s", r)
+				}
+				return
+			}
+			if err != nil {
+				if !strings.Contains(err.Error(), tc.err) {
+					t.Fatalf("want error %q, got %v", tc.err, err)
+				}
+				return
+			}
+			if r.GetStatus() != pb.Status_VALIDATE_FAILED || !strings.Contains(r.Error.Message, tc.err) {
+				t.Fatalf("want VALIDATE_FAILED with %q, got: %s", tc.err, r)
+			}
+		})
+	}
+}
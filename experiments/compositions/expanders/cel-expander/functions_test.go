@@ -0,0 +1,70 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main_test
+
+import (
+	"context"
+	"testing"
+
+	pb "github.com/cloud-native-compositions/compositions/composition/proto"
+)
+
+func TestEvaluateFunctions(t *testing.T) {
+	resources := `  - name: secret
+    definition:
+      apiVersion: v1
+      kind: Secret
+      metadata:
+        name: ${dnsLabel(sqls.metadata.name + '_' + sqls.spec.car)}
+      data:
+        car: ${base64.encode(sqls.spec.car)}
+      stringData:
+        memory: ${quantity.add('1Gi', '512Mi')}
+        replicas: ${string(sqls.spec.?replicas.orValue(1))}
+        tier: ${default(sqls.spec.tier, 'basic')}
+        ports: "${'%s=%d'.format([sqls.spec.ports[0].name, sqls.spec.ports[0].port])}"
+`
+	r, err := expanderClient.Evaluate(context.Background(),
+		&pb.EvaluateRequest{
+			Resource: "sqls",
+			Config:   configFrom(t, resourcesConfig(resources)),
+			Context:  testContext(t),
+			Facade:   testFacade(t, portsFacade),
+			Value:    dummyValues(t),
+		})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if r.GetStatus() != pb.Status_SUCCESS {
+		t.Fatalf("want SUCCESS, got: %s", r)
+	}
+	want := `
+---
+apiVersion: v1
+data:
+  car: c2VkYW4=
+kind: Secret
+metadata:
+  name: appteam-sample-sedan
+stringData:
+  memory: 1536Mi
+  ports: http=80
+  replicas: "1"
+  tier: basic
+`
+	if string(r.Manifests) != want {
+		t.Errorf("\nwant:\n%s\ngot:\n%s", want, r.Manifests)
+	}
+}
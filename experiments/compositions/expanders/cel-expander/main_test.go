@@ -25,6 +25,7 @@ import (
 	"time"
 
 	pb "github.com/cloud-native-compositions/compositions/composition/proto"
+	pbv2 "github.com/cloud-native-compositions/compositions/composition/proto/v2"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials/insecure"
 	"sigs.k8s.io/yaml"
@@ -119,7 +120,10 @@ spec:
 `
 )
 
-var expanderClient pb.ExpanderClient
+var (
+	expanderClient   pb.ExpanderClient
+	expanderClientV2 pbv2.ExpanderClient
+)
 
 func dummyValues(t *testing.T) []byte {
 	y := `foo: bar
@@ -187,6 +191,7 @@ func TestMain(m *testing.M) {
 		log.Fatalf("did not connect: %v", err)
 	}
 	expanderClient = pb.NewExpanderClient(conn)
+	expanderClientV2 = pbv2.NewExpanderClient(conn)
 
 	exitCode := m.Run()
 	os.Exit(exitCode)
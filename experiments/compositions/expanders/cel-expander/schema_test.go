@@ -0,0 +1,178 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	pbv2 "github.com/cloud-native-compositions/compositions/composition/proto/v2"
+)
+
+// sqlsSchema is the schema of the facade CRD
+const sqlsSchema = `type: object
+properties:
+  apiVersion:
+    type: string
+  kind:
+    type: string
+  metadata:
+    type: object
+  spec:
+    type: object
+    properties:
+      foo:
+        type: string
+      car:
+        type: string
+      replicas:
+        type: integer
+      labels:
+        type: object
+        additionalProperties:
+          type: string
+      ports:
+        type: array
+        items:
+          type: object
+          properties:
+            name:
+              type: string
+            port:
+              type: integer
+      extra:
+        type: object
+        x-kubernetes-preserve-unknown-fields: true
+  status:
+    type: object
+    x-kubernetes-preserve-unknown-fields: true
+`
+
+func TestValidateFacadeSchema(t *testing.T) {
+	testcases := []struct {
+		name      string
+		resources string
+		codes     []string
+		messages  []string
+	}{
+		{
+			name: "valid",
+			resources: `  - name: services
+    forEach:
+      expression: ${sqls.spec.ports}
+      iterator: port
+    includeWhen:
+    - ${port.port > 0 && sqls.spec.replicas > 1}
+    definition:
+      metadata:
+        name: ${sqls.metadata.name}-${port.name}
+        namespace: ${sqls.metadata.namespace}
+        labels: ${sqls.spec.labels}
+      spec:
+        ports: ${[port]}
+        extra: ${sqls.spec.extra.anything}
+        replicas: replicas ${sqls.spec.replicas}
+`,
+		},
+		{
+			name: "unknown fields",
+			resources: `  - name: configmap
+    definition:
+      metadata:
+        name: ${sqls.spec.fo}
+      data:
+        port: ${sqls.spec.ports[0].prot}
+  - name: services
+    forEach:
+      expression: sqls.spec.ports
+      iterator: port
+    definition:
+      metadata:
+        name: ${port.nmae}
+`,
+			codes: []string{"UnknownField", "UnknownField", "UnknownField"},
+			messages: []string{
+				"configmap: data.port: ${sqls.spec.ports[0].prot}: ERROR: <input>:1:19: undefined field 'prot'",
+				"configmap: metadata.name: ${sqls.spec.fo}: ERROR: <input>:1:10: undefined field 'fo'",
+				"services: metadata.name: ${port.nmae}: ERROR: <input>:1:5: undefined field 'nmae'",
+			},
+		},
+		{
+			name: "type mismatches",
+			resources: `  - name: configmap
+    includeWhen: [sqls.spec.car]
+    forEach:
+      expression: sqls.spec.replicas
+      iterator: i
+    definition:
+      metadata:
+        name: ${sqls.spec.car + 1}
+      data:
+        labels: labels ${sqls.spec.labels}
+`,
+			codes: []string{"TypeMismatch", "TypeMismatch", "TypeMismatch", "TypeMismatch"},
+			messages: []string{
+				"configmap: forEach.expression: sqls.spec.replicas: want a list, got int",
+				"configmap: includeWhen[0]: sqls.spec.car: want a bool, got string",
+				"configmap: data.labels: ${sqls.spec.labels}: a map or list can only be the whole field, not part of a string",
+				"configmap: metadata.name: ${sqls.spec.car + 1}: ERROR: <input>:1:15: found no matching overload for '_+_' applied to '(string, int)'",
+			},
+		},
+		{
+			name: "syntax error",
+			resources: `  - name: configmap
+    definition:
+      metadata:
+        name: ${sqls.spec.car +}
+`,
+			codes:    []string{"ExpressionSyntaxError"},
+			messages: []string{"configmap: metadata.name: ${sqls.spec.car +}: ERROR: <input>:1:16: Syntax error"},
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			r, err := expanderClientV2.Validate(context.Background(),
+				&pbv2.ValidateRequest{
+					Config:       configFrom(t, resourcesConfig(tc.resources)),
+					Resource:     "sqls",
+					FacadeSchema: configFrom(t, sqlsSchema),
+				})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(tc.messages) == 0 {
+				if r.GetStatus() != pbv2.Status_SUCCESS {
+					t.Fatalf("want SUCCESS, got: %s", r)
+				}
+				return
+			}
+			if r.GetStatus() != pbv2.Status_VALIDATE_FAILED {
+				t.Fatalf("want VALIDATE_FAILED, got: %s", r)
+			}
+			if len(r.Diagnostics) != len(tc.messages) {
+				t.Fatalf("want %d diagnostics, got: %s", len(tc.messages), r.Diagnostics)
+			}
+			for i, d := range r.Diagnostics {
+				if d.Code != tc.codes[i] {
+					t.Errorf("want code %s, got: %s", tc.codes[i], d)
+				}
+				if !strings.HasPrefix(d.Message, tc.messages[i]) {
+					t.Errorf("\nwant message starting with: %s\ngot: %s", tc.messages[i], d.Message)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,75 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// File is a CUE file
+type File struct {
+	Name    string `json:"name"`
+	Content string `json:"content"`
+}
+
+// Package is a CUE package other packages can import
+type Package struct {
+	// ImportPath is the path the package is imported with, for example example.com/apps
+	ImportPath string `json:"importPath"`
+	Files      []File `json:"files"`
+}
+
+// CUEConfigurationSpec defines the desired state of CUEConfiguration
+type CUEConfigurationSpec struct {
+	// Files of the main package. The facade, the context and the fetched
+	// values are unified into its facade, context and values fields.
+	Files []File `json:"files"`
+	// Packages the main package and the other packages can import
+	Packages []Package `json:"packages,omitempty"`
+	// OutputPath is the path of the objects in the main package
+	// +kubebuilder:default=objects
+	OutputPath string `json:"outputPath,omitempty"`
+}
+
+// CUEConfigurationStatus defines the observed state of CUEConfiguration
+type CUEConfigurationStatus struct {
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+
+// CUEConfiguration is the Schema for the cueconfigurations API
+type CUEConfiguration struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   CUEConfigurationSpec   `json:"spec,omitempty"`
+	Status CUEConfigurationStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// CUEConfigurationList contains a list of CUEConfiguration
+type CUEConfigurationList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []CUEConfiguration `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&CUEConfiguration{}, &CUEConfigurationList{})
+}
@@ -0,0 +1,27 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main_test
+
+import (
+	"testing"
+
+	"github.com/cloud-native-compositions/compositions/composition/pkg/expandersdk/expandertest"
+	"github.com/cloud-native-compositions/compositions/expander/cue-expander/pkg/expander"
+)
+
+// TestGolden runs the cases in testdata, in-process or against -addr
+func TestGolden(t *testing.T) {
+	expandertest.Run(t, expander.New(), "testdata")
+}
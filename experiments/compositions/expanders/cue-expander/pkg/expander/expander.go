@@ -0,0 +1,271 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package expander implements the CUE expander.
+// The packages of the configuration are built in memory with the CUE API, so
+// no cue binary, no module registry and no network access is needed.
+package expander
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+
+	"cuelang.org/go/cue"
+	"cuelang.org/go/cue/build"
+	"cuelang.org/go/cue/cuecontext"
+	"cuelang.org/go/cue/errors"
+	"cuelang.org/go/cue/parser"
+	"github.com/cloud-native-compositions/compositions/composition/pkg/expandersdk"
+	cueconfigurationv1alpha1 "github.com/cloud-native-compositions/compositions/expander/cue-expander/api/v1alpha1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+const (
+	// FacadePath, ContextPath and ValuesPath are the fields of the main package
+	// the facade, the Context object and the fetched values are unified into
+	FacadePath  = "facade"
+	ContextPath = "context"
+	ValuesPath  = "values"
+
+	// DefaultOutputPath is the path of the objects if spec.outputPath is not set
+	DefaultOutputPath = "objects"
+
+	// InputsFile is added to the main package. It declares the facade, context
+	// and values fields, so that the files can reference them without doing so.
+	InputsFile = "composition_inputs.cue"
+)
+
+type Request = expandersdk.Request[cueconfigurationv1alpha1.CUEConfiguration]
+
+// Build builds the main package of the configuration. Every package can
+// import the others by their import path.
+func Build(req *Request) (cue.Value, error) {
+	spec := req.Config.Spec
+	if len(spec.Files) == 0 {
+		return cue.Value{}, expandersdk.Failed(expandersdk.ErrorDiagnostic("MissingFiles", "spec.files: the main package has no files"))
+	}
+
+	ctx := build.NewContext()
+	diagnostics := []*expandersdk.Diagnostic{}
+	packages := []*build.Instance{}
+	importPaths := map[string]bool{}
+	for i, p := range spec.Packages {
+		if p.ImportPath == "" || importPaths[p.ImportPath] {
+			diagnostics = append(diagnostics, expandersdk.ErrorDiagnostic("InvalidImportPath", "spec.packages[%d].importPath: missing or duplicate import path %q", i, p.ImportPath))
+			continue
+		}
+		importPaths[p.ImportPath] = true
+		inst := ctx.NewInstance(p.ImportPath, nil)
+		inst.ImportPath = p.ImportPath
+		diagnostics = append(diagnostics, addFiles(inst, fmt.Sprintf("spec.packages[%d].files", i), p.ImportPath, p.Files)...)
+		packages = append(packages, inst)
+	}
+	main := ctx.NewInstance("", nil)
+	main.ImportPath = "main"
+	diagnostics = append(diagnostics, addFiles(main, "spec.files", "", spec.Files)...)
+	if len(diagnostics) != 0 {
+		return cue.Value{}, expandersdk.Failed(diagnostics...)
+	}
+	inputs := fmt.Sprintf("%s: _\n%s: _\n%s: _\n", FacadePath, ContextPath, ValuesPath)
+	if main.PkgName != "" {
+		inputs = fmt.Sprintf("package %s\n\n%s", main.PkgName, inputs)
+	}
+	file, err := parser.ParseFile(InputsFile, inputs)
+	if err != nil {
+		return cue.Value{}, fmt.Errorf("failed to parse %s: %w", InputsFile, err)
+	}
+	if err := main.AddSyntax(file); err != nil {
+		return cue.Value{}, fmt.Errorf("failed to add %s: %w", InputsFile, err)
+	}
+
+	for _, inst := range append(packages, main) {
+		for _, p := range packages {
+			if p != inst {
+				inst.Imports = append(inst.Imports, p)
+			}
+		}
+	}
+	v := cuecontext.New().BuildInstance(main)
+	if err := v.Err(); err != nil {
+		return cue.Value{}, expandersdk.Failed(cueDiagnostics("CUEBuildError", err)...)
+	}
+	return v, nil
+}
+
+// addFiles parses the files of a package, they are named <import path>/<name> in errors
+func addFiles(inst *build.Instance, field string, dir string, files []cueconfigurationv1alpha1.File) []*expandersdk.Diagnostic {
+	diagnostics := []*expandersdk.Diagnostic{}
+	for i, f := range files {
+		if f.Name == "" {
+			diagnostics = append(diagnostics, expandersdk.ErrorDiagnostic("InvalidFileName", "%s[%d].name: a name is required", field, i))
+			continue
+		}
+		file, err := parser.ParseFile(path.Join(dir, f.Name), f.Content)
+		if err != nil {
+			diagnostics = append(diagnostics, cueDiagnostics("CUESyntaxError", err)...)
+			continue
+		}
+		if err := inst.AddSyntax(file); err != nil {
+			diagnostics = append(diagnostics, cueDiagnostics("CUESyntaxError", err)...)
+		}
+	}
+	return diagnostics
+}
+
+// cueDiagnostics returns a diagnostic per CUE error, located at the first
+// position CUE reports. Errors in the facade have the facade field path.
+func cueDiagnostics(code string, err error) []*expandersdk.Diagnostic {
+	diagnostics := []*expandersdk.Diagnostic{}
+	for _, e := range errors.Errors(errors.Sanitize(errors.Promote(err, ""))) {
+		d := expandersdk.ErrorDiagnostic(code, "%s", strings.TrimSpace(errors.Details(e, nil)))
+		if pos := e.Position(); pos.IsValid() {
+			d.File, d.Line, d.Column = pos.Filename(), pos.Line(), pos.Column()
+		}
+		if p := e.Path(); len(p) > 1 && p[0] == FacadePath {
+			d.FieldPath = strings.Join(p[1:], ".")
+		}
+		diagnostics = append(diagnostics, d)
+	}
+	return diagnostics
+}
+
+// unify fills the facade, the Context object and the fetched values the
+// request has into the main package and checks the result for conflicts
+func unify(v cue.Value, req *Request) (cue.Value, error) {
+	if req.Facade != nil {
+		v = v.FillPath(cue.ParsePath(FacadePath), req.Facade.Object)
+	}
+	if req.Context != nil {
+		v = v.FillPath(cue.ParsePath(ContextPath), req.Context.Object)
+	}
+	if req.Values != nil {
+		v = v.FillPath(cue.ParsePath(ValuesPath), req.Values)
+	}
+	// The facade first, so its conflicts are not reported again by every field using it
+	for _, p := range []string{FacadePath, ""} {
+		if err := v.LookupPath(cue.ParsePath(p)).Validate(); err != nil {
+			return cue.Value{}, expandersdk.Failed(cueDiagnostics("CUEConstraintError", err)...)
+		}
+	}
+	return v, nil
+}
+
+// output returns the value at the output path
+func output(v cue.Value, req *Request) (cue.Value, error) {
+	outputPath := req.Config.Spec.OutputPath
+	if outputPath == "" {
+		outputPath = DefaultOutputPath
+	}
+	p := cue.ParsePath(outputPath)
+	if err := p.Err(); err != nil {
+		return cue.Value{}, expandersdk.Failed(expandersdk.ErrorDiagnostic("InvalidOutputPath", "spec.outputPath: %v", err))
+	}
+	out := v.LookupPath(p)
+	if !out.Exists() {
+		return cue.Value{}, expandersdk.Failed(expandersdk.ErrorDiagnostic("MissingOutput", "the main package has no %s field", outputPath))
+	}
+	return out, nil
+}
+
+// objects collects the objects in the output. Lists are walked in order, and
+// structs that are not kubernetes objects in field order.
+func objects(v cue.Value, out *[]*unstructured.Unstructured) error {
+	switch v.Kind() {
+	case cue.NullKind:
+		return nil
+	case cue.ListKind:
+		items, err := v.List()
+		if err != nil {
+			return err
+		}
+		for items.Next() {
+			if err := objects(items.Value(), out); err != nil {
+				return err
+			}
+		}
+		return nil
+	case cue.StructKind:
+		if v.LookupPath(cue.ParsePath("apiVersion")).Exists() && v.LookupPath(cue.ParsePath("kind")).Exists() {
+			obj := map[string]interface{}{}
+			if err := v.Decode(&obj); err != nil {
+				return expandersdk.Failed(cueDiagnostics("InvalidOutput", err)...)
+			}
+			*out = append(*out, &unstructured.Unstructured{Object: obj})
+			return nil
+		}
+		fields, err := v.Fields()
+		if err != nil {
+			return err
+		}
+		for fields.Next() {
+			if err := objects(fields.Value(), out); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return expandersdk.Failed(expandersdk.ErrorDiagnostic("InvalidOutput", "%s: want a struct or a list, got %s", v.Path(), v.Kind()))
+}
+
+// Validate builds the packages, unifies the inputs the request has and checks
+// the output path exists. Fields that are not concrete yet are not errors.
+func Validate(ctx context.Context, req *Request) (*expandersdk.Result, error) {
+	v, err := Build(req)
+	if err != nil {
+		return nil, err
+	}
+	v, err = unify(v, req)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := output(v, req); err != nil {
+		return nil, err
+	}
+	return nil, nil
+}
+
+func Evaluate(ctx context.Context, req *Request) (*expandersdk.Result, error) {
+	v, err := Build(req)
+	if err != nil {
+		return nil, err
+	}
+	v, err = unify(v, req)
+	if err != nil {
+		return nil, err
+	}
+	out, err := output(v, req)
+	if err != nil {
+		return nil, err
+	}
+	if err := out.Validate(cue.Concrete(true)); err != nil {
+		return nil, expandersdk.Failed(cueDiagnostics("CUEIncompleteError", err)...)
+	}
+	result := []*unstructured.Unstructured{}
+	if err := objects(out, &result); err != nil {
+		return nil, err
+	}
+	return &expandersdk.Result{Objects: result}, nil
+}
+
+// New returns the CUE expander
+func New() *expandersdk.Expander[cueconfigurationv1alpha1.CUEConfiguration] {
+	return &expandersdk.Expander[cueconfigurationv1alpha1.CUEConfiguration]{
+		Name:     "cue",
+		Config:   cueconfigurationv1alpha1.GroupVersion.WithKind("CUEConfiguration"),
+		Validate: Validate,
+		Evaluate: Evaluate,
+	}
+}
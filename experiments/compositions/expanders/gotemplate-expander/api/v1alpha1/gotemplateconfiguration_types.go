@@ -0,0 +1,66 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Template is a named Go text/template https://pkg.go.dev/text/template
+type Template struct {
+	// Name is used by the template and include functions and in errors
+	Name     string `json:"name"`
+	Template string `json:"template"`
+}
+
+// GoTemplateConfigurationSpec defines the desired state of GoTemplateConfiguration
+type GoTemplateConfigurationSpec struct {
+	// Templates are executed in order. Each one renders a YAML stream of objects.
+	Templates []Template `json:"templates,omitempty"`
+	// Partials are available to the templates and to each other through the
+	// template and include functions. They are not rendered by themselves.
+	Partials []Template `json:"partials,omitempty"`
+}
+
+// GoTemplateConfigurationStatus defines the observed state of GoTemplateConfiguration
+type GoTemplateConfigurationStatus struct {
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+
+// GoTemplateConfiguration is the Schema for the gotemplateconfigurations API
+type GoTemplateConfiguration struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   GoTemplateConfigurationSpec   `json:"spec,omitempty"`
+	Status GoTemplateConfigurationStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// GoTemplateConfigurationList contains a list of GoTemplateConfiguration
+type GoTemplateConfigurationList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []GoTemplateConfiguration `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&GoTemplateConfiguration{}, &GoTemplateConfigurationList{})
+}
@@ -5,11 +5,11 @@
 package v1alpha1
 
 import (
-	"k8s.io/apimachinery/pkg/runtime"
+	runtime "k8s.io/apimachinery/pkg/runtime"
 )
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *CELConfiguration) DeepCopyInto(out *CELConfiguration) {
+func (in *GoTemplateConfiguration) DeepCopyInto(out *GoTemplateConfiguration) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
@@ -17,18 +17,18 @@ func (in *CELConfiguration) DeepCopyInto(out *CELConfiguration) {
 	out.Status = in.Status
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CELConfiguration.
-func (in *CELConfiguration) DeepCopy() *CELConfiguration {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GoTemplateConfiguration.
+func (in *GoTemplateConfiguration) DeepCopy() *GoTemplateConfiguration {
 	if in == nil {
 		return nil
 	}
-	out := new(CELConfiguration)
+	out := new(GoTemplateConfiguration)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *CELConfiguration) DeepCopyObject() runtime.Object {
+func (in *GoTemplateConfiguration) DeepCopyObject() runtime.Object {
 	if c := in.DeepCopy(); c != nil {
 		return c
 	}
@@ -36,31 +36,31 @@ func (in *CELConfiguration) DeepCopyObject() runtime.Object {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *CELConfigurationList) DeepCopyInto(out *CELConfigurationList) {
+func (in *GoTemplateConfigurationList) DeepCopyInto(out *GoTemplateConfigurationList) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ListMeta.DeepCopyInto(&out.ListMeta)
 	if in.Items != nil {
 		in, out := &in.Items, &out.Items
-		*out = make([]CELConfiguration, len(*in))
+		*out = make([]GoTemplateConfiguration, len(*in))
 		for i := range *in {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CELConfigurationList.
-func (in *CELConfigurationList) DeepCopy() *CELConfigurationList {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GoTemplateConfigurationList.
+func (in *GoTemplateConfigurationList) DeepCopy() *GoTemplateConfigurationList {
 	if in == nil {
 		return nil
 	}
-	out := new(CELConfigurationList)
+	out := new(GoTemplateConfigurationList)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *CELConfigurationList) DeepCopyObject() runtime.Object {
+func (in *GoTemplateConfigurationList) DeepCopyObject() runtime.Object {
 	if c := in.DeepCopy(); c != nil {
 		return c
 	}
@@ -68,54 +68,56 @@ func (in *CELConfigurationList) DeepCopyObject() runtime.Object {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *CELConfigurationSpec) DeepCopyInto(out *CELConfigurationSpec) {
+func (in *GoTemplateConfigurationSpec) DeepCopyInto(out *GoTemplateConfigurationSpec) {
 	*out = *in
-	if in.Resources != nil {
-		in, out := &in.Resources, &out.Resources
-		*out = make([]Resource, len(*in))
-		for i := range *in {
-			(*in)[i].DeepCopyInto(&(*out)[i])
-		}
+	if in.Templates != nil {
+		in, out := &in.Templates, &out.Templates
+		*out = make([]Template, len(*in))
+		copy(*out, *in)
+	}
+	if in.Partials != nil {
+		in, out := &in.Partials, &out.Partials
+		*out = make([]Template, len(*in))
+		copy(*out, *in)
 	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CELConfigurationSpec.
-func (in *CELConfigurationSpec) DeepCopy() *CELConfigurationSpec {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GoTemplateConfigurationSpec.
+func (in *GoTemplateConfigurationSpec) DeepCopy() *GoTemplateConfigurationSpec {
 	if in == nil {
 		return nil
 	}
-	out := new(CELConfigurationSpec)
+	out := new(GoTemplateConfigurationSpec)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *CELConfigurationStatus) DeepCopyInto(out *CELConfigurationStatus) {
+func (in *GoTemplateConfigurationStatus) DeepCopyInto(out *GoTemplateConfigurationStatus) {
 	*out = *in
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CELConfigurationStatus.
-func (in *CELConfigurationStatus) DeepCopy() *CELConfigurationStatus {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GoTemplateConfigurationStatus.
+func (in *GoTemplateConfigurationStatus) DeepCopy() *GoTemplateConfigurationStatus {
 	if in == nil {
 		return nil
 	}
-	out := new(CELConfigurationStatus)
+	out := new(GoTemplateConfigurationStatus)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *Resource) DeepCopyInto(out *Resource) {
+func (in *Template) DeepCopyInto(out *Template) {
 	*out = *in
-	in.Definition.DeepCopyInto(&out.Definition)
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Resource.
-func (in *Resource) DeepCopy() *Resource {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Template.
+func (in *Template) DeepCopy() *Template {
 	if in == nil {
 		return nil
 	}
-	out := new(Resource)
+	out := new(Template)
 	in.DeepCopyInto(out)
 	return out
 }
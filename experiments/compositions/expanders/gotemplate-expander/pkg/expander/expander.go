@@ -0,0 +1,267 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package expander implements the Go template expander.
+// Templates are executed with text/template and the sprig functions, so no
+// template binary and no temporary files are needed.
+package expander
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/template"
+	"text/template/parse"
+
+	"github.com/Masterminds/sprig/v3"
+	"github.com/cloud-native-compositions/compositions/composition/pkg/expandersdk"
+	gotemplateconfigurationv1alpha1 "github.com/cloud-native-compositions/compositions/expander/gotemplate-expander/api/v1alpha1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/kubebuilder-declarative-pattern/pkg/patterns/declarative/pkg/manifest"
+	"sigs.k8s.io/yaml"
+)
+
+type Request = expandersdk.Request[gotemplateconfigurationv1alpha1.GoTemplateConfiguration]
+
+// errorLocation matches the template name, line and column text/template
+// prefixes its parse and execution errors with
+var errorLocation = regexp.MustCompile(`^template: (.*?):(\d+):(?:(\d+):)?`)
+
+// Expander holds the parsed templates and partials of a configuration
+type Expander struct {
+	root      *template.Template
+	templates []string
+}
+
+// NewExpander parses the templates and partials of the configuration. Missing
+// map keys are errors when the templates are executed.
+func NewExpander(req *Request) (*Expander, error) {
+	spec := req.Config.Spec
+	e := &Expander{}
+	e.root = template.New("").Funcs(e.funcs()).Option("missingkey=error")
+
+	names := map[string]bool{}
+	diagnostics := []*expandersdk.Diagnostic{}
+	parseAll := func(field string, templates []gotemplateconfigurationv1alpha1.Template) {
+		for i, t := range templates {
+			if t.Name == "" {
+				diagnostics = append(diagnostics, expandersdk.ErrorDiagnostic("InvalidTemplateName", "%s[%d].name: a name is required", field, i))
+				continue
+			}
+			if names[t.Name] {
+				diagnostics = append(diagnostics, expandersdk.ErrorDiagnostic("DuplicateTemplateName", "%s[%d].name: duplicate template %q", field, i, t.Name))
+				continue
+			}
+			names[t.Name] = true
+			if _, err := e.root.New(t.Name).Parse(t.Template); err != nil {
+				diagnostics = append(diagnostics, templateDiagnostic("TemplateSyntaxError", err))
+			}
+		}
+	}
+	parseAll("spec.partials", spec.Partials)
+	parseAll("spec.templates", spec.Templates)
+	if len(diagnostics) == 0 {
+		diagnostics = e.undefinedTemplates()
+	}
+	if len(diagnostics) != 0 {
+		return nil, expandersdk.Failed(diagnostics...)
+	}
+
+	for _, t := range spec.Templates {
+		e.templates = append(e.templates, t.Name)
+	}
+	return e, nil
+}
+
+// funcs returns the sprig functions, without the ones reading the environment
+// of the expander, and the helm style include, required, toYaml and fromYaml
+func (e *Expander) funcs() template.FuncMap {
+	funcs := sprig.TxtFuncMap()
+	delete(funcs, "env")
+	delete(funcs, "expandenv")
+
+	funcs["include"] = func(name string, data interface{}) (string, error) {
+		var buf bytes.Buffer
+		if err := e.root.ExecuteTemplate(&buf, name, data); err != nil {
+			return "", err
+		}
+		return buf.String(), nil
+	}
+	funcs["required"] = func(message string, value interface{}) (interface{}, error) {
+		if value == nil {
+			return nil, fmt.Errorf("%s", message)
+		}
+		if s, ok := value.(string); ok && s == "" {
+			return nil, fmt.Errorf("%s", message)
+		}
+		return value, nil
+	}
+	funcs["toYaml"] = func(value interface{}) (string, error) {
+		out, err := yaml.Marshal(value)
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimSuffix(string(out), "\n"), nil
+	}
+	funcs["fromYaml"] = func(s string) (map[string]interface{}, error) {
+		out := map[string]interface{}{}
+		if err := yaml.Unmarshal([]byte(s), &out); err != nil {
+			return nil, err
+		}
+		return out, nil
+	}
+	return funcs
+}
+
+// undefinedTemplates reports template actions calling a template that is
+// neither a template, a partial nor defined in one of them
+func (e *Expander) undefinedTemplates() []*expandersdk.Diagnostic {
+	diagnostics := []*expandersdk.Diagnostic{}
+	for _, t := range e.root.Templates() {
+		if t.Tree == nil {
+			continue
+		}
+		walk(t.Tree.Root, func(n *parse.TemplateNode) {
+			if e.root.Lookup(n.Name) != nil {
+				return
+			}
+			location, _ := t.Tree.ErrorContext(n)
+			d := expandersdk.ErrorDiagnostic("UndefinedTemplate", "template: %s: no template %q", location, n.Name)
+			d.File, d.Line, d.Column = splitLocation(location)
+			diagnostics = append(diagnostics, d)
+		})
+	}
+	return diagnostics
+}
+
+func walk(node parse.Node, visit func(*parse.TemplateNode)) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, child := range n.Nodes {
+			walk(child, visit)
+		}
+	case *parse.IfNode:
+		walk(n.List, visit)
+		walk(n.ElseList, visit)
+	case *parse.RangeNode:
+		walk(n.List, visit)
+		walk(n.ElseList, visit)
+	case *parse.WithNode:
+		walk(n.List, visit)
+		walk(n.ElseList, visit)
+	case *parse.TemplateNode:
+		visit(n)
+	}
+}
+
+// templateDiagnostic locates a text/template error in the template it occurred in
+func templateDiagnostic(code string, err error) *expandersdk.Diagnostic {
+	d := expandersdk.ErrorDiagnostic(code, "%v", err)
+	if m := errorLocation.FindStringSubmatch(err.Error()); m != nil {
+		d.File = m[1]
+		d.Line, _ = strconv.Atoi(m[2])
+		d.Column, _ = strconv.Atoi(m[3])
+	}
+	return d
+}
+
+// splitLocation splits the name:line:col location of a parse node
+func splitLocation(location string) (string, int, int) {
+	parts := strings.Split(location, ":")
+	if len(parts) < 3 {
+		return location, 0, 0
+	}
+	line, _ := strconv.Atoi(parts[len(parts)-2])
+	column, _ := strconv.Atoi(parts[len(parts)-1])
+	return strings.Join(parts[:len(parts)-2], ":"), line, column
+}
+
+// Data returns what the templates are executed with: the facade under the
+// resource name, the Context object under context and the fetched values under
+// values, as in the jinja2 expander, and under fetched, as in the CEL expander.
+func Data(req *Request) map[string]interface{} {
+	data := req.Inputs()
+	if _, ok := data["context"]; !ok {
+		data["context"] = map[string]interface{}{}
+	}
+	values := req.Values
+	if values == nil {
+		values = map[string]interface{}{}
+	}
+	data["values"] = values
+	data["fetched"] = values
+	return data
+}
+
+// Execute runs the templates in order and parses the objects they render
+func (e *Expander) Execute(data map[string]interface{}) ([]*unstructured.Unstructured, error) {
+	objects := []*unstructured.Unstructured{}
+	for _, name := range e.templates {
+		var buf bytes.Buffer
+		if err := e.root.ExecuteTemplate(&buf, name, data); err != nil {
+			return nil, expandersdk.Failed(templateDiagnostic("TemplateExecutionError", err))
+		}
+		parsed, err := manifest.ParseObjects(context.Background(), buf.String())
+		if err != nil {
+			d := expandersdk.ErrorDiagnostic("InvalidManifest", "template %s did not render a YAML stream of objects: %v", name, err)
+			d.File = name
+			return nil, expandersdk.Failed(d)
+		}
+		if len(parsed.Blobs) != 0 {
+			d := expandersdk.ErrorDiagnostic("InvalidManifest", "template %s rendered a document that is not an object: %s", name, strings.TrimSpace(string(parsed.Blobs[0])))
+			d.File = name
+			return nil, expandersdk.Failed(d)
+		}
+		for _, item := range parsed.Items {
+			objects = append(objects, item.UnstructuredObject())
+		}
+	}
+	return objects, nil
+}
+
+// Validate parses every template and partial and checks the templates they call exist
+func Validate(ctx context.Context, req *Request) (*expandersdk.Result, error) {
+	if _, err := NewExpander(req); err != nil {
+		return nil, err
+	}
+	return nil, nil
+}
+
+func Evaluate(ctx context.Context, req *Request) (*expandersdk.Result, error) {
+	e, err := NewExpander(req)
+	if err != nil {
+		return nil, err
+	}
+	objects, err := e.Execute(Data(req))
+	if err != nil {
+		return nil, err
+	}
+	return &expandersdk.Result{Objects: objects}, nil
+}
+
+// New returns the Go template expander
+func New() *expandersdk.Expander[gotemplateconfigurationv1alpha1.GoTemplateConfiguration] {
+	return &expandersdk.Expander[gotemplateconfigurationv1alpha1.GoTemplateConfiguration]{
+		Name:     "gotemplate",
+		Config:   gotemplateconfigurationv1alpha1.GroupVersion.WithKind("GoTemplateConfiguration"),
+		Validate: Validate,
+		Evaluate: Evaluate,
+	}
+}
@@ -27,17 +27,104 @@ type FileContent struct {
 	Template string               `json:"template,omitempty"`
 }
 
+// ArchiveReference is a key of a ConfigMap or Secret holding a packaged chart
+type ArchiveReference struct {
+	Name string `json:"name"`
+	// Namespace defaults to the namespace of the HelmConfiguration
+	Namespace string `json:"namespace,omitempty"`
+	// Key is the key of the .tgz in binaryData or data. Defaults to chart.tgz
+	Key string `json:"key,omitempty"`
+}
+
+// SecretReference is a Secret with the credentials of a registry or chart repository
+type SecretReference struct {
+	Name string `json:"name"`
+	// Namespace defaults to the namespace of the HelmConfiguration
+	Namespace string `json:"namespace,omitempty"`
+}
+
+// ChartReference locates a packaged chart. Exactly one of oci, repoURL,
+// configMap and secret is set.
+type ChartReference struct {
+	// OCI is a chart in an OCI registry, for example oci://ghcr.io/org/charts/app
+	OCI string `json:"oci,omitempty"`
+	// RepoURL is a chart repository serving an index.yaml
+	RepoURL string `json:"repoURL,omitempty"`
+	// Chart is the name of the chart in the repository at RepoURL
+	Chart string `json:"chart,omitempty"`
+	// Version is a version or a semver constraint of an OCI or repository chart.
+	// Empty is the latest stable version.
+	Version string `json:"version,omitempty"`
+	// ConfigMap holds a packaged chart
+	ConfigMap *ArchiveReference `json:"configMap,omitempty"`
+	// Secret holds a packaged chart
+	Secret *ArchiveReference `json:"secret,omitempty"`
+	// PullSecret has the credentials of the registry or chart repository,
+	// either in .dockerconfigjson or in username and password keys
+	PullSecret *SecretReference `json:"pullSecret,omitempty"`
+	// PlainHTTP talks to the registry over http instead of https
+	PlainHTTP bool `json:"plainHTTP,omitempty"`
+}
+
+// Subchart is a chart the dependencies of a chart are resolved from. It is
+// either inline or referenced with chartRef.
+type Subchart struct {
+	// Name is the name of the dependency in Chart.yaml
+	Name          string               `json:"name"`
+	Chart         runtime.RawExtension `json:"chart,omitempty"`
+	DefaultValues runtime.RawExtension `json:"defaultValues,omitempty"`
+	Templates     []FileContent        `json:"templates,omitempty"`
+	CRDs          []FileContent        `json:"crds,omitempty"`
+	ChartRef      *ChartReference      `json:"chartRef,omitempty"`
+}
+
 // HelmConfigurationSpec defines the desired state of HelmConfiguration
 type HelmConfigurationSpec struct {
 	// Chart https://helm.sh/docs/topics/charts/#the-chartyaml-file
-	Chart         runtime.RawExtension `json:"chart"`
+	Chart runtime.RawExtension `json:"chart,omitempty"`
+	// ChartRef references a packaged chart instead of the chart, templates and
+	// crds of this spec
+	ChartRef      *ChartReference      `json:"chartRef,omitempty"`
 	DefaultValues runtime.RawExtension `json:"defaultValues,omitempty"`
-	Templates     []FileContent        `json:"templates"`
+	Templates     []FileContent        `json:"templates,omitempty"`
 	CRDs          []FileContent        `json:"crds,omitempty"`
+	// Subcharts are the charts of dependencies in Chart.yaml with the same
+	// name. Other dependencies are fetched from their repository.
+	Subcharts []Subchart `json:"subcharts,omitempty"`
+	// Tags sets tags.<tag> of the values to a value of the facade, the context
+	// or the fetched values, by path, for example sqls.spec.monitoring
+	Tags map[string]string `json:"tags,omitempty"`
+	// ValuesSchema is a JSON schema, like the values.schema.json of a chart,
+	// the values are checked against. Evaluate checks the values of the chart
+	// merged with the facade, the context and the fetched values.
+	// https://helm.sh/docs/topics/charts/#schema-files
+	ValuesSchema runtime.RawExtension `json:"valuesSchema,omitempty"`
+}
+
+// PinnedChart is the chart spec.chartRef resolved to
+type PinnedChart struct {
+	// ObservedGeneration is the generation of the spec the chart was resolved for
+	ObservedGeneration int64  `json:"observedGeneration"`
+	Name               string `json:"name"`
+	Version            string `json:"version"`
+	// Digest is the sha256 digest of the packaged chart
+	Digest string `json:"digest"`
+}
+
+// PinnedDependency is the chart a dependency resolved to
+type PinnedDependency struct {
+	// Dependency is the name of the dependency in Chart.yaml
+	Dependency  string `json:"dependency"`
+	PinnedChart `json:",inline"`
 }
 
 // HelmConfigurationStatus defines the observed state of HelmConfiguration
 type HelmConfigurationStatus struct {
+	// Chart is the chart spec.chartRef is pinned to
+	Chart *PinnedChart `json:"chart,omitempty"`
+	// Dependencies are the charts the dependencies fetched from chart
+	// references are pinned to
+	Dependencies []PinnedDependency `json:"dependencies,omitempty"`
 }
 
 //+kubebuilder:object:root=true
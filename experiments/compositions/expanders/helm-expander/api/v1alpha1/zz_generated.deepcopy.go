@@ -8,6 +8,51 @@ import (
 	"k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ArchiveReference) DeepCopyInto(out *ArchiveReference) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ArchiveReference.
+func (in *ArchiveReference) DeepCopy() *ArchiveReference {
+	if in == nil {
+		return nil
+	}
+	out := new(ArchiveReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ChartReference) DeepCopyInto(out *ChartReference) {
+	*out = *in
+	if in.ConfigMap != nil {
+		in, out := &in.ConfigMap, &out.ConfigMap
+		*out = new(ArchiveReference)
+		**out = **in
+	}
+	if in.Secret != nil {
+		in, out := &in.Secret, &out.Secret
+		*out = new(ArchiveReference)
+		**out = **in
+	}
+	if in.PullSecret != nil {
+		in, out := &in.PullSecret, &out.PullSecret
+		*out = new(SecretReference)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ChartReference.
+func (in *ChartReference) DeepCopy() *ChartReference {
+	if in == nil {
+		return nil
+	}
+	out := new(ChartReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *FileContent) DeepCopyInto(out *FileContent) {
 	*out = *in
@@ -30,7 +75,7 @@ func (in *HelmConfiguration) DeepCopyInto(out *HelmConfiguration) {
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
 	in.Spec.DeepCopyInto(&out.Spec)
-	out.Status = in.Status
+	in.Status.DeepCopyInto(&out.Status)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HelmConfiguration.
@@ -87,6 +132,11 @@ func (in *HelmConfigurationList) DeepCopyObject() runtime.Object {
 func (in *HelmConfigurationSpec) DeepCopyInto(out *HelmConfigurationSpec) {
 	*out = *in
 	in.Chart.DeepCopyInto(&out.Chart)
+	if in.ChartRef != nil {
+		in, out := &in.ChartRef, &out.ChartRef
+		*out = new(ChartReference)
+		(*in).DeepCopyInto(*out)
+	}
 	in.DefaultValues.DeepCopyInto(&out.DefaultValues)
 	if in.Templates != nil {
 		in, out := &in.Templates, &out.Templates
@@ -102,6 +152,21 @@ func (in *HelmConfigurationSpec) DeepCopyInto(out *HelmConfigurationSpec) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.Subcharts != nil {
+		in, out := &in.Subcharts, &out.Subcharts
+		*out = make([]Subchart, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Tags != nil {
+		in, out := &in.Tags, &out.Tags
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	in.ValuesSchema.DeepCopyInto(&out.ValuesSchema)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HelmConfigurationSpec.
@@ -117,6 +182,16 @@ func (in *HelmConfigurationSpec) DeepCopy() *HelmConfigurationSpec {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *HelmConfigurationStatus) DeepCopyInto(out *HelmConfigurationStatus) {
 	*out = *in
+	if in.Chart != nil {
+		in, out := &in.Chart, &out.Chart
+		*out = new(PinnedChart)
+		**out = **in
+	}
+	if in.Dependencies != nil {
+		in, out := &in.Dependencies, &out.Dependencies
+		*out = make([]PinnedDependency, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HelmConfigurationStatus.
@@ -128,3 +203,85 @@ func (in *HelmConfigurationStatus) DeepCopy() *HelmConfigurationStatus {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PinnedChart) DeepCopyInto(out *PinnedChart) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PinnedChart.
+func (in *PinnedChart) DeepCopy() *PinnedChart {
+	if in == nil {
+		return nil
+	}
+	out := new(PinnedChart)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PinnedDependency) DeepCopyInto(out *PinnedDependency) {
+	*out = *in
+	out.PinnedChart = in.PinnedChart
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PinnedDependency.
+func (in *PinnedDependency) DeepCopy() *PinnedDependency {
+	if in == nil {
+		return nil
+	}
+	out := new(PinnedDependency)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SecretReference) DeepCopyInto(out *SecretReference) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SecretReference.
+func (in *SecretReference) DeepCopy() *SecretReference {
+	if in == nil {
+		return nil
+	}
+	out := new(SecretReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Subchart) DeepCopyInto(out *Subchart) {
+	*out = *in
+	in.Chart.DeepCopyInto(&out.Chart)
+	in.DefaultValues.DeepCopyInto(&out.DefaultValues)
+	if in.Templates != nil {
+		in, out := &in.Templates, &out.Templates
+		*out = make([]FileContent, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.CRDs != nil {
+		in, out := &in.CRDs, &out.CRDs
+		*out = make([]FileContent, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.ChartRef != nil {
+		in, out := &in.ChartRef, &out.ChartRef
+		*out = new(ChartReference)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Subchart.
+func (in *Subchart) DeepCopy() *Subchart {
+	if in == nil {
+		return nil
+	}
+	out := new(Subchart)
+	in.DeepCopyInto(out)
+	return out
+}
@@ -0,0 +1,214 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	pb "github.com/cloud-native-compositions/compositions/composition/proto"
+)
+
+// greetingChart is a packaged chart with default values, greeting the facade
+// field at foo
+func greetingChart(t *testing.T, foo string) []byte {
+	files := []struct{ name, content string }{
+		{"greeting/Chart.yaml", "apiVersion: v2\nname: greeting\nversion: 1.2.0\n"},
+		{"greeting/values.yaml", "greeting: hello\nrepeat: 1\n"},
+		{"greeting/templates/configmap.yaml", `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: {{ .Release.Name }}
+data:
+  greeting: {{ .Values.greeting }} {{ ` + foo + ` }}
+  repeat: "{{ .Values.repeat }}"
+`},
+	}
+	buf := &bytes.Buffer{}
+	gz := gzip.NewWriter(buf)
+	tw := tar.NewWriter(gz)
+	for _, f := range files {
+		if err := tw.WriteHeader(&tar.Header{Name: f.name, Mode: 0644, Size: int64(len(f.content))}); err != nil {
+			t.Fatalf("error writing chart: %v", err)
+		}
+		if _, err := tw.Write([]byte(f.content)); err != nil {
+			t.Fatalf("error writing chart: %v", err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatalf("error writing chart: %v", err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatalf("error writing chart: %v", err)
+	}
+	return buf.Bytes()
+}
+
+// chartRepository serves the chart as a chart repository on this host
+func chartRepository(t *testing.T, chart []byte) *httptest.Server {
+	if !strings.HasPrefix(*addr, "[::]:") && !strings.HasPrefix(*addr, "localhost:") && !strings.HasPrefix(*addr, "127.0.0.1:") {
+		t.Skip("the chart repository is only reachable by an expander on this host")
+	}
+	index := fmt.Sprintf(`apiVersion: v1
+entries:
+  greeting:
+  - apiVersion: v2
+    name: greeting
+    version: 1.2.0
+    digest: %x
+    urls:
+    - greeting-1.2.0.tgz
+`, sha256.Sum256(chart))
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/index.yaml":
+			w.Write([]byte(index))
+		case "/greeting-1.2.0.tgz":
+			w.Write(chart)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+}
+
+func TestEvaluateChartRef(t *testing.T) {
+	repository := chartRepository(t, greetingChart(t, ".Values.sqls.spec.foo"))
+	defer repository.Close()
+
+	config := fmt.Sprintf(`apiVersion: composition.google.com/v1alpha1
+kind: HelmConfiguration
+metadata:
+  name: greeting
+  namespace: config-control
+spec:
+  chartRef:
+    repoURL: %s
+    chart: greeting
+    version: ^1.0.0
+  defaultValues:
+    repeat: 3
+`, repository.URL)
+	r, err := expanderClient.Evaluate(context.Background(),
+		&pb.EvaluateRequest{
+			Resource: "sqls",
+			Config:   configFrom(t, config),
+			Context:  testContext(t),
+			Facade:   testFacade(t, ""),
+			Value:    dummyValues(t),
+		})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if r.GetStatus() != pb.Status_SUCCESS {
+		t.Fatalf("want SUCCESS, got: %s", r)
+	}
+	expected := `---
+# Source: greeting/templates/configmap.yaml
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: appteam-sample
+data:
+  greeting: hello bar
+  repeat: "3"
+`
+	if string(r.Manifests) != expected {
+		t.Fatalf("\nexpected: %s\ngot: %s", expected, r.Manifests)
+	}
+}
+
+func TestValidateChartRef(t *testing.T) {
+	testcases := []struct {
+		name   string
+		config string
+		err    string
+	}{
+		{
+			name: "inline chart",
+			config: `apiVersion: composition.google.com/v1alpha1
+kind: HelmConfiguration
+metadata:
+  name: greeting
+  namespace: config-control
+spec:
+  chart:
+    apiVersion: v2
+    name: greeting
+    version: 0.1.0
+  chartRef:
+    oci: oci://ghcr.io/org/charts/greeting
+`,
+			err: "spec.chartRef: spec.chart, spec.templates and spec.crds can not be set with spec.chartRef",
+		},
+		{
+			name: "two sources",
+			config: `apiVersion: composition.google.com/v1alpha1
+kind: HelmConfiguration
+metadata:
+  name: greeting
+  namespace: config-control
+spec:
+  chartRef:
+    oci: oci://ghcr.io/org/charts/greeting
+    configMap:
+      name: greeting
+`,
+			err: "spec.chartRef: only one of oci, repoURL, configMap and secret can be set, got: oci, configMap",
+		},
+		{
+			name: "configmap without cluster",
+			config: `apiVersion: composition.google.com/v1alpha1
+kind: HelmConfiguration
+metadata:
+  name: greeting
+  namespace: config-control
+spec:
+  chartRef:
+    configMap:
+      name: greeting
+`,
+			err: "spec.chartRef.configMap: config-control/greeting can not be read",
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.name == "configmap without cluster" && *addr != "[::]:8443" {
+				t.Skip("the expander may have a kubernetes client")
+			}
+			r, err := expanderClient.Validate(context.Background(),
+				&pb.ValidateRequest{
+					Resource: "sqls",
+					Config:   configFrom(t, tc.config),
+				})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if r.GetStatus() != pb.Status_VALIDATE_FAILED {
+				t.Fatalf("want VALIDATE_FAILED, got: %s", r)
+			}
+			if !strings.Contains(r.Error.Message, tc.err) {
+				t.Fatalf("expected error: %s \n got: %s", tc.err, r.Error.Message)
+			}
+		})
+	}
+}
@@ -0,0 +1,265 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/cloud-native-compositions/compositions/composition/pkg/expandersdk"
+	helmconfigurationv1alpha1 "github.com/cloud-native-compositions/compositions/expander/helm-expander/api/v1alpha1"
+	"github.com/cloud-native-compositions/compositions/expander/helm-expander/pkg/chartref"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chart/loader"
+	"helm.sh/helm/v3/pkg/chartutil"
+	"helm.sh/helm/v3/pkg/registry"
+	"tailscale.com/atomicfile"
+)
+
+// dependency is a dependency of a chart that is not in its charts/ directory
+type dependency struct {
+	name string
+	// dir is the charts/ directory of the chart depending on it
+	dir string
+	// subchart is the inline chart of spec.subcharts, if the dependency is not
+	// fetched from a chart reference
+	subchart *helmconfigurationv1alpha1.Subchart
+	// ref is the chart reference the dependency is fetched from
+	ref *chartref.Dependency
+}
+
+// invalidDependency fails the call with a dependency that can not be resolved
+func invalidDependency(format string, args ...interface{}) error {
+	return expandersdk.Failed(expandersdk.ErrorDiagnostic("InvalidDependency", format, args...))
+}
+
+// writeDependencies writes the dependencies of the chart listed in Chart.yaml
+// to its charts/ directory. A dependency is the subchart with the same name in
+// spec.subcharts, or else the chart with its name in the oci or http
+// repository of the dependency. Dependencies already in the charts/ of a
+// packaged chart are used as they are.
+func (e *Expander) writeDependencies(ctx context.Context) error {
+	var dependencies []dependency
+	var err error
+	if e.chart != nil {
+		dependencies, err = e.packagedChartDependencies()
+	} else {
+		metadata := &chart.Metadata{}
+		if len(e.config.Spec.Chart.Raw) != 0 {
+			if err := json.Unmarshal(e.config.Spec.Chart.Raw, metadata); err != nil {
+				return invalidDependency("spec.chart: %v", err)
+			}
+		}
+		e.hasDependencies = len(metadata.Dependencies) != 0
+		dependencies, err = e.planDependencies("spec.chart", metadata.Dependencies, nil, filepath.Join(e.path, "charts"), nil)
+	}
+	if err != nil {
+		return err
+	}
+
+	used := map[string]bool{}
+	for _, d := range dependencies {
+		used[d.name] = true
+	}
+	for i, subchart := range e.config.Spec.Subcharts {
+		if !used[subchart.Name] {
+			return invalidDependency("spec.subcharts[%d]: no dependency is named %s", i, subchart.Name)
+		}
+	}
+
+	// The same chart may be a dependency of several charts
+	refs := []chartref.Dependency{}
+	fetched := map[string]*chartref.Dependency{}
+	for _, d := range dependencies {
+		if d.ref == nil {
+			continue
+		}
+		if other, ok := fetched[d.name]; ok {
+			if !reflect.DeepEqual(other.Ref, d.ref.Ref) {
+				return invalidDependency("%s: dependency %s is also fetched from %s", d.ref.Field, d.name, other.Field)
+			}
+			continue
+		}
+		fetched[d.name] = d.ref
+		refs = append(refs, *d.ref)
+	}
+	charts := map[string]*chartref.Chart{}
+	if len(refs) != 0 || len(e.config.Status.Dependencies) != 0 {
+		charts, err = fetcher.FetchDependencies(ctx, e.config, refs)
+		if err != nil {
+			return expandersdk.Failed(expandersdk.ErrorDiagnostic("ChartFetchFailed", "%v", err))
+		}
+	}
+
+	for _, d := range dependencies {
+		if d.subchart != nil {
+			s := d.subchart
+			if err := writeChart(filepath.Join(d.dir, d.name), s.Chart, s.DefaultValues, s.Templates, s.CRDs); err != nil {
+				return fmt.Errorf("error writing subchart %s: %w", d.name, err)
+			}
+			continue
+		}
+		c := charts[d.name]
+		if err := os.MkdirAll(d.dir, 0700); err != nil {
+			return fmt.Errorf("%s dir creation failed: %w", d.dir, err)
+		}
+		if err := atomicfile.WriteFile(filepath.Join(d.dir, fmt.Sprintf("%s-%s.tgz", c.Name, c.Version)), c.Archive, 0644); err != nil {
+			return fmt.Errorf("failed to write dependency %s: %w", d.name, err)
+		}
+	}
+	return nil
+}
+
+// packagedChartDependencies returns the dependencies of the chart of
+// spec.chartRef that it was packaged without. The chart is expanded to add them.
+func (e *Expander) packagedChartDependencies() ([]dependency, error) {
+	c, err := loader.LoadArchive(bytes.NewReader(e.chart.Archive))
+	if err != nil {
+		return nil, invalidDependency("spec.chartRef: %s %s is not a packaged chart: %v", e.chart.Name, e.chart.Version, err)
+	}
+	e.hasDependencies = len(c.Metadata.Dependencies) != 0
+	packaged := map[string]bool{}
+	for _, subchart := range c.Dependencies() {
+		packaged[subchart.Name()] = true
+	}
+	chartDir := filepath.Join(e.path, "chart", c.Name())
+	dependencies, err := e.planDependencies(fmt.Sprintf("chart %s of spec.chartRef", c.Name()), c.Metadata.Dependencies, packaged, filepath.Join(chartDir, "charts"), nil)
+	if err != nil || len(dependencies) == 0 {
+		return dependencies, err
+	}
+	if err := chartutil.Expand(filepath.Join(e.path, "chart"), bytes.NewReader(e.chart.Archive)); err != nil {
+		return nil, fmt.Errorf("error expanding chart %s: %w", c.Name(), err)
+	}
+	e.chartDir = chartDir
+	return dependencies, nil
+}
+
+// planDependencies returns where the dependencies of a chart are resolved
+// from, and the dependencies of its inline subcharts. location names the
+// chart in errors, parents are the inline subcharts depending on it.
+func (e *Expander) planDependencies(location string, dependencies []*chart.Dependency, packaged map[string]bool, dir string, parents []string) ([]dependency, error) {
+	planned := []dependency{}
+	seen := map[string]bool{}
+	for i, d := range dependencies {
+		// Aliases of the same chart are written once
+		if packaged[d.Name] || seen[d.Name] {
+			continue
+		}
+		seen[d.Name] = true
+		if d.Name == "" || d.Name == "." || d.Name == ".." || strings.ContainsAny(d.Name, `/\`) {
+			return nil, invalidDependency("%s: dependencies[%d]: invalid name %q", location, i, d.Name)
+		}
+
+		index := slices.IndexFunc(e.config.Spec.Subcharts, func(s helmconfigurationv1alpha1.Subchart) bool { return s.Name == d.Name })
+		if index == -1 {
+			ref, err := repositoryRef(d)
+			if err != nil {
+				return nil, invalidDependency("%s: dependencies[%d]: %v", location, i, err)
+			}
+			planned = append(planned, dependency{
+				name: d.Name,
+				dir:  dir,
+				ref:  &chartref.Dependency{Name: d.Name, Field: fmt.Sprintf("%s dependencies[%d]", location, i), Ref: ref},
+			})
+			continue
+		}
+
+		subchart := &e.config.Spec.Subcharts[index]
+		field := fmt.Sprintf("spec.subcharts[%d]", index)
+		inline := len(subchart.Chart.Raw) != 0 || len(subchart.Templates) != 0 || len(subchart.CRDs) != 0
+		switch {
+		case subchart.ChartRef != nil && inline:
+			return nil, invalidDependency("%s: chart, templates and crds can not be set with chartRef", field)
+		case subchart.ChartRef != nil:
+			planned = append(planned, dependency{
+				name: d.Name,
+				dir:  dir,
+				ref:  &chartref.Dependency{Name: d.Name, Field: field + ".chartRef", Ref: subchart.ChartRef},
+			})
+			continue
+		case len(subchart.Chart.Raw) == 0:
+			return nil, invalidDependency("%s: one of chart and chartRef is required", field)
+		}
+		if slices.Contains(parents, d.Name) {
+			return nil, invalidDependency("%s: %s depends on itself through %s", field, d.Name, strings.Join(parents, ", "))
+		}
+		planned = append(planned, dependency{name: d.Name, dir: dir, subchart: subchart})
+
+		metadata := &chart.Metadata{}
+		if err := json.Unmarshal(subchart.Chart.Raw, metadata); err != nil {
+			return nil, invalidDependency("%s.chart: %v", field, err)
+		}
+		nested, err := e.planDependencies(field+".chart", metadata.Dependencies, nil, filepath.Join(dir, d.Name, "charts"), append(slices.Clone(parents), d.Name))
+		if err != nil {
+			return nil, err
+		}
+		planned = append(planned, nested...)
+	}
+	return planned, nil
+}
+
+// repositoryRef is the chart reference of a dependency with an oci or http repository
+func repositoryRef(d *chart.Dependency) (*helmconfigurationv1alpha1.ChartReference, error) {
+	switch {
+	case registry.IsOCI(d.Repository):
+		return &helmconfigurationv1alpha1.ChartReference{
+			OCI:     strings.TrimSuffix(d.Repository, "/") + "/" + d.Name,
+			Version: d.Version,
+		}, nil
+	case strings.HasPrefix(d.Repository, "https://") || strings.HasPrefix(d.Repository, "http://"):
+		return &helmconfigurationv1alpha1.ChartReference{
+			RepoURL: d.Repository,
+			Chart:   d.Name,
+			Version: d.Version,
+		}, nil
+	case d.Repository == "":
+		return nil, fmt.Errorf("%s is not in spec.subcharts and has no repository", d.Name)
+	default:
+		return nil, fmt.Errorf("%s is not in spec.subcharts and its repository %s is not an oci or http repository", d.Name, d.Repository)
+	}
+}
+
+// tags resolves spec.tags in the values
+func (e *Expander) tags(values map[string]interface{}) (map[string]interface{}, error) {
+	tags := map[string]interface{}{}
+	for tag, path := range e.config.Spec.Tags {
+		var value interface{} = values
+		for _, key := range strings.Split(path, ".") {
+			m, ok := value.(map[string]interface{})
+			if !ok {
+				value = nil
+				break
+			}
+			value = m[key]
+		}
+		// Missing values leave the tag to the chart, as for Validate calls without a facade
+		if value == nil {
+			continue
+		}
+		if _, ok := value.(bool); !ok {
+			return nil, expandersdk.Failed(expandersdk.ErrorDiagnostic("InvalidTag",
+				"spec.tags.%s: %s is %v, not a boolean", tag, path, value))
+		}
+		tags[tag] = value
+	}
+	return tags, nil
+}
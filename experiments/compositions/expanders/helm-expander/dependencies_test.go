@@ -0,0 +1,368 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main_test
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	pb "github.com/cloud-native-compositions/compositions/composition/proto"
+)
+
+// dependenciesChart has a library subchart, a subchart toggled by a
+// condition and one toggled by a tag
+const dependenciesChart = `apiVersion: composition.google.com/v1alpha1
+kind: HelmConfiguration
+metadata:
+  name: app
+  namespace: config-control
+spec:
+  chart:
+    apiVersion: v2
+    name: app
+    version: 0.1.0
+    dependencies:
+    - name: common
+      version: 0.1.0
+    - name: cache
+      version: 0.1.0
+      condition: sqls.spec.cache
+    - name: monitoring
+      version: 0.1.0
+      tags:
+      - monitoring
+  defaultValues:
+    cache:
+      size: 2
+    tags:
+      monitoring: false
+  templates:
+  - name: configmap.yaml
+    template: |
+      apiVersion: v1
+      kind: ConfigMap
+      metadata:
+        name: {{ .Release.Name }}
+        labels:
+          {{- include "common.labels" . | nindent 4 }}
+  subcharts:
+  - name: common
+    chart:
+      apiVersion: v2
+      name: common
+      type: library
+      version: 0.1.0
+    templates:
+    - name: _labels.tpl
+      template: |
+        {{- define "common.labels" -}}
+        team: {{ .Values.sqls.spec.foo }}
+        {{- end }}
+  - name: cache
+    chart:
+      apiVersion: v2
+      name: cache
+      version: 0.1.0
+    defaultValues:
+      size: 1
+    templates:
+    - name: configmap.yaml
+      template: |
+        apiVersion: v1
+        kind: ConfigMap
+        metadata:
+          name: {{ .Release.Name }}-cache
+        data:
+          size: "{{ .Values.size }}"
+          car: {{ .Values.global.sqls.spec.car }}
+  - name: monitoring
+    chart:
+      apiVersion: v2
+      name: monitoring
+      version: 0.1.0
+    templates:
+    - name: configmap.yaml
+      template: |
+        apiVersion: v1
+        kind: ConfigMap
+        metadata:
+          name: {{ .Release.Name }}-monitoring
+  tags:
+    monitoring: sqls.spec.monitoring
+`
+
+func TestEvaluateDependencies(t *testing.T) {
+	testcases := []struct {
+		name     string
+		spec     string
+		expected []string
+		missing  []string
+	}{
+		{
+			name:     "disabled",
+			spec:     "foo: payments\n  car: sedan\n  cache: false",
+			expected: []string{"team: payments"},
+			missing:  []string{"appteam-sample-cache", "appteam-sample-monitoring"},
+		},
+		{
+			name:     "condition",
+			spec:     "foo: payments\n  car: sedan\n  cache: true",
+			expected: []string{"team: payments", "name: appteam-sample-cache", `size: "2"`, "car: sedan"},
+			missing:  []string{"appteam-sample-monitoring"},
+		},
+		{
+			name:     "tag",
+			spec:     "foo: payments\n  car: sedan\n  cache: false\n  monitoring: true",
+			expected: []string{"name: appteam-sample-monitoring"},
+			missing:  []string{"appteam-sample-cache"},
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			facade := fmt.Sprintf(`apiVersion: facade.foobar.com/v1alpha1
+kind: Foo
+metadata:
+  name: appteam-sample
+  namespace: default
+spec:
+  %s
+`, tc.spec)
+			r, err := expanderClient.Evaluate(context.Background(),
+				&pb.EvaluateRequest{
+					Resource: "sqls",
+					Config:   configFrom(t, dependenciesChart),
+					Context:  testContext(t),
+					Facade:   testFacade(t, facade),
+					Value:    dummyValues(t),
+				})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if r.GetStatus() != pb.Status_SUCCESS {
+				t.Fatalf("want SUCCESS, got: %s", r)
+			}
+			for _, expected := range tc.expected {
+				if !strings.Contains(string(r.Manifests), expected) {
+					t.Fatalf("\nexpected to contain: %s\ngot: %s", expected, r.Manifests)
+				}
+			}
+			for _, missing := range tc.missing {
+				if strings.Contains(string(r.Manifests), missing) {
+					t.Fatalf("\nexpected not to contain: %s\ngot: %s", missing, r.Manifests)
+				}
+			}
+		})
+	}
+}
+
+func TestEvaluateRepositoryDependency(t *testing.T) {
+	repository := chartRepository(t, greetingChart(t, ".Values.global.sqls.spec.foo"))
+	defer repository.Close()
+
+	config := fmt.Sprintf(`apiVersion: composition.google.com/v1alpha1
+kind: HelmConfiguration
+metadata:
+  name: app
+  namespace: config-control
+spec:
+  chart:
+    apiVersion: v2
+    name: app
+    version: 0.1.0
+    dependencies:
+    - name: greeting
+      version: ~1.2.0
+      repository: %s
+  defaultValues:
+    greeting:
+      greeting: hi
+`, repository.URL)
+	r, err := expanderClient.Evaluate(context.Background(),
+		&pb.EvaluateRequest{
+			Resource: "sqls",
+			Config:   configFrom(t, config),
+			Context:  testContext(t),
+			Facade:   testFacade(t, ""),
+			Value:    dummyValues(t),
+		})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if r.GetStatus() != pb.Status_SUCCESS {
+		t.Fatalf("want SUCCESS, got: %s", r)
+	}
+	// The subchart sees the facade in the global values only
+	expected := "greeting: hi bar\n"
+	if !strings.Contains(string(r.Manifests), expected) {
+		t.Fatalf("\nexpected to contain: %q\ngot: %s", expected, r.Manifests)
+	}
+}
+
+func TestValidateDependencies(t *testing.T) {
+	testcases := []struct {
+		name   string
+		config string
+		err    string
+	}{
+		{
+			name:   "valid",
+			config: dependenciesChart,
+		},
+		{
+			name: "no repository",
+			config: `apiVersion: composition.google.com/v1alpha1
+kind: HelmConfiguration
+metadata:
+  name: app
+  namespace: config-control
+spec:
+  chart:
+    apiVersion: v2
+    name: app
+    version: 0.1.0
+    dependencies:
+    - name: cache
+      version: 0.1.0
+`,
+			err: "spec.chart: dependencies[0]: cache is not in spec.subcharts and has no repository",
+		},
+		{
+			name: "file repository",
+			config: `apiVersion: composition.google.com/v1alpha1
+kind: HelmConfiguration
+metadata:
+  name: app
+  namespace: config-control
+spec:
+  chart:
+    apiVersion: v2
+    name: app
+    version: 0.1.0
+    dependencies:
+    - name: cache
+      version: 0.1.0
+      repository: file://../cache
+`,
+			err: "spec.chart: dependencies[0]: cache is not in spec.subcharts and its repository file://../cache is not an oci or http repository",
+		},
+		{
+			name: "unused subchart",
+			config: `apiVersion: composition.google.com/v1alpha1
+kind: HelmConfiguration
+metadata:
+  name: app
+  namespace: config-control
+spec:
+  chart:
+    apiVersion: v2
+    name: app
+    version: 0.1.0
+  subcharts:
+  - name: cache
+    chart:
+      apiVersion: v2
+      name: cache
+      version: 0.1.0
+`,
+			err: "spec.subcharts[0]: no dependency is named cache",
+		},
+		{
+			name: "cycle",
+			config: `apiVersion: composition.google.com/v1alpha1
+kind: HelmConfiguration
+metadata:
+  name: app
+  namespace: config-control
+spec:
+  chart:
+    apiVersion: v2
+    name: app
+    version: 0.1.0
+    dependencies:
+    - name: a
+  subcharts:
+  - name: a
+    chart:
+      apiVersion: v2
+      name: a
+      version: 0.1.0
+      dependencies:
+      - name: b
+  - name: b
+    chart:
+      apiVersion: v2
+      name: b
+      version: 0.1.0
+      dependencies:
+      - name: a
+`,
+			err: "spec.subcharts[0]: a depends on itself through a, b",
+		},
+		{
+			name: "inline and chartRef",
+			config: `apiVersion: composition.google.com/v1alpha1
+kind: HelmConfiguration
+metadata:
+  name: app
+  namespace: config-control
+spec:
+  chart:
+    apiVersion: v2
+    name: app
+    version: 0.1.0
+    dependencies:
+    - name: cache
+  subcharts:
+  - name: cache
+    chart:
+      apiVersion: v2
+      name: cache
+      version: 0.1.0
+    chartRef:
+      oci: oci://ghcr.io/org/charts/cache
+`,
+			err: "spec.subcharts[0]: chart, templates and crds can not be set with chartRef",
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			r, err := expanderClient.Validate(context.Background(),
+				&pb.ValidateRequest{
+					Resource: "sqls",
+					Config:   configFrom(t, tc.config),
+					Context:  testContext(t),
+					Facade:   testFacade(t, ""),
+					Value:    dummyValues(t),
+				})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tc.err == "" {
+				if r.GetStatus() != pb.Status_SUCCESS {
+					t.Fatalf("want SUCCESS, got: %s", r)
+				}
+				return
+			}
+			if r.GetStatus() != pb.Status_VALIDATE_FAILED {
+				t.Fatalf("want VALIDATE_FAILED, got: %s", r)
+			}
+			if !strings.Contains(r.Error.Message, tc.err) {
+				t.Fatalf("expected error: %s \n got: %s", tc.err, r.Error.Message)
+			}
+		})
+	}
+}
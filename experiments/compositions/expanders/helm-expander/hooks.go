@@ -0,0 +1,134 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/cloud-native-compositions/compositions/composition/pkg/expandersdk"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/yaml"
+)
+
+// https://helm.sh/docs/topics/charts_hooks/
+const (
+	hookAnnotation             = "helm.sh/hook"
+	hookWeightAnnotation       = "helm.sh/hook-weight"
+	hookDeletePolicyAnnotation = "helm.sh/hook-delete-policy"
+)
+
+// hookPhases are the helm hooks the controller runs, in the order of their phases
+var hookPhases = []expandersdk.HookPhase{
+	expandersdk.HookPreInstall,
+	expandersdk.HookPostInstall,
+	expandersdk.HookPreUpgrade,
+	expandersdk.HookPostUpgrade,
+	expandersdk.HookPreDelete,
+	expandersdk.HookPostDelete,
+}
+
+var hookDeletePolicies = []expandersdk.HookDeletePolicy{
+	expandersdk.HookBeforeHookCreation,
+	expandersdk.HookSucceeded,
+	expandersdk.HookFailed,
+}
+
+var documentSeparator = regexp.MustCompile(`(?m)^---[ \t]*\n`)
+
+// splitHooks moves the objects of helm hooks out of the manifests. Hooks the
+// controller does not run, like test and rollback hooks, are dropped with an
+// informational diagnostic, as helm does not install them with the release
+// either.
+func splitHooks(manifests []byte) ([]byte, []*expandersdk.Hook, []*expandersdk.Diagnostic, error) {
+	docs := documentSeparator.Split(string(manifests), -1)
+	kept := []string{}
+	hooks := []*expandersdk.Hook{}
+	diagnostics := []*expandersdk.Diagnostic{}
+	for _, doc := range docs {
+		u := &unstructured.Unstructured{}
+		if err := yaml.Unmarshal([]byte(doc), &u.Object); err != nil {
+			return nil, nil, nil, fmt.Errorf("failed to parse helm output: %w", err)
+		}
+		annotation, ok := u.GetAnnotations()[hookAnnotation]
+		if u.Object == nil || !ok {
+			if strings.TrimSpace(doc) != "" {
+				kept = append(kept, doc)
+			}
+			continue
+		}
+
+		// helm ignores weights that are not numbers and unknown policies
+		weight, _ := strconv.Atoi(strings.TrimSpace(u.GetAnnotations()[hookWeightAnnotation]))
+		policies := []expandersdk.HookDeletePolicy{}
+		for _, p := range strings.Split(u.GetAnnotations()[hookDeletePolicyAnnotation], ",") {
+			policy := expandersdk.HookDeletePolicy(strings.TrimSpace(p))
+			if slices.Contains(hookDeletePolicies, policy) && !slices.Contains(policies, policy) {
+				policies = append(policies, policy)
+			}
+		}
+		sort.Slice(policies, func(i, j int) bool { return policies[i] < policies[j] })
+
+		dropped := []string{}
+		for _, p := range strings.Split(annotation, ",") {
+			phase := expandersdk.HookPhase(strings.TrimSpace(p))
+			if !slices.Contains(hookPhases, phase) {
+				dropped = append(dropped, string(phase))
+				continue
+			}
+			hooks = addHook(hooks, phase, int32(weight), policies, u)
+		}
+		if len(dropped) != 0 {
+			diagnostics = append(diagnostics, &expandersdk.Diagnostic{
+				Severity: expandersdk.SeverityInfo,
+				Code:     "HookNotRun",
+				Message:  fmt.Sprintf("%s %s: %s hooks are not run", u.GetKind(), u.GetName(), strings.Join(dropped, ", ")),
+			})
+		}
+	}
+	if len(hooks) == 0 && len(diagnostics) == 0 {
+		return manifests, nil, nil, nil
+	}
+
+	sort.SliceStable(hooks, func(i, j int) bool {
+		pi, pj := slices.Index(hookPhases, hooks[i].Phase), slices.Index(hookPhases, hooks[j].Phase)
+		if pi != pj {
+			return pi < pj
+		}
+		return hooks[i].Weight < hooks[j].Weight
+	})
+	out := []byte{}
+	for _, doc := range kept {
+		out = append(out, "---\n"...)
+		out = append(out, doc...)
+	}
+	return out, hooks, diagnostics, nil
+}
+
+// addHook adds the object to the hook with the same phase, weight and delete
+// policies
+func addHook(hooks []*expandersdk.Hook, phase expandersdk.HookPhase, weight int32, policies []expandersdk.HookDeletePolicy, u *unstructured.Unstructured) []*expandersdk.Hook {
+	for _, h := range hooks {
+		if h.Phase == phase && h.Weight == weight && slices.Equal(h.DeletePolicies, policies) {
+			h.Objects = append(h.Objects, u)
+			return hooks
+		}
+	}
+	return append(hooks, &expandersdk.Hook{Phase: phase, Weight: weight, DeletePolicies: policies, Objects: []*unstructured.Unstructured{u}})
+}
@@ -0,0 +1,161 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main_test
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	pb "github.com/cloud-native-compositions/compositions/composition/proto"
+	pbv2 "github.com/cloud-native-compositions/compositions/composition/proto/v2"
+)
+
+// hooksChart has a migration Job run before installs and upgrades, a
+// ConfigMap created after installs and a test Pod
+const hooksChart = `apiVersion: composition.google.com/v1alpha1
+kind: HelmConfiguration
+metadata:
+  name: app
+  namespace: config-control
+spec:
+  chart:
+    apiVersion: v2
+    name: app
+    version: 0.1.0
+  templates:
+  - name: configmap.yaml
+    template: |
+      apiVersion: v1
+      kind: ConfigMap
+      metadata:
+        name: {{ .Release.Name }}
+  - name: migrate.yaml
+    template: |
+      apiVersion: batch/v1
+      kind: Job
+      metadata:
+        name: {{ .Release.Name }}-migrate
+        annotations:
+          helm.sh/hook: pre-install,pre-upgrade
+          helm.sh/hook-weight: "-5"
+          helm.sh/hook-delete-policy: hook-succeeded,before-hook-creation
+      spec:
+        template:
+          spec:
+            restartPolicy: Never
+            containers:
+            - name: migrate
+              image: migrate
+  - name: schema.yaml
+    template: |
+      apiVersion: v1
+      kind: ConfigMap
+      metadata:
+        name: {{ .Release.Name }}-schema
+        annotations:
+          helm.sh/hook: pre-install
+  - name: installed.yaml
+    template: |
+      apiVersion: v1
+      kind: ConfigMap
+      metadata:
+        name: {{ .Release.Name }}-installed
+        annotations:
+          helm.sh/hook: post-install
+  - name: test.yaml
+    template: |
+      apiVersion: v1
+      kind: Pod
+      metadata:
+        name: {{ .Release.Name }}-test
+        annotations:
+          helm.sh/hook: test
+      spec:
+        containers:
+        - name: test
+          image: test
+`
+
+func TestEvaluateHooks(t *testing.T) {
+	r, err := expanderClientV2.Evaluate(context.Background(),
+		&pbv2.EvaluateRequest{
+			Resource: "sqls",
+			Config:   configFrom(t, hooksChart),
+			Context:  testContext(t),
+			Facade:   testFacade(t, ""),
+			Value:    dummyValues(t),
+		})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if r.GetStatus() != pbv2.Status_SUCCESS {
+		t.Fatalf("want SUCCESS, got: %s", r)
+	}
+	if len(r.Objects) != 1 || r.Objects[0].Name != "appteam-sample" {
+		t.Fatalf("want only the appteam-sample ConfigMap, got: %s", r.Objects)
+	}
+
+	type hook struct {
+		phase    pbv2.HookPhase
+		weight   int32
+		policies []pbv2.HookDeletePolicy
+		names    []string
+	}
+	want := []hook{
+		{pbv2.HookPhase_PRE_INSTALL, -5, []pbv2.HookDeletePolicy{pbv2.HookDeletePolicy_BEFORE_HOOK_CREATION, pbv2.HookDeletePolicy_HOOK_SUCCEEDED}, []string{"appteam-sample-migrate"}},
+		{pbv2.HookPhase_PRE_INSTALL, 0, nil, []string{"appteam-sample-schema"}},
+		{pbv2.HookPhase_POST_INSTALL, 0, nil, []string{"appteam-sample-installed"}},
+		{pbv2.HookPhase_PRE_UPGRADE, -5, []pbv2.HookDeletePolicy{pbv2.HookDeletePolicy_BEFORE_HOOK_CREATION, pbv2.HookDeletePolicy_HOOK_SUCCEEDED}, []string{"appteam-sample-migrate"}},
+	}
+	got := []hook{}
+	for _, h := range r.Hooks {
+		names := []string{}
+		for _, o := range h.Objects {
+			names = append(names, o.Name)
+		}
+		got = append(got, hook{h.Phase, h.Weight, h.DeletePolicies, names})
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("\nwant hooks: %v\ngot: %v", want, got)
+	}
+
+	if len(r.Diagnostics) != 1 || r.Diagnostics[0].Severity != pbv2.Severity_INFO ||
+		r.Diagnostics[0].Message != "Pod appteam-sample-test: test hooks are not run" {
+		t.Fatalf("want an info diagnostic for the test hook, got: %s", r.Diagnostics)
+	}
+}
+
+func TestEvaluateHooksV1(t *testing.T) {
+	r, err := expanderClient.Evaluate(context.Background(),
+		&pb.EvaluateRequest{
+			Resource: "sqls",
+			Config:   configFrom(t, hooksChart),
+			Context:  testContext(t),
+			Facade:   testFacade(t, ""),
+			Value:    dummyValues(t),
+		})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// v1 has no hooks, so the chart fails instead of being applied without them
+	if r.GetStatus() != pb.Status_EVALUATE_FAILED || !strings.Contains(r.GetError().GetMessage(), "HooksNeedV2") {
+		t.Fatalf("want EVALUATE_FAILED with HooksNeedV2, got: %s", r)
+	}
+	if len(r.Manifests) != 0 {
+		t.Fatalf("want no manifests, got: %s", r.Manifests)
+	}
+}
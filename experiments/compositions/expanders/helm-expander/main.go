@@ -17,149 +17,158 @@ package main
 import (
 	"context"
 	"encoding/json"
-	"flag"
 	"fmt"
 	"log"
-	"net"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"strings"
 
-	pb "github.com/cloud-native-compositions/compositions/composition/proto"
+	"github.com/cloud-native-compositions/compositions/composition/pkg/expandersdk"
 	helmconfigurationv1alpha1 "github.com/cloud-native-compositions/compositions/expander/helm-expander/api/v1alpha1"
-	"google.golang.org/grpc"
+	"github.com/cloud-native-compositions/compositions/expander/helm-expander/pkg/chartref"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/dynamic"
+	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/yaml"
 	"tailscale.com/atomicfile"
 )
 
-var (
-	port = flag.Int("port", 8443, "The server port")
-)
+type Request = expandersdk.Request[helmconfigurationv1alpha1.HelmConfiguration]
+
+// fetcher fetches and caches the charts of spec.chartRef
+var fetcher = &chartref.Fetcher{}
 
 type Expander struct {
 	config        *helmconfigurationv1alpha1.HelmConfiguration
 	facade        *unstructured.Unstructured
 	context       *unstructured.Unstructured
-	fetchedValues *map[string]interface{}
+	fetchedValues map[string]interface{}
 	inputResource string
-	ctx           context.Context
+	cluster       *expandersdk.Cluster
 	path          string
+	// chart is the packaged chart of spec.chartRef
+	chart *chartref.Chart
+	// chartDir is the chart of spec.chartRef expanded to add its dependencies
+	chartDir string
+	// hasDependencies is set if Chart.yaml lists dependencies
+	hasDependencies bool
+	// values are the facade, context and fetched values passed to helm
+	values map[string]interface{}
 }
 
-func NewExpander(ctx context.Context, req *pb.EvaluateRequest, vreq *pb.ValidateRequest) (*Expander, string, error) {
-	var configBytes []byte
-	var facadeBytes []byte
-	var contextBytes []byte
-	var fetchedValueBytes []byte
-	var inputResource string
-
-	failedMessage := ""
-
+// NewExpander writes the chart to a temporary directory. Call cleanup when done.
+func NewExpander(ctx context.Context, req *Request, prefix string) (*Expander, func(), error) {
 	e := &Expander{
-		ctx: ctx,
-	}
-
-	if req != nil {
-		configBytes = req.Config
-		facadeBytes = req.Facade
-		contextBytes = req.Context
-		fetchedValueBytes = req.Value
-		inputResource = req.Resource
-		if string(facadeBytes) == "" {
-			return nil, failedMessage, fmt.Errorf("Empty Facade for an Evaluate call")
+		config:        req.Config,
+		facade:        req.Facade,
+		context:       req.Context,
+		fetchedValues: req.Values,
+		inputResource: req.Resource,
+		cluster:       req.Cluster,
+	}
+
+	if ref := req.Config.Spec.ChartRef; ref != nil {
+		if len(req.Config.Spec.Chart.Raw) != 0 || len(req.Config.Spec.Templates) != 0 || len(req.Config.Spec.CRDs) != 0 {
+			return nil, nil, expandersdk.Failed(expandersdk.ErrorDiagnostic("InvalidChartRef",
+				"spec.chartRef: spec.chart, spec.templates and spec.crds can not be set with spec.chartRef"))
 		}
+		if err := chartref.Validate("spec.chartRef", ref); err != nil {
+			return nil, nil, expandersdk.Failed(expandersdk.ErrorDiagnostic("InvalidChartRef", "%v", err))
+		}
+		chart, err := fetcher.Fetch(ctx, req.Config)
+		if err != nil {
+			return nil, nil, expandersdk.Failed(expandersdk.ErrorDiagnostic("ChartFetchFailed", "%v", err))
+		}
+		e.chart = chart
 	}
-	if vreq != nil {
-		configBytes = vreq.Config
-		facadeBytes = vreq.Facade
-		contextBytes = vreq.Context
-		fetchedValueBytes = vreq.Value
-		inputResource = vreq.Resource
-	}
-
-	e.inputResource = inputResource
-	e.config = &helmconfigurationv1alpha1.HelmConfiguration{}
 
-	if string(configBytes) == "" {
-		failedMessage = fmt.Sprintf("empty Config passed")
-		return nil, failedMessage, nil
-	}
-	err := json.Unmarshal(configBytes, e.config)
+	dir, err := os.MkdirTemp("", prefix)
 	if err != nil {
-		return nil, failedMessage, fmt.Errorf("error unmarshalling req.Config into HelmConfiguration CR: %w", err)
+		return nil, nil, fmt.Errorf("tmp %s dir creation failure. %w", prefix, err)
 	}
+	cleanup := func() { os.RemoveAll(dir) }
+	e.path = dir
 
-	if string(contextBytes) != "" {
-		e.context = &unstructured.Unstructured{}
-		err := e.context.UnmarshalJSON(contextBytes)
-		if err != nil {
-			return nil, failedMessage, fmt.Errorf("error unmarshalling req.Context: %w", err)
-		}
+	if err = e.WriteInputsToFileSystem(); err != nil {
+		cleanup()
+		return nil, nil, fmt.Errorf("error processing inputs: %w", err)
+	}
+	if err = e.writeDependencies(ctx); err != nil {
+		cleanup()
+		return nil, nil, err
+	}
+	if err = e.writeFacadeValues(); err != nil {
+		cleanup()
+		return nil, nil, fmt.Errorf("error processing inputs: %w", err)
 	}
+	return e, cleanup, nil
+}
 
-	if string(fetchedValueBytes) != "" {
-		e.fetchedValues = &map[string]interface{}{}
-		err := json.Unmarshal(fetchedValueBytes, e.fetchedValues)
-		if err != nil {
-			return nil, failedMessage, fmt.Errorf("error unmarshalling req.Context: %w", err)
-		}
+// chartPath is the chart directory or the packaged chart of spec.chartRef
+func (e *Expander) chartPath() string {
+	if e.chartDir != "" {
+		return e.chartDir
 	}
+	if e.chart != nil {
+		return filepath.Join(e.path, "chart.tgz")
+	}
+	return e.path
+}
 
-	if string(facadeBytes) != "" {
-		e.facade = &unstructured.Unstructured{}
-		err := e.facade.UnmarshalJSON(facadeBytes)
-		if err != nil {
-			return nil, failedMessage, fmt.Errorf("error unmarshalling req.Facade into Unstructured: %w", err)
-		}
-		if e.facade.GetNamespace() == "" {
-			//e.facade.SetNamespace("default")
-			failedMessage = fmt.Sprintf("missing namespace in req.Facade object")
-			return nil, failedMessage, nil
-		}
+func (e *Expander) WriteInputsToFileSystem() error {
+	if e.chart != nil {
+		return e.writeChartRefInputs()
 	}
 
-	return e, failedMessage, nil
+	return writeChart(e.path, e.config.Spec.Chart, e.config.Spec.DefaultValues, e.config.Spec.Templates, e.config.Spec.CRDs)
 }
 
-func (e *Expander) WriteInputsToFileSystem() error {
+// writeChart writes an inline chart to dir
+func writeChart(dir string, chart, defaultValues runtime.RawExtension, templates, crds []helmconfigurationv1alpha1.FileContent) error {
+	for _, d := range []string{"templates", "crds"} {
+		if err := os.MkdirAll(filepath.Join(dir, d), 0700); err != nil {
+			return fmt.Errorf("%s/%s dir creation failed: %w", dir, d, err)
+		}
+	}
+
 	// Write Chart.yaml to file
-	yamlContent, err := yaml.JSONToYAML(e.config.Spec.Chart.Raw)
+	yamlContent, err := yaml.JSONToYAML(chart.Raw)
 	if err != nil {
 		return fmt.Errorf("failed to marshall chart.yaml: %w", err)
 	}
-	err = atomicfile.WriteFile(filepath.Join(e.path, "Chart.yaml"), yamlContent, 0644)
+	err = atomicfile.WriteFile(filepath.Join(dir, "Chart.yaml"), yamlContent, 0644)
 	if err != nil {
 		return fmt.Errorf("failed to write Chart file: %w", err)
 	}
 
 	// Write values.yaml to file
-	if len(e.config.Spec.DefaultValues.Raw) != 0 {
-		yamlContent, err := yaml.JSONToYAML(e.config.Spec.DefaultValues.Raw)
+	if len(defaultValues.Raw) != 0 {
+		yamlContent, err := yaml.JSONToYAML(defaultValues.Raw)
 		if err != nil {
 			return fmt.Errorf("failed to marshall values.yaml: %w", err)
 		}
-		err = atomicfile.WriteFile(filepath.Join(e.path, "values.yaml"), yamlContent, 0644)
+		err = atomicfile.WriteFile(filepath.Join(dir, "values.yaml"), yamlContent, 0644)
 		if err != nil {
 			return fmt.Errorf("failed to write values.yaml file: %w", err)
 		}
 	}
 
 	// Write CRDs
-	for _, crd := range e.config.Spec.CRDs {
+	for _, crd := range crds {
 		yamlContent, err := yaml.JSONToYAML(crd.Content.Raw)
 		if err != nil {
 			return fmt.Errorf("failed to marshall crds/%s file to yaml: %w", crd.FileName, err)
 		}
-		err = atomicfile.WriteFile(filepath.Join(e.path, "crds", crd.FileName), yamlContent, 0644)
+		err = atomicfile.WriteFile(filepath.Join(dir, "crds", crd.FileName), yamlContent, 0644)
 		if err != nil {
 			return fmt.Errorf("failed to write crds/%s file: %w", crd.FileName, err)
 		}
 	}
 
 	// Write template files
-	for _, template := range e.config.Spec.Templates {
+	for _, template := range templates {
 		content := []byte{}
 		if len(template.Content.Raw) != 0 {
 			yamlContent, err := yaml.JSONToYAML(template.Content.Raw)
@@ -170,12 +179,36 @@ func (e *Expander) WriteInputsToFileSystem() error {
 		} else if template.Template != "" {
 			content = []byte(template.Template)
 		}
-		err = atomicfile.WriteFile(filepath.Join(e.path, "templates", template.FileName), content, 0644)
+		err = atomicfile.WriteFile(filepath.Join(dir, "templates", template.FileName), content, 0644)
 		if err != nil {
 			return fmt.Errorf("failed to write templates/%s file: %w", template.FileName, err)
 		}
 	}
 
+	return nil
+}
+
+// writeChartRefInputs writes the packaged chart and spec.defaultValues, which
+// override the values of the chart
+func (e *Expander) writeChartRefInputs() error {
+	err := atomicfile.WriteFile(e.chartPath(), e.chart.Archive, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to write chart.tgz file: %w", err)
+	}
+	if len(e.config.Spec.DefaultValues.Raw) != 0 {
+		yamlContent, err := yaml.JSONToYAML(e.config.Spec.DefaultValues.Raw)
+		if err != nil {
+			return fmt.Errorf("failed to marshall default-values.yaml: %w", err)
+		}
+		err = atomicfile.WriteFile(filepath.Join(e.path, "default-values.yaml"), yamlContent, 0644)
+		if err != nil {
+			return fmt.Errorf("failed to write default-values.yaml file: %w", err)
+		}
+	}
+	return nil
+}
+
+func (e *Expander) writeFacadeValues() error {
 	valuesObj := map[string]interface{}{}
 
 	// Write Facade as values ?
@@ -190,11 +223,27 @@ func (e *Expander) WriteInputsToFileSystem() error {
 		valuesObj["context"] = spec
 	}
 	if e.fetchedValues != nil {
-		valuesObj["fetched"] = *e.fetchedValues
+		valuesObj["fetched"] = e.fetchedValues
 	}
 	if e.facade != nil {
 		valuesObj[e.inputResource] = e.facade.Object
 	}
+	tags, err := e.tags(valuesObj)
+	if err != nil {
+		return err
+	}
+	// Subcharts only see their own values and the global ones
+	if e.hasDependencies {
+		global := map[string]interface{}{}
+		for k, v := range valuesObj {
+			global[k] = v
+		}
+		valuesObj["global"] = global
+	}
+	if len(tags) != 0 {
+		valuesObj["tags"] = tags
+	}
+	e.values = valuesObj
 	valuesBytes, err := json.Marshal(valuesObj)
 	if err != nil {
 		return fmt.Errorf("Unable to marshall values object")
@@ -206,12 +255,22 @@ func (e *Expander) WriteInputsToFileSystem() error {
 	return nil
 }
 
-func (e *Expander) Validate(result *pb.ValidateResult) (*pb.ValidateResult, error) {
+func (e *Expander) Validate() (*expandersdk.Result, error) {
+	if _, err := e.valuesSchema(); err != nil {
+		return nil, err
+	}
+	if e.facade != nil {
+		if err := e.validateValues(); err != nil {
+			return nil, err
+		}
+	}
+
 	// https://helm.sh/docs/helm/helm_lint/
 	// Usage:
 	//  helm lint PATH [flags]
 	//
-	args := []string{"lint", e.path}
+	args := []string{"lint", e.chartPath()}
+	args = append(args, e.defaultValuesArgs()...)
 	if e.facade != nil {
 		facadeFile := filepath.Join(e.path, "facade-values.yaml")
 		args = append(args, "-f", facadeFile)
@@ -219,27 +278,35 @@ func (e *Expander) Validate(result *pb.ValidateResult) (*pb.ValidateResult, erro
 
 	op, err := exec.Command("helm", args...).CombinedOutput()
 	if err != nil {
-		result.Error.Message = fmt.Sprintf("failed validating template:\n %s", string(op))
-		result.Status = pb.Status_VALIDATE_FAILED
-		log.Print(result.Error.Message)
-		return result, nil
+		return nil, expandersdk.Failedf("failed validating template:\n %s", string(op))
 	}
 
-	//log.Printf("Result:\n  type: %s\n  error: %s\n  status: %s\n", result.Type, result.Errors, result.Status)
-	return result, nil
+	return nil, nil
 }
 
-func (e *Expander) Evaluate(result *pb.EvaluateResult) (*pb.EvaluateResult, error) {
+func (e *Expander) Evaluate() (*expandersdk.Result, error) {
+	if err := e.validateValues(); err != nil {
+		return nil, err
+	}
+
 	// https://helm.sh/docs/helm/helm_template/
 	// Usage:
 	//  helm template [NAME] [CHART] [flags]
 	//
-	name := "unknown"
-	args := []string{"template", name, e.path}
-	if e.facade != nil {
-		name = e.facade.GetName()
-		facadeFile := filepath.Join(e.path, "facade-values.yaml")
-		args = append(args, "-f", facadeFile)
+	// The facade is the release. Evaluate calls always have one.
+	args := []string{"template", e.facade.GetName(), e.chartPath(), "--namespace", e.facade.GetNamespace()}
+	args = append(args, e.defaultValuesArgs()...)
+	facadeFile := filepath.Join(e.path, "facade-values.yaml")
+	args = append(args, "-f", facadeFile)
+
+	// .Capabilities are helm's defaults if the controller did not send the cluster
+	if e.cluster != nil {
+		if e.cluster.KubeVersion != "" {
+			args = append(args, "--kube-version", e.cluster.KubeVersion)
+		}
+		if len(e.cluster.APIVersions) != 0 {
+			args = append(args, "--api-versions", strings.Join(e.cluster.APIVersions, ","))
+		}
 	}
 
 	//args = append(args, "--output-dir", filepath.Join(e.path, "rendered"))
@@ -247,10 +314,7 @@ func (e *Expander) Evaluate(result *pb.EvaluateResult) (*pb.EvaluateResult, erro
 	log.Printf("running: helm %s", strings.Join(args, " "))
 	op, err := exec.Command("helm", args...).CombinedOutput()
 	if err != nil {
-		result.Error.Message = fmt.Sprintf("failed evaluating helm chart:\n %s", string(op))
-		result.Status = pb.Status_EVALUATE_FAILED
-		log.Print(result.Error.Message)
-		return result, nil
+		return nil, expandersdk.Failedf("failed evaluating helm chart:\n %s", string(op))
 	}
 
 	manifests := op
@@ -275,132 +339,54 @@ func (e *Expander) Evaluate(result *pb.EvaluateResult) (*pb.EvaluateResult, erro
 				}
 			}
 	*/
-	result.Manifests = manifests
-
-	//log.Printf("Result:\n  type: %s\n  error: %s\n  status: %s\n  manifests: %s\n  values: %s\n", result.Type, result.Errors, result.Status, result.Manifests, result.Values)
-	return result, nil
-}
-
-// ------------- GRPC Server implementation ----------------
-
-// server is used to implement exander.Evaluator
-type server struct {
-	pb.UnimplementedExpanderServer
-}
-
-// Verify the expander config/template
-func (s *server) Validate(ctx context.Context, req *pb.ValidateRequest) (*pb.ValidateResult, error) {
-	result := &pb.ValidateResult{
-		Status: pb.Status_SUCCESS,
-		Error:  &pb.Error{},
-	}
-
-	log.Printf("Validate called")
-	e, failedMessage, err := NewExpander(ctx, nil, req)
-	if err != nil {
-		return nil, err
-	}
-	if failedMessage != "" {
-		result.Error.Message = failedMessage
-		result.Status = pb.Status_VALIDATE_FAILED
-		return result, nil
-	}
-
-	dir, err := os.MkdirTemp("", "validate")
-	if err != nil {
-		newerr := fmt.Errorf("unexpected tmp file creation failure. %w", err)
-		log.Print(newerr.Error())
-		return nil, newerr
-	}
-	err = os.Mkdir(filepath.Join(dir, "templates"), 0700)
-	if err != nil {
-		newerr := fmt.Errorf("%s/templates dir creation failed: %w", dir, err)
-		log.Print(newerr.Error())
-		return nil, newerr
-	}
-	err = os.Mkdir(filepath.Join(dir, "crds"), 0700)
+	// Hooks are applied by the controller in their phase, not with the release
+	manifests, hooks, diagnostics, err := splitHooks(manifests)
 	if err != nil {
-		newerr := fmt.Errorf("%s/crds dir creation failed: %w", dir, err)
-		log.Print(newerr.Error())
-		return nil, newerr
-	}
-	// cleanup
-	defer os.RemoveAll(dir)
-
-	e.path = dir
-
-	if err = e.WriteInputsToFileSystem(); err != nil {
-		newerr := fmt.Errorf("error processing inputs: %w", err)
-		log.Print(newerr.Error())
-		return nil, newerr
+		return nil, expandersdk.Failedf("%v", err)
 	}
-
-	return e.Validate(result)
+	return &expandersdk.Result{Manifests: manifests, Hooks: hooks, Diagnostics: diagnostics}, nil
 }
 
-// Evaluate the expander config in context of inputs and return manifests
-func (s *server) Evaluate(ctx context.Context, req *pb.EvaluateRequest) (*pb.EvaluateResult, error) {
-	result := &pb.EvaluateResult{
-		Status: pb.Status_SUCCESS,
-		Type:   pb.ResultType_MANIFESTS,
-		Error:  &pb.Error{},
+// defaultValuesArgs passes spec.defaultValues to helm when the chart is a
+// packaged one with its own values.yaml
+func (e *Expander) defaultValuesArgs() []string {
+	if e.chart == nil || len(e.config.Spec.DefaultValues.Raw) == 0 {
+		return nil
 	}
+	return []string{"-f", filepath.Join(e.path, "default-values.yaml")}
+}
 
-	log.Printf("Evaluate called")
-	e, failedMessage, err := NewExpander(ctx, req, nil)
+func Validate(ctx context.Context, req *Request) (*expandersdk.Result, error) {
+	e, cleanup, err := NewExpander(ctx, req, "validate")
 	if err != nil {
 		return nil, err
 	}
-	if failedMessage != "" {
-		result.Error.Message = failedMessage
-		result.Status = pb.Status_EVALUATE_FAILED
-		return result, nil
-	}
+	defer cleanup()
+	return e.Validate()
+}
 
-	dir, err := os.MkdirTemp("", "eval")
-	if err != nil {
-		newerr := fmt.Errorf("tmp eval dir creation failure. %w", err)
-		log.Print(newerr.Error())
-		return nil, newerr
-	}
-	err = os.Mkdir(filepath.Join(dir, "templates"), 0700)
+func Evaluate(ctx context.Context, req *Request) (*expandersdk.Result, error) {
+	e, cleanup, err := NewExpander(ctx, req, "eval")
 	if err != nil {
-		newerr := fmt.Errorf("%s/templates dir creation failed: %w", dir, err)
-		log.Print(newerr.Error())
-		return nil, newerr
-	}
-	err = os.Mkdir(filepath.Join(dir, "crds"), 0700)
-	if err != nil {
-		newerr := fmt.Errorf("%s/crds dir creation failed: %w", dir, err)
-		log.Print(newerr.Error())
-		return nil, newerr
-	}
-	// cleanup
-	defer os.RemoveAll(dir)
-
-	e.path = dir
-
-	if err = e.WriteInputsToFileSystem(); err != nil {
-		newerr := fmt.Errorf("error processing inputs: %w", err)
-		log.Print(newerr.Error())
-		return nil, newerr
+		return nil, err
 	}
-
-	return e.Evaluate(result)
+	defer cleanup()
+	return e.Evaluate()
 }
 
-// -------------------- MAIN -------------------------------
-
 func main() {
-	flag.Parse()
-	lis, err := net.Listen("tcp", fmt.Sprintf(":%d", *port))
-	if err != nil {
-		log.Fatalf("failed to listen: %v", err)
-	}
-	s := grpc.NewServer()
-	pb.RegisterExpanderServer(s, &server{})
-	log.Printf("server listening at %v", lis.Addr())
-	if err := s.Serve(lis); err != nil {
-		log.Fatalf("failed to serve: %v", err)
-	}
+	// Without a cluster the expander still renders inline charts and charts
+	// of registries and repositories without pull secrets
+	if config, err := ctrl.GetConfig(); err != nil {
+		log.Printf("no kubernetes config, chart references can not be read or pinned: %v", err)
+	} else if fetcher.Client, err = dynamic.NewForConfig(config); err != nil {
+		log.Fatalf("failed to get dynamic client error: %v", err)
+	}
+
+	expandersdk.Main(&expandersdk.Expander[helmconfigurationv1alpha1.HelmConfiguration]{
+		Name:     "helm",
+		Config:   helmconfigurationv1alpha1.GroupVersion.WithKind("HelmConfiguration"),
+		Validate: Validate,
+		Evaluate: Evaluate,
+	})
 }
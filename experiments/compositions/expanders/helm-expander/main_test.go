@@ -25,6 +25,7 @@ import (
 	"time"
 
 	pb "github.com/cloud-native-compositions/compositions/composition/proto"
+	pbv2 "github.com/cloud-native-compositions/compositions/composition/proto/v2"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials/insecure"
 	"sigs.k8s.io/yaml"
@@ -173,9 +174,37 @@ spec:
         car: "{{ .Values.fetched.car }}"
         {{ if eq .Values.fetched.car "sedan" }}trunk: "true"{{ end }}
 `
+
+	// Chart rendering the release metadata and the capabilities
+	releaseChart = `apiVersion: composition.google.com/v1alpha1
+kind: HelmConfiguration
+metadata:
+  name: foobar
+  namespace: config-control
+spec:
+  chart:
+    apiVersion: v2
+    name: hello-world
+    description: sample chart
+    version: 0.1.0
+  templates:
+  - name: configmap.yaml
+    template: |
+      apiVersion: v1
+      kind: ConfigMap
+      metadata:
+        name: {{ .Release.Name }}
+        namespace: {{ .Release.Namespace }}
+      data:
+        kubeVersion: {{ .Capabilities.KubeVersion.Version }}
+        hasWidget: "{{ .Capabilities.APIVersions.Has "example.com/v1/Widget" }}"
+`
 )
 
-var expanderClient pb.ExpanderClient
+var (
+	expanderClient   pb.ExpanderClient
+	expanderClientV2 pbv2.ExpanderClient
+)
 
 func dummyValues(t *testing.T) []byte {
 	y := `foo: bar
@@ -243,6 +272,7 @@ func TestMain(m *testing.M) {
 		log.Fatalf("did not connect: %v", err)
 	}
 	expanderClient = pb.NewExpanderClient(conn)
+	expanderClientV2 = pbv2.NewExpanderClient(conn)
 
 	exitCode := m.Run()
 	os.Exit(exitCode)
@@ -701,3 +731,53 @@ func TestValidateNoTemplateConfig(t *testing.T) {
 		t.Fatalf("want SUCCESS, got: %s", r.GetStatus())
 	}
 }
+
+func TestEvaluateReleaseMetadata(t *testing.T) {
+	r, err := expanderClient.Evaluate(context.Background(),
+		&pb.EvaluateRequest{
+			Resource: "sqls",
+			Config:   configFrom(t, releaseChart),
+			Context:  testContext(t),
+			Facade:   testFacade(t, ""),
+			Value:    dummyValues(t),
+		})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if r.GetStatus() != pb.Status_SUCCESS {
+		t.Fatalf("want SUCCESS, got: %s", r)
+	}
+	for _, expected := range []string{"name: appteam-sample\n", "namespace: default\n", "hasWidget: \"false\"\n"} {
+		if !strings.Contains(string(r.Manifests), expected) {
+			t.Fatalf("\nexpected to contain: %s\ngot: %s", expected, r.Manifests)
+		}
+	}
+}
+
+func TestEvaluateClusterCapabilities(t *testing.T) {
+	r, err := expanderClientV2.Evaluate(context.Background(),
+		&pbv2.EvaluateRequest{
+			Resource: "sqls",
+			Config:   configFrom(t, releaseChart),
+			Context:  testContext(t),
+			Facade:   testFacade(t, ""),
+			Value:    dummyValues(t),
+			Cluster: &pbv2.Cluster{
+				KubeVersion: "v1.30.2",
+				ApiVersions: []string{"v1", "v1/ConfigMap", "example.com/v1", "example.com/v1/Widget"},
+			},
+		})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if r.GetStatus() != pbv2.Status_SUCCESS {
+		t.Fatalf("want SUCCESS, got: %s", r)
+	}
+	if len(r.Objects) != 1 {
+		t.Fatalf("want 1 object, got: %s", r)
+	}
+	expected := `{"apiVersion":"v1","data":{"hasWidget":"true","kubeVersion":"v1.30.2"},"kind":"ConfigMap","metadata":{"name":"appteam-sample","namespace":"default"}}`
+	if strings.TrimSpace(string(r.Objects[0].Json)) != expected {
+		t.Fatalf("\nexpected: %s\ngot: %s", expected, r.Objects[0].Json)
+	}
+}
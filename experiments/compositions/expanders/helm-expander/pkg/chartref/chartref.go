@@ -0,0 +1,546 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package chartref fetches the packaged charts a HelmConfiguration references
+// from OCI registries, chart repositories, ConfigMaps and Secrets.
+package chartref
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/Masterminds/semver/v3"
+	helmconfigurationv1alpha1 "github.com/cloud-native-compositions/compositions/expander/helm-expander/api/v1alpha1"
+	"helm.sh/helm/v3/pkg/chart/loader"
+	"helm.sh/helm/v3/pkg/registry"
+	"helm.sh/helm/v3/pkg/repo"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/dynamic"
+	"sigs.k8s.io/yaml"
+)
+
+// DefaultKey is the key of the packaged chart in a ConfigMap or Secret
+const DefaultKey = "chart.tgz"
+
+// CacheSize is the number of packaged charts a Fetcher keeps
+const CacheSize = 64
+
+var (
+	configMaps         = corev1.SchemeGroupVersion.WithResource("configmaps")
+	secrets            = corev1.SchemeGroupVersion.WithResource("secrets")
+	helmConfigurations = helmconfigurationv1alpha1.GroupVersion.WithResource("helmconfigurations")
+)
+
+// Chart is a packaged chart
+type Chart struct {
+	Name    string
+	Version string
+	// Digest is the sha256 digest of Archive, for example sha256:5e8f...
+	Digest  string
+	Archive []byte
+}
+
+// Fetcher fetches charts and pins them in the status of the HelmConfiguration.
+// The zero value fetches OCI and repository charts without credentials.
+type Fetcher struct {
+	// Client reads ConfigMaps and Secrets and writes the status of
+	// HelmConfigurations. Without it only OCI and repository charts without
+	// pull secrets can be fetched and nothing is pinned.
+	Client dynamic.Interface
+	// HTTPClient fetches repository indexes and charts. Defaults to http.DefaultClient
+	HTTPClient *http.Client
+
+	mu    sync.Mutex
+	cache map[string][]byte
+	// order has the digests in cache, oldest first
+	order []string
+}
+
+// Dependency is a dependency of a chart fetched from a chart reference
+type Dependency struct {
+	// Name is the name of the dependency in Chart.yaml
+	Name string
+	// Field is the field of the reference in the config, for example spec.subcharts[0].chartRef
+	Field string
+	Ref   *helmconfigurationv1alpha1.ChartReference
+}
+
+// Validate checks that exactly one source of the chart is set. field is the
+// field of the reference in the config, for example spec.chartRef.
+func Validate(field string, ref *helmconfigurationv1alpha1.ChartReference) error {
+	sources := []string{}
+	if ref.OCI != "" {
+		sources = append(sources, "oci")
+		if !registry.IsOCI(ref.OCI) {
+			return fmt.Errorf("%s.oci: %q does not start with oci://", field, ref.OCI)
+		}
+	}
+	if ref.RepoURL != "" {
+		sources = append(sources, "repoURL")
+		if ref.Chart == "" {
+			return fmt.Errorf("%s.chart: the name of the chart in %s is required", field, ref.RepoURL)
+		}
+	}
+	if ref.ConfigMap != nil {
+		sources = append(sources, "configMap")
+	}
+	if ref.Secret != nil {
+		sources = append(sources, "secret")
+	}
+	switch len(sources) {
+	case 0:
+		return fmt.Errorf("%s: one of oci, repoURL, configMap and secret is required", field)
+	case 1:
+	default:
+		return fmt.Errorf("%s: only one of oci, repoURL, configMap and secret can be set, got: %s", field, strings.Join(sources, ", "))
+	}
+	if ref.Chart != "" && ref.RepoURL == "" {
+		return fmt.Errorf("%s.chart is only used with %s.repoURL", field, field)
+	}
+	if ref.Version != "" && ref.OCI == "" && ref.RepoURL == "" {
+		return fmt.Errorf("%s.version is only used with %s.oci and %s.repoURL", field, field, field)
+	}
+	if ref.Version != "" {
+		if _, err := semver.NewConstraint(ref.Version); err != nil {
+			return fmt.Errorf("%s.version: %w", field, err)
+		}
+	}
+	return nil
+}
+
+// Fetch returns the chart of spec.chartRef.
+//
+// OCI and repository charts are pinned: the version and digest they resolve to
+// are written to the status of the config, and later calls for the same
+// generation of the config use that version and fail if its digest changed.
+// Charts in ConfigMaps and Secrets are read on every call.
+func (f *Fetcher) Fetch(ctx context.Context, config *helmconfigurationv1alpha1.HelmConfiguration) (*Chart, error) {
+	field := "spec.chartRef"
+	if err := Validate(field, config.Spec.ChartRef); err != nil {
+		return nil, err
+	}
+	chart, err := f.resolve(ctx, config.Namespace, field, config.Spec.ChartRef, current(config, config.Status.Chart))
+	if err != nil {
+		return nil, err
+	}
+	status := config.Status.DeepCopy()
+	status.Chart = pinnedChart(config, chart)
+	f.pin(ctx, config, status)
+	return chart, nil
+}
+
+// FetchDependencies returns the charts of dependencies by name. They are
+// pinned in status.dependencies as the chart of spec.chartRef is in status.chart.
+func (f *Fetcher) FetchDependencies(ctx context.Context, config *helmconfigurationv1alpha1.HelmConfiguration, dependencies []Dependency) (map[string]*Chart, error) {
+	pins := map[string]*helmconfigurationv1alpha1.PinnedChart{}
+	for i := range config.Status.Dependencies {
+		pins[config.Status.Dependencies[i].Dependency] = &config.Status.Dependencies[i].PinnedChart
+	}
+
+	charts := map[string]*Chart{}
+	status := config.Status.DeepCopy()
+	status.Dependencies = nil
+	for _, dependency := range dependencies {
+		if err := Validate(dependency.Field, dependency.Ref); err != nil {
+			return nil, err
+		}
+		chart, err := f.resolve(ctx, config.Namespace, dependency.Field, dependency.Ref, current(config, pins[dependency.Name]))
+		if err != nil {
+			return nil, fmt.Errorf("dependency %s: %w", dependency.Name, err)
+		}
+		charts[dependency.Name] = chart
+		status.Dependencies = append(status.Dependencies, helmconfigurationv1alpha1.PinnedDependency{
+			Dependency:  dependency.Name,
+			PinnedChart: *pinnedChart(config, chart),
+		})
+	}
+	f.pin(ctx, config, status)
+	return charts, nil
+}
+
+// current returns the pinned chart if it was pinned for the generation of the config
+func current(config *helmconfigurationv1alpha1.HelmConfiguration, pinned *helmconfigurationv1alpha1.PinnedChart) *helmconfigurationv1alpha1.PinnedChart {
+	if pinned != nil && pinned.ObservedGeneration == config.Generation && pinned.Digest != "" {
+		return pinned
+	}
+	return nil
+}
+
+func pinnedChart(config *helmconfigurationv1alpha1.HelmConfiguration, chart *Chart) *helmconfigurationv1alpha1.PinnedChart {
+	return &helmconfigurationv1alpha1.PinnedChart{
+		ObservedGeneration: config.Generation,
+		Name:               chart.Name,
+		Version:            chart.Version,
+		Digest:             chart.Digest,
+	}
+}
+
+// resolve fetches a chart. An OCI or repository chart is fetched at the
+// version of pinned, if set, and must have its digest.
+func (f *Fetcher) resolve(ctx context.Context, namespace string, field string, ref *helmconfigurationv1alpha1.ChartReference, pinned *helmconfigurationv1alpha1.PinnedChart) (*Chart, error) {
+	if ref.ConfigMap != nil || ref.Secret != nil {
+		return f.archive(ctx, namespace, field, ref)
+	}
+
+	version := ref.Version
+	if pinned != nil {
+		if archive, ok := f.cached(pinned.Digest); ok {
+			return &Chart{Name: pinned.Name, Version: pinned.Version, Digest: pinned.Digest, Archive: archive}, nil
+		}
+		version = pinned.Version
+	}
+
+	var chart *Chart
+	var err error
+	if ref.OCI != "" {
+		chart, err = f.oci(ctx, namespace, field, ref, version)
+	} else {
+		chart, err = f.repository(ctx, namespace, field, ref, version)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if pinned != nil && chart.Digest != pinned.Digest {
+		return nil, fmt.Errorf("chart %s %s has digest %s, %s is pinned in the status", chart.Name, chart.Version, chart.Digest, pinned.Digest)
+	}
+	return chart, nil
+}
+
+// pin writes the pinned charts to the status of the config. The charts are
+// still used if this fails, and pinned by a later call.
+func (f *Fetcher) pin(ctx context.Context, config *helmconfigurationv1alpha1.HelmConfiguration, status *helmconfigurationv1alpha1.HelmConfigurationStatus) {
+	if reflect.DeepEqual(&config.Status, status) {
+		return
+	}
+	// Later calls for the same config patch on top of this status
+	config.Status = *status
+	// Configs of stage templates have no name
+	if f.Client == nil || config.Name == "" {
+		return
+	}
+	// null removes the charts that are no longer pinned
+	patch, err := json.Marshal(map[string]interface{}{"status": map[string]interface{}{
+		"chart":        status.Chart,
+		"dependencies": status.Dependencies,
+	}})
+	if err != nil {
+		log.Printf("error marshalling status of %s/%s: %v", config.Namespace, config.Name, err)
+		return
+	}
+	_, err = f.Client.Resource(helmConfigurations).Namespace(config.Namespace).
+		Patch(ctx, config.Name, types.MergePatchType, patch, metav1.PatchOptions{}, "status")
+	if err != nil {
+		log.Printf("error pinning charts in %s/%s: %v", config.Namespace, config.Name, err)
+	}
+}
+
+// oci pulls a chart from a registry
+func (f *Fetcher) oci(ctx context.Context, namespace string, field string, ref *helmconfigurationv1alpha1.ChartReference, version string) (*Chart, error) {
+	name := strings.TrimPrefix(ref.OCI, fmt.Sprintf("%s://", registry.OCIScheme))
+	host, _, _ := strings.Cut(name, "/")
+
+	username, password, err := f.credentials(ctx, namespace, field, ref.PullSecret, host)
+	if err != nil {
+		return nil, err
+	}
+	// The registry client reads credentials from a docker config file only
+	credentials, err := os.CreateTemp("", "registry-config")
+	if err != nil {
+		return nil, fmt.Errorf("error creating registry config: %w", err)
+	}
+	defer os.Remove(credentials.Name())
+	auths := map[string]interface{}{}
+	if username != "" || password != "" {
+		auths[host] = map[string]string{"auth": base64.StdEncoding.EncodeToString([]byte(username + ":" + password))}
+	}
+	if err := json.NewEncoder(credentials).Encode(map[string]interface{}{"auths": auths}); err != nil {
+		credentials.Close()
+		return nil, fmt.Errorf("error writing registry config: %w", err)
+	}
+	credentials.Close()
+
+	options := []registry.ClientOption{registry.ClientOptCredentialsFile(credentials.Name())}
+	if f.HTTPClient != nil {
+		options = append(options, registry.ClientOptHTTPClient(f.HTTPClient))
+	}
+	if ref.PlainHTTP {
+		options = append(options, registry.ClientOptPlainHTTP())
+	}
+	client, err := registry.NewClient(options...)
+	if err != nil {
+		return nil, fmt.Errorf("error creating registry client: %w", err)
+	}
+
+	// Exact versions are pulled without listing the tags
+	if _, err := semver.StrictNewVersion(version); err != nil {
+		tags, err := client.Tags(name)
+		if err != nil {
+			return nil, fmt.Errorf("error listing the versions of %s: %w", ref.OCI, err)
+		}
+		version, err = registry.GetTagMatchingVersionOrConstraint(tags, version)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", ref.OCI, err)
+		}
+	}
+
+	result, err := client.Pull(fmt.Sprintf("%s:%s", name, version))
+	if err != nil {
+		return nil, fmt.Errorf("error pulling %s:%s: %w", ref.OCI, version, err)
+	}
+	if digest := sha256Digest(result.Chart.Data); digest != result.Chart.Digest {
+		return nil, fmt.Errorf("chart %s:%s has digest %s, the registry sent %s", ref.OCI, version, digest, result.Chart.Digest)
+	}
+	chart := &Chart{
+		Name:    result.Chart.Meta.Name,
+		Version: result.Chart.Meta.Version,
+		Digest:  result.Chart.Digest,
+		Archive: result.Chart.Data,
+	}
+	f.add(chart)
+	return chart, nil
+}
+
+// repository downloads a chart from a chart repository
+func (f *Fetcher) repository(ctx context.Context, namespace string, field string, ref *helmconfigurationv1alpha1.ChartReference, version string) (*Chart, error) {
+	repoURL, err := url.Parse(ref.RepoURL)
+	if err != nil {
+		return nil, fmt.Errorf("%s.repoURL: %w", field, err)
+	}
+	username, password, err := f.credentials(ctx, namespace, field, ref.PullSecret, repoURL.Host)
+	if err != nil {
+		return nil, err
+	}
+
+	indexURL := strings.TrimSuffix(ref.RepoURL, "/") + "/index.yaml"
+	data, err := f.get(ctx, indexURL, repoURL.Host, username, password)
+	if err != nil {
+		return nil, err
+	}
+	index := &repo.IndexFile{}
+	if err := yaml.Unmarshal(data, index); err != nil {
+		return nil, fmt.Errorf("error reading %s: %w", indexURL, err)
+	}
+	index.SortEntries()
+	entry, err := index.Get(ref.Chart, version)
+	if err != nil {
+		return nil, fmt.Errorf("chart %s %s in %s: %w", ref.Chart, version, ref.RepoURL, err)
+	}
+	if len(entry.URLs) == 0 {
+		return nil, fmt.Errorf("chart %s %s in %s has no urls", entry.Name, entry.Version, ref.RepoURL)
+	}
+
+	if entry.Digest != "" {
+		if archive, ok := f.cached("sha256:" + entry.Digest); ok {
+			return &Chart{Name: entry.Name, Version: entry.Version, Digest: "sha256:" + entry.Digest, Archive: archive}, nil
+		}
+	}
+	chartURL, err := repo.ResolveReferenceURL(strings.TrimSuffix(ref.RepoURL, "/")+"/", entry.URLs[0])
+	if err != nil {
+		return nil, fmt.Errorf("chart %s %s in %s: %w", entry.Name, entry.Version, ref.RepoURL, err)
+	}
+	archive, err := f.get(ctx, chartURL, repoURL.Host, username, password)
+	if err != nil {
+		return nil, err
+	}
+	chart := &Chart{Name: entry.Name, Version: entry.Version, Digest: sha256Digest(archive), Archive: archive}
+	if entry.Digest != "" && chart.Digest != "sha256:"+entry.Digest {
+		return nil, fmt.Errorf("chart %s has digest %s, the index of %s has sha256:%s", chartURL, chart.Digest, ref.RepoURL, entry.Digest)
+	}
+	f.add(chart)
+	return chart, nil
+}
+
+// get downloads a url. The credentials are only sent to the host of the repository.
+func (f *Fetcher) get(ctx context.Context, u string, host string, username string, password string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching %s: %w", u, err)
+	}
+	if (username != "" || password != "") && req.URL.Host == host {
+		req.SetBasicAuth(username, password)
+	}
+	client := f.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching %s: %w", u, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("error fetching %s: %s", u, resp.Status)
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching %s: %w", u, err)
+	}
+	return data, nil
+}
+
+// archive reads a packaged chart from a ConfigMap or Secret
+func (f *Fetcher) archive(ctx context.Context, namespace string, field string, ref *helmconfigurationv1alpha1.ChartReference) (*Chart, error) {
+	source, resource, sourceField := ref.ConfigMap, configMaps, field+".configMap"
+	if ref.Secret != nil {
+		source, resource, sourceField = ref.Secret, secrets, field+".secret"
+	}
+	field = sourceField
+	if source.Namespace != "" {
+		namespace = source.Namespace
+	}
+	key := source.Key
+	if key == "" {
+		key = DefaultKey
+	}
+	if f.Client == nil {
+		return nil, fmt.Errorf("%s: %s/%s can not be read, the expander has no kubernetes client", field, namespace, source.Name)
+	}
+	u, err := f.Client.Resource(resource).Namespace(namespace).Get(ctx, source.Name, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("%s: error getting %s/%s: %w", field, namespace, source.Name, err)
+	}
+
+	var archive []byte
+	var found bool
+	if ref.Secret != nil {
+		secret := &corev1.Secret{}
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(u.Object, secret); err != nil {
+			return nil, fmt.Errorf("%s: error converting %s/%s: %w", field, namespace, source.Name, err)
+		}
+		archive, found = secret.Data[key]
+	} else {
+		configMap := &corev1.ConfigMap{}
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(u.Object, configMap); err != nil {
+			return nil, fmt.Errorf("%s: error converting %s/%s: %w", field, namespace, source.Name, err)
+		}
+		archive, found = configMap.BinaryData[key]
+		if !found {
+			var s string
+			s, found = configMap.Data[key]
+			archive = []byte(s)
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf("%s: %s/%s has no key %s", field, namespace, source.Name, key)
+	}
+
+	digest := sha256Digest(archive)
+	if cached, ok := f.cached(digest); ok {
+		archive = cached
+	}
+	chart, err := loader.LoadArchive(bytes.NewReader(archive))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s/%s key %s is not a packaged chart: %w", field, namespace, source.Name, key, err)
+	}
+	c := &Chart{Name: chart.Metadata.Name, Version: chart.Metadata.Version, Digest: digest, Archive: archive}
+	f.add(c)
+	return c, nil
+}
+
+// credentials reads the username and password for a host from a pull secret
+func (f *Fetcher) credentials(ctx context.Context, namespace string, field string, ref *helmconfigurationv1alpha1.SecretReference, host string) (string, string, error) {
+	if ref == nil {
+		return "", "", nil
+	}
+	field += ".pullSecret"
+	if ref.Namespace != "" {
+		namespace = ref.Namespace
+	}
+	if f.Client == nil {
+		return "", "", fmt.Errorf("%s: %s/%s can not be read, the expander has no kubernetes client", field, namespace, ref.Name)
+	}
+	u, err := f.Client.Resource(secrets).Namespace(namespace).Get(ctx, ref.Name, metav1.GetOptions{})
+	if err != nil {
+		return "", "", fmt.Errorf("%s: error getting %s/%s: %w", field, namespace, ref.Name, err)
+	}
+	secret := &corev1.Secret{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(u.Object, secret); err != nil {
+		return "", "", fmt.Errorf("%s: error converting %s/%s: %w", field, namespace, ref.Name, err)
+	}
+
+	dockerConfig, ok := secret.Data[corev1.DockerConfigJsonKey]
+	if !ok {
+		return string(secret.Data["username"]), string(secret.Data["password"]), nil
+	}
+	config := struct {
+		Auths map[string]struct {
+			Username string `json:"username"`
+			Password string `json:"password"`
+			Auth     string `json:"auth"`
+		} `json:"auths"`
+	}{}
+	if err := json.Unmarshal(dockerConfig, &config); err != nil {
+		return "", "", fmt.Errorf("%s: %s/%s has an invalid %s: %w", field, namespace, ref.Name, corev1.DockerConfigJsonKey, err)
+	}
+	for server, auth := range config.Auths {
+		// Servers are hosts or urls, like https://index.docker.io/v1/
+		if server != host && !strings.HasPrefix(server, "https://"+host) && !strings.HasPrefix(server, "http://"+host) {
+			continue
+		}
+		if auth.Auth == "" {
+			return auth.Username, auth.Password, nil
+		}
+		decoded, err := base64.StdEncoding.DecodeString(auth.Auth)
+		if err != nil {
+			return "", "", fmt.Errorf("%s: %s/%s has an invalid auth for %s: %w", field, namespace, ref.Name, server, err)
+		}
+		username, password, _ := strings.Cut(string(decoded), ":")
+		return username, password, nil
+	}
+	return "", "", fmt.Errorf("%s: %s/%s has no credentials for %s", field, namespace, ref.Name, host)
+}
+
+func (f *Fetcher) cached(digest string) ([]byte, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	archive, ok := f.cache[digest]
+	return archive, ok
+}
+
+// add caches a chart, dropping the oldest one if the cache is full
+func (f *Fetcher) add(chart *Chart) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.cache == nil {
+		f.cache = map[string][]byte{}
+	}
+	if _, ok := f.cache[chart.Digest]; ok {
+		return
+	}
+	if len(f.order) == CacheSize {
+		delete(f.cache, f.order[0])
+		f.order = f.order[1:]
+	}
+	f.cache[chart.Digest] = chart.Archive
+	f.order = append(f.order, chart.Digest)
+}
+
+func sha256Digest(data []byte) string {
+	return fmt.Sprintf("sha256:%x", sha256.Sum256(data))
+}
@@ -0,0 +1,637 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package chartref
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	helmconfigurationv1alpha1 "github.com/cloud-native-compositions/compositions/expander/helm-expander/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/dynamic/fake"
+)
+
+// packageChart returns a packaged chart with a ConfigMap template
+func packageChart(t *testing.T, name, version, data string) []byte {
+	files := map[string]string{
+		"Chart.yaml":               fmt.Sprintf("apiVersion: v2\nname: %s\nversion: %s\n", name, version),
+		"templates/configmap.yaml": fmt.Sprintf("apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: %s\ndata:\n  data: %s\n", name, data),
+	}
+	buf := &bytes.Buffer{}
+	gz := gzip.NewWriter(buf)
+	tw := tar.NewWriter(gz)
+	for _, file := range []string{"Chart.yaml", "templates/configmap.yaml"} {
+		content := files[file]
+		if err := tw.WriteHeader(&tar.Header{Name: name + "/" + file, Mode: 0644, Size: int64(len(content))}); err != nil {
+			t.Fatalf("error writing chart: %v", err)
+		}
+		if _, err := tw.Write([]byte(content)); err != nil {
+			t.Fatalf("error writing chart: %v", err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatalf("error writing chart: %v", err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatalf("error writing chart: %v", err)
+	}
+	return buf.Bytes()
+}
+
+// server is a stand-in for a chart repository or an OCI registry. It
+// requires basic auth when username is set and counts the requests.
+type server struct {
+	username string
+	password string
+
+	mu       sync.Mutex
+	requests int
+	// charts has the packaged charts by version
+	charts map[string][]byte
+	name   string
+}
+
+func (s *server) count() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests
+}
+
+func (s *server) authorized(w http.ResponseWriter, r *http.Request) bool {
+	s.mu.Lock()
+	s.requests++
+	s.mu.Unlock()
+	if s.username == "" {
+		return true
+	}
+	if username, password, ok := r.BasicAuth(); ok && username == s.username && password == s.password {
+		return true
+	}
+	w.Header().Set("WWW-Authenticate", `Basic realm="test"`)
+	w.WriteHeader(http.StatusUnauthorized)
+	return false
+}
+
+// repository serves index.yaml and the charts under charts/
+func (s *server) repository(w http.ResponseWriter, r *http.Request) {
+	if !s.authorized(w, r) {
+		return
+	}
+	if r.URL.Path == "/index.yaml" {
+		index := fmt.Sprintf("apiVersion: v1\nentries:\n  %s:\n", s.name)
+		for version, chart := range s.charts {
+			index += fmt.Sprintf("  - apiVersion: v2\n    name: %s\n    version: %s\n    digest: %x\n    urls:\n    - charts/%s-%s.tgz\n",
+				s.name, version, sha256.Sum256(chart), s.name, version)
+		}
+		w.Write([]byte(index))
+		return
+	}
+	for version, chart := range s.charts {
+		if r.URL.Path == fmt.Sprintf("/charts/%s-%s.tgz", s.name, version) {
+			w.Write(chart)
+			return
+		}
+	}
+	w.WriteHeader(http.StatusNotFound)
+}
+
+// registry serves the charts as the OCI distribution API does, under charts/<name>
+func (s *server) registry(w http.ResponseWriter, r *http.Request) {
+	if !s.authorized(w, r) {
+		return
+	}
+	if r.URL.Path == "/v2/" {
+		return
+	}
+	prefix := fmt.Sprintf("/v2/charts/%s/", s.name)
+	if !strings.HasPrefix(r.URL.Path, prefix) {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	path := strings.TrimPrefix(r.URL.Path, prefix)
+
+	blobs := map[string][]byte{}
+	manifests := map[string][]byte{}
+	tags := []string{}
+	for version, chart := range s.charts {
+		config := []byte(fmt.Sprintf(`{"apiVersion":"v2","name":%q,"version":%q}`, s.name, version))
+		blobs[sha256Digest(config)] = config
+		blobs[sha256Digest(chart)] = chart
+		manifest := []byte(fmt.Sprintf(`{"schemaVersion":2,"mediaType":"application/vnd.oci.image.manifest.v1+json",`+
+			`"config":{"mediaType":"application/vnd.cncf.helm.config.v1+json","digest":%q,"size":%d},`+
+			`"layers":[{"mediaType":"application/vnd.cncf.helm.chart.content.v1.tar+gzip","digest":%q,"size":%d}]}`,
+			sha256Digest(config), len(config), sha256Digest(chart), len(chart)))
+		manifests[version] = manifest
+		manifests[sha256Digest(manifest)] = manifest
+		tags = append(tags, version)
+	}
+
+	var content []byte
+	var ok bool
+	switch {
+	case path == "tags/list":
+		content, _ = json.Marshal(map[string]interface{}{"name": "charts/" + s.name, "tags": tags})
+		w.Header().Set("Content-Type", "application/json")
+		ok = true
+	case strings.HasPrefix(path, "manifests/"):
+		content, ok = manifests[strings.TrimPrefix(path, "manifests/")]
+		w.Header().Set("Content-Type", "application/vnd.oci.image.manifest.v1+json")
+		w.Header().Set("Docker-Content-Digest", sha256Digest(content))
+	case strings.HasPrefix(path, "blobs/"):
+		content, ok = blobs[strings.TrimPrefix(path, "blobs/")]
+		w.Header().Set("Content-Type", "application/octet-stream")
+	}
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Length", fmt.Sprint(len(content)))
+	if r.Method != http.MethodHead {
+		w.Write(content)
+	}
+}
+
+func testClient(t *testing.T, objects ...interface{}) dynamic.Interface {
+	objs := []runtime.Object{}
+	for _, o := range objects {
+		u, err := runtime.DefaultUnstructuredConverter.ToUnstructured(o)
+		if err != nil {
+			t.Fatalf("error converting %v: %v", o, err)
+		}
+		objs = append(objs, &unstructured.Unstructured{Object: u})
+	}
+	return fake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{
+			helmConfigurations: "HelmConfigurationList",
+			configMaps:         "ConfigMapList",
+			secrets:            "SecretList",
+		}, objs...)
+}
+
+func testConfig(ref *helmconfigurationv1alpha1.ChartReference) *helmconfigurationv1alpha1.HelmConfiguration {
+	return &helmconfigurationv1alpha1.HelmConfiguration{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: helmconfigurationv1alpha1.GroupVersion.String(),
+			Kind:       "HelmConfiguration",
+		},
+		ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "default", Generation: 2},
+		Spec:       helmconfigurationv1alpha1.HelmConfigurationSpec{ChartRef: ref},
+	}
+}
+
+func pinned(t *testing.T, client dynamic.Interface) *helmconfigurationv1alpha1.PinnedChart {
+	u, err := client.Resource(helmConfigurations).Namespace("default").Get(context.Background(), "app", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("error getting config: %v", err)
+	}
+	config := &helmconfigurationv1alpha1.HelmConfiguration{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(u.Object, config); err != nil {
+		t.Fatalf("error converting config: %v", err)
+	}
+	return config.Status.Chart
+}
+
+func TestValidate(t *testing.T) {
+	testcases := []struct {
+		name string
+		ref  helmconfigurationv1alpha1.ChartReference
+		err  string
+	}{
+		{
+			name: "oci",
+			ref:  helmconfigurationv1alpha1.ChartReference{OCI: "oci://ghcr.io/org/charts/app", Version: "^1.2"},
+		},
+		{
+			name: "repository",
+			ref:  helmconfigurationv1alpha1.ChartReference{RepoURL: "https://charts.example.com", Chart: "app"},
+		},
+		{
+			name: "configmap",
+			ref:  helmconfigurationv1alpha1.ChartReference{ConfigMap: &helmconfigurationv1alpha1.ArchiveReference{Name: "app"}},
+		},
+		{
+			name: "no source",
+			err:  "spec.chartRef: one of oci, repoURL, configMap and secret is required",
+		},
+		{
+			name: "two sources",
+			ref: helmconfigurationv1alpha1.ChartReference{
+				OCI:    "oci://ghcr.io/org/charts/app",
+				Secret: &helmconfigurationv1alpha1.ArchiveReference{Name: "app"},
+			},
+			err: "spec.chartRef: only one of oci, repoURL, configMap and secret can be set, got: oci, secret",
+		},
+		{
+			name: "oci without scheme",
+			ref:  helmconfigurationv1alpha1.ChartReference{OCI: "ghcr.io/org/charts/app"},
+			err:  `spec.chartRef.oci: "ghcr.io/org/charts/app" does not start with oci://`,
+		},
+		{
+			name: "repository without chart",
+			ref:  helmconfigurationv1alpha1.ChartReference{RepoURL: "https://charts.example.com"},
+			err:  "spec.chartRef.chart: the name of the chart in https://charts.example.com is required",
+		},
+		{
+			name: "configmap with version",
+			ref: helmconfigurationv1alpha1.ChartReference{
+				ConfigMap: &helmconfigurationv1alpha1.ArchiveReference{Name: "app"},
+				Version:   "1.0.0",
+			},
+			err: "spec.chartRef.version is only used with spec.chartRef.oci and spec.chartRef.repoURL",
+		},
+		{
+			name: "invalid version",
+			ref:  helmconfigurationv1alpha1.ChartReference{OCI: "oci://ghcr.io/org/charts/app", Version: "one"},
+			err:  "spec.chartRef.version: improper constraint: one",
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := Validate("spec.chartRef", &tc.ref)
+			if tc.err == "" {
+				if err != nil {
+					t.Fatalf("expected no error. got: %v", err)
+				}
+				return
+			}
+			if err == nil || err.Error() != tc.err {
+				t.Fatalf("\nexpected error: %s\n got: %v", tc.err, err)
+			}
+		})
+	}
+}
+
+func TestFetchRepository(t *testing.T) {
+	s := &server{
+		name:     "app",
+		username: "user",
+		password: "secret",
+		charts: map[string][]byte{
+			"0.1.0":      packageChart(t, "app", "0.1.0", "a"),
+			"0.1.1":      packageChart(t, "app", "0.1.1", "b"),
+			"0.2.0":      packageChart(t, "app", "0.2.0", "c"),
+			"1.0.0-rc.1": packageChart(t, "app", "1.0.0-rc.1", "d"),
+		},
+	}
+	ts := httptest.NewServer(http.HandlerFunc(s.repository))
+	defer ts.Close()
+
+	secret := &corev1.Secret{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Secret"},
+		ObjectMeta: metav1.ObjectMeta{Name: "charts", Namespace: "default"},
+		Data:       map[string][]byte{"username": []byte("user"), "password": []byte("secret")},
+	}
+
+	for _, tc := range []struct {
+		version  string
+		expected string
+	}{
+		{version: "", expected: "0.2.0"},
+		{version: "~0.1", expected: "0.1.1"},
+		{version: "0.1.0", expected: "0.1.0"},
+	} {
+		t.Run(tc.expected, func(t *testing.T) {
+			config := testConfig(&helmconfigurationv1alpha1.ChartReference{
+				RepoURL:    ts.URL,
+				Chart:      "app",
+				Version:    tc.version,
+				PullSecret: &helmconfigurationv1alpha1.SecretReference{Name: "charts"},
+			})
+			client := testClient(t, secret, config)
+			f := &Fetcher{Client: client}
+			chart, err := f.Fetch(context.Background(), config)
+			if err != nil {
+				t.Fatalf("expected no error. got: %v", err)
+			}
+			if chart.Version != tc.expected || !bytes.Equal(chart.Archive, s.charts[tc.expected]) {
+				t.Fatalf("expected chart %s. got: %s", tc.expected, chart.Version)
+			}
+			expected := helmconfigurationv1alpha1.PinnedChart{
+				ObservedGeneration: 2,
+				Name:               "app",
+				Version:            tc.expected,
+				Digest:             sha256Digest(s.charts[tc.expected]),
+			}
+			if p := pinned(t, client); p == nil || *p != expected {
+				t.Fatalf("\nexpected pinned: %+v\n got: %+v", expected, p)
+			}
+
+			// The pinned chart is served from the cache
+			requests := s.count()
+			config.Status.Chart = &expected
+			if _, err := f.Fetch(context.Background(), config); err != nil {
+				t.Fatalf("expected no error. got: %v", err)
+			}
+			if s.count() != requests {
+				t.Fatalf("expected the pinned chart to be cached. got %d requests", s.count()-requests)
+			}
+		})
+	}
+}
+
+func TestFetchPinned(t *testing.T) {
+	s := &server{
+		name: "app",
+		charts: map[string][]byte{
+			"0.1.0": packageChart(t, "app", "0.1.0", "a"),
+			"0.2.0": packageChart(t, "app", "0.2.0", "b"),
+		},
+	}
+	ts := httptest.NewServer(http.HandlerFunc(s.repository))
+	defer ts.Close()
+
+	config := testConfig(&helmconfigurationv1alpha1.ChartReference{RepoURL: ts.URL, Chart: "app"})
+	pin := helmconfigurationv1alpha1.PinnedChart{
+		ObservedGeneration: 2,
+		Name:               "app",
+		Version:            "0.1.0",
+		Digest:             sha256Digest(s.charts["0.1.0"]),
+	}
+	config.Status.Chart = pin.DeepCopy()
+	chart, err := (&Fetcher{}).Fetch(context.Background(), config)
+	if err != nil {
+		t.Fatalf("expected no error. got: %v", err)
+	}
+	if chart.Version != "0.1.0" {
+		t.Fatalf("expected the pinned version 0.1.0. got: %s", chart.Version)
+	}
+
+	// A new generation resolves the version again
+	config.Generation = 3
+	chart, err = (&Fetcher{}).Fetch(context.Background(), config)
+	if err != nil {
+		t.Fatalf("expected no error. got: %v", err)
+	}
+	if chart.Version != "0.2.0" {
+		t.Fatalf("expected the latest version 0.2.0. got: %s", chart.Version)
+	}
+
+	// The pinned version was published again with other content
+	config.Generation = 2
+	config.Status.Chart = pin.DeepCopy()
+	s.charts["0.1.0"] = packageChart(t, "app", "0.1.0", "changed")
+	_, err = (&Fetcher{}).Fetch(context.Background(), config)
+	expected := fmt.Sprintf("chart app 0.1.0 has digest %s, %s is pinned in the status", sha256Digest(s.charts["0.1.0"]), config.Status.Chart.Digest)
+	if err == nil || err.Error() != expected {
+		t.Fatalf("\nexpected error: %s\n got: %v", expected, err)
+	}
+}
+
+func TestFetchOCI(t *testing.T) {
+	s := &server{
+		name:     "app",
+		username: "user",
+		password: "secret",
+		charts: map[string][]byte{
+			"1.0.0": packageChart(t, "app", "1.0.0", "a"),
+			"1.1.0": packageChart(t, "app", "1.1.0", "b"),
+			"2.0.0": packageChart(t, "app", "2.0.0", "c"),
+		},
+	}
+	ts := httptest.NewServer(http.HandlerFunc(s.registry))
+	defer ts.Close()
+	host := strings.TrimPrefix(ts.URL, "http://")
+
+	dockerConfig := fmt.Sprintf(`{"auths": {%q: {"auth": %q}}}`, host, base64.StdEncoding.EncodeToString([]byte("user:secret")))
+	secret := &corev1.Secret{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Secret"},
+		ObjectMeta: metav1.ObjectMeta{Name: "registry", Namespace: "default"},
+		Type:       corev1.SecretTypeDockerConfigJson,
+		Data:       map[string][]byte{corev1.DockerConfigJsonKey: []byte(dockerConfig)},
+	}
+
+	for _, tc := range []struct {
+		version  string
+		expected string
+	}{
+		{version: "", expected: "2.0.0"},
+		{version: "^1.0.0", expected: "1.1.0"},
+		{version: "1.0.0", expected: "1.0.0"},
+	} {
+		t.Run(tc.expected, func(t *testing.T) {
+			config := testConfig(&helmconfigurationv1alpha1.ChartReference{
+				OCI:        fmt.Sprintf("oci://%s/charts/app", host),
+				Version:    tc.version,
+				PullSecret: &helmconfigurationv1alpha1.SecretReference{Name: "registry"},
+				PlainHTTP:  true,
+			})
+			client := testClient(t, secret, config)
+			chart, err := (&Fetcher{Client: client}).Fetch(context.Background(), config)
+			if err != nil {
+				t.Fatalf("expected no error. got: %v", err)
+			}
+			if chart.Name != "app" || chart.Version != tc.expected || !bytes.Equal(chart.Archive, s.charts[tc.expected]) {
+				t.Fatalf("expected chart app %s. got: %s %s", tc.expected, chart.Name, chart.Version)
+			}
+			if p := pinned(t, client); p == nil || p.Version != tc.expected || p.Digest != sha256Digest(s.charts[tc.expected]) {
+				t.Fatalf("expected %s to be pinned. got: %+v", tc.expected, p)
+			}
+		})
+	}
+
+	t.Run("unauthorized", func(t *testing.T) {
+		config := testConfig(&helmconfigurationv1alpha1.ChartReference{
+			OCI:       fmt.Sprintf("oci://%s/charts/app", host),
+			Version:   "1.0.0",
+			PlainHTTP: true,
+		})
+		_, err := (&Fetcher{}).Fetch(context.Background(), config)
+		if err == nil || !strings.Contains(err.Error(), "error pulling") {
+			t.Fatalf("expected a pull error. got: %v", err)
+		}
+	})
+}
+
+func TestFetchArchive(t *testing.T) {
+	archive := packageChart(t, "app", "0.3.0", "a")
+	configMap := &corev1.ConfigMap{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "ConfigMap"},
+		ObjectMeta: metav1.ObjectMeta{Name: "app-chart", Namespace: "charts"},
+		BinaryData: map[string][]byte{"app.tgz": archive},
+	}
+	secret := &corev1.Secret{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Secret"},
+		ObjectMeta: metav1.ObjectMeta{Name: "app-chart", Namespace: "default"},
+		Data:       map[string][]byte{DefaultKey: archive},
+	}
+
+	testcases := []struct {
+		name string
+		ref  helmconfigurationv1alpha1.ChartReference
+		err  string
+	}{
+		{
+			name: "configmap",
+			ref: helmconfigurationv1alpha1.ChartReference{
+				ConfigMap: &helmconfigurationv1alpha1.ArchiveReference{Name: "app-chart", Namespace: "charts", Key: "app.tgz"},
+			},
+		},
+		{
+			name: "secret",
+			ref: helmconfigurationv1alpha1.ChartReference{
+				Secret: &helmconfigurationv1alpha1.ArchiveReference{Name: "app-chart"},
+			},
+		},
+		{
+			name: "missing key",
+			ref: helmconfigurationv1alpha1.ChartReference{
+				ConfigMap: &helmconfigurationv1alpha1.ArchiveReference{Name: "app-chart", Namespace: "charts"},
+			},
+			err: "spec.chartRef.configMap: charts/app-chart has no key chart.tgz",
+		},
+		{
+			name: "missing configmap",
+			ref: helmconfigurationv1alpha1.ChartReference{
+				ConfigMap: &helmconfigurationv1alpha1.ArchiveReference{Name: "app-chart"},
+			},
+			err: `spec.chartRef.configMap: error getting default/app-chart: configmaps "app-chart" not found`,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			config := testConfig(&tc.ref)
+			client := testClient(t, configMap, secret, config)
+			chart, err := (&Fetcher{Client: client}).Fetch(context.Background(), config)
+			if tc.err != "" {
+				if err == nil || err.Error() != tc.err {
+					t.Fatalf("\nexpected error: %s\n got: %v", tc.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("expected no error. got: %v", err)
+			}
+			if chart.Name != "app" || chart.Version != "0.3.0" || chart.Digest != sha256Digest(archive) {
+				t.Fatalf("expected chart app 0.3.0. got: %s %s %s", chart.Name, chart.Version, chart.Digest)
+			}
+			if p := pinned(t, client); p == nil || p.Version != "0.3.0" {
+				t.Fatalf("expected 0.3.0 to be pinned. got: %+v", p)
+			}
+		})
+	}
+
+	t.Run("no client", func(t *testing.T) {
+		config := testConfig(&helmconfigurationv1alpha1.ChartReference{
+			Secret: &helmconfigurationv1alpha1.ArchiveReference{Name: "app-chart"},
+		})
+		_, err := (&Fetcher{}).Fetch(context.Background(), config)
+		expected := "spec.chartRef.secret: default/app-chart can not be read, the expander has no kubernetes client"
+		if err == nil || err.Error() != expected {
+			t.Fatalf("\nexpected error: %s\n got: %v", expected, err)
+		}
+	})
+}
+
+func TestCache(t *testing.T) {
+	f := &Fetcher{}
+	for i := 0; i <= CacheSize; i++ {
+		f.add(&Chart{Digest: fmt.Sprint(i)})
+	}
+	if _, ok := f.cached("0"); ok {
+		t.Fatalf("expected the oldest chart to be dropped")
+	}
+	if _, ok := f.cached(fmt.Sprint(CacheSize)); !ok {
+		t.Fatalf("expected the newest chart to be cached")
+	}
+}
+
+func TestFetchDependencies(t *testing.T) {
+	s := &server{
+		name: "cache",
+		charts: map[string][]byte{
+			"1.0.0": packageChart(t, "cache", "1.0.0", "a"),
+		},
+	}
+	ts := httptest.NewServer(http.HandlerFunc(s.repository))
+	defer ts.Close()
+
+	archive := packageChart(t, "common", "0.1.0", "b")
+	configMap := &corev1.ConfigMap{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "ConfigMap"},
+		ObjectMeta: metav1.ObjectMeta{Name: "common", Namespace: "default"},
+		BinaryData: map[string][]byte{DefaultKey: archive},
+	}
+	config := testConfig(nil)
+	config.Status.Chart = &helmconfigurationv1alpha1.PinnedChart{ObservedGeneration: 2, Name: "app", Version: "2.0.0", Digest: "sha256:0"}
+	config.Status.Dependencies = []helmconfigurationv1alpha1.PinnedDependency{
+		{Dependency: "removed", PinnedChart: helmconfigurationv1alpha1.PinnedChart{ObservedGeneration: 1, Name: "removed", Version: "1.0.0", Digest: "sha256:1"}},
+	}
+	client := testClient(t, configMap, config)
+
+	charts, err := (&Fetcher{Client: client}).FetchDependencies(context.Background(), config, []Dependency{
+		{
+			Name:  "cache",
+			Field: "spec.chart dependencies[0]",
+			Ref:   &helmconfigurationv1alpha1.ChartReference{RepoURL: ts.URL, Chart: "cache", Version: "^1.0.0"},
+		},
+		{
+			Name:  "common",
+			Field: "spec.subcharts[0].chartRef",
+			Ref:   &helmconfigurationv1alpha1.ChartReference{ConfigMap: &helmconfigurationv1alpha1.ArchiveReference{Name: "common"}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("expected no error. got: %v", err)
+	}
+	if charts["cache"].Version != "1.0.0" || charts["common"].Version != "0.1.0" {
+		t.Fatalf("expected cache 1.0.0 and common 0.1.0. got: %+v", charts)
+	}
+
+	u, err := client.Resource(helmConfigurations).Namespace("default").Get(context.Background(), "app", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("error getting config: %v", err)
+	}
+	status := &helmconfigurationv1alpha1.HelmConfiguration{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(u.Object, status); err != nil {
+		t.Fatalf("error converting config: %v", err)
+	}
+	expected := []helmconfigurationv1alpha1.PinnedDependency{
+		{Dependency: "cache", PinnedChart: helmconfigurationv1alpha1.PinnedChart{ObservedGeneration: 2, Name: "cache", Version: "1.0.0", Digest: sha256Digest(s.charts["1.0.0"])}},
+		{Dependency: "common", PinnedChart: helmconfigurationv1alpha1.PinnedChart{ObservedGeneration: 2, Name: "common", Version: "0.1.0", Digest: sha256Digest(archive)}},
+	}
+	if !reflect.DeepEqual(status.Status.Dependencies, expected) {
+		t.Fatalf("\nexpected pinned: %+v\n got: %+v", expected, status.Status.Dependencies)
+	}
+	if status.Status.Chart == nil || status.Status.Chart.Version != "2.0.0" {
+		t.Fatalf("expected status.chart to be kept. got: %+v", status.Status.Chart)
+	}
+
+	// A new generation with another version
+	config.Generation = 3
+	_, err = (&Fetcher{}).FetchDependencies(context.Background(), config, []Dependency{
+		{Name: "cache", Field: "spec.chart dependencies[0]", Ref: &helmconfigurationv1alpha1.ChartReference{RepoURL: ts.URL, Chart: "cache", Version: "^2.0.0"}},
+	})
+	message := fmt.Sprintf("dependency cache: chart cache ^2.0.0 in %s: no chart version found for cache-^2.0.0", ts.URL)
+	if err == nil || err.Error() != message {
+		t.Fatalf("\nexpected error: %s\n got: %v", message, err)
+	}
+}
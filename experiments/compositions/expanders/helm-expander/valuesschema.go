@@ -0,0 +1,113 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/cloud-native-compositions/compositions/composition/pkg/expandersdk"
+	"github.com/xeipuuv/gojsonschema"
+	"helm.sh/helm/v3/pkg/chart/loader"
+	"helm.sh/helm/v3/pkg/chartutil"
+)
+
+// valuesSchema compiles spec.valuesSchema. It returns nil if there is none.
+func (e *Expander) valuesSchema() (*gojsonschema.Schema, error) {
+	raw := e.config.Spec.ValuesSchema.Raw
+	if len(raw) == 0 {
+		return nil, nil
+	}
+	schema, err := gojsonschema.NewSchema(gojsonschema.NewBytesLoader(raw))
+	if err != nil {
+		return nil, expandersdk.Failed(expandersdk.ErrorDiagnostic("InvalidValuesSchema", "spec.valuesSchema: %v", err))
+	}
+	return schema, nil
+}
+
+// mergedValues are the values helm renders the chart with: the values of the
+// chart, spec.defaultValues of packaged charts, and the facade, context and
+// fetched values
+func (e *Expander) mergedValues() (map[string]interface{}, error) {
+	c, err := loader.Load(e.chartPath())
+	if err != nil {
+		return nil, fmt.Errorf("failed to load chart: %w", err)
+	}
+	// The values are copied as coalescing changes them
+	values := map[string]interface{}{}
+	if err := roundTrip(e.values, &values); err != nil {
+		return nil, err
+	}
+	if e.chart != nil && len(e.config.Spec.DefaultValues.Raw) != 0 {
+		defaults := map[string]interface{}{}
+		if err := json.Unmarshal(e.config.Spec.DefaultValues.Raw, &defaults); err != nil {
+			return nil, fmt.Errorf("failed to parse spec.defaultValues: %w", err)
+		}
+		values = chartutil.CoalesceTables(values, defaults)
+	}
+	return chartutil.CoalesceValues(c, values)
+}
+
+// validateValues checks the merged values against spec.valuesSchema. Each
+// violation is an error diagnostic with the path of the value, and the field
+// path when the value is a field of the facade.
+func (e *Expander) validateValues() error {
+	schema, err := e.valuesSchema()
+	if schema == nil || err != nil {
+		return err
+	}
+	values, err := e.mergedValues()
+	if err != nil {
+		return err
+	}
+	result, err := schema.Validate(gojsonschema.NewGoLoader(map[string]interface{}(values)))
+	if err != nil {
+		return expandersdk.Failed(expandersdk.ErrorDiagnostic("InvalidValuesSchema", "spec.valuesSchema: %v", err))
+	}
+	if result.Valid() {
+		return nil
+	}
+
+	diagnostics := []*expandersdk.Diagnostic{}
+	for _, re := range result.Errors() {
+		path := []string{}
+		if field := re.Field(); field != gojsonschema.STRING_CONTEXT_ROOT {
+			path = strings.Split(field, ".")
+		}
+		if property, ok := re.Details()["property"].(string); ok && re.Type() == "required" {
+			path = append(path, property)
+		}
+		d := expandersdk.ErrorDiagnostic("ValuesSchemaViolation", "%s: %s",
+			strings.Join(append([]string{"values"}, path...), "."), re.Description())
+		// The facade is passed to helm at .Values.<resource>
+		if len(path) > 1 && path[0] == e.inputResource {
+			d.FieldPath = strings.Join(path[1:], ".")
+		}
+		diagnostics = append(diagnostics, d)
+	}
+	return expandersdk.Failed(diagnostics...)
+}
+
+func roundTrip(in, out interface{}) error {
+	b, err := json.Marshal(in)
+	if err != nil {
+		return fmt.Errorf("failed to copy values: %w", err)
+	}
+	if err := json.Unmarshal(b, out); err != nil {
+		return fmt.Errorf("failed to copy values: %w", err)
+	}
+	return nil
+}
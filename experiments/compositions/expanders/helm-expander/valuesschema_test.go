@@ -0,0 +1,215 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main_test
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	pb "github.com/cloud-native-compositions/compositions/composition/proto"
+	pbv2 "github.com/cloud-native-compositions/compositions/composition/proto/v2"
+)
+
+// schemaChart checks the facade, the fetched values and the default values
+func schemaChart(defaultValues, schema string) string {
+	return fmt.Sprintf(`apiVersion: composition.google.com/v1alpha1
+kind: HelmConfiguration
+metadata:
+  name: greeting
+  namespace: config-control
+spec:
+  chart:
+    apiVersion: v2
+    name: greeting
+    version: 0.1.0
+  defaultValues:
+    %s
+  valuesSchema:
+    %s
+  templates:
+  - name: configmap.yaml
+    template: |
+      apiVersion: v1
+      kind: ConfigMap
+      metadata:
+        name: {{ .Release.Name }}
+      data:
+        greeting: hello {{ .Values.sqls.spec.foo }}
+        repeat: "{{ .Values.repeat }}"
+`, defaultValues, schema)
+}
+
+const greetingSchema = `type: object
+    required: [repeat, fetched]
+    properties:
+      repeat:
+        type: integer
+      fetched:
+        type: object
+        required: [foo]
+      sqls:
+        type: object
+        properties:
+          spec:
+            type: object
+            properties:
+              car:
+                enum: [hatchback, sedan]`
+
+func TestEvaluateValuesSchema(t *testing.T) {
+	testcases := []struct {
+		name          string
+		defaultValues string
+		schema        string
+		facade        string
+		values        []byte
+		messages      []string
+		fieldPaths    []string
+	}{
+		{
+			name:          "valid values",
+			defaultValues: "repeat: 2",
+			schema:        greetingSchema,
+		},
+		{
+			name:          "default values",
+			defaultValues: "repeat: twice",
+			schema:        greetingSchema,
+			messages:      []string{"values.repeat: Invalid type. Expected: integer, given: string"},
+			fieldPaths:    []string{""},
+		},
+		{
+			name:          "facade",
+			defaultValues: "repeat: 2",
+			schema:        greetingSchema,
+			facade: `apiVersion: facade.foobar.com/v1alpha1
+kind: Foo
+metadata:
+  name: appteam-sample
+  namespace: default
+spec:
+  foo: bar
+  car: truck
+`,
+			messages:   []string{`values.sqls.spec.car: sqls.spec.car must be one of the following: "hatchback", "sedan"`},
+			fieldPaths: []string{"spec.car"},
+		},
+		{
+			name:          "missing values",
+			defaultValues: "{}",
+			schema:        greetingSchema,
+			values:        []byte(`{"car": "sedan"}`),
+			messages:      []string{"values.repeat: repeat is required", "values.fetched.foo: foo is required"},
+			fieldPaths:    []string{"", ""},
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			values := tc.values
+			if values == nil {
+				values = dummyValues(t)
+			}
+			r, err := expanderClientV2.Evaluate(context.Background(),
+				&pbv2.EvaluateRequest{
+					Resource: "sqls",
+					Config:   configFrom(t, schemaChart(tc.defaultValues, tc.schema)),
+					Context:  testContext(t),
+					Facade:   testFacade(t, tc.facade),
+					Value:    values,
+				})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(tc.messages) == 0 {
+				if r.GetStatus() != pbv2.Status_SUCCESS {
+					t.Fatalf("want SUCCESS, got: %s", r)
+				}
+				return
+			}
+			if r.GetStatus() != pbv2.Status_EVALUATE_FAILED {
+				t.Fatalf("want EVALUATE_FAILED, got: %s", r)
+			}
+			messages, fieldPaths := []string{}, []string{}
+			for _, d := range r.Diagnostics {
+				if d.Code != "ValuesSchemaViolation" {
+					t.Errorf("want code ValuesSchemaViolation, got: %s", d)
+				}
+				messages = append(messages, d.Message)
+				fieldPaths = append(fieldPaths, d.FieldPath)
+			}
+			if strings.Join(messages, "\n") != strings.Join(tc.messages, "\n") {
+				t.Errorf("\nwant messages: %q\ngot: %q", tc.messages, messages)
+			}
+			if strings.Join(fieldPaths, "\n") != strings.Join(tc.fieldPaths, "\n") {
+				t.Errorf("\nwant field paths: %q\ngot: %q", tc.fieldPaths, fieldPaths)
+			}
+		})
+	}
+}
+
+func TestValidateValuesSchema(t *testing.T) {
+	testcases := []struct {
+		name   string
+		schema string
+		facade []byte
+		err    string
+	}{
+		{
+			name:   "invalid schema",
+			schema: "type: dictionary",
+			err:    "spec.valuesSchema: has a primitive type that is NOT VALID -- given: /dictionary/ Expected valid values are:[array boolean integer number null object string]",
+		},
+		{
+			name:   "valid values",
+			schema: greetingSchema,
+			facade: testFacade(t, ""),
+		},
+		{
+			name:   "facade",
+			schema: strings.Replace(greetingSchema, "[hatchback, sedan]", "[hatchback]", 1),
+			facade: testFacade(t, ""),
+			err:    `values.sqls.spec.car: sqls.spec.car must be one of the following: "hatchback"`,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			r, err := expanderClient.Validate(context.Background(),
+				&pb.ValidateRequest{
+					Config:   configFrom(t, schemaChart("repeat: 2", tc.schema)),
+					Resource: "sqls",
+					Context:  testContext(t),
+					Facade:   tc.facade,
+					Value:    dummyValues(t),
+				})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tc.err == "" {
+				if r.GetStatus() != pb.Status_SUCCESS {
+					t.Fatalf("want SUCCESS, got: %s", r)
+				}
+				return
+			}
+			if r.GetStatus() != pb.Status_VALIDATE_FAILED {
+				t.Fatalf("want VALIDATE_FAILED, got: %s", r)
+			}
+			if !strings.Contains(r.Error.Message, tc.err) {
+				t.Fatalf("\nwant error: %s\ngot: %s", tc.err, r.Error.Message)
+			}
+		})
+	}
+}
@@ -21,9 +21,30 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
+// ConditionType defines the type of Jinja2Configuration condition
+type ConditionType string
+
+// LibraryReference is a Jinja2Configuration whose templates can be included
+// and imported by other configurations
+type LibraryReference struct {
+	Name string `json:"name"`
+	// Namespace defaults to the namespace of the configuration
+	Namespace string `json:"namespace,omitempty"`
+}
+
 // Jinja2ConfigurationSpec defines the desired state of Jinja2Configuration
 type Jinja2ConfigurationSpec struct {
-	Template string `json:"template"`
+	// Template is rendered when Entrypoint is not set
+	Template string `json:"template,omitempty"`
+	// Templates are named template files, for example macros/labels.j2, that
+	// can be rendered, included, imported and extended. Names are not relative
+	// to the template using them.
+	Templates map[string]string `json:"templates,omitempty"`
+	// Entrypoint is the name of the template in Templates that is rendered
+	Entrypoint string `json:"entrypoint,omitempty"`
+	// Libraries are Jinja2Configurations whose templates can be included and
+	// imported as <library name>/<template name>, for example common/macros.j2
+	Libraries []LibraryReference `json:"libraries,omitempty"`
 }
 
 // Jinja2ConfigurationStatus defines the observed state of Jinja2Configuration
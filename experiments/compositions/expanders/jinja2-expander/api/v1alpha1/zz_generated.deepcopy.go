@@ -14,7 +14,7 @@ func (in *Jinja2Configuration) DeepCopyInto(out *Jinja2Configuration) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
-	out.Spec = in.Spec
+	in.Spec.DeepCopyInto(&out.Spec)
 	in.Status.DeepCopyInto(&out.Status)
 }
 
@@ -71,6 +71,18 @@ func (in *Jinja2ConfigurationList) DeepCopyObject() runtime.Object {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Jinja2ConfigurationSpec) DeepCopyInto(out *Jinja2ConfigurationSpec) {
 	*out = *in
+	if in.Templates != nil {
+		in, out := &in.Templates, &out.Templates
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Libraries != nil {
+		in, out := &in.Libraries, &out.Libraries
+		*out = make([]LibraryReference, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Jinja2ConfigurationSpec.
@@ -104,3 +116,18 @@ func (in *Jinja2ConfigurationStatus) DeepCopy() *Jinja2ConfigurationStatus {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *LibraryReference) DeepCopyInto(out *LibraryReference) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LibraryReference.
+func (in *LibraryReference) DeepCopy() *LibraryReference {
+	if in == nil {
+		return nil
+	}
+	out := new(LibraryReference)
+	in.DeepCopyInto(out)
+	return out
+}
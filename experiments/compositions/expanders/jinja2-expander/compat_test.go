@@ -0,0 +1,128 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main_test
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	pb "github.com/cloud-native-compositions/compositions/composition/proto"
+	"sigs.k8s.io/yaml"
+)
+
+// compatInputs are the inputs of a compatibility case
+type compatInputs struct {
+	Resource string                 `json:"resource"`
+	Facade   map[string]interface{} `json:"facade,omitempty"`
+	Context  map[string]interface{} `json:"context,omitempty"`
+	Values   map[string]interface{} `json:"values,omitempty"`
+}
+
+func marshalInput(t *testing.T, input map[string]interface{}) []byte {
+	if input == nil {
+		return nil
+	}
+	j, err := json.Marshal(input)
+	if err != nil {
+		t.Fatalf("error marshalling to json: %v", err)
+	}
+	return j
+}
+
+// objectInput completes the facade or the Context of a case into an object
+func objectInput(t *testing.T, apiVersion, kind string, input map[string]interface{}) []byte {
+	if input == nil {
+		return nil
+	}
+	return object(t, apiVersion, kind, input)
+}
+
+var updateCompat = flag.Bool("update-compat", false, "write the output files of testdata/compat instead of comparing")
+
+// TestCompatibility renders the cases in testdata/compat and compares them
+// with golden files. A case has a template.j2, the inputs.yaml it is rendered
+// with, and either the expected output or the expected error, which is a part
+// of the error message. The cases are the jinja2 stages of the samples and the
+// behaviours of the jinja2 cli the expander keeps.
+//
+// The golden files are the output of the expander, that is of gonja, written
+// with -update-compat and reviewed against the jinja2 cli by hand. They are not
+// generated by python jinja2.
+func TestCompatibility(t *testing.T) {
+	templates, err := filepath.Glob("testdata/compat/*/template.j2")
+	if err != nil {
+		t.Fatalf("error listing cases: %v", err)
+	}
+	for _, template := range templates {
+		dir := filepath.Dir(template)
+		t.Run(filepath.Base(dir), func(t *testing.T) {
+			template, err := os.ReadFile(template)
+			if err != nil {
+				t.Fatalf("error reading template: %v", err)
+			}
+			y, err := os.ReadFile(filepath.Join(dir, "inputs.yaml"))
+			if err != nil {
+				t.Fatalf("error reading inputs: %v", err)
+			}
+			inputs := compatInputs{}
+			if err := yaml.Unmarshal(y, &inputs); err != nil {
+				t.Fatalf("error unmarshalling inputs: %v", err)
+			}
+
+			r, err := expanderClient.Evaluate(context.Background(),
+				&pb.EvaluateRequest{
+					Config:   template,
+					Resource: inputs.Resource,
+					Facade:   objectInput(t, "facade.compositions.google.com/v1", "Facade", inputs.Facade),
+					Context:  objectInput(t, "composition.google.com/v1alpha1", "Context", inputs.Context),
+					Value:    marshalInput(t, inputs.Values),
+				})
+			if err != nil {
+				t.Fatalf("expected no error. got: %v", err)
+			}
+
+			if expected, err := os.ReadFile(filepath.Join(dir, "error")); err == nil {
+				if r.Status != pb.Status_EVALUATE_FAILED {
+					t.Fatalf("expected EVALUATE_FAILED. got: %s", r)
+				}
+				if !strings.Contains(r.Error.Message, string(expected)) {
+					t.Fatalf("expected error contains: %s \n got: %s", expected, r.Error.Message)
+				}
+				return
+			}
+			if r.Status != pb.Status_SUCCESS {
+				t.Fatalf("expected SUCCESS. got: %s", r)
+			}
+			if *updateCompat {
+				if err := os.WriteFile(filepath.Join(dir, "output"), r.Manifests, 0644); err != nil {
+					t.Fatalf("error writing output: %v", err)
+				}
+				return
+			}
+			expected, err := os.ReadFile(filepath.Join(dir, "output"))
+			if err != nil {
+				t.Fatalf("error reading output: %v", err)
+			}
+			if string(r.Manifests) != string(expected) {
+				t.Fatalf("\nexpected: %q\n got: %q", expected, r.Manifests)
+			}
+		})
+	}
+}
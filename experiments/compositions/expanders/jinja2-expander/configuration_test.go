@@ -0,0 +1,233 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main_test
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	pb "github.com/cloud-native-compositions/compositions/composition/proto"
+	jinja2configurationv1alpha1 "github.com/cloud-native-compositions/compositions/expander/jinja2-expander/api/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/dynamic/fake"
+)
+
+// libraryClient serves the common library the configurations of these tests use
+func libraryClient() dynamic.Interface {
+	library := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "composition.google.com/v1alpha1",
+		"kind":       "Jinja2Configuration",
+		"metadata": map[string]interface{}{
+			"name":      "common",
+			"namespace": "default",
+		},
+		"spec": map[string]interface{}{
+			"templates": map[string]interface{}{
+				"macros.j2": "{% macro labels(team) %}    team: {{ team }}{% endmacro %}",
+			},
+		},
+	}}
+	return fake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{
+			jinja2configurationv1alpha1.GroupVersion.WithResource("jinja2configurations"): "Jinja2ConfigurationList",
+		}, library)
+}
+
+func configuration(t *testing.T, spec jinja2configurationv1alpha1.Jinja2ConfigurationSpec) []byte {
+	config := &jinja2configurationv1alpha1.Jinja2Configuration{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: jinja2configurationv1alpha1.GroupVersion.String(),
+			Kind:       "Jinja2Configuration",
+		},
+		ObjectMeta: metav1.ObjectMeta{Name: "appteam", Namespace: "default"},
+		Spec:       spec,
+	}
+	j, err := json.Marshal(config)
+	if err != nil {
+		t.Fatalf("error marshalling to json: %v", err)
+	}
+	return j
+}
+
+func TestEvaluateConfiguration(t *testing.T) {
+	if *addr != "" {
+		t.Skip("the common library is only served by the in-process expander")
+	}
+	config := configuration(t, jinja2configurationv1alpha1.Jinja2ConfigurationSpec{
+		Entrypoint: "main.j2",
+		Templates: map[string]string{
+			"main.j2": `{% import "names.j2" as names -%}
+{% from "common/macros.j2" import labels -%}
+{% for env in appteams.spec.environments %}
+---
+{% include "namespace.j2" %}
+{% endfor %}`,
+			"names.j2": `{% macro namespace(team, env) %}{{ team }}-{{ env }}{% endmacro %}`,
+			"namespace.j2": `apiVersion: v1
+kind: Namespace
+metadata:
+  name: {{ names.namespace(appteams.metadata.name, env) }}
+  labels:
+{{ labels(appteams.metadata.name) }}`,
+		},
+		Libraries: []jinja2configurationv1alpha1.LibraryReference{{Name: "common"}},
+	})
+	expected := `
+---
+apiVersion: v1
+kind: Namespace
+metadata:
+  name: payments-dev
+  labels:
+    team: payments
+
+---
+apiVersion: v1
+kind: Namespace
+metadata:
+  name: payments-prod
+  labels:
+    team: payments
+`
+	r, err := expanderClient.Evaluate(context.Background(),
+		&pb.EvaluateRequest{
+			Config:   config,
+			Resource: "appteams",
+			Facade:   facade(t, `{"metadata": {"name": "payments"}, "spec": {"environments": ["dev", "prod"]}}`),
+		})
+	if err != nil {
+		t.Fatalf("expected no error. got: %v", err)
+	}
+	if r.Status != pb.Status_SUCCESS {
+		t.Fatalf("expected SUCCESS. got: %s", r)
+	}
+	if string(r.Manifests) != expected {
+		t.Fatalf("\nexpected: %q\n got: %q", expected, r.Manifests)
+	}
+}
+
+func TestEvaluateConfigurationTemplate(t *testing.T) {
+	config := configuration(t, jinja2configurationv1alpha1.Jinja2ConfigurationSpec{
+		Template: `{% include "name.j2" %}: {{ context.spec.project }}`,
+		Templates: map[string]string{
+			"name.j2": "{{ sqls.metadata.name }}\n",
+		},
+	})
+	r, err := expanderClient.Evaluate(context.Background(),
+		&pb.EvaluateRequest{
+			Config:   config,
+			Resource: "sqls",
+			Context:  contextObject(t, `{"spec": {"project": "foo"}}`),
+			Facade:   facade(t, `{"metadata": {"name": "db"}}`),
+		})
+	if err != nil {
+		t.Fatalf("expected no error. got: %v", err)
+	}
+	if string(r.Manifests) != "db: foo" {
+		t.Fatalf("\nexpected: %s\n got: %s", "db: foo", r.Manifests)
+	}
+}
+
+func TestValidateConfiguration(t *testing.T) {
+	testcases := []struct {
+		name string
+		spec jinja2configurationv1alpha1.Jinja2ConfigurationSpec
+		err  string
+	}{
+		{
+			name: "entrypoint",
+			spec: jinja2configurationv1alpha1.Jinja2ConfigurationSpec{
+				Entrypoint: "main.j2",
+				Templates:  map[string]string{"main.j2": `{% include "other.j2" %}`, "other.j2": "{{ x }}"},
+			},
+		},
+		{
+			name: "missing entrypoint",
+			spec: jinja2configurationv1alpha1.Jinja2ConfigurationSpec{
+				Entrypoint: "main.j2",
+				Templates:  map[string]string{"other.j2": "{{ x }}"},
+			},
+			err: "spec.entrypoint: template 'main.j2' not found in spec.templates",
+		},
+		{
+			name: "template and entrypoint",
+			spec: jinja2configurationv1alpha1.Jinja2ConfigurationSpec{
+				Template:   "{{ x }}",
+				Entrypoint: "main.j2",
+				Templates:  map[string]string{"main.j2": "{{ x }}"},
+			},
+			err: "spec.template and spec.entrypoint can not both be set",
+		},
+		{
+			name: "syntax error in included template",
+			spec: jinja2configurationv1alpha1.Jinja2ConfigurationSpec{
+				Template:  `{% if x %}{% include "other.j2" %}{% endif %}`,
+				Templates: map[string]string{"other.j2": "\n{{ % }}"},
+			},
+			err: "other.j2:2:4: unexpected '%'",
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			r, err := expanderClient.Validate(context.Background(),
+				&pb.ValidateRequest{
+					Config:   configuration(t, tc.spec),
+					Resource: "sqls",
+				})
+			if err != nil {
+				t.Fatalf("expected no error. got: %v", err)
+			}
+			if tc.err == "" {
+				if r.Status != pb.Status_SUCCESS {
+					t.Fatalf("expected SUCCESS. got: %s", r)
+				}
+				return
+			}
+			if r.Status != pb.Status_VALIDATE_FAILED {
+				t.Fatalf("expected VALIDATE_FAILED. got: %s", r)
+			}
+			if !strings.Contains(r.Error.Message, tc.err) {
+				t.Fatalf("expected error contains: %s \n got: %s", tc.err, r.Error.Message)
+			}
+		})
+	}
+}
+
+func TestEvaluateConfigurationMissingInclude(t *testing.T) {
+	config := configuration(t, jinja2configurationv1alpha1.Jinja2ConfigurationSpec{
+		Template: `{% include "missing.j2" %}`,
+	})
+	r, err := expanderClient.Evaluate(context.Background(),
+		&pb.EvaluateRequest{
+			Config:   config,
+			Resource: "sqls",
+			Facade:   facade(t, trivialJsonObject),
+		})
+	if err != nil {
+		t.Fatalf("expected no error. got: %v", err)
+	}
+	if r.Status != pb.Status_EVALUATE_FAILED {
+		t.Fatalf("expected EVALUATE_FAILED. got: %s", r)
+	}
+	if expected := "template 'missing.j2' not found"; !strings.Contains(r.Error.Message, expected) {
+		t.Fatalf("expected error contains: %s \n got: %s", expected, r.Error.Message)
+	}
+}
@@ -16,15 +16,21 @@ package main_test
 
 import (
 	"context"
+	"encoding/json"
 	"flag"
 	"log"
+	"net"
 	"os"
 	"strings"
 	"testing"
 
+	"github.com/cloud-native-compositions/compositions/composition/pkg/expandersdk"
 	pb "github.com/cloud-native-compositions/compositions/composition/proto"
+	pbv2 "github.com/cloud-native-compositions/compositions/composition/proto/v2"
+	"github.com/cloud-native-compositions/compositions/expander/jinja2-expander/pkg/expander"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials/insecure"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 )
 
 const (
@@ -33,21 +39,78 @@ const (
 )
 
 var (
-	addr = flag.String("addr", "[::]:8443", "the address to connect to")
+	addr = flag.String("addr", "", "the address to connect to. The expander is served in-process if empty")
 )
 
-var expanderClient pb.ExpanderClient
+var (
+	expanderClient   pb.ExpanderClient
+	expanderClientV2 pbv2.ExpanderClient
+)
+
+// object completes fields with the apiVersion, kind, name and namespace the
+// controller sends the facade and the Context with
+func object(t *testing.T, apiVersion, kind string, fields map[string]interface{}) []byte {
+	u := &unstructured.Unstructured{Object: fields}
+	if u.GetAPIVersion() == "" {
+		u.SetAPIVersion(apiVersion)
+	}
+	if u.GetKind() == "" {
+		u.SetKind(kind)
+	}
+	if u.GetName() == "" {
+		u.SetName("test")
+	}
+	if u.GetNamespace() == "" {
+		u.SetNamespace("default")
+	}
+	j, err := u.MarshalJSON()
+	if err != nil {
+		t.Fatalf("error marshalling to json: %v", err)
+	}
+	return j
+}
+
+func unmarshalFields(t *testing.T, fields string) map[string]interface{} {
+	m := map[string]interface{}{}
+	if err := json.Unmarshal([]byte(fields), &m); err != nil {
+		t.Fatalf("error unmarshalling %s: %v", fields, err)
+	}
+	return m
+}
+
+// facade returns the facade with the fields
+func facade(t *testing.T, fields string) []byte {
+	return object(t, "facade.compositions.google.com/v1", "Facade", unmarshalFields(t, fields))
+}
+
+// contextObject returns the Context with the fields
+func contextObject(t *testing.T, fields string) []byte {
+	return object(t, "composition.google.com/v1alpha1", "Context", unmarshalFields(t, fields))
+}
 
 // TestMain - umbrella test that runs all test cases
 func TestMain(m *testing.M) {
 	flag.Parse()
 
+	target := *addr
+	if target == "" {
+		lis, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			log.Fatalf("failed to listen: %v", err)
+		}
+		s := grpc.NewServer()
+		expandersdk.NewServer(expander.New(libraryClient())).Register(s)
+		go s.Serve(lis)
+		target = lis.Addr().String()
+	}
+
 	// Set up a connection to the server.
-	conn, err := grpc.NewClient(*addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	conn, err := grpc.NewClient(target, grpc.WithTransportCredentials(insecure.NewCredentials()))
 	if err != nil {
 		log.Fatalf("did not connect: %v", err)
 	}
 	expanderClient = pb.NewExpanderClient(conn)
+	expanderClientV2 = pbv2.NewExpanderClient(conn)
 
 	exitCode := m.Run()
 	os.Exit(exitCode)
@@ -58,8 +121,8 @@ func TestEvaluateEmptyConfig(t *testing.T) {
 		&pb.EvaluateRequest{
 			Config:   []byte{},
 			Resource: "sqls",
-			Context:  []byte("{\"project\": \"foo\"}"),
-			Facade:   []byte(trivialJsonObject),
+			Context:  contextObject(t, "{\"project\": \"foo\"}"),
+			Facade:   facade(t, trivialJsonObject),
 			Value:    []byte(trivialJsonObject),
 		})
 	if err != nil {
@@ -78,7 +141,7 @@ func TestEvaluateEmptyContext(t *testing.T) {
 			Config:   []byte(config),
 			Resource: "sqls",
 			Context:  []byte{},
-			Facade:   []byte(trivialJsonObject),
+			Facade:   facade(t, trivialJsonObject),
 			Value:    []byte(trivialJsonObject),
 		})
 	if err != nil {
@@ -91,21 +154,22 @@ func TestEvaluateEmptyContext(t *testing.T) {
 }
 
 func TestEvaluateEmptyFacade(t *testing.T) {
-	manifests := trivialJsonObject
 	r, err := expanderClient.Evaluate(context.Background(),
 		&pb.EvaluateRequest{
 			Config:   []byte(trivialJsonObject),
 			Resource: "sqls",
-			Context:  []byte(trivialJsonObject),
+			Context:  contextObject(t, trivialJsonObject),
 			Facade:   []byte{},
 			Value:    []byte(trivialJsonObject),
 		})
-	if err != nil {
-		t.Fatalf("expected no error. got: %v", err)
+	t.Logf("status returned: %s", r)
+	if err == nil {
+		t.Fatalf("expected error. got none")
 	}
 
-	if string(r.Manifests) != manifests {
-		t.Fatalf("\nexpected: %s\n got: %s", manifests, r.Manifests)
+	errMessage := "Empty Facade for an Evaluate call"
+	if !strings.Contains(err.Error(), errMessage) {
+		t.Fatalf("Did not find expected string in err: %s, got: %s", errMessage, err.Error())
 	}
 }
 
@@ -116,8 +180,8 @@ func TestEvaluateEmptyValue(t *testing.T) {
 		&pb.EvaluateRequest{
 			Config:   []byte(config),
 			Resource: "sqls",
-			Context:  []byte(trivialJsonObject),
-			Facade:   []byte(trivialJsonObject),
+			Context:  contextObject(t, trivialJsonObject),
+			Facade:   facade(t, trivialJsonObject),
 			Value:    []byte{},
 		})
 	if err != nil {
@@ -136,7 +200,7 @@ func TestEvaluateTemplateUsesFacade(t *testing.T) {
 		&pb.EvaluateRequest{
 			Config:   []byte(config),
 			Resource: "sqls",
-			Facade:   []byte("{\"region\": \"us-west1\"}"),
+			Facade:   facade(t, "{\"region\": \"us-west1\"}"),
 		})
 	if err != nil {
 		t.Fatalf("expected no error. got: %v", err)
@@ -155,7 +219,7 @@ func TestEvaluateTemplateMissingFacadeField(t *testing.T) {
 			Config:   []byte(config),
 			Resource: "sqls",
 			// expects zone providing region
-			Facade: []byte("{\"region\": \"us-west1\"}"),
+			Facade: facade(t, "{\"region\": \"us-west1\"}"),
 		})
 	if err != nil {
 		t.Fatalf("expected no error. got: %v", err)
@@ -177,9 +241,9 @@ func TestEvaluateTemplateUsesContext(t *testing.T) {
 	r, err := expanderClient.Evaluate(context.Background(),
 		&pb.EvaluateRequest{
 			Config:   []byte(config),
-			Context:  []byte("{\"project\": \"foobar\"}"),
+			Context:  contextObject(t, "{\"project\": \"foobar\"}"),
 			Resource: "sqls",
-			Facade:   []byte("{\"region\": \"us-west1\"}"),
+			Facade:   facade(t, "{\"region\": \"us-west1\"}"),
 		})
 	if err != nil {
 		t.Fatalf("expected no error. got: %v", err)
@@ -197,8 +261,8 @@ func TestEvaluateTemplateMissingContextField(t *testing.T) {
 			Config:   []byte(config),
 			Resource: "sqls",
 			// expects zone providing project
-			Context: []byte("{\"project\": \"foobar\"}"),
-			Facade:  []byte("{\"region\": \"us-west1\"}"),
+			Context: contextObject(t, "{\"project\": \"foobar\"}"),
+			Facade:  facade(t, "{\"region\": \"us-west1\"}"),
 		})
 	if err != nil {
 		t.Fatalf("expected no error. got: %v", err)
@@ -222,7 +286,7 @@ func TestEvaluateTemplateUsesValues(t *testing.T) {
 			Config:   []byte(config),
 			Value:    []byte("{\"email\": \"foobar@acme.com\"}"),
 			Resource: "sqls",
-			Facade:   []byte("{\"region\": \"us-west1\"}"),
+			Facade:   facade(t, "{\"region\": \"us-west1\"}"),
 		})
 	if err != nil {
 		t.Fatalf("expected no error. got: %v", err)
@@ -241,7 +305,7 @@ func TestEvaluateTemplateMissingValuesField(t *testing.T) {
 			Resource: "sqls",
 			// expects zone providing email
 			Value:  []byte("{\"email\": \"foobar@acme.comm\"}"),
-			Facade: []byte("{\"region\": \"us-west1\"}"),
+			Facade: facade(t, "{\"region\": \"us-west1\"}"),
 		})
 	if err != nil {
 		t.Fatalf("expected no error. got: %v", err)
@@ -264,7 +328,7 @@ func TestEvaluateTemplateWrongTopLevelField(t *testing.T) {
 		&pb.EvaluateRequest{
 			Config:   []byte(config),
 			Resource: "sqls",
-			Facade:   []byte("{\"region\": \"us-west1\"}"),
+			Facade:   facade(t, "{\"region\": \"us-west1\"}"),
 		})
 	if err != nil {
 		t.Fatalf("expected no error. got: %v", err)
@@ -305,8 +369,8 @@ func TestValidateEmptyConfig(t *testing.T) {
 		&pb.ValidateRequest{
 			Config:   []byte{},
 			Resource: "sqls",
-			Context:  []byte("{\"project\": \"foo\"}"),
-			Facade:   []byte(trivialJsonObject),
+			Context:  contextObject(t, "{\"project\": \"foo\"}"),
+			Facade:   facade(t, trivialJsonObject),
 			Value:    []byte(trivialJsonObject),
 		})
 	if err != nil {
@@ -324,7 +388,7 @@ func TestValidateEmptyContext(t *testing.T) {
 			Config:   []byte(config),
 			Resource: "sqls",
 			Context:  []byte{},
-			Facade:   []byte(trivialJsonObject),
+			Facade:   facade(t, trivialJsonObject),
 			Value:    []byte(trivialJsonObject),
 		})
 	if err != nil {
@@ -340,7 +404,7 @@ func TestValidateEmptyFacade(t *testing.T) {
 		&pb.ValidateRequest{
 			Config:   []byte(trivialJsonObject),
 			Resource: "sqls",
-			Context:  []byte(trivialJsonObject),
+			Context:  contextObject(t, trivialJsonObject),
 			Facade:   []byte{},
 			Value:    []byte(trivialJsonObject),
 		})
@@ -359,8 +423,8 @@ func TestValidateEmptyValue(t *testing.T) {
 		&pb.ValidateRequest{
 			Config:   []byte(config),
 			Resource: "sqls",
-			Context:  []byte(trivialJsonObject),
-			Facade:   []byte(trivialJsonObject),
+			Context:  contextObject(t, trivialJsonObject),
+			Facade:   facade(t, trivialJsonObject),
 			Value:    []byte{},
 		})
 	if err != nil {
@@ -378,7 +442,7 @@ func TestValidateTemplateUsesFacade(t *testing.T) {
 		&pb.ValidateRequest{
 			Config:   []byte(config),
 			Resource: "sqls",
-			Facade:   []byte("{\"region\": \"us-west1\"}"),
+			Facade:   facade(t, "{\"region\": \"us-west1\"}"),
 		})
 	if err != nil {
 		t.Fatalf("expected no error. got: %v", err)
@@ -397,7 +461,7 @@ func TestValidateTemplateMissingFacadeField(t *testing.T) {
 			Config:   []byte(config),
 			Resource: "sqls",
 			// expects zone providing region
-			Facade: []byte("{\"region\": \"us-west1\"}"),
+			Facade: facade(t, "{\"region\": \"us-west1\"}"),
 		})
 	if err != nil {
 		t.Fatalf("expected no error. got: %v", err)
@@ -417,7 +481,7 @@ func TestValidateJinja2ErrorTemplate(t *testing.T) {
 			Config:   []byte(config),
 			Resource: "sqls",
 			// expects zone providing region
-			Facade: []byte("{\"region\": \"us-west1\"}"),
+			Facade: facade(t, "{\"region\": \"us-west1\"}"),
 		})
 	if err != nil {
 		t.Fatalf("expected no error. got: %v", err)
@@ -428,8 +492,70 @@ func TestValidateJinja2ErrorTemplate(t *testing.T) {
 		t.Fatalf("expected VALIDATE_FAILED. got: %s", r)
 	}
 
-	expectedErrorString := "template\njinja2.exceptions.TemplateSyntaxError: unexpected '%'"
+	expectedErrorString := "/template:1:11: unexpected '%'"
 	if !strings.Contains(r.Error.Message, expectedErrorString) {
 		t.Fatalf("expected error contains: %s \n got: %s", expectedErrorString, r)
 	}
 }
+
+func TestValidateV2SyntaxDiagnostic(t *testing.T) {
+	r, err := expanderClientV2.Validate(context.Background(),
+		&pbv2.ValidateRequest{
+			Config:   []byte("region: {{% sqls.zone.foobar %}}"),
+			Resource: "sqls",
+		})
+	if err != nil {
+		t.Fatalf("expected no error. got: %v", err)
+	}
+	if r.GetStatus() != pbv2.Status_VALIDATE_FAILED || len(r.Diagnostics) != 1 {
+		t.Fatalf("want VALIDATE_FAILED with a diagnostic, got: %s", r)
+	}
+	d := r.Diagnostics[0]
+	if d.Code != "TemplateSyntaxError" {
+		t.Errorf("want code TemplateSyntaxError, got: %s", d.Code)
+	}
+	if d.Source.GetFile() != "/template" || d.Source.GetLine() != 1 || d.Source.GetColumn() != 11 {
+		t.Errorf("want the source /template:1:11, got: %s", d.Source)
+	}
+}
+
+func TestEvaluateV2UndefinedDiagnostic(t *testing.T) {
+	r, err := expanderClientV2.Evaluate(context.Background(),
+		&pbv2.EvaluateRequest{
+			Config:   []byte("region: {{ sqls.zone.foobar }}"),
+			Resource: "sqls",
+			Facade:   facade(t, "{\"region\": \"us-west1\"}"),
+		})
+	if err != nil {
+		t.Fatalf("expected no error. got: %v", err)
+	}
+	if r.GetStatus() != pbv2.Status_EVALUATE_FAILED || len(r.Diagnostics) != 1 {
+		t.Fatalf("want EVALUATE_FAILED with a diagnostic, got: %s", r)
+	}
+	d := r.Diagnostics[0]
+	if d.Code != "UndefinedVariable" || !strings.Contains(d.Message, noAttributeZoneErrorString) {
+		t.Errorf("want an UndefinedVariable diagnostic, got: %s", d)
+	}
+	if d.Source.GetFile() != "/template" || d.Source.GetLine() != 1 {
+		t.Errorf("want the source /template:1, got: %s", d.Source)
+	}
+}
+
+func TestEvaluateV2Objects(t *testing.T) {
+	r, err := expanderClientV2.Evaluate(context.Background(),
+		&pbv2.EvaluateRequest{
+			Config:   []byte("apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: {{ sqls.metadata.name }}\n"),
+			Resource: "sqls",
+			Facade:   facade(t, "{\"metadata\": {\"name\": \"db\"}}"),
+		})
+	if err != nil {
+		t.Fatalf("expected no error. got: %v", err)
+	}
+	if r.GetStatus() != pbv2.Status_SUCCESS || len(r.Objects) != 1 {
+		t.Fatalf("want SUCCESS with 1 object, got: %s", r)
+	}
+	expected := `{"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"db"}}`
+	if strings.TrimSpace(string(r.Objects[0].Json)) != expected {
+		t.Fatalf("\nexpected: %s\ngot: %s", expected, r.Objects[0].Json)
+	}
+}
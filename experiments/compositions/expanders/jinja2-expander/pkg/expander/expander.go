@@ -0,0 +1,451 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package expander implements the jinja2 expander.
+// Templates are rendered in-process with gonja, a Jinja compatible engine, so
+// no python runtime, no jinja2 cli and no temporary files are needed.
+package expander
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/cloud-native-compositions/compositions/composition/pkg/expandersdk"
+	jinja2configurationv1alpha1 "github.com/cloud-native-compositions/compositions/expander/jinja2-expander/api/v1alpha1"
+	"github.com/nikolalohinski/gonja/v2/builtins"
+	"github.com/nikolalohinski/gonja/v2/config"
+	"github.com/nikolalohinski/gonja/v2/exec"
+	"github.com/nikolalohinski/gonja/v2/loaders"
+	"github.com/nikolalohinski/gonja/v2/parser"
+	"github.com/nikolalohinski/gonja/v2/tokens"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/dynamic"
+)
+
+// TemplateName is the name of the inline template and of spec.template
+const TemplateName = "/template"
+
+var (
+	// jinja2Configurations is the resource of the configurations libraries are read from
+	jinja2Configurations = jinja2configurationv1alpha1.GroupVersion.WithResource("jinja2configurations")
+
+	// parseError matches the location gonja appends to syntax errors
+	parseError = regexp.MustCompile(`(?s)^(.*) \(Line: (\d+) Col: (\d+), near "(.*)"\)$`)
+
+	// undefinedError matches gonja errors for attributes and items looked up
+	// on an undefined value, and captures the line and the expression
+	undefinedError = regexp.MustCompile(`at line (\d+): .*Unable to evaluate (.+?): Can't use (?:Getitem|getattr) on None`)
+
+	// lastAccess splits the last attribute or item access off an expression
+	lastAccess = regexp.MustCompile(`^(.*?)(?:\.([A-Za-z_][A-Za-z0-9_]*)|\[(.*)\])$`)
+)
+
+// dictMethods are the methods of jinja2 dicts templates use. gonja only has keys.
+var dictMethods = exec.NewMethodSet[map[string]interface{}](map[string]exec.Method[map[string]interface{}]{
+	"keys": func(self map[string]interface{}, _ *exec.Value, arguments *exec.VarArgs) (interface{}, error) {
+		if err := arguments.Take(); err != nil {
+			return nil, exec.ErrInvalidCall(err)
+		}
+		return sortedKeys(self), nil
+	},
+	"values": func(self map[string]interface{}, _ *exec.Value, arguments *exec.VarArgs) (interface{}, error) {
+		if err := arguments.Take(); err != nil {
+			return nil, exec.ErrInvalidCall(err)
+		}
+		values := []interface{}{}
+		for _, key := range sortedKeys(self) {
+			values = append(values, self[key])
+		}
+		return values, nil
+	},
+	"items": func(self map[string]interface{}, _ *exec.Value, arguments *exec.VarArgs) (interface{}, error) {
+		if err := arguments.Take(); err != nil {
+			return nil, exec.ErrInvalidCall(err)
+		}
+		return items(self), nil
+	},
+	"get": func(self map[string]interface{}, _ *exec.Value, arguments *exec.VarArgs) (interface{}, error) {
+		var key string
+		var fallback interface{}
+		if err := arguments.Take(
+			exec.PositionalArgument("key", nil, exec.StringArgument(&key)),
+			exec.PositionalArgument("default", exec.AsValue(nil), exec.AnyArgument(&fallback)),
+		); err != nil {
+			return nil, exec.ErrInvalidCall(err)
+		}
+		if value, ok := self[key]; ok {
+			return value, nil
+		}
+		return fallback, nil
+	},
+})
+
+// filters are the jinja2 filters gonja does not have
+var filters = exec.NewFilterSet(map[string]exec.FilterFunction{
+	// items returns the key and value pairs of a dict, or nothing if it is undefined
+	"items": func(e *exec.Evaluator, in *exec.Value, params *exec.VarArgs) *exec.Value {
+		if in.IsError() {
+			return in
+		}
+		if p := params.ExpectNothing(); p.IsError() {
+			return exec.AsValue(fmt.Errorf("wrong signature for 'items': %s", p))
+		}
+		if in.IsNil() {
+			return exec.AsValue([]interface{}{})
+		}
+		m, ok := in.Interface().(map[string]interface{})
+		if !ok {
+			return exec.AsValue(fmt.Errorf("can only get item pairs from a mapping, got %s", in))
+		}
+		return exec.AsValue(items(m))
+	},
+}).Update(builtins.Filters)
+
+// items returns the key and value pairs of a dict in key order
+func items(m map[string]interface{}) []interface{} {
+	pairs := []interface{}{}
+	for _, key := range sortedKeys(m) {
+		pairs = append(pairs, []interface{}{key, m[key]})
+	}
+	return pairs
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := []string{}
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// environment is the gonja environment with the jinja2 filters and dict methods
+var environment = &exec.Environment{
+	Context:           exec.EmptyContext().Update(builtins.GlobalFunctions).Update(builtins.GlobalVariables),
+	Filters:           filters,
+	Tests:             builtins.Tests,
+	ControlStructures: builtins.ControlStructures,
+	Methods: exec.Methods{
+		Bool:  builtins.Methods.Bool,
+		Int:   builtins.Methods.Int,
+		Float: builtins.Methods.Float,
+		Str:   builtins.Methods.Str,
+		Dict:  dictMethods,
+		List:  builtins.Methods.List,
+	},
+}
+
+// Source returns the template as jinja2 sees it with its default settings:
+// newlines are normalized and a single trailing newline is removed
+func Source(template []byte) string {
+	source := strings.ReplaceAll(string(template), "\r\n", "\n")
+	return strings.TrimSuffix(source, "\n")
+}
+
+// loader loads templates by name like jinja2's DictLoader does. Names are not
+// relative to the template including, importing or extending them.
+type loader map[string]string
+
+func (l loader) Read(name string) (io.Reader, error) {
+	name, err := l.Resolve(name)
+	if err != nil {
+		return nil, err
+	}
+	return strings.NewReader(l[name]), nil
+}
+
+func (l loader) Resolve(name string) (string, error) {
+	if _, ok := l[name]; !ok {
+		return "", fmt.Errorf("template '%s' not found", name)
+	}
+	return name, nil
+}
+
+func (l loader) Inherit(string) (loaders.Loader, error) {
+	return l, nil
+}
+
+type Request = expandersdk.Request[jinja2configurationv1alpha1.Jinja2Configuration]
+
+// DecodeConfig returns the Jinja2Configuration of the request. Stages with a
+// configref send the configuration object, stages with a template the
+// template itself.
+func DecodeConfig(raw []byte) (*jinja2configurationv1alpha1.Jinja2Configuration, error) {
+	config := &jinja2configurationv1alpha1.Jinja2Configuration{}
+	if err := json.Unmarshal(raw, config); err == nil && config.Kind == "Jinja2Configuration" {
+		return config, nil
+	}
+	config = &jinja2configurationv1alpha1.Jinja2Configuration{}
+	config.Spec.Template = string(raw)
+	return config, nil
+}
+
+// Template is the entry template of a configuration
+type Template struct {
+	Name     string
+	template *exec.Template
+}
+
+// expander renders Jinja2Configurations
+type expander struct {
+	// client reads the libraries of the configurations. Without it
+	// configurations with libraries fail.
+	client dynamic.Interface
+}
+
+// load returns the entry template of the configuration. The templates of the
+// configuration and of its libraries can be included and imported by each
+// other. All of them are parsed, so syntax errors are reported even in the
+// templates that are only included under some conditions.
+func (e *expander) load(ctx context.Context, config *jinja2configurationv1alpha1.Jinja2Configuration) (*Template, error) {
+	spec := config.Spec
+	templates := loader{}
+	for name, template := range spec.Templates {
+		templates[name] = Source([]byte(template))
+	}
+
+	entrypoint := spec.Entrypoint
+	switch {
+	case entrypoint != "" && spec.Template != "":
+		return nil, expandersdk.Failed(expandersdk.ErrorDiagnostic("InvalidConfiguration", "spec.template and spec.entrypoint can not both be set"))
+	case entrypoint != "":
+		if _, ok := templates[entrypoint]; !ok {
+			return nil, expandersdk.Failed(expandersdk.ErrorDiagnostic("InvalidConfiguration", "spec.entrypoint: template '%s' not found in spec.templates", entrypoint))
+		}
+	default:
+		if _, ok := templates[TemplateName]; ok {
+			return nil, expandersdk.Failed(expandersdk.ErrorDiagnostic("InvalidConfiguration", "spec.templates: '%s' is the name of spec.template", TemplateName))
+		}
+		entrypoint = TemplateName
+		templates[TemplateName] = Source([]byte(spec.Template))
+	}
+
+	for i, ref := range spec.Libraries {
+		library, err := e.library(ctx, config.Namespace, ref)
+		if err != nil {
+			return nil, expandersdk.Failed(expandersdk.ErrorDiagnostic("InvalidLibrary", "spec.libraries[%d]: %v", i, err))
+		}
+		for name, template := range library.Spec.Templates {
+			name = ref.Name + "/" + name
+			if _, ok := templates[name]; ok {
+				return nil, expandersdk.Failed(expandersdk.ErrorDiagnostic("InvalidLibrary", "spec.libraries[%d]: template '%s' is defined more than once", i, name))
+			}
+			templates[name] = Source([]byte(template))
+		}
+	}
+	return parse(templates, entrypoint)
+}
+
+// library gets a Jinja2Configuration referenced as a library. The namespace
+// defaults to the one of the configuration referencing it.
+func (e *expander) library(ctx context.Context, namespace string, ref jinja2configurationv1alpha1.LibraryReference) (*jinja2configurationv1alpha1.Jinja2Configuration, error) {
+	if ref.Namespace != "" {
+		namespace = ref.Namespace
+	}
+	if e.client == nil {
+		return nil, fmt.Errorf("library %s/%s can not be read, the expander has no kubernetes client", namespace, ref.Name)
+	}
+	u, err := e.client.Resource(jinja2Configurations).Namespace(namespace).Get(ctx, ref.Name, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("error getting library %s/%s: %w", namespace, ref.Name, err)
+	}
+	library := &jinja2configurationv1alpha1.Jinja2Configuration{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(u.Object, library); err != nil {
+		return nil, fmt.Errorf("error converting library %s/%s: %w", namespace, ref.Name, err)
+	}
+	return library, nil
+}
+
+// parse parses the templates and returns the entry template
+func parse(templates loader, entrypoint string) (*Template, error) {
+	cfg := config.New()
+	// Each template is parsed first on its own so that the error does not
+	// quote the whole template as NewTemplate's does
+	for _, name := range sortedKeys(templates) {
+		if _, err := parser.NewParser(name, tokens.Lex(templates[name], cfg), cfg, templates, environment.ControlStructures).Parse(); err != nil {
+			return nil, expandersdk.Failed(syntaxError(name, err))
+		}
+	}
+	template, err := exec.NewTemplate(entrypoint, cfg, templates, environment)
+	if err != nil {
+		d := expandersdk.ErrorDiagnostic("TemplateSyntaxError", "%s: %v", entrypoint, err)
+		d.File = entrypoint
+		return nil, expandersdk.Failed(d)
+	}
+	return &Template{Name: entrypoint, template: template}, nil
+}
+
+// syntaxError words a gonja syntax error like jinja2 does and locates it in
+// the template
+func syntaxError(name string, err error) *expandersdk.Diagnostic {
+	m := parseError.FindStringSubmatch(err.Error())
+	if m == nil {
+		d := expandersdk.ErrorDiagnostic("TemplateSyntaxError", "%s: %v", name, err)
+		d.File = name
+		return d
+	}
+	var d *expandersdk.Diagnostic
+	if m[2] == "0" {
+		d = expandersdk.ErrorDiagnostic("TemplateSyntaxError", "%s: unexpected end of template: %s", name, m[1])
+	} else {
+		d = expandersdk.ErrorDiagnostic("TemplateSyntaxError", "%s:%s:%s: unexpected '%s': %s", name, m[2], m[3], m[4], m[1])
+	}
+	d.File = name
+	d.Line, _ = strconv.Atoi(m[2])
+	d.Column, _ = strconv.Atoi(m[3])
+	return d
+}
+
+// Data returns the variables the template is rendered with: the context, the
+// facade under its resource name and the fetched values. Missing inputs are
+// empty dicts.
+func Data(req *Request) (map[string]interface{}, error) {
+	data := map[string]interface{}{"context": map[string]interface{}{}}
+	if req.Context != nil {
+		data["context"] = numbers(req.Context.DeepCopy().Object)
+	}
+	data[req.Resource] = map[string]interface{}{}
+	if req.Facade != nil {
+		data[req.Resource] = numbers(req.Facade.DeepCopy().Object)
+	}
+	values := map[string]interface{}{}
+	if req.Values != nil {
+		// The values are decoded with float64 numbers, they are decoded again
+		// to tell ints from floats
+		raw, err := json.Marshal(req.Values)
+		if err != nil {
+			return nil, fmt.Errorf("error marshalling values: %w", err)
+		}
+		decoder := json.NewDecoder(bytes.NewReader(raw))
+		decoder.UseNumber()
+		if err := decoder.Decode(&values); err != nil {
+			return nil, fmt.Errorf("error unmarshalling values: %w", err)
+		}
+	}
+	data["values"] = numbers(values)
+	return data, nil
+}
+
+// numbers converts JSON numbers and the int64 numbers of objects to ints and
+// floats like python's json module does, so that 3 renders as 3 and not 3.0
+func numbers(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, item := range v {
+			v[key] = numbers(item)
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = numbers(item)
+		}
+	case int64:
+		return int(v)
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return int(i)
+		}
+		f, _ := v.Float64()
+		return f
+	}
+	return value
+}
+
+// Render renders the template with the data
+func (t *Template) Render(data map[string]interface{}) ([]byte, error) {
+	out, err := t.template.ExecuteToBytes(exec.NewContext(data))
+	if err != nil {
+		return nil, expandersdk.Failed(undefined(t.Name, err))
+	}
+	return out, nil
+}
+
+// undefined words errors on undefined values like jinja2 does. gonja renders
+// undefined values as empty strings too, but fails differently on lookups in
+// them. The line is the one of the entry template the error occurred under.
+func undefined(name string, err error) *expandersdk.Diagnostic {
+	failed := expandersdk.ErrorDiagnostic("TemplateRenderError", "%s: %v", name, err)
+	failed.File = name
+	m := undefinedError.FindStringSubmatch(err.Error())
+	if m == nil {
+		return failed
+	}
+	line, expression := m[1], m[2]
+	// expression is <undefined value>.<attribute>, the undefined value is
+	// either a variable or a lookup in a defined value
+	target := lastAccess.FindStringSubmatch(expression)
+	if target == nil {
+		return failed
+	}
+	var d *expandersdk.Diagnostic
+	lookup := lastAccess.FindStringSubmatch(target[1])
+	switch {
+	case lookup == nil:
+		d = expandersdk.ErrorDiagnostic("UndefinedVariable", "%s:%s: '%s' is undefined", name, line, target[1])
+	case lookup[2] != "":
+		d = expandersdk.ErrorDiagnostic("UndefinedVariable", "%s:%s: '%s' has no attribute '%s'", name, line, lookup[1], lookup[2])
+	default:
+		d = expandersdk.ErrorDiagnostic("UndefinedVariable", "%s:%s: '%s' has no item %s", name, line, lookup[1], lookup[3])
+	}
+	d.File = name
+	d.Line, _ = strconv.Atoi(line)
+	return d
+}
+
+// validate parses the templates of the configuration and of its libraries
+func (e *expander) validate(ctx context.Context, req *Request) (*expandersdk.Result, error) {
+	if _, err := e.load(ctx, req.Config); err != nil {
+		return nil, err
+	}
+	return nil, nil
+}
+
+// evaluate renders the entry template with the context, the facade and the values
+func (e *expander) evaluate(ctx context.Context, req *Request) (*expandersdk.Result, error) {
+	data, err := Data(req)
+	if err != nil {
+		return nil, fmt.Errorf("error processing inputs: %w", err)
+	}
+	template, err := e.load(ctx, req.Config)
+	if err != nil {
+		return nil, err
+	}
+	manifests, err := template.Render(data)
+	if err != nil {
+		return nil, err
+	}
+	return &expandersdk.Result{Manifests: manifests}, nil
+}
+
+// New returns the jinja2 expander. client reads the libraries of the
+// configurations, configurations with libraries fail without it.
+func New(client dynamic.Interface) *expandersdk.Expander[jinja2configurationv1alpha1.Jinja2Configuration] {
+	e := &expander{client: client}
+	return &expandersdk.Expander[jinja2configurationv1alpha1.Jinja2Configuration]{
+		Name:   "jinja2",
+		Config: jinja2configurationv1alpha1.GroupVersion.WithKind("Jinja2Configuration"),
+		// An empty template renders nothing
+		OptionalConfig: true,
+		DecodeConfig:   DecodeConfig,
+		Validate:       e.validate,
+		Evaluate:       e.evaluate,
+	}
+}
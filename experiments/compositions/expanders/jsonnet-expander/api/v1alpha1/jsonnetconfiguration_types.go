@@ -0,0 +1,89 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// InputMode is how the facade, the context and the fetched values are passed to Main
+type InputMode string
+
+const (
+	// InputModeExtVars passes them as the facade, context and values external
+	// variables, read with std.extVar
+	InputModeExtVars InputMode = "ExtVars"
+	// InputModeTopLevelArguments passes them as the facade, context and values
+	// arguments of the function Main evaluates to
+	InputModeTopLevelArguments InputMode = "TopLevelArguments"
+)
+
+// OutputType is what Main evaluates to
+type OutputType string
+
+const (
+	// OutputObjects is an object, or arrays and objects nesting objects
+	OutputObjects OutputType = "Objects"
+	// OutputValues is an object returned as values to the stages after this one
+	OutputValues OutputType = "Values"
+)
+
+// JsonnetConfigurationSpec defines the desired state of JsonnetConfiguration
+type JsonnetConfigurationSpec struct {
+	// Main is the jsonnet program, imported as main.jsonnet
+	Main string `json:"main"`
+	// Libraries are the files Main and the libraries can import, keyed by
+	// import path, for example lib/app.libsonnet
+	Libraries map[string]string `json:"libraries,omitempty"`
+	// Inputs is how the facade, the context and the fetched values are passed
+	// +kubebuilder:validation:Enum=ExtVars;TopLevelArguments
+	// +kubebuilder:default=ExtVars
+	Inputs InputMode `json:"inputs,omitempty"`
+	// Output is what Main evaluates to
+	// +kubebuilder:validation:Enum=Objects;Values
+	// +kubebuilder:default=Objects
+	Output OutputType `json:"output,omitempty"`
+}
+
+// JsonnetConfigurationStatus defines the observed state of JsonnetConfiguration
+type JsonnetConfigurationStatus struct {
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+
+// JsonnetConfiguration is the Schema for the jsonnetconfigurations API
+type JsonnetConfiguration struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   JsonnetConfigurationSpec   `json:"spec,omitempty"`
+	Status JsonnetConfigurationStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// JsonnetConfigurationList contains a list of JsonnetConfiguration
+type JsonnetConfigurationList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []JsonnetConfiguration `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&JsonnetConfiguration{}, &JsonnetConfigurationList{})
+}
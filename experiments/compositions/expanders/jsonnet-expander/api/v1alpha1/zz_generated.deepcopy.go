@@ -0,0 +1,105 @@
+//go:build !ignore_autogenerated
+
+// Code generated by controller-gen. DO NOT EDIT.
+
+package v1alpha1
+
+import (
+	runtime "k8s.io/apimachinery/pkg/runtime"
+)
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *JsonnetConfiguration) DeepCopyInto(out *JsonnetConfiguration) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	out.Status = in.Status
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new JsonnetConfiguration.
+func (in *JsonnetConfiguration) DeepCopy() *JsonnetConfiguration {
+	if in == nil {
+		return nil
+	}
+	out := new(JsonnetConfiguration)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *JsonnetConfiguration) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *JsonnetConfigurationList) DeepCopyInto(out *JsonnetConfigurationList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]JsonnetConfiguration, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new JsonnetConfigurationList.
+func (in *JsonnetConfigurationList) DeepCopy() *JsonnetConfigurationList {
+	if in == nil {
+		return nil
+	}
+	out := new(JsonnetConfigurationList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *JsonnetConfigurationList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *JsonnetConfigurationSpec) DeepCopyInto(out *JsonnetConfigurationSpec) {
+	*out = *in
+	if in.Libraries != nil {
+		in, out := &in.Libraries, &out.Libraries
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new JsonnetConfigurationSpec.
+func (in *JsonnetConfigurationSpec) DeepCopy() *JsonnetConfigurationSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(JsonnetConfigurationSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *JsonnetConfigurationStatus) DeepCopyInto(out *JsonnetConfigurationStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new JsonnetConfigurationStatus.
+func (in *JsonnetConfigurationStatus) DeepCopy() *JsonnetConfigurationStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(JsonnetConfigurationStatus)
+	in.DeepCopyInto(out)
+	return out
+}
@@ -0,0 +1,209 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package expander implements the Jsonnet expander.
+// Main and its libraries are evaluated with go-jsonnet from memory, so no
+// jsonnet binary and no temporary files are needed.
+package expander
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/cloud-native-compositions/compositions/composition/pkg/expandersdk"
+	jsonnetconfigurationv1alpha1 "github.com/cloud-native-compositions/compositions/expander/jsonnet-expander/api/v1alpha1"
+	"github.com/google/go-jsonnet"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// MainFile is the import path of spec.main
+const MainFile = "main.jsonnet"
+
+type Request = expandersdk.Request[jsonnetconfigurationv1alpha1.JsonnetConfiguration]
+
+// errorLocation matches the first file:line:column go-jsonnet reports in an error
+var errorLocation = regexp.MustCompile(`([^\s:]+):(\d+):(\d+)`)
+
+// importer resolves imports relative to the importing file first, then
+// from the root of spec.libraries
+type importer struct {
+	files map[string]jsonnet.Contents
+}
+
+func (i *importer) Import(importedFrom, importedPath string) (jsonnet.Contents, string, error) {
+	for _, candidate := range []string{path.Join(path.Dir(importedFrom), importedPath), path.Clean(importedPath)} {
+		if contents, ok := i.files[candidate]; ok {
+			return contents, candidate, nil
+		}
+	}
+	return jsonnet.Contents{}, "", fmt.Errorf("%s is not in spec.libraries", importedPath)
+}
+
+// NewVM returns a VM importing Main and the libraries of the configuration
+func NewVM(req *Request) (*jsonnet.VM, error) {
+	spec := req.Config.Spec
+	if spec.Main == "" {
+		return nil, expandersdk.Failed(expandersdk.ErrorDiagnostic("MissingMain", "spec.main is required"))
+	}
+
+	files := map[string]jsonnet.Contents{MainFile: jsonnet.MakeContents(spec.Main)}
+	for name, content := range spec.Libraries {
+		clean := path.Clean(name)
+		if name == "" || path.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, "../") || clean == MainFile {
+			return nil, expandersdk.Failed(expandersdk.ErrorDiagnostic("InvalidLibraryName", "spec.libraries: invalid library name %q", name))
+		}
+		files[clean] = jsonnet.MakeContents(content)
+	}
+
+	vm := jsonnet.MakeVM()
+	vm.Importer(&importer{files: files})
+	return vm, nil
+}
+
+// jsonnetDiagnostic locates a go-jsonnet error in the file it occurred in
+func jsonnetDiagnostic(code string, err error) *expandersdk.Diagnostic {
+	d := expandersdk.ErrorDiagnostic(code, "%s", strings.TrimSpace(err.Error()))
+	if m := errorLocation.FindStringSubmatch(err.Error()); m != nil {
+		d.File = m[1]
+		d.Line, _ = strconv.Atoi(m[2])
+		d.Column, _ = strconv.Atoi(m[3])
+	}
+	return d
+}
+
+// setInputs passes the facade, the context and the fetched values to Main.
+// The context and the values are empty objects when there are none.
+func setInputs(vm *jsonnet.VM, req *Request) error {
+	inputs := map[string]interface{}{"facade": nil, "context": map[string]interface{}{}, "values": map[string]interface{}{}}
+	if req.Facade != nil {
+		inputs["facade"] = req.Facade.Object
+	}
+	if req.Context != nil {
+		inputs["context"] = req.Context.Object
+	}
+	if req.Values != nil {
+		inputs["values"] = req.Values
+	}
+	for name, value := range inputs {
+		code, err := json.Marshal(value)
+		if err != nil {
+			return fmt.Errorf("failed to marshal %s: %w", name, err)
+		}
+		if req.Config.Spec.Inputs == jsonnetconfigurationv1alpha1.InputModeTopLevelArguments {
+			vm.TLACode(name, string(code))
+		} else {
+			vm.ExtCode(name, string(code))
+		}
+	}
+	return nil
+}
+
+// objects collects the objects in the output of Main. Arrays and objects that
+// are not kubernetes objects are walked, in key order for objects.
+func objects(value interface{}, field string, out *[]*unstructured.Unstructured) error {
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case []interface{}:
+		for i, item := range v {
+			if err := objects(item, fmt.Sprintf("%s[%d]", field, i), out); err != nil {
+				return err
+			}
+		}
+		return nil
+	case map[string]interface{}:
+		if _, ok := v["kind"]; ok {
+			if _, ok := v["apiVersion"]; ok {
+				*out = append(*out, &unstructured.Unstructured{Object: v})
+				return nil
+			}
+		}
+		keys := []string{}
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			if err := objects(v[k], field+"."+k, out); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return expandersdk.Failed(expandersdk.ErrorDiagnostic("InvalidOutput", "%s: want an object or an array, got %v", field, value))
+}
+
+// Validate parses Main and the libraries it imports, and evaluates Main if a
+// facade is passed
+func Validate(ctx context.Context, req *Request) (*expandersdk.Result, error) {
+	vm, err := NewVM(req)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := vm.FindDependencies("", []string{MainFile}); err != nil {
+		return nil, expandersdk.Failed(jsonnetDiagnostic("JsonnetImportError", err))
+	}
+	if req.Facade == nil {
+		return nil, nil
+	}
+	_, err = Evaluate(ctx, req)
+	return nil, err
+}
+
+func Evaluate(ctx context.Context, req *Request) (*expandersdk.Result, error) {
+	vm, err := NewVM(req)
+	if err != nil {
+		return nil, err
+	}
+	if err := setInputs(vm, req); err != nil {
+		return nil, err
+	}
+	output, err := vm.EvaluateFile(MainFile)
+	if err != nil {
+		return nil, expandersdk.Failed(jsonnetDiagnostic("JsonnetEvaluationError", err))
+	}
+
+	var value interface{}
+	if err := json.Unmarshal([]byte(output), &value); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal the output of %s: %w", MainFile, err)
+	}
+	if req.Config.Spec.Output == jsonnetconfigurationv1alpha1.OutputValues {
+		values, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, expandersdk.Failed(expandersdk.ErrorDiagnostic("InvalidOutput", "%s: want an object of values, got %v", MainFile, value))
+		}
+		return &expandersdk.Result{Values: values, ReturnsValues: true}, nil
+	}
+	out := []*unstructured.Unstructured{}
+	if err := objects(value, MainFile, &out); err != nil {
+		return nil, err
+	}
+	return &expandersdk.Result{Objects: out}, nil
+}
+
+// New returns the Jsonnet expander
+func New() *expandersdk.Expander[jsonnetconfigurationv1alpha1.JsonnetConfiguration] {
+	return &expandersdk.Expander[jsonnetconfigurationv1alpha1.JsonnetConfiguration]{
+		Name:     "jsonnet",
+		Config:   jsonnetconfigurationv1alpha1.GroupVersion.WithKind("JsonnetConfiguration"),
+		Validate: Validate,
+		Evaluate: Evaluate,
+	}
+}
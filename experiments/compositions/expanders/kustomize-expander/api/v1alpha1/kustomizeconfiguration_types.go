@@ -0,0 +1,98 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	runtime "k8s.io/apimachinery/pkg/runtime"
+)
+
+// FileContent is a file of a kustomization. Content holds a single object,
+// Template holds the file as is, for example a YAML stream.
+type FileContent struct {
+	FileName string               `json:"name"`
+	Content  runtime.RawExtension `json:"content,omitempty"`
+	Template string               `json:"template,omitempty"`
+}
+
+// Base is a kustomization in the bases/<name> directory
+type Base struct {
+	Name string `json:"name"`
+	// Kustomization https://kubectl.docs.kubernetes.io/references/kustomize/kustomization/
+	Kustomization runtime.RawExtension `json:"kustomization"`
+	// Files referenced by the kustomization
+	Files []FileContent `json:"files,omitempty"`
+}
+
+// PatchTarget selects the objects a patch applies to
+type PatchTarget struct {
+	Group              string `json:"group,omitempty"`
+	Version            string `json:"version,omitempty"`
+	Kind               string `json:"kind,omitempty"`
+	Name               string `json:"name,omitempty"`
+	Namespace          string `json:"namespace,omitempty"`
+	LabelSelector      string `json:"labelSelector,omitempty"`
+	AnnotationSelector string `json:"annotationSelector,omitempty"`
+}
+
+// Patch is a strategic merge patch, or a JSON 6902 patch with a target
+type Patch struct {
+	Patch  string       `json:"patch"`
+	Target *PatchTarget `json:"target,omitempty"`
+}
+
+// KustomizeConfigurationSpec defines the desired state of KustomizeConfiguration
+type KustomizeConfigurationSpec struct {
+	// Kustomization is the root kustomization.yaml. Bases and Resources it does
+	// not list are added to its resources, and Patches to its patches.
+	// https://kubectl.docs.kubernetes.io/references/kustomize/kustomization/
+	Kustomization runtime.RawExtension `json:"kustomization,omitempty"`
+	// Bases are written to bases/<name>
+	Bases []Base `json:"bases,omitempty"`
+	// Resources are written next to the root kustomization
+	Resources []FileContent `json:"resources,omitempty"`
+	Patches   []Patch       `json:"patches,omitempty"`
+}
+
+// KustomizeConfigurationStatus defines the observed state of KustomizeConfiguration
+type KustomizeConfigurationStatus struct {
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+
+// KustomizeConfiguration is the Schema for the kustomizeconfigurations API
+type KustomizeConfiguration struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   KustomizeConfigurationSpec   `json:"spec,omitempty"`
+	Status KustomizeConfigurationStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// KustomizeConfigurationList contains a list of KustomizeConfiguration
+type KustomizeConfigurationList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []KustomizeConfiguration `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&KustomizeConfiguration{}, &KustomizeConfigurationList{})
+}
@@ -0,0 +1,194 @@
+//go:build !ignore_autogenerated
+
+// Code generated by controller-gen. DO NOT EDIT.
+
+package v1alpha1
+
+import (
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Base) DeepCopyInto(out *Base) {
+	*out = *in
+	in.Kustomization.DeepCopyInto(&out.Kustomization)
+	if in.Files != nil {
+		in, out := &in.Files, &out.Files
+		*out = make([]FileContent, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Base.
+func (in *Base) DeepCopy() *Base {
+	if in == nil {
+		return nil
+	}
+	out := new(Base)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FileContent) DeepCopyInto(out *FileContent) {
+	*out = *in
+	in.Content.DeepCopyInto(&out.Content)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FileContent.
+func (in *FileContent) DeepCopy() *FileContent {
+	if in == nil {
+		return nil
+	}
+	out := new(FileContent)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KustomizeConfiguration) DeepCopyInto(out *KustomizeConfiguration) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	out.Status = in.Status
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KustomizeConfiguration.
+func (in *KustomizeConfiguration) DeepCopy() *KustomizeConfiguration {
+	if in == nil {
+		return nil
+	}
+	out := new(KustomizeConfiguration)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *KustomizeConfiguration) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KustomizeConfigurationList) DeepCopyInto(out *KustomizeConfigurationList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]KustomizeConfiguration, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KustomizeConfigurationList.
+func (in *KustomizeConfigurationList) DeepCopy() *KustomizeConfigurationList {
+	if in == nil {
+		return nil
+	}
+	out := new(KustomizeConfigurationList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *KustomizeConfigurationList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KustomizeConfigurationSpec) DeepCopyInto(out *KustomizeConfigurationSpec) {
+	*out = *in
+	in.Kustomization.DeepCopyInto(&out.Kustomization)
+	if in.Bases != nil {
+		in, out := &in.Bases, &out.Bases
+		*out = make([]Base, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Resources != nil {
+		in, out := &in.Resources, &out.Resources
+		*out = make([]FileContent, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Patches != nil {
+		in, out := &in.Patches, &out.Patches
+		*out = make([]Patch, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KustomizeConfigurationSpec.
+func (in *KustomizeConfigurationSpec) DeepCopy() *KustomizeConfigurationSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(KustomizeConfigurationSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KustomizeConfigurationStatus) DeepCopyInto(out *KustomizeConfigurationStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KustomizeConfigurationStatus.
+func (in *KustomizeConfigurationStatus) DeepCopy() *KustomizeConfigurationStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(KustomizeConfigurationStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Patch) DeepCopyInto(out *Patch) {
+	*out = *in
+	if in.Target != nil {
+		in, out := &in.Target, &out.Target
+		*out = new(PatchTarget)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Patch.
+func (in *Patch) DeepCopy() *Patch {
+	if in == nil {
+		return nil
+	}
+	out := new(Patch)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PatchTarget) DeepCopyInto(out *PatchTarget) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PatchTarget.
+func (in *PatchTarget) DeepCopy() *PatchTarget {
+	if in == nil {
+		return nil
+	}
+	out := new(PatchTarget)
+	in.DeepCopyInto(out)
+	return out
+}
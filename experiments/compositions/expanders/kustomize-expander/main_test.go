@@ -0,0 +1,476 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main_test
+
+import (
+	"context"
+	"flag"
+	"log"
+	"net"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/cloud-native-compositions/compositions/composition/pkg/expandersdk"
+	pb "github.com/cloud-native-compositions/compositions/composition/proto"
+	"github.com/cloud-native-compositions/compositions/expander/kustomize-expander/pkg/expander"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"sigs.k8s.io/yaml"
+)
+
+var (
+	addr = flag.String("addr", "", "the address to connect to. The expander is served in-process if empty")
+
+	// Plain resources without a kustomization
+	staticConfig = `apiVersion: composition.google.com/v1alpha1
+kind: KustomizeConfiguration
+metadata:
+  name: foobar
+  namespace: config-control
+spec:
+  resources:
+  - name: configmap.yaml
+    content:
+      apiVersion: v1
+      kind: ConfigMap
+      metadata:
+        name: demo
+      data:
+        foo: "3"
+        bar: "interface"
+`
+	staticConfigRenderedManifests = `apiVersion: v1
+data:
+  bar: interface
+  foo: "3"
+kind: ConfigMap
+metadata:
+  name: demo
+`
+
+	// A base patched by the root kustomization, with replacements reading the
+	// facade, the context and the fetched values
+	kustomizationConfig = `apiVersion: composition.google.com/v1alpha1
+kind: KustomizeConfiguration
+metadata:
+  name: foobar
+  namespace: config-control
+spec:
+  kustomization:
+    namePrefix: team-
+    labels:
+    - pairs:
+        team: platform
+    replacements:
+    - source:
+        kind: Foo
+        fieldPath: spec.foo
+      targets:
+      - select:
+          kind: ConfigMap
+        fieldPaths:
+        - data.foo
+    - source:
+        kind: Context
+        fieldPath: spec.project
+      targets:
+      - select:
+          kind: ConfigMap
+        fieldPaths:
+        - data.project
+    - source:
+        kind: Values
+        name: fetched
+        fieldPath: spec.car
+      targets:
+      - select:
+          kind: Deployment
+        fieldPaths:
+        - spec.template.metadata.labels.car
+        options:
+          create: true
+  bases:
+  - name: app
+    kustomization:
+      resources:
+      - deployment.yaml
+      - configmap.yaml
+    files:
+    - name: deployment.yaml
+      template: |
+        apiVersion: apps/v1
+        kind: Deployment
+        metadata:
+          name: app
+        spec:
+          replicas: 1
+          template:
+            metadata:
+              labels:
+                app: demo
+    - name: configmap.yaml
+      content:
+        apiVersion: v1
+        kind: ConfigMap
+        metadata:
+          name: demo
+        data:
+          foo: placeholder
+          project: placeholder
+  patches:
+  - patch: |
+      apiVersion: apps/v1
+      kind: Deployment
+      metadata:
+        name: app
+      spec:
+        replicas: 3
+  - patch: |
+      - op: add
+        path: /data/extra
+        value: "true"
+    target:
+      kind: ConfigMap
+      name: demo
+`
+	kustomizationConfigRenderedManifests = `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  labels:
+    team: platform
+  name: team-app
+spec:
+  replicas: 3
+  template:
+    metadata:
+      labels:
+        app: demo
+        car: sedan
+---
+apiVersion: v1
+data:
+  extra: "true"
+  foo: bar
+  project: test-project
+kind: ConfigMap
+metadata:
+  labels:
+    team: platform
+  name: team-demo
+`
+)
+
+var expanderClient pb.ExpanderClient
+
+func dummyValues(t *testing.T) []byte {
+	y := `foo: bar
+car: sedan
+`
+	j, err := yaml.YAMLToJSON([]byte(y))
+	if err != nil {
+		t.Fatalf("error marshalling to json: %v\n %s", err, y)
+	}
+	return j
+}
+
+func configFrom(t *testing.T, config string) []byte {
+	j, err := yaml.YAMLToJSON([]byte(config))
+	if err != nil {
+		t.Fatalf("error marshalling to json: %v\n %s", err, config)
+	}
+	return j
+}
+
+func testFacade(t *testing.T) []byte {
+	facade := `apiVersion: facade.foobar.com/v1alpha1
+kind: Foo
+metadata:
+  name: appteam-sample
+  namespace: default
+spec:
+  foo: bar
+  car: sedan
+`
+	j, err := yaml.YAMLToJSON([]byte(facade))
+	if err != nil {
+		t.Fatalf("error marshalling to json: %v\n %s", err, facade)
+	}
+	return j
+}
+
+func testContext(t *testing.T) []byte {
+	y := `apiVersion: composition.google.com/v1alpha1
+kind: Context
+metadata:
+  name: context
+  namespace: config-control
+spec:
+  project: test-project
+`
+	j, err := yaml.YAMLToJSON([]byte(y))
+	if err != nil {
+		t.Fatalf("error marshalling to json: %v\n %s", err, y)
+	}
+	return j
+}
+
+// TestMain - umbrella test that runs all test cases
+func TestMain(m *testing.M) {
+	flag.Parse()
+
+	target := *addr
+	if target == "" {
+		lis, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			log.Fatalf("failed to listen: %v", err)
+		}
+		s := grpc.NewServer()
+		expandersdk.NewServer(expander.New()).Register(s)
+		go s.Serve(lis)
+		target = lis.Addr().String()
+	}
+
+	// Set up a connection to the server.
+	conn, err := grpc.Dial(target, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		log.Fatalf("did not connect: %v", err)
+	}
+	expanderClient = pb.NewExpanderClient(conn)
+	exitCode := m.Run()
+	os.Exit(exitCode)
+}
+
+func TestEvaluateEmptyConfig(t *testing.T) {
+	r, err := expanderClient.Evaluate(context.Background(),
+		&pb.EvaluateRequest{
+			Config:   []byte{},
+			Resource: "foos",
+			Context:  testContext(t),
+			Facade:   testFacade(t),
+			Value:    dummyValues(t),
+		})
+	if err != nil {
+		t.Fatalf("could not evaluate: %v", err)
+	}
+	if r.GetStatus() != pb.Status_EVALUATE_FAILED {
+		t.Fatalf("want FAILURE, got: %s", r.GetStatus())
+	}
+}
+
+func TestEvaluateBadConfig(t *testing.T) {
+	_, err := expanderClient.Evaluate(context.Background(),
+		&pb.EvaluateRequest{
+			Resource: "foos",
+			Config:   configFrom(t, `dummy config`),
+			Context:  testContext(t),
+			Facade:   testFacade(t),
+			Value:    dummyValues(t),
+		})
+	if err == nil {
+		t.Fatalf("expected error. got none")
+	}
+	errMessage := "cannot unmarshal string into Go value of type v1alpha1.KustomizeConfiguration"
+	if !strings.Contains(err.Error(), errMessage) {
+		t.Fatalf("Did not find expected string in err: %s, got: %s", errMessage, err.Error())
+	}
+}
+
+func TestEvaluateStaticConfig(t *testing.T) {
+	r, err := expanderClient.Evaluate(context.Background(),
+		&pb.EvaluateRequest{
+			Resource: "foos",
+			Config:   configFrom(t, staticConfig),
+			Context:  testContext(t),
+			Facade:   testFacade(t),
+			Value:    dummyValues(t),
+		})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if r.GetStatus() != pb.Status_SUCCESS {
+		t.Fatalf("want SUCCESS, got: %s", r)
+	}
+	if string(r.Manifests) != staticConfigRenderedManifests {
+		t.Fatalf("\nexpected: %s\n got: %s", staticConfigRenderedManifests, r.Manifests)
+	}
+}
+
+func TestEvaluateKustomization(t *testing.T) {
+	r, err := expanderClient.Evaluate(context.Background(),
+		&pb.EvaluateRequest{
+			Resource: "foos",
+			Config:   configFrom(t, kustomizationConfig),
+			Context:  testContext(t),
+			Facade:   testFacade(t),
+			Value:    dummyValues(t),
+		})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if r.GetStatus() != pb.Status_SUCCESS {
+		t.Fatalf("want SUCCESS, got: %s", r)
+	}
+	if string(r.Manifests) != kustomizationConfigRenderedManifests {
+		t.Fatalf("\nexpected: %s\n got: %s", kustomizationConfigRenderedManifests, r.Manifests)
+	}
+}
+
+func TestEvaluateFailures(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		config string
+		want   string
+	}{
+		{
+			name: "remote resource",
+			config: `apiVersion: composition.google.com/v1alpha1
+kind: KustomizeConfiguration
+metadata:
+  name: foobar
+  namespace: config-control
+spec:
+  kustomization:
+    resources:
+    - https://github.com/kubernetes-sigs/kustomize//examples/helloWorld?ref=v3.3.1
+`,
+			want: `MissingResource: kustomization.yaml: resource "https://github.com/kubernetes-sigs/kustomize//examples/helloWorld?ref=v3.3.1" is not in the configuration, remote resources are not supported`,
+		},
+		{
+			name: "unknown field",
+			config: `apiVersion: composition.google.com/v1alpha1
+kind: KustomizeConfiguration
+metadata:
+  name: foobar
+  namespace: config-control
+spec:
+  kustomization:
+    namePrefx: team-
+`,
+			want: `InvalidKustomization: spec.kustomization: json: unknown field "namePrefx"`,
+		},
+		{
+			name: "missing source field",
+			config: `apiVersion: composition.google.com/v1alpha1
+kind: KustomizeConfiguration
+metadata:
+  name: foobar
+  namespace: config-control
+spec:
+  kustomization:
+    replacements:
+    - source:
+        kind: Foo
+        fieldPath: spec.missing
+      targets:
+      - select:
+          kind: ConfigMap
+        fieldPaths:
+        - data.foo
+  resources:
+  - name: configmap.yaml
+    content:
+      apiVersion: v1
+      kind: ConfigMap
+      metadata:
+        name: demo
+`,
+			want: "KustomizeBuildFailed: ",
+		},
+		{
+			name: "file outside of the kustomization",
+			config: `apiVersion: composition.google.com/v1alpha1
+kind: KustomizeConfiguration
+metadata:
+  name: foobar
+  namespace: config-control
+spec:
+  resources:
+  - name: ../configmap.yaml
+    template: ""
+`,
+			want: `InvalidFileName: spec.resources[0].name: invalid file name "../configmap.yaml"`,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			r, err := expanderClient.Evaluate(context.Background(),
+				&pb.EvaluateRequest{
+					Resource: "foos",
+					Config:   configFrom(t, tc.config),
+					Context:  testContext(t),
+					Facade:   testFacade(t),
+					Value:    dummyValues(t),
+				})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if r.GetStatus() != pb.Status_EVALUATE_FAILED {
+				t.Fatalf("want FAILURE, got: %s", r)
+			}
+			if !strings.Contains(r.GetError().GetMessage(), tc.want) {
+				t.Fatalf("expected error: %s \n got: %s", tc.want, r.GetError().GetMessage())
+			}
+		})
+	}
+}
+
+func TestValidateKustomization(t *testing.T) {
+	r, err := expanderClient.Validate(context.Background(),
+		&pb.ValidateRequest{
+			Resource: "foos",
+			Config:   configFrom(t, kustomizationConfig),
+			Context:  testContext(t),
+			Facade:   testFacade(t),
+			Value:    dummyValues(t),
+		})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if r.GetStatus() != pb.Status_SUCCESS {
+		t.Fatalf("want SUCCESS, got: %s", r)
+	}
+}
+
+func TestValidateWithoutFacade(t *testing.T) {
+	// Replacements reading the facade can not be checked without one
+	r, err := expanderClient.Validate(context.Background(),
+		&pb.ValidateRequest{
+			Resource: "foos",
+			Config:   configFrom(t, kustomizationConfig),
+		})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if r.GetStatus() != pb.Status_SUCCESS {
+		t.Fatalf("want SUCCESS, got: %s", r)
+	}
+
+	config := strings.Replace(kustomizationConfig, "      - configmap.yaml\n", "      - configmap.yaml\n      - service.yaml\n", 1)
+	r, err = expanderClient.Validate(context.Background(),
+		&pb.ValidateRequest{
+			Resource: "foos",
+			Config:   configFrom(t, config),
+		})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if r.GetStatus() != pb.Status_VALIDATE_FAILED {
+		t.Fatalf("want FAILURE, got: %s", r)
+	}
+	want := `bases/app/kustomization.yaml: resource "service.yaml" is not in the configuration`
+	if !strings.Contains(r.GetError().GetMessage(), want) {
+		t.Fatalf("expected error: %s \n got: %s", want, r.GetError().GetMessage())
+	}
+}
@@ -0,0 +1,294 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package expander implements the Kustomize expander.
+// The configuration is written to an in-memory file system and built with the
+// kustomize API, so no kustomize or git binary and no network access is needed.
+package expander
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/cloud-native-compositions/compositions/composition/pkg/expandersdk"
+	kustomizeconfigurationv1alpha1 "github.com/cloud-native-compositions/compositions/expander/kustomize-expander/api/v1alpha1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/kustomize/api/konfig"
+	"sigs.k8s.io/kustomize/api/krusty"
+	"sigs.k8s.io/kustomize/api/types"
+	"sigs.k8s.io/kustomize/kyaml/filesys"
+	"sigs.k8s.io/kustomize/kyaml/resid"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// root is the directory of the root kustomization in the in-memory file system
+	root = "/composition"
+
+	// InputsFile is added to the resources of the root kustomization. It holds the
+	// facade, the Context and the fetched values as local-config objects, which
+	// replacements can read from and which are dropped from the output.
+	InputsFile = "composition-inputs.yaml"
+
+	// ValuesKind is the kind of the object holding the fetched values in its spec
+	ValuesKind = "Values"
+	// ValuesName is the name of the object holding the fetched values
+	ValuesName = "fetched"
+)
+
+type Request = expandersdk.Request[kustomizeconfigurationv1alpha1.KustomizeConfiguration]
+
+type Expander struct {
+	fs filesys.FileSystem
+}
+
+// NewExpander writes the kustomizations, their files and the inputs of the request
+// to an in-memory file system
+func NewExpander(req *Request) (*Expander, error) {
+	e := &Expander{fs: filesys.MakeFsInMemory()}
+	spec := req.Config.Spec
+
+	kustomization, err := parseKustomization(spec.Kustomization.Raw)
+	if err != nil {
+		return nil, expandersdk.Failed(expandersdk.ErrorDiagnostic("InvalidKustomization", "spec.kustomization: %v", err))
+	}
+
+	bases := map[string]bool{}
+	for i, base := range spec.Bases {
+		if base.Name == "" || strings.ContainsAny(base.Name, "/\\") || base.Name == "." || base.Name == ".." {
+			return nil, expandersdk.Failed(expandersdk.ErrorDiagnostic("InvalidBaseName", "spec.bases[%d].name: invalid base name %q", i, base.Name))
+		}
+		if bases[base.Name] {
+			return nil, expandersdk.Failed(expandersdk.ErrorDiagnostic("DuplicateBaseName", "spec.bases[%d].name: duplicate base %q", i, base.Name))
+		}
+		bases[base.Name] = true
+
+		dir := path.Join(root, "bases", base.Name)
+		k, err := parseKustomization(base.Kustomization.Raw)
+		if err != nil {
+			return nil, expandersdk.Failed(expandersdk.ErrorDiagnostic("InvalidKustomization", "spec.bases[%d].kustomization: %v", i, err))
+		}
+		if err := e.writeFiles(dir, fmt.Sprintf("spec.bases[%d].files", i), base.Files); err != nil {
+			return nil, err
+		}
+		if err := e.writeKustomization(dir, k); err != nil {
+			return nil, err
+		}
+	}
+	if err := e.writeFiles(root, "spec.resources", spec.Resources); err != nil {
+		return nil, err
+	}
+
+	for _, base := range spec.Bases {
+		addResource(kustomization, path.Join("bases", base.Name))
+	}
+	for _, resource := range spec.Resources {
+		addResource(kustomization, path.Clean(resource.FileName))
+	}
+	for _, patch := range spec.Patches {
+		kustomization.Patches = append(kustomization.Patches, toPatch(patch))
+	}
+
+	inputs, err := inputObjects(req)
+	if err != nil {
+		return nil, err
+	}
+	if len(inputs) != 0 {
+		if err := e.fs.WriteFile(path.Join(root, InputsFile), inputs); err != nil {
+			return nil, fmt.Errorf("failed to write %s: %w", InputsFile, err)
+		}
+		addResource(kustomization, InputsFile)
+	}
+	if err := e.writeKustomization(root, kustomization); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// parseKustomization rejects unknown fields, kustomize would ignore misspelled ones
+func parseKustomization(raw []byte) (*types.Kustomization, error) {
+	k := &types.Kustomization{}
+	if len(raw) != 0 {
+		decoder := json.NewDecoder(bytes.NewReader(raw))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(k); err != nil {
+			return nil, err
+		}
+	}
+	k.FixKustomization()
+	return k, nil
+}
+
+func (e *Expander) writeKustomization(dir string, k *types.Kustomization) error {
+	// Remote bases would need git and the network
+	for _, list := range [][]string{k.Resources, k.Components} {
+		for _, r := range list {
+			if !e.fs.Exists(path.Join(dir, r)) {
+				return expandersdk.Failed(expandersdk.ErrorDiagnostic("MissingResource",
+					"%s: resource %q is not in the configuration, remote resources are not supported", kustomizationPath(dir), r))
+			}
+		}
+	}
+	content, err := yaml.Marshal(k)
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s: %w", kustomizationPath(dir), err)
+	}
+	if err := e.fs.WriteFile(path.Join(dir, konfig.DefaultKustomizationFileName()), content); err != nil {
+		return fmt.Errorf("failed to write %s: %w", kustomizationPath(dir), err)
+	}
+	return nil
+}
+
+func (e *Expander) writeFiles(dir string, field string, files []kustomizeconfigurationv1alpha1.FileContent) error {
+	for i, file := range files {
+		name := path.Clean(file.FileName)
+		if file.FileName == "" || path.IsAbs(name) || name == ".." || strings.HasPrefix(name, "../") {
+			return expandersdk.Failed(expandersdk.ErrorDiagnostic("InvalidFileName", "%s[%d].name: invalid file name %q", field, i, file.FileName))
+		}
+		content := []byte(file.Template)
+		if len(file.Content.Raw) != 0 {
+			yamlContent, err := yaml.JSONToYAML(file.Content.Raw)
+			if err != nil {
+				return fmt.Errorf("failed to marshall %s file to yaml: %w", name, err)
+			}
+			content = yamlContent
+		}
+		if err := e.fs.MkdirAll(path.Dir(path.Join(dir, name))); err != nil {
+			return fmt.Errorf("failed to create the directory of %s: %w", name, err)
+		}
+		if err := e.fs.WriteFile(path.Join(dir, name), content); err != nil {
+			return fmt.Errorf("failed to write %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// kustomizationPath returns the path shown in errors, relative to the root
+func kustomizationPath(dir string) string {
+	return strings.TrimPrefix(path.Join(strings.TrimPrefix(dir, root), konfig.DefaultKustomizationFileName()), "/")
+}
+
+func addResource(k *types.Kustomization, resource string) {
+	for _, r := range k.Resources {
+		if path.Clean(r) == resource {
+			return
+		}
+	}
+	k.Resources = append(k.Resources, resource)
+}
+
+func toPatch(p kustomizeconfigurationv1alpha1.Patch) types.Patch {
+	patch := types.Patch{Patch: p.Patch}
+	if p.Target != nil {
+		patch.Target = &types.Selector{
+			ResId: resid.ResId{
+				Gvk:       resid.Gvk{Group: p.Target.Group, Version: p.Target.Version, Kind: p.Target.Kind},
+				Name:      p.Target.Name,
+				Namespace: p.Target.Namespace,
+			},
+			LabelSelector:      p.Target.LabelSelector,
+			AnnotationSelector: p.Target.AnnotationSelector,
+		}
+	}
+	return patch
+}
+
+// inputObjects returns the facade, the Context and the fetched values as a
+// YAML stream of local-config objects
+func inputObjects(req *Request) ([]byte, error) {
+	objects := []*unstructured.Unstructured{}
+	if req.Facade != nil {
+		objects = append(objects, req.Facade.DeepCopy())
+	}
+	if req.Context != nil {
+		objects = append(objects, req.Context.DeepCopy())
+	}
+	if len(req.Values) != 0 {
+		values := &unstructured.Unstructured{Object: map[string]interface{}{"spec": req.Values}}
+		values.SetAPIVersion(kustomizeconfigurationv1alpha1.GroupVersion.String())
+		values.SetKind(ValuesKind)
+		values.SetName(ValuesName)
+		objects = append(objects, values)
+	}
+
+	stream := []byte{}
+	for _, obj := range objects {
+		annotations := obj.GetAnnotations()
+		if annotations == nil {
+			annotations = map[string]string{}
+		}
+		annotations[konfig.IgnoredByKustomizeAnnotation] = "true"
+		obj.SetAnnotations(annotations)
+		obj.SetManagedFields(nil)
+
+		content, err := yaml.Marshal(obj.Object)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal %s %s: %w", obj.GetKind(), obj.GetName(), err)
+		}
+		stream = append(stream, []byte("---\n")...)
+		stream = append(stream, content...)
+	}
+	return stream, nil
+}
+
+// Build runs kustomize build on the root kustomization
+func (e *Expander) Build() ([]byte, error) {
+	k := krusty.MakeKustomizer(krusty.MakeDefaultOptions())
+	m, err := k.Run(e.fs, root)
+	if err != nil {
+		return nil, expandersdk.Failed(expandersdk.ErrorDiagnostic("KustomizeBuildFailed", "%s", strings.ReplaceAll(err.Error(), root+"/", "")))
+	}
+	return m.AsYaml()
+}
+
+// Validate builds the kustomization if a facade is passed, replacements may read from it.
+// Otherwise only the kustomizations and the files they reference are checked.
+func Validate(ctx context.Context, req *Request) (*expandersdk.Result, error) {
+	e, err := NewExpander(req)
+	if err != nil {
+		return nil, err
+	}
+	if req.Facade != nil {
+		if _, err := e.Build(); err != nil {
+			return nil, err
+		}
+	}
+	return nil, nil
+}
+
+func Evaluate(ctx context.Context, req *Request) (*expandersdk.Result, error) {
+	e, err := NewExpander(req)
+	if err != nil {
+		return nil, err
+	}
+	manifests, err := e.Build()
+	if err != nil {
+		return nil, err
+	}
+	return &expandersdk.Result{Manifests: manifests}, nil
+}
+
+// New returns the Kustomize expander
+func New() *expandersdk.Expander[kustomizeconfigurationv1alpha1.KustomizeConfiguration] {
+	return &expandersdk.Expander[kustomizeconfigurationv1alpha1.KustomizeConfiguration]{
+		Name:     "kustomize",
+		Config:   kustomizeconfigurationv1alpha1.GroupVersion.WithKind("KustomizeConfiguration"),
+		Validate: Validate,
+		Evaluate: Evaluate,
+	}
+}
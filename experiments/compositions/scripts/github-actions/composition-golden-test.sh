@@ -0,0 +1,31 @@
+#!/bin/bash
+# Copyright 2024 Google LLC
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#      http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+set -o errexit
+set -o nounset
+set -o pipefail
+
+export PATH=$PATH:$(go env GOPATH)/bin
+REPO_ROOT=$(git rev-parse --show-toplevel)
+BASE_DIR=${REPO_ROOT}/experiments/compositions
+cd ${BASE_DIR}/composition
+go build -o bin/compositions ./cmd/compositions
+
+# Golden file tests of the samples: samples/<sample>/tests next to samples/<sample>/composition
+cd ${BASE_DIR}
+for tests in samples/*/tests; do
+  for composition in $(dirname ${tests})/composition/*.yaml; do
+    ./composition/bin/compositions test --composition ${composition} ${tests}
+  done
+done
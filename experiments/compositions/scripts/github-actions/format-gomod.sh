@@ -34,4 +34,24 @@ go mod tidy
 
 cd "${BASE_DIR}"/expanders/helm-expander
 go version
+go mod tidy
+
+cd "${BASE_DIR}"/expanders/jinja2-expander
+go version
+go mod tidy
+
+cd "${BASE_DIR}"/expanders/kustomize-expander
+go version
+go mod tidy
+
+cd "${BASE_DIR}"/expanders/gotemplate-expander
+go version
+go mod tidy
+
+cd "${BASE_DIR}"/expanders/jsonnet-expander
+go version
+go mod tidy
+
+cd "${BASE_DIR}"/expanders/cue-expander
+go version
 go mod tidy
\ No newline at end of file
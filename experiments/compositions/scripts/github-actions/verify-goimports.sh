@@ -23,7 +23,7 @@ set -o pipefail
 
 # cd to the repo root
 cd experiments/compositions
-paths=("composition/." "expanders/cel-expander/." "expanders/helm-expander/.")
+paths=("composition/." "expanders/cel-expander/." "expanders/helm-expander/." "expanders/jinja2-expander/." "expanders/kustomize-expander/." "expanders/gotemplate-expander/." "expanders/jsonnet-expander/." "expanders/cue-expander/.")
 for dir in "${paths[@]}"; do
   echo "Verifying go-imports in $dir"
   files=$(go run golang.org/x/tools/cmd/goimports -format-only -l $dir)